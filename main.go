@@ -3,11 +3,17 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"mcp-mysql/service"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -24,6 +30,21 @@ var (
 	db     *sql.DB
 	cli    *milvusclient.Client
 	logger *zap.SugaredLogger
+
+	// dbConnector 是 db 连接池使用的连接器，重新连接时替换其中的 DSN；
+	// connMu 保护 cli 以及 Config.DB、Config.Milvus 中连接地址与凭据的替换
+	dbConnector *mysqlConnector
+	connMu      sync.RWMutex
+
+	// Milvus 及向量集合在首次使用时才初始化，配置有误时不影响 execute_sql 等只依赖 MySQL 的工具。
+	// 初始化失败后记录错误和时间，间隔 vectorRetryInterval 之后的调用会重新尝试
+	vectorMu       sync.Mutex
+	vectorErr      error
+	vectorFailedAt time.Time
+	vectorReady    atomic.Bool
+
+	// migrate 为 true 时，向量集合定义与配置不一致会按当前配置重建索引而不是退出
+	migrate bool
 )
 
 // AppConfig 应用配置结构体
@@ -35,16 +56,224 @@ type AppConfig struct {
 		Port     string
 		Name     string
 		Params   string
+		// OptionFile MySQL 选项文件路径，环境变量未设置的连接参数从其中的 OptionGroups 分组读取
+		OptionFile   string
+		OptionGroups []string
+		// IAMAuth 为 aws 或 gcp 时使用 IAM 令牌代替静态密码登录，令牌在过期前自动刷新
+		IAMAuth   string
+		IAMRegion string
+		// ConnectionName 连接名称，用于在执行策略文件中选择该连接的策略
+		ConnectionName string
+		PolicyFile     string
+		// ServerVersion 非空时按该版本启用兼容模式，不再检测服务端版本
+		ServerVersion string
+		// Proxy 为 auto、none、proxysql 或 vitess，经过代理时调整 processlist 和表结构读取方式
+		Proxy string
+		// DiffTargets diff_schemas 可以对比的其他数据库，名称到 DSN 的映射
+		DiffTargets map[string]string
+		// PoolWait 连接池或服务端连接数已满时排队等待的最长时间
+		PoolWait time.Duration
+		// FairSchedule 多个会话共享服务时按会话公平分配数据库访问名额
+		FairSchedule service.FairScheduleConfig
+		// ReadOnly 为 true 时只允许执行查询语句，不注册写入类工具
+		ReadOnly bool
+		// AllowedStatements 非空时只允许执行这些类型的语句
+		AllowedStatements []string
+		// DeniedStatements 禁止执行的语句类型
+		DeniedStatements []string
+		// AutoLimit 大于0时为没有 LIMIT 的 SELECT 自动追加的行数上限
+		AutoLimit int
+		// ResultLimits 为查询结果返回的行数和字节数上限
+		ResultLimits service.ResultLimitConfig
+		// QueryTimeout 为 execute_sql 的默认超时，QueryTimeoutMax 为 timeout_seconds 参数允许的上限
+		QueryTimeout    time.Duration
+		QueryTimeoutMax time.Duration
+		// PayloadGuard 允许多条语句、服务端文件访问或可执行注释的开关，默认全部拒绝
+		PayloadGuard service.PayloadGuardConfig
+		// ScopeDatabases 非空时 execute_sql 的 database 参数只能是其中的数据库
+		ScopeDatabases []string
+		// HiddenTables、HiddenSchemas 为对模型完全不可见的表和数据库
+		HiddenTables  []string
+		HiddenSchemas []string
+		// SafeDDL 为 true 时 DROP、TRUNCATE、ALTER 需要在 confirm 参数中输入目标对象名
+		SafeDDL bool
+		// MaskRules 查询结果中需要脱敏的列，MaskHashSalt 为 hash 方式的盐
+		MaskRules    []service.MaskRule
+		MaskHashSalt string
 	}
 	Milvus struct {
-		Host       string
-		Port       string
-		Collection string
+		Host             string
+		Port             string
+		Username         string
+		Password         string
+		APIKey           string
+		Collection       string
+		ConsistencyLevel string
+		SearchTimeout    time.Duration
+		RetryAttempts    int
+		RetryBackoff     time.Duration
+		KeepaliveTime    time.Duration
+		KeepaliveTimeout time.Duration
+		// 向量存储精度与索引参数
+		VectorPrecision    string
+		IndexType          string
+		IndexNList         int
+		HNSWM              int
+		HNSWEfConstruction int
 	}
 	SiliconFlow struct {
 		Token string
 		URL   string
 	}
+	Embedding struct {
+		Model          string
+		SecondaryModel string
+		SecondaryLang  string
+		SecondaryDim   int
+	}
+	LLM struct {
+		URL   string
+		Token string
+		Model string
+	}
+	Discovery struct {
+		Translate bool
+		// Async 为 true 时 get_can_use_table 默认异步检索，AsyncWait 内未完成时先返回关键词匹配
+		Async     bool
+		AsyncWait time.Duration
+		// 外部重排序服务
+		RerankURL     string
+		RerankToken   string
+		RerankTimeout time.Duration
+		// 置顶的表与表权重
+		PinnedTables []string
+		TableWeights map[string]float64
+		// 汇总表识别与聚合类问题的偏好
+		SummaryPatterns []string
+		SummaryTables   []string
+		SummaryBoost    float64
+		// MinScore 相似度阈值，所有候选都低于它时返回 no_confident_match
+		MinScore float64
+		// SchemaStrip 返回给模型的建表语句中需要去掉的部分
+		SchemaStrip []string
+		// 数据新鲜度：更新时间列与陈旧天数
+		FreshnessColumns   map[string]string
+		FreshnessStaleDays int
+		// 读取业务数据的采样语句：服务端执行时间上限与允许执行的时间窗口
+		SampleMaxExecution time.Duration
+		SampleOffPeak      string
+		// 列统计信息的采集间隔与每张表的样本行数
+		ColumnStatsInterval   time.Duration
+		ColumnStatsSampleRows int
+		// 表结构快照与 what_changed_since 的配置
+		Changelog service.ChangelogConfig
+		// 按查询历史统计热点表的范围，以及热点表在检索排序中的加分
+		HotTables service.HotTableConfig
+	}
+	LoadData struct {
+		Dir string
+	}
+	Export struct {
+		// Dir 为 CSV 导出目录
+		Dir string
+	}
+	Backup struct {
+		// Dir 为逻辑备份所在目录，非空且开启管理类工具时注册 verify_backups
+		Dir            string
+		MaxAge         time.Duration
+		Command        string
+		CommandTimeout time.Duration
+	}
+	Templates struct {
+		File   string
+		Strict bool
+	}
+	ColumnHints struct {
+		// File 表和列说明文件，为列标注语义类型
+		File string
+	}
+	Plugins struct {
+		// ExternalToolsFile 通过 HTTP 桥接的外部工具定义文件
+		ExternalToolsFile string
+	}
+	HistoryExport struct {
+		// Type 为 http、kafka 或 syslog，为空时不导出
+		Type   string
+		Target string
+		Token  string
+		Topic  string
+	}
+	Approval struct {
+		// Mode 为 confirm 时使用两阶段确认，否则在配置了 URL 时使用审批回调
+		Mode string
+		// URL 为审批回调地址，为空时危险语句不需要审批
+		URL        string
+		Token      string
+		Timeout    time.Duration
+		Statements []string
+	}
+	Admin struct {
+		// Enabled 为 true 时才注册会修改索引的管理类工具
+		Enabled bool
+		// KillQuery 为 true 时注册 kill_query 工具，并在语句超时后终止服务端仍在执行的语句
+		KillQuery bool
+	}
+	Audit struct {
+		// Enabled 为 true 时记录每条执行的语句，File 非空时同时追加写入该文件
+		Enabled bool
+		File    string
+	}
+	Tools struct {
+		// PermissionsFile 工具权限配置文件，可禁用工具并限制参数取值
+		PermissionsFile string
+		// Enabled 非空时只注册这些工具，Disabled 中的工具不注册
+		Enabled  []string
+		Disabled []string
+	}
+	Label struct {
+		Enabled  bool
+		Template string
+	}
+	Output struct {
+		// Language 为工具结果中说明性文字的语言（en 或 zh），与日志语言无关
+		Language string
+	}
+	Debug struct {
+		// Addr 调试页面的监听地址，为空时不启动；只给出端口时只监听本机
+		Addr string
+		// Token 为访问调试页面的令牌，为空时启动时随机生成
+		Token string
+	}
+	Transport struct {
+		// Type 为 stdio（默认）或 sse
+		Type string
+		// Addr、BaseURL 为 SSE 传输的监听地址和客户端访问服务使用的外部地址
+		Addr    string
+		BaseURL string
+		// AccessFile 按客户端身份分配角色的访问控制文件
+		AccessFile string
+	}
+	Scheduler struct {
+		Interval time.Duration
+		Jitter   time.Duration
+		// LockEnabled 为 true 时多个实例通过 MySQL 咨询锁协调增量索引
+		LockEnabled bool
+		// LeaseEnabled 为 true 时只有持有索引租约的实例写入向量集合
+		LeaseEnabled bool
+		InstanceID   string
+		// MemoryBudget 索引流水线中缓存的表结构总字节数上限，Workers 为并发向量化协程数
+		MemoryBudget int64
+		Workers      int
+	}
+	Transaction struct {
+		// Timeout 事务空闲超过该时长自动回滚
+		Timeout time.Duration
+	}
+	Breaker struct {
+		FailureThreshold int
+		LatencyThreshold time.Duration
+		Cooldown         time.Duration
+	}
 }
 
 // Config 全局配置实例
@@ -106,10 +335,12 @@ func initDB(dsn string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	db, err = sql.Open("mysql", dsn)
+	// 通过可替换的连接器打开连接池，reload_connections 更换凭据时不需要重建 *sql.DB
+	dbConnector, err = newMySQLConnector(dsn, &Config)
 	if err != nil {
 		return fmt.Errorf("failed to connect to MySQL: %v", err)
 	}
+	db = sql.OpenDB(dbConnector)
 
 	// 测试连接（使用带超时的上下文）
 	err = db.PingContext(ctx)
@@ -118,8 +349,8 @@ func initDB(dsn string) error {
 	}
 
 	// 设置连接池参数
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
+	db.SetMaxOpenConns(dbMaxOpenConns)
+	db.SetMaxIdleConns(dbMaxIdleConns)
 	db.SetConnMaxLifetime(time.Minute * 5) // 设置连接最大生命周期
 	db.SetConnMaxIdleTime(time.Minute * 2) // 设置空闲连接最大生命周期
 
@@ -127,20 +358,85 @@ func initDB(dsn string) error {
 }
 
 func initMilvus(ctx context.Context) error {
-	milvusAddress := Config.Milvus.Host + ":" + Config.Milvus.Port
-	var err error
-	// 设置连接超时上下文
-	connCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	cli, err = milvusclient.New(connCtx, &milvusclient.ClientConfig{
-		Address: milvusAddress,
-	})
+	cfg := connectionConfig()
+	client, err := newMilvusClient(ctx, &cfg)
 	if err != nil {
-		return fmt.Errorf("failed to connect to Milvus: %v", err)
+		return err
 	}
+	connMu.Lock()
+	cli = client
+	connMu.Unlock()
 
 	service.InitMilvusConfig(Config.Milvus.Collection)
+
+	level, err := service.ParseConsistencyLevel(Config.Milvus.ConsistencyLevel)
+	if err != nil {
+		return err
+	}
+	service.InitMilvusCallConfig(service.MilvusCallConfig{
+		ConsistencyLevel: level,
+		SearchTimeout:    Config.Milvus.SearchTimeout,
+		RetryAttempts:    Config.Milvus.RetryAttempts,
+		RetryBackoff:     Config.Milvus.RetryBackoff,
+	})
+
+	return service.InitVectorIndexConfig(service.VectorIndexConfig{
+		Precision:          Config.Milvus.VectorPrecision,
+		IndexType:          Config.Milvus.IndexType,
+		NList:              Config.Milvus.IndexNList,
+		HNSWM:              Config.Milvus.HNSWM,
+		HNSWEfConstruction: Config.Milvus.HNSWEfConstruction,
+	})
+}
+
+// vectorRetryInterval 向量检索初始化失败后，再次尝试前的最短间隔，避免每次工具调用都等待连接超时
+const vectorRetryInterval = 30 * time.Second
+
+// getVectorClient 返回 Milvus 客户端，首次调用时连接 Milvus、准备向量集合并启动定时更新。
+// 初始化失败时在 vectorRetryInterval 内直接返回上次的错误，之后的调用重新尝试，Milvus 恢复后无需重启
+func getVectorClient() (*milvusclient.Client, error) {
+	if vectorReady.Load() {
+		return vectorClient(), nil
+	}
+	vectorMu.Lock()
+	defer vectorMu.Unlock()
+	if vectorReady.Load() {
+		return vectorClient(), nil
+	}
+	if vectorErr != nil && time.Since(vectorFailedAt) < vectorRetryInterval {
+		return nil, fmt.Errorf("向量检索不可用，execute_sql 等工具不受影响: %w", vectorErr)
+	}
+
+	if err := initVectorSearch(); err != nil {
+		vectorErr, vectorFailedAt = err, time.Now()
+		return nil, fmt.Errorf("向量检索不可用，execute_sql 等工具不受影响: %w", err)
+	}
+	vectorErr = nil
+	vectorReady.Store(true)
+	logger.Info("向量检索初始化完成")
+	return vectorClient(), nil
+}
+
+// initVectorSearch 连接 Milvus 并准备向量集合，成功后启动定时更新。
+// 连接限时 10 秒，首次启动需要全量向量化，准备集合使用宽松的超时
+func initVectorSearch() error {
+	logger.Info("正在初始化向量检索...")
+	if vectorClient() == nil {
+		connCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := initMilvus(connCtx); err != nil {
+			return fmt.Errorf("Milvus初始化失败: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := initVectorDB(ctx, vectorClient()); err != nil {
+		return fmt.Errorf("向量数据库初始化失败: %w", err)
+	}
+	// 索引完成后输出结构统计摘要，便于确认采集到的表是否符合预期
+	service.LogSchemaStats(ctx, db)
+	go service.UpdateSchema(db, vectorClient)
 	return nil
 }
 
@@ -151,76 +447,45 @@ func initVectorDB(ctx context.Context, cli *milvusclient.Client) error {
 	}
 
 	if !hasCollection {
-		err = service.CreateCollection(ctx, cli, service.Config.CollectionName)
+		if !service.IsIndexWriter() {
+			return fmt.Errorf("向量集合 %s 不存在，本实例为只读消费者，需等待持有索引租约的实例创建", Config.Milvus.Collection)
+		}
+		// 首次启动时同样以蓝绿方式构建，集合名称作为别名指向实际集合
+		res, err := service.Reindex(ctx, db, cli)
 		if err != nil {
-			return fmt.Errorf("CreateCollection failed: %v", err)
+			return fmt.Errorf("Reindex failed: %v", err)
 		}
-
-		// 创建带缓冲的通道
-		schemaChan := make(chan map[string]string, 10)
-
-		// 创建子上下文用于控制goroutine生命周期
-		workCtx, workCancel := context.WithCancel(ctx)
-		defer workCancel() // 确保函数退出时取消所有子goroutine
-
-		// 启动一个协程获取所有表结构
-		go func() {
-			service.GetAllTableSchema(workCtx, db, schemaChan)
-		}()
-
-		// 创建工作池处理表结构
-		var wg sync.WaitGroup
-		const maxWorkers = 5
-
-		// 信号量控制并发数
-		semaphore := make(chan struct{}, maxWorkers)
-
-		// 处理表结构
-		for tableMap := range schemaChan {
-			select {
-			case <-ctx.Done():
-				logger.Info("上下文取消，停止处理表结构")
-				return ctx.Err()
-			default:
-				if len(tableMap) == 0 {
-					continue
-				}
-
-				// 获取信号量
-				semaphore <- struct{}{}
-
-				wg.Add(1)
-				go func(s map[string]string) {
-					defer wg.Done()
-					defer func() { <-semaphore }() // 释放信号量
-
-					// 检查上下文是否已取消
-					select {
-					case <-workCtx.Done():
-						return
-					default:
-						// 继续处理
-					}
-					for _, schema := range s {
-						vectors, err := service.EmbedQuery(schema)
-						if err != nil {
-							logger.Errorw("向量嵌入失败", "error", err)
-							return
-						}
-
-						err = service.SaveToVDB(workCtx, cli, []string{schema}, [][]float32{vectors})
-						if err != nil {
-							logger.Errorw("保存向量失败", "error", err)
-						}
-					}
-
-				}(tableMap)
+		logger.Info(res)
+	} else {
+		// 集合已存在时校验其定义与当前配置一致，避免之后在写入或检索时才报出笼统的错误
+		mismatches, err := service.ValidateCollectionSchema(ctx, cli)
+		if err != nil {
+			return err
+		}
+		if len(mismatches) > 0 {
+			for _, m := range mismatches {
+				logger.Warnw("向量集合定义与配置不一致", "collection", Config.Milvus.Collection, "detail", m)
+			}
+			if !migrate {
+				return fmt.Errorf("向量集合 %s 的定义与当前配置不一致（%s），"+
+					"请使用 --migrate 参数启动以按当前配置重建索引，或恢复原有配置",
+					Config.Milvus.Collection, strings.Join(mismatches, "; "))
 			}
+			logger.Info("已指定 --migrate，按当前配置重建向量索引...")
+			res, err := service.Reindex(ctx, db, cli)
+			if err != nil {
+				return fmt.Errorf("Reindex failed: %v", err)
+			}
+			logger.Info(res)
 		}
+	}
 
-		// 等待所有工作完成
-		wg.Wait()
-		logger.Info("所有表结构向量化处理完成")
+	// 历史SQL集合用于相似查询检索，只读消费者不创建集合
+	if !service.IsIndexWriter() {
+		return nil
+	}
+	if err = service.EnsureQueryCollection(ctx, cli); err != nil {
+		return fmt.Errorf("EnsureQueryCollection failed: %v", err)
 	}
 
 	return nil
@@ -228,23 +493,225 @@ func initVectorDB(ctx context.Context, cli *milvusclient.Client) error {
 
 // 从配置加载环境变量
 func loadConfig() error {
+	// 加载数据库和Milvus的连接地址与凭据
+	if err := loadConnectionConfig(&Config); err != nil {
+		return err
+	}
+
 	// 加载数据库配置
-	Config.DB.User = os.Getenv("DB_USER")
-	Config.DB.Password = os.Getenv("DB_PASSWORD")
-	Config.DB.Host = os.Getenv("DB_HOST")
-	Config.DB.Port = os.Getenv("DB_PORT")
-	Config.DB.Name = os.Getenv("DB_NAME")
-	Config.DB.Params = os.Getenv("DB_PARAMS")
+	Config.DB.ConnectionName = os.Getenv("DB_CONNECTION_NAME")
+	Config.DB.PolicyFile = os.Getenv("DB_POLICY_FILE")
+	Config.DB.ServerVersion = os.Getenv("DB_SERVER_VERSION")
+	Config.DB.Proxy = os.Getenv("DB_PROXY")
+	diffTargets, err := service.ParseSchemaDiffTargets(os.Getenv("SCHEMA_DIFF_TARGETS"))
+	if err != nil {
+		return fmt.Errorf("SCHEMA_DIFF_TARGETS 配置错误: %v", err)
+	}
+	Config.DB.DiffTargets = diffTargets
+	Config.DB.FairSchedule = service.FairScheduleConfig{
+		Slots:        getEnvInt("DB_FAIR_SLOTS", 0),
+		SessionSlots: getEnvInt("DB_FAIR_SESSION_SLOTS", 0),
+	}
+	Config.DB.PoolWait = time.Duration(getEnvInt("DB_POOL_WAIT_MS", 5000)) * time.Millisecond
+	Config.DB.ReadOnly = os.Getenv("READONLY") == "true"
+	Config.DB.AllowedStatements = splitList(os.Getenv("DB_ALLOWED_STATEMENTS"))
+	Config.DB.DeniedStatements = splitList(os.Getenv("DB_DENIED_STATEMENTS"))
+	Config.DB.AutoLimit = getEnvInt("DB_AUTO_LIMIT", 0)
+	Config.DB.QueryTimeout = time.Duration(getEnvInt("QUERY_TIMEOUT_SECONDS", 30)) * time.Second
+	Config.DB.QueryTimeoutMax = time.Duration(getEnvInt("QUERY_TIMEOUT_MAX_SECONDS", 300)) * time.Second
+	if Config.DB.QueryTimeout <= 0 {
+		Config.DB.QueryTimeout = 30 * time.Second
+	}
+	Config.DB.QueryTimeoutMax = max(Config.DB.QueryTimeoutMax, Config.DB.QueryTimeout)
+	Config.DB.ResultLimits = service.ResultLimitConfig{
+		MaxRows:  getEnvInt("MAX_RESULT_ROWS", 10000),
+		MaxBytes: getEnvInt("MAX_RESULT_BYTES", 1<<20),
+	}
+	Config.DB.ScopeDatabases = splitList(os.Getenv("DB_SCOPE_DATABASES"))
+	Config.DB.HiddenTables = splitList(os.Getenv("HIDDEN_TABLES"))
+	Config.DB.HiddenSchemas = splitList(os.Getenv("HIDDEN_SCHEMAS"))
+	Config.DB.SafeDDL = os.Getenv("DDL_SAFE_MODE") == "true"
+	Config.DB.PayloadGuard.AllowMultiStatements = os.Getenv("SQL_ALLOW_MULTI_STATEMENTS") == "true"
+	Config.DB.PayloadGuard.AllowFileAccess = os.Getenv("SQL_ALLOW_FILE_ACCESS") == "true"
+	Config.DB.PayloadGuard.AllowExecutableComments = os.Getenv("SQL_ALLOW_EXECUTABLE_COMMENTS") == "true"
+	maskRules, err := service.ParseMaskRules(os.Getenv("MASK_COLUMNS"))
+	if err != nil {
+		return fmt.Errorf("MASK_COLUMNS 配置错误: %v", err)
+	}
+	Config.DB.MaskRules = maskRules
+	Config.DB.MaskHashSalt = os.Getenv("MASK_HASH_SALT")
 
 	// 加载Milvus配置
-	Config.Milvus.Host = os.Getenv("MILVUS_HOST")
-	Config.Milvus.Port = os.Getenv("MILVUS_PORT")
 	Config.Milvus.Collection = os.Getenv("MILVUS_COLLECTION")
+	Config.Milvus.ConsistencyLevel = os.Getenv("MILVUS_CONSISTENCY_LEVEL")
+	Config.Milvus.SearchTimeout = time.Duration(getEnvInt("MILVUS_SEARCH_TIMEOUT_MS", 0)) * time.Millisecond
+	Config.Milvus.RetryAttempts = getEnvInt("MILVUS_RETRY_ATTEMPTS", 1)
+	Config.Milvus.RetryBackoff = time.Duration(getEnvInt("MILVUS_RETRY_BACKOFF_MS", 200)) * time.Millisecond
+	Config.Milvus.KeepaliveTime = time.Duration(getEnvInt("MILVUS_KEEPALIVE_TIME_SECONDS", 30)) * time.Second
+	Config.Milvus.KeepaliveTimeout = time.Duration(getEnvInt("MILVUS_KEEPALIVE_TIMEOUT_SECONDS", 10)) * time.Second
+	Config.Milvus.VectorPrecision = os.Getenv("MILVUS_VECTOR_PRECISION")
+	Config.Milvus.IndexType = os.Getenv("MILVUS_INDEX_TYPE")
+	Config.Milvus.IndexNList = getEnvInt("MILVUS_INDEX_NLIST", 128)
+	Config.Milvus.HNSWM = getEnvInt("MILVUS_HNSW_M", 16)
+	Config.Milvus.HNSWEfConstruction = getEnvInt("MILVUS_HNSW_EF_CONSTRUCTION", 200)
 
 	// 加载SiliconFlow配置
-	Config.SiliconFlow.Token = os.Getenv("SILICONFLOW_TOKEN")
+	Config.SiliconFlow.Token, err = service.ResolveSecret("SILICONFLOW_TOKEN")
+	if err != nil {
+		return err
+	}
 	Config.SiliconFlow.URL = os.Getenv("SILICONFLOW_URL")
 
+	// 加载嵌入模型配置
+	Config.Embedding.Model = os.Getenv("EMBEDDING_MODEL")
+	Config.Embedding.SecondaryModel = os.Getenv("EMBEDDING_SECONDARY_MODEL")
+	Config.Embedding.SecondaryLang = os.Getenv("EMBEDDING_SECONDARY_LANG")
+	if Config.Embedding.SecondaryLang == "" {
+		Config.Embedding.SecondaryLang = "en"
+	}
+	Config.Embedding.SecondaryDim = getEnvInt("EMBEDDING_SECONDARY_DIM", 1024)
+
+	// 加载LLM配置（OpenAI 兼容的对话接口），未单独配置令牌时复用 SiliconFlow 令牌
+	Config.LLM.URL = os.Getenv("LLM_URL")
+	Config.LLM.Token, err = service.ResolveSecret("LLM_TOKEN")
+	if err != nil {
+		return err
+	}
+	if Config.LLM.Token == "" {
+		Config.LLM.Token = Config.SiliconFlow.Token
+	}
+	Config.LLM.Model = os.Getenv("LLM_MODEL")
+
+	// 加载检索配置
+	Config.Discovery.Translate = os.Getenv("DISCOVERY_TRANSLATE") == "true"
+	Config.Discovery.Async = os.Getenv("DISCOVERY_ASYNC") == "true"
+	Config.Discovery.AsyncWait = time.Duration(getEnvInt("DISCOVERY_ASYNC_WAIT_MS", 1500)) * time.Millisecond
+	Config.Discovery.RerankURL = os.Getenv("RERANK_URL")
+	Config.Discovery.RerankToken, err = service.ResolveSecret("RERANK_TOKEN")
+	if err != nil {
+		return err
+	}
+	Config.Discovery.RerankTimeout = time.Duration(getEnvInt("RERANK_TIMEOUT_MS", 5000)) * time.Millisecond
+	Config.Discovery.PinnedTables = splitList(os.Getenv("DISCOVERY_PINNED_TABLES"))
+	Config.Discovery.SummaryPatterns = splitList(os.Getenv("DISCOVERY_SUMMARY_PATTERNS"))
+	Config.Discovery.SummaryTables = splitList(os.Getenv("DISCOVERY_SUMMARY_TABLES"))
+	Config.Discovery.SummaryBoost = 1.3
+	if v := os.Getenv("DISCOVERY_SUMMARY_BOOST"); v != "" {
+		boost, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("DISCOVERY_SUMMARY_BOOST 配置错误: %v", err)
+		}
+		Config.Discovery.SummaryBoost = boost
+	}
+	Config.Discovery.MinScore = 0.3
+	if v := os.Getenv("DISCOVERY_MIN_SCORE"); v != "" {
+		minScore, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("DISCOVERY_MIN_SCORE 配置错误: %v", err)
+		}
+		Config.Discovery.MinScore = minScore
+	}
+	Config.Discovery.SchemaStrip = splitList(os.Getenv("SCHEMA_DDL_STRIP"))
+	freshnessColumns, err := service.ParseFreshnessColumns(os.Getenv("DISCOVERY_FRESHNESS_COLUMNS"))
+	if err != nil {
+		return fmt.Errorf("DISCOVERY_FRESHNESS_COLUMNS 配置错误: %v", err)
+	}
+	Config.Discovery.FreshnessColumns = freshnessColumns
+	Config.Discovery.FreshnessStaleDays = getEnvInt("DISCOVERY_STALE_DAYS", 90)
+	Config.Discovery.SampleMaxExecution = time.Duration(getEnvInt("SAMPLING_MAX_EXECUTION_MS", 0)) * time.Millisecond
+	Config.Discovery.SampleOffPeak = os.Getenv("SAMPLING_OFF_PEAK_WINDOW")
+	Config.Discovery.ColumnStatsInterval = time.Duration(getEnvInt("COLUMN_STATS_INTERVAL_MINUTES", 1440)) * time.Minute
+	Config.Discovery.ColumnStatsSampleRows = getEnvInt("COLUMN_STATS_SAMPLE_ROWS", 10000)
+	Config.Discovery.Changelog = service.ChangelogConfig{
+		Interval:        time.Duration(getEnvInt("SCHEMA_SNAPSHOT_INTERVAL_MINUTES", 360)) * time.Minute,
+		Retention:       time.Duration(getEnvInt("SCHEMA_SNAPSHOT_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		VolumeShift:     float64(getEnvInt("CHANGELOG_VOLUME_SHIFT_PERCENT", 20)) / 100,
+		Binlog:          os.Getenv("CHANGELOG_BINLOG") == "true",
+		BinlogMaxEvents: getEnvInt("CHANGELOG_BINLOG_MAX_EVENTS", 10000),
+	}
+	Config.Discovery.HotTables = service.HotTableConfig{
+		Window: time.Duration(getEnvInt("HOT_TABLE_WINDOW_DAYS", 7)) * 24 * time.Hour,
+		Boost:  float64(getEnvInt("HOT_TABLE_BOOST_PERCENT", 10)) / 100,
+		SlowMs: int64(getEnvInt("HOT_TABLE_SLOW_MS", 1000)),
+	}
+	weights, err := service.ParseTableWeights(os.Getenv("DISCOVERY_TABLE_WEIGHTS"))
+	if err != nil {
+		return fmt.Errorf("DISCOVERY_TABLE_WEIGHTS 配置错误: %v", err)
+	}
+	Config.Discovery.TableWeights = weights
+
+	// 加载语句标识配置，默认开启
+	Config.Label.Enabled = os.Getenv("SQL_COMMENT_ENABLED") != "false"
+	Config.Label.Template = os.Getenv("SQL_COMMENT_TEMPLATE")
+
+	// 加载熔断器配置
+	Config.Breaker.FailureThreshold = getEnvInt("BREAKER_FAILURE_THRESHOLD", 5)
+	Config.Breaker.LatencyThreshold = time.Duration(getEnvInt("BREAKER_LATENCY_THRESHOLD_MS", 10000)) * time.Millisecond
+	Config.Breaker.Cooldown = time.Duration(getEnvInt("BREAKER_COOLDOWN_SECONDS", 30)) * time.Second
+
+	// 加载定时增量索引配置
+	Config.Scheduler.Interval = time.Duration(getEnvInt("INDEX_UPDATE_INTERVAL_SECONDS", 300)) * time.Second
+	Config.Scheduler.Jitter = time.Duration(getEnvInt("INDEX_UPDATE_JITTER_SECONDS", 60)) * time.Second
+	Config.Scheduler.LockEnabled = os.Getenv("INDEX_UPDATE_LOCK") != "false"
+	Config.Scheduler.LeaseEnabled = os.Getenv("INDEX_LEASE_ENABLED") == "true"
+	Config.Scheduler.InstanceID = os.Getenv("INSTANCE_ID")
+	Config.Scheduler.MemoryBudget = int64(getEnvInt("INDEX_MEMORY_BUDGET_MB", 64)) << 20
+	Config.Scheduler.Workers = getEnvInt("INDEX_WORKERS", 5)
+	if Config.Scheduler.InstanceID == "" {
+		Config.Scheduler.InstanceID = service.DefaultInstanceID()
+	}
+
+	// 加载事务配置
+	Config.Transaction.Timeout = time.Duration(getEnvInt("TRANSACTION_TIMEOUT_SECONDS", 300)) * time.Second
+
+	// 加载批量导入配置，未设置目录时不启用 LOAD DATA
+	Config.LoadData.Dir = os.Getenv("LOAD_DATA_DIR")
+	Config.Export.Dir = os.Getenv("EXPORT_DIR")
+	Config.Backup.Dir = os.Getenv("BACKUP_DIR")
+	Config.Backup.MaxAge = time.Duration(getEnvInt("BACKUP_MAX_AGE_HOURS", 26)) * time.Hour
+	Config.Backup.Command = os.Getenv("BACKUP_COMMAND")
+	Config.Backup.CommandTimeout = time.Duration(getEnvInt("BACKUP_COMMAND_TIMEOUT_SECONDS", 3600)) * time.Second
+
+	// 加载查询历史导出配置
+	Config.HistoryExport.Type = os.Getenv("HISTORY_EXPORT_TYPE")
+	Config.HistoryExport.Target = os.Getenv("HISTORY_EXPORT_TARGET")
+	Config.HistoryExport.Token = os.Getenv("HISTORY_EXPORT_TOKEN")
+	Config.HistoryExport.Topic = os.Getenv("HISTORY_EXPORT_TOPIC")
+
+	// 加载危险语句审批配置
+	Config.Approval.Mode = strings.ToLower(os.Getenv("APPROVAL_MODE"))
+	Config.Approval.URL = os.Getenv("APPROVAL_WEBHOOK_URL")
+	Config.Approval.Token = os.Getenv("APPROVAL_WEBHOOK_TOKEN")
+	Config.Approval.Timeout = time.Duration(getEnvInt("APPROVAL_TIMEOUT_SECONDS", 300)) * time.Second
+	Config.Approval.Statements = splitList(os.Getenv("APPROVAL_STATEMENTS"))
+
+	Config.Admin.Enabled = os.Getenv("ADMIN_TOOLS_ENABLED") == "true"
+	Config.Admin.KillQuery = os.Getenv("KILL_QUERY_ENABLED") == "true"
+	Config.Tools.PermissionsFile = os.Getenv("TOOL_PERMISSIONS_FILE")
+	Config.Audit.Enabled = os.Getenv("AUDIT_LOG_ENABLED") != "false"
+	Config.Audit.File = os.Getenv("AUDIT_LOG_FILE")
+	Config.Tools.Enabled = splitList(os.Getenv("TOOLS_ENABLED"))
+	Config.Tools.Disabled = splitList(os.Getenv("TOOLS_DISABLED"))
+	Config.Debug.Addr = os.Getenv("DEBUG_ADDR")
+	if Config.Debug.Token, err = service.ResolveSecret("DEBUG_TOKEN"); err != nil {
+		return err
+	}
+	Config.Output.Language = os.Getenv("OUTPUT_LANGUAGE")
+	Config.Transport.Type = os.Getenv("MCP_TRANSPORT")
+	if Config.Transport.Type == "" {
+		Config.Transport.Type = "stdio"
+	}
+	Config.Transport.Addr = os.Getenv("MCP_SSE_ADDR")
+	if Config.Transport.Addr == "" {
+		Config.Transport.Addr = ":8080"
+	}
+	Config.Transport.BaseURL = os.Getenv("MCP_SSE_BASE_URL")
+	Config.Transport.AccessFile = os.Getenv("ACCESS_CONTROL_FILE")
+	Config.Templates.File = os.Getenv("QUERY_TEMPLATES_FILE")
+	Config.Templates.Strict = os.Getenv("QUERY_TEMPLATE_STRICT") == "true"
+	Config.Plugins.ExternalToolsFile = os.Getenv("EXTERNAL_TOOLS_FILE")
+	Config.ColumnHints.File = os.Getenv("COLUMN_DESCRIPTIONS_FILE")
+
 	// 验证必要的配置
 	if Config.DB.User == "" || Config.DB.Host == "" || Config.DB.Name == "" {
 		return fmt.Errorf("数据库配置不完整")
@@ -256,24 +723,160 @@ func loadConfig() error {
 	return nil
 }
 
+// appendDSNParam 在连接参数中追加一项，已经设置的参数保持不变
+func appendDSNParam(params, key, value string) string {
+	if strings.Contains(params, key+"=") {
+		return params
+	}
+	if params != "" {
+		params += "&"
+	}
+	return params + key + "=" + value
+}
+
+// loadConnectionConfig 将数据库和Milvus的连接地址与凭据读取到 cfg。reload_connections 在副本上读取，
+// 连接重建后再在 connMu 下替换全局配置
+func loadConnectionConfig(cfg *AppConfig) error {
+	// 凭据可以来自环境变量、<NAME>_FILE 指定的文件或 Vault
+	vaultToken, err := service.ResolveSecret("VAULT_TOKEN")
+	if err != nil {
+		return err
+	}
+	service.InitSecrets(service.SecretsConfig{
+		VaultAddr:      os.Getenv("VAULT_ADDR"),
+		VaultToken:     vaultToken,
+		VaultNamespace: os.Getenv("VAULT_NAMESPACE"),
+		Timeout:        time.Duration(getEnvInt("VAULT_TIMEOUT_SECONDS", 10)) * time.Second,
+	})
+	for field, name := range map[*string]string{
+		&cfg.DB.Password:     "DB_PASSWORD",
+		&cfg.Milvus.Username: "MILVUS_USERNAME",
+		&cfg.Milvus.Password: "MILVUS_PASSWORD",
+		&cfg.Milvus.APIKey:   "MILVUS_TOKEN",
+	} {
+		if *field, err = service.ResolveSecret(name); err != nil {
+			return err
+		}
+	}
+
+	cfg.DB.User = os.Getenv("DB_USER")
+	cfg.DB.Host = os.Getenv("DB_HOST")
+	cfg.DB.Port = os.Getenv("DB_PORT")
+	cfg.DB.Name = os.Getenv("DB_NAME")
+	cfg.DB.Params = os.Getenv("DB_PARAMS")
+	cfg.Milvus.Host = os.Getenv("MILVUS_HOST")
+	cfg.Milvus.Port = os.Getenv("MILVUS_PORT")
+	cfg.DB.IAMAuth = os.Getenv("DB_IAM_AUTH")
+	cfg.DB.IAMRegion = os.Getenv("DB_IAM_REGION")
+	if cfg.DB.IAMRegion == "" {
+		cfg.DB.IAMRegion = os.Getenv("AWS_REGION")
+	}
+
+	// 选项文件只补齐环境变量中没有设置的参数
+	cfg.DB.OptionFile = os.Getenv("DB_OPTION_FILE")
+	cfg.DB.OptionGroups = splitList(os.Getenv("DB_OPTION_GROUPS"))
+	if len(cfg.DB.OptionGroups) == 0 {
+		cfg.DB.OptionGroups = []string{"client"}
+	}
+	if cfg.DB.OptionFile == "" {
+		return nil
+	}
+	options, err := service.ReadOptionFile(cfg.DB.OptionFile, cfg.DB.OptionGroups...)
+	if err != nil {
+		return fmt.Errorf("DB_OPTION_FILE 读取失败: %v", err)
+	}
+	for field, key := range map[*string]string{
+		&cfg.DB.User:     "user",
+		&cfg.DB.Password: "password",
+		&cfg.DB.Host:     "host",
+		&cfg.DB.Port:     "port",
+		&cfg.DB.Name:     "database",
+	} {
+		if *field == "" {
+			*field = options[key]
+		}
+	}
+	return nil
+}
+
+// envFilePath 返回可执行文件所在目录下的 .env 文件路径
+func envFilePath() string {
+	return filepath.Join(filepath.Dir(os.Args[0]), ".env")
+}
+
+// getEnvInt 读取整数类型的环境变量，未设置或格式错误时返回默认值
+func getEnvInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		logger.Warnf("环境变量 %s 不是有效整数(%s)，使用默认值 %d", key, val, def)
+		return def
+	}
+	return n
+}
+
 // 从配置构建DSN字符串
-func buildDSNFromConfig() string {
+func buildDSNFromConfig(cfg *AppConfig) string {
 	// 构建DSN字符串
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-		Config.DB.User,
-		Config.DB.Password,
-		Config.DB.Host,
-		Config.DB.Port,
-		Config.DB.Name)
-
-	if Config.DB.Params != "" {
-		dsn += "?" + Config.DB.Params
+		cfg.DB.User,
+		cfg.DB.Password,
+		cfg.DB.Host,
+		cfg.DB.Port,
+		cfg.DB.Name)
+
+	params := cfg.DB.Params
+	// 设置连接属性，便于在 performance_schema.session_connect_attrs 中识别本服务的连接
+	params = appendDSNParam(params, "connectionAttributes", "program_name:mcp-mysql")
+	// IAM 令牌以明文方式发送，必须通过 TLS 连接
+	if cfg.DB.IAMAuth != "" {
+		params = appendDSNParam(params, "allowCleartextPasswords", "true")
+		params = appendDSNParam(params, "tls", "true")
 	}
+	dsn += "?" + params
 
 	return dsn
 }
 
+// runBenchRetrieval 执行检索评测并将报告以 JSON 输出到标准输出
+func runBenchRetrieval(ctx context.Context, path string) error {
+	suite, err := service.LoadBenchSuite(path)
+	if err != nil {
+		return err
+	}
+	logger.Infof("开始检索评测: %s, 查询数: %d, k: %d", path, len(suite.Queries), suite.K)
+
+	vc, err := getVectorClient()
+	if err != nil {
+		return err
+	}
+	report := service.RunRetrievalBench(ctx, vc, suite)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report to JSON: %v", err)
+	}
+	fmt.Println(string(data))
+	fmt.Printf("hit@%d: %.3f  MRR: %.3f  errors: %d\n", report.K, report.HitAtK, report.MRR, report.Errors)
+	return nil
+}
+
+// hasArg 判断命令行参数中是否包含指定开关。
+// MCP 客户端可能会传入任意参数，因此不使用 flag 包，避免遇到未知参数时直接退出
+func hasArg(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "-"+name || arg == "--"+name {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
+	migrate = hasArg("migrate")
+
 	// 创建根上下文
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -286,7 +889,7 @@ func main() {
 	defer logger.Sync() // 确保缓冲的日志被写入
 
 	// 加载.env文件
-	envPath := filepath.Join(filepath.Dir(os.Args[0]), ".env")
+	envPath := envFilePath()
 	err := godotenv.Load(envPath)
 	if err != nil {
 		logger.Warnf("无法加载.env文件(%s): %v，尝试使用环境变量", envPath, err)
@@ -297,120 +900,2201 @@ func main() {
 		logger.Fatalf("配置加载失败: %v", err)
 	}
 
+	if err = service.InitEmbeddingConfig(service.EmbeddingConfig{
+		Model:          Config.Embedding.Model,
+		SecondaryModel: Config.Embedding.SecondaryModel,
+		SecondaryLang:  Config.Embedding.SecondaryLang,
+		SecondaryDim:   Config.Embedding.SecondaryDim,
+	}); err != nil {
+		logger.Fatalf("嵌入模型配置错误: %v", err)
+	}
+	// 令牌可能来自 SILICONFLOW_TOKEN_FILE 或 Vault，使用解析后的值而不是直接读取环境变量
+	service.SetEmbeddingEndpoint(Config.SiliconFlow.URL, Config.SiliconFlow.Token)
+	service.InitLLMConfig(Config.LLM.URL, Config.LLM.Token, Config.LLM.Model)
+	service.InitRankingConfig(Config.Discovery.PinnedTables, Config.Discovery.TableWeights)
+	service.InitSummaryTableConfig(service.SummaryTableConfig{
+		Patterns: Config.Discovery.SummaryPatterns,
+		Tables:   Config.Discovery.SummaryTables,
+		Boost:    Config.Discovery.SummaryBoost,
+	})
+	service.InitFreshnessConfig(service.FreshnessConfig{
+		Columns:   Config.Discovery.FreshnessColumns,
+		StaleDays: Config.Discovery.FreshnessStaleDays,
+	})
+	service.InitColumnStatsConfig(service.ColumnStatsConfig{
+		Interval:   Config.Discovery.ColumnStatsInterval,
+		SampleRows: Config.Discovery.ColumnStatsSampleRows,
+	})
+	service.InitChangelogConfig(Config.Discovery.Changelog)
+	service.InitHotTables(Config.Discovery.HotTables)
+	if err = service.InitLowPriority(service.LowPriorityConfig{
+		MaxExecutionTime: Config.Discovery.SampleMaxExecution,
+		OffPeak:          Config.Discovery.SampleOffPeak,
+	}); err != nil {
+		logger.Fatalf("SAMPLING_OFF_PEAK_WINDOW 配置错误: %v", err)
+	}
+	if Config.Discovery.RerankURL != "" {
+		service.SetReranker(&service.HTTPReranker{
+			URL:     Config.Discovery.RerankURL,
+			Token:   Config.Discovery.RerankToken,
+			Timeout: Config.Discovery.RerankTimeout,
+		})
+	}
+	if Config.HistoryExport.Type != "" {
+		exporter, err := service.NewHistoryExporter(Config.HistoryExport.Type, Config.HistoryExport.Target,
+			Config.HistoryExport.Token, Config.HistoryExport.Topic)
+		if err != nil {
+			logger.Fatalf("查询历史导出配置错误: %v", err)
+		}
+		service.SetHistoryExporter(exporter)
+	}
+	if Config.Approval.Mode == "confirm" {
+		service.SetApprover(service.NewConfirmApprover(), service.ApprovalConfig{
+			Statements: Config.Approval.Statements,
+			Timeout:    Config.Approval.Timeout,
+		})
+	} else if Config.Approval.URL != "" {
+		service.SetApprover(&service.WebhookApprover{
+			URL:   Config.Approval.URL,
+			Token: Config.Approval.Token,
+		}, service.ApprovalConfig{
+			Statements: Config.Approval.Statements,
+			Timeout:    Config.Approval.Timeout,
+		})
+	}
+	service.InitLabelConfig(Config.Label.Enabled, Config.Label.Template)
+	if err = service.InitQueryTemplates(Config.Templates.File, Config.Templates.Strict); err != nil {
+		logger.Fatalf("查询模板加载失败: %v", err)
+	}
+	if err = service.InitColumnHints(Config.ColumnHints.File); err != nil {
+		logger.Fatalf("列说明加载失败: %v", err)
+	}
+	if err = service.LoadExternalTools(Config.Plugins.ExternalToolsFile); err != nil {
+		logger.Fatalf("外部工具加载失败: %v", err)
+	}
+	if err = service.InitToolPermissions(Config.Tools.PermissionsFile, Config.Tools.Enabled, Config.Tools.Disabled); err != nil {
+		logger.Fatalf("工具权限加载失败: %v", err)
+	}
+	if err = service.InitOutputLanguage(Config.Output.Language); err != nil {
+		logger.Fatalf("OUTPUT_LANGUAGE 配置错误: %v", err)
+	}
+	if err = validateTransport(); err != nil {
+		logger.Fatalf("MCP_TRANSPORT 配置错误: %v", err)
+	}
+	if err = service.InitAccessControl(Config.Transport.AccessFile); err != nil {
+		logger.Fatalf("访问控制加载失败: %v", err)
+	}
+	if err = service.InitAudit(service.AuditConfig{Enabled: Config.Audit.Enabled, File: Config.Audit.File}); err != nil {
+		logger.Fatalf("语句审计初始化失败: %v", err)
+	}
+	if err = service.InitPolicies(Config.DB.PolicyFile, Config.DB.ConnectionName); err != nil {
+		logger.Fatalf("执行策略加载失败: %v", err)
+	}
+	service.InitReadOnlyMode(Config.DB.ReadOnly)
+	service.InitStatementRules(Config.DB.AllowedStatements, Config.DB.DeniedStatements)
+	service.InitAutoLimit(Config.DB.AutoLimit)
+	service.InitResultLimits(Config.DB.ResultLimits)
+	if err = service.InitSchemaNormalize(Config.Discovery.SchemaStrip); err != nil {
+		logger.Fatalf("SCHEMA_DDL_STRIP 配置错误: %v", err)
+	}
+	service.InitDatabaseScope(Config.DB.ScopeDatabases)
+	if err = service.InitHiddenObjects(Config.DB.HiddenTables, Config.DB.HiddenSchemas); err != nil {
+		logger.Fatalf("HIDDEN_TABLES/HIDDEN_SCHEMAS 配置错误: %v", err)
+	}
+	service.InitSafeDDL(Config.DB.SafeDDL)
+	service.InitPayloadGuard(Config.DB.PayloadGuard)
+	service.InitMasking(service.MaskingConfig{Rules: Config.DB.MaskRules, HashSalt: Config.DB.MaskHashSalt})
+	schedulerCfg := service.SchedulerConfig{
+		Interval: Config.Scheduler.Interval,
+		Jitter:   Config.Scheduler.Jitter,
+	}
+	if Config.Scheduler.LockEnabled {
+		// 指向同一集合的实例共用一把锁
+		schedulerCfg.LockName = "mcp-mysql:index:" + Config.Milvus.Collection
+	}
+	service.InitSchedulerConfig(schedulerCfg)
+	service.InitIndexBudget(service.IndexBudgetConfig{
+		MaxBytes: Config.Scheduler.MemoryBudget,
+		Workers:  Config.Scheduler.Workers,
+	})
+	service.InitTransactionConfig(Config.Transaction.Timeout)
+	service.InitKillConfig(Config.Admin.KillQuery)
+	service.InitSchemaDiffTargets(Config.DB.DiffTargets)
+	service.InitPoolWait(Config.DB.PoolWait)
+	service.InitFairSchedule(Config.DB.FairSchedule)
+	service.InitBreakerConfig(service.BreakerConfig{
+		FailureThreshold: Config.Breaker.FailureThreshold,
+		LatencyThreshold: Config.Breaker.LatencyThreshold,
+		Cooldown:         Config.Breaker.Cooldown,
+	})
+
 	// 初始化数据库连接
-	dsn := buildDSNFromConfig()
+	dsn := buildDSNFromConfig(&Config)
 	logger.Info("正在连接MySQL数据库...")
 	if err = initDB(dsn); err != nil {
 		logger.Fatalf("数据库初始化失败: %v", err)
 	}
 	logger.Info("成功连接到MySQL数据库")
+	versionCtx, versionCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if version, err := service.InitServerVersion(versionCtx, db, Config.DB.ServerVersion); err != nil {
+		logger.Warnw("服务端版本检测失败，不启用兼容模式", "error", err)
+	} else {
+		logger.Infow("MySQL服务端版本", "version", version.Raw)
+	}
+	if proxy, err := service.InitServerProxy(versionCtx, db, Config.DB.Proxy); err != nil {
+		logger.Warnw("数据库代理检测失败，按直连处理", "error", err)
+	} else if proxy != service.ProxyNone {
+		logger.Infow("检测到数据库代理，processlist 相关功能将不可用", "proxy", proxy)
+	}
+	versionCancel()
 	defer func() {
 		if db != nil {
 			db.Close()
 		}
 	}()
 
-	// 初始化Milvus连接
-	if err = initMilvus(ctx); err != nil {
-		logger.Fatalf("Milvus初始化失败: %v", err)
+	// 多个实例共享同一集合时，只有持有租约的实例负责更新
+	if Config.Scheduler.LeaseEnabled {
+		lease := service.StartIndexLease(db, Config.Scheduler.InstanceID, "mcp-mysql:lease:"+Config.Milvus.Collection)
+		defer lease.Close()
+		logger.Info(service.IndexLeaseStatus())
 	}
+
+	// Milvus 连接在首次使用时建立
 	defer func() {
-		if cli != nil {
-			cli.Close(context.Background())
+		if client := vectorClient(); client != nil {
+			client.Close(context.Background())
 		}
 	}()
 
-	// 初始化向量数据库
-	if err := initVectorDB(ctx, cli); err != nil {
-		logger.Fatalf("向量数据库初始化失败: %v", err)
-	}
-
-	// 初始化SQLite数据库
+	// 初始化SQLite数据库，向量化时需要记录索引元数据
 	logger.Info("正在初始化SQLite数据库...")
 	if err = service.InitSQLite(); err != nil {
 		logger.Fatalf("SQLite初始化失败: %v", err)
 	}
-	go func() {
-		service.UpdateSchema(db, cli)
-	}()
+
+	// 检索质量评测命令：mcp-mysql bench-retrieval queries.yaml，直接使用现有索引，运行后退出
+	if len(os.Args) > 1 && os.Args[1] == "bench-retrieval" {
+		if len(os.Args) < 3 {
+			logger.Fatal("用法: mcp-mysql bench-retrieval queries.yaml")
+		}
+		if err = runBenchRetrieval(ctx, os.Args[2]); err != nil {
+			logger.Fatalf("检索评测失败: %v", err)
+		}
+		return
+	}
+
 	defer service.CloseSQLite()
+	go service.HarvestColumnStats(ctx, db)
+	go service.RecordSchemaSnapshots(ctx, db)
+
+	if Config.Debug.Addr != "" {
+		startWebUI(Config.Debug.Addr, Config.Debug.Token)
+	}
 
 	// Create a new MCP server
+	// 记录客户端名称和版本，写入语句审计
+	hooks := &server.Hooks{}
+	hooks.AddAfterInitialize(func(id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		service.SetAuditClient(message.Params.ClientInfo.Name, message.Params.ClientInfo.Version)
+	})
 	s := server.NewMCPServer(
 		"mcp-mysql",
 		"1.0.0",
+		server.WithHooks(hooks),
 	)
 	// Add tool
 	getCanUseTabletool := mcp.NewTool("get_can_use_table",
-		mcp.WithDescription("Find relevant database tables based on natural language description, used before executing SQL queries"),
+		mcp.WithDescription("Find relevant database tables based on natural language description, used before executing SQL queries. If no table is similar enough, returns JSON with status \"no_confident_match\", the closest candidate tables and their scores; then call list_tables instead of guessing"),
 		mcp.WithString("query",
 			mcp.Required(),
 			mcp.Description("Natural language query description"),
 		),
+		mcp.WithNumber("max_tokens_hint",
+			mcp.Description("Approximate context budget in tokens for the result; more or fewer table schemas are returned to fit it"),
+		),
+		mcp.WithBoolean("async",
+			mcp.Description(fmt.Sprintf("If semantic search does not finish quickly, return preliminary keyword matches at once with a pending_token and push the better-ranked results as a notifications/message notification when ready (default %v)", Config.Discovery.Async)),
+		),
+		mcp.WithString("pending_token",
+			mcp.Description("pending_token from a preliminary async result; waits for and returns the final semantic search results for it"),
+		),
 	)
 
-	executeSqltool := mcp.NewTool("execute_sql",
-		mcp.WithDescription("Execute SQL query statements on MySQL database and return the results"),
-		mcp.WithString("query",
+	listTablesTool := mcp.NewTool("list_tables",
+		mcp.WithDescription("List all tables and views in the configured database with their row count estimates and comments, as JSON"),
+	)
+
+	estimateRowCountsTool := mcp.NewTool("estimate_row_counts",
+		mcp.WithDescription("Return approximate row counts for all tables from information_schema.TABLES (TABLE_ROWS), largest first, without scanning any table. Use this instead of SELECT COUNT(*) on large tables; pass exact=true with a table to run COUNT(*) bounded by a timeout"),
+		mcp.WithString("table",
+			mcp.Description("Only return this table"),
+		),
+		mcp.WithBoolean("exact",
+			mcp.Description("Run SELECT COUNT(*) on the table; requires table. If it does not finish within timeout_seconds it is cancelled and only the estimate is returned"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Timeout for the exact count (default 10, max 60)"),
+		),
+	)
+
+	getPartitionsTool := mcp.NewTool("get_partitions",
+		mcp.WithDescription("Return the partitioning scheme (method and expression, including subpartitions) and per-partition row estimates, data/index sizes and bounds of a table as JSON, from information_schema.PARTITIONS. Use it to write WHERE clauses that allow partition pruning on large partitioned tables"),
+		mcp.WithString("table",
 			mcp.Required(),
-			mcp.Description("SQL query to execute"),
+			mcp.Description("Table name"),
 		),
 	)
 
-	// Add tool handler
-	s.AddTool(getCanUseTabletool, getCanUseTable)
-	s.AddTool(executeSqltool, executeSql)
+	listRoutinesTool := mcp.NewTool("list_routines",
+		mcp.WithDescription("List stored procedures and functions in the configured database as JSON, with parameter signatures, return types, determinism and data access, from information_schema.ROUTINES and PARAMETERS. Routines are not part of the table index, so use this to find them"),
+		mcp.WithString("name",
+			mcp.Description("Only return the routine with this name"),
+		),
+		mcp.WithBoolean("include_body",
+			mcp.Description("Also return the routine body (empty when the account lacks privileges to see the definition)"),
+		),
+	)
 
-	// Start the stdio server
-	logger.Info("启动MCP服务器...")
-	if err := server.ServeStdio(s); err != nil {
-		logger.Errorf("服务器错误: %v", err)
-	}
+	showGrantsTool := mcp.NewTool("show_grants",
+		mcp.WithDescription("Report the privileges of the connected MySQL account as JSON: its SHOW GRANTS statements plus a summary of SELECT/INSERT/UPDATE/DELETE/DDL privileges on the current database. Call it before writing to learn whether a statement is allowed instead of discovering it from an execution error"),
+		mcp.WithString("user",
+			mcp.Description("Another account to inspect, as name or name@host (host defaults to %); requires SELECT on the mysql schema"),
+		),
+	)
 
-}
+	describeTableTool := mcp.NewTool("describe_table",
+		mcp.WithDescription("Describe a table as JSON: columns with types, nullability, keys, defaults and comments (plus stored null fraction and distinct estimates when collected), and its indexes and table comment"),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Table name"),
+		),
+		mcp.WithBoolean("include_ddl",
+			mcp.Description("Also return the CREATE TABLE statement, compacted by dropping AUTO_INCREMENT counters, ROW_FORMAT, charset and collation clauses (default false)"),
+		),
+	)
 
-func executeSql(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query := request.Params.Arguments["query"].(string)
-	logger.Infof("执行查询: %s", query)
-	if query == "" {
-		return nil, fmt.Errorf("query is empty")
-	}
+	columnProfileTool := mcp.NewTool("column_profile",
+		mcp.WithDescription("Return stored per-column statistics of a table as JSON: null fraction and distinct value estimate with their source (histogram, index cardinality or a bounded sample), collected periodically in the background so the live table is not scanned. Use it to pick selective filter and join columns"),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Table name"),
+		),
+		mcp.WithString("column",
+			mcp.Description("Only return this column"),
+		),
+		mcp.WithBoolean("refresh",
+			mcp.Description("Collect the statistics of this table now instead of using the stored ones (default false)"),
+		),
+	)
 
-	// 创建带超时的上下文
-	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	fetchCellTool := mcp.NewTool("fetch_cell",
+		mcp.WithDescription("Read a slice of a large TEXT/BLOB value of one row identified by its primary key, without loading the whole value. Returns JSON with the slice, total_length, has_more and next_offset so the value can be paged through; offset and length are in characters for text columns and bytes for binary columns, binary data that is not valid UTF-8 is returned base64 encoded"),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Table name"),
+		),
+		mcp.WithString("pk",
+			mcp.Required(),
+			mcp.Description("Primary key value of the row; for a composite primary key a JSON object mapping each key column to its value"),
+		),
+		mcp.WithString("column",
+			mcp.Required(),
+			mcp.Description("Column holding the large value"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Offset to start reading from, 0-based (default 0)"),
+		),
+		mcp.WithNumber("length",
+			mcp.Description("Number of characters or bytes to read (default 8000, max 65536)"),
+		),
+	)
 
-	res, err := service.Execute(queryCtx, db, query)
-	if err != nil {
-		logger.Errorw("SQL执行失败", "query", query, "error", err)
-		return nil, err
-	}
+	findColumnsTool := mcp.NewTool("find_columns",
+		mcp.WithDescription("Find columns across the whole database by exact name pattern or comment text, returning matching table.column pairs with types and comments as JSON. Use it for exact column lookups when semantic search is not precise enough"),
+		mcp.WithString("pattern",
+			mcp.Description("Text the column name must contain; % and _ are matched literally unless like is true"),
+		),
+		mcp.WithBoolean("like",
+			mcp.Description("Treat pattern as a LIKE pattern with % and _ wildcards (default false)"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Text the column comment must contain"),
+		),
+	)
 
-	return mcp.NewToolResultText(res), nil
-}
+	getTableRelationshipsTool := mcp.NewTool("get_table_relationships",
+		mcp.WithDescription("Return the foreign key graph as JSON edges (from_table.from_columns -> to_table.to_columns), read from information_schema.KEY_COLUMN_USAGE. Use it to write correct JOINs between the tables returned by get_can_use_table"),
+		mcp.WithString("table",
+			mcp.Description("Table name; only edges from or to this table are returned. Omit to return the whole schema"),
+		),
+	)
 
-func getCanUseTable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query := request.Params.Arguments["query"].(string)
-	logger.Infof("执行相似度查询: %s", query)
-	if query == "" {
-		return nil, fmt.Errorf("query is empty")
-	}
+	diffSchemasTool := mcp.NewTool("diff_schemas",
+		mcp.WithDescription("Compare table definitions of the configured database with another database and report added/removed tables and added/removed/changed columns and indexes as JSON. \"added\" means present only in the other database. Useful for migration reviews"),
+		mcp.WithString("schema",
+			mcp.Description("Name of another database on the same server to compare with"),
+		),
+		mcp.WithString("target",
+			mcp.Description(fmt.Sprintf("Name of a configured comparison database on another server (configured: %s)",
+				strings.Join(service.SchemaDiffTargetNames(), ", "))),
+		),
+	)
+
+	whatChangedSinceTool := mcp.NewTool("what_changed_since",
+		mcp.WithDescription("Summarize database activity since a point in time: DDL changes (new/dropped tables, added/removed/changed columns and indexes) and notable row-count shifts compared with the schema snapshot taken at that time, statements run through this server from the audit log, and optionally a binlog summary. Starts with a short narrative summary"),
+		mcp.WithString("since",
+			mcp.Required(),
+			mcp.Description("Start time: RFC3339, \"2006-01-02 15:04:05\", \"2006-01-02\", or how long ago such as 90m, 24h, 7d"),
+		),
+	)
+
+	explainQueryTool := mcp.NewTool("explain_query",
+		mcp.WithDescription("Show the execution plan of a SQL statement without running it, to inspect index usage and cost before executing expensive SQL"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL statement to explain, without the EXPLAIN prefix"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Plan format: traditional (default, rows as JSON), json (EXPLAIN FORMAT=JSON) or tree (EXPLAIN FORMAT=TREE, MySQL 8.0.16+)"),
+		),
+	)
+
+	getTableSampleTool := mcp.NewTool("get_table_sample",
+		mcp.WithDescription("Return the first rows of a table to see the shape of its values without writing a SELECT"),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Table name"),
+		),
+		mcp.WithNumber("rows",
+			mcp.Description("Number of rows to return (default 10, max 100)"),
+		),
+		mcp.WithNumber("max_tokens_hint",
+			mcp.Description("Approximate context budget in tokens for the result; rows are compacted, or summarized with per-column statistics when they still do not fit"),
+		),
+	)
+
+	explainRunningQueryTool := mcp.NewTool("explain_running_query",
+		mcp.WithDescription("Inspect currently running queries: without connection_id, list running statements from the processlist (longest first); with connection_id, return that statement together with its plan from EXPLAIN FOR CONNECTION"),
+		mcp.WithNumber("connection_id",
+			mcp.Description("Processlist ID of the connection running the query"),
+		),
+		mcp.WithNumber("min_seconds",
+			mcp.Description("When listing, only include statements running at least this many seconds (default 0)"),
+		),
+	)
+
+	getSlowQueriesTool := mcp.NewTool("get_slow_queries",
+		mcp.WithDescription("Return the slowest statements of the current database with timings, rows examined and rows sent, as JSON. Reads statement digests from performance_schema, or mysql.slow_log when log_output=TABLE"),
+		mcp.WithString("source",
+			mcp.Description("auto (default), performance_schema (aggregated by statement digest) or slow_log (individual executions)"),
+		),
+		mcp.WithString("order_by",
+			mcp.Description("For performance_schema: total (default, total time), avg, max or count"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of statements to return (default 10, max 50)"),
+		),
+	)
+
+	hotTablesTool := mcp.NewTool("hot_tables",
+		mcp.WithDescription(fmt.Sprintf("Return the most queried tables of the last %d days from the query history of execute_sql, with query count, failures and average/max/total latency, as JSON. index_review lists frequently queried tables whose average latency is at least %d ms, by total time: the best candidates to check indexes (e.g. with explain_query)", int(Config.Discovery.HotTables.Window.Hours()/24), Config.Discovery.HotTables.SlowMs)),
+		mcp.WithString("order_by",
+			mcp.Description("count (default, number of queries), total (total latency) or avg (average latency)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of tables to return (default 20)"),
+		),
+	)
+
+	confirmExecutionTool := mcp.NewTool("confirm_execution",
+		mcp.WithDescription("Execute a statement that returned status \"pending_confirmation\", after the user has reviewed and approved it. Runs the original tool call with its original arguments. Only call this when the user explicitly approved the statement"),
+		mcp.WithString("token",
+			mcp.Required(),
+			mcp.Description("Confirmation token returned with the pending statement"),
+		),
+	)
+
+	killQueryTool := mcp.NewTool("kill_query",
+		mcp.WithDescription("Stop a runaway statement: without connection_id, list active connections from the processlist (longest first); with connection_id, run KILL QUERY on it. Only connections of the MySQL account this server uses can be killed, and the connection itself is kept"),
+		mcp.WithNumber("connection_id",
+			mcp.Description("Processlist ID of the connection whose statement should be killed"),
+		),
+		mcp.WithNumber("min_seconds",
+			mcp.Description("When listing, only include connections active for at least this many seconds (default 0)"),
+		),
+	)
+
+	explainResultTool := mcp.NewTool("explain_result",
+		mcp.WithDescription("Use the configured LLM to write a concise narrative summary of a query result, with caveats such as truncation or stale data, for report-style answers"),
+		mcp.WithString("question",
+			mcp.Required(),
+			mcp.Description("The original question the result should answer"),
+		),
+		mcp.WithString("result",
+			mcp.Description("Result text returned by execute_sql or another tool"),
+		),
+		mcp.WithString("handle",
+			mcp.Description("Result handle stored by execute_sql with store_as; used instead of result"),
+		),
+		mcp.WithNumber("history_id",
+			mcp.Description("History ID of an executed query; used instead of result, the query is re-run to fetch current data"),
+		),
+	)
+
+	executeSqltool := mcp.NewTool("execute_sql",
+		mcp.WithDescription("Execute SQL query statements on MySQL database and return the results"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL query to execute"),
+		),
+		mcp.WithNumber("max_tokens_hint",
+			mcp.Description("Approximate context budget in tokens for the result; rows are compacted, or summarized with per-column statistics when they still do not fit"),
+		),
+		mcp.WithBoolean("raw",
+			mcp.Description("Never summarize: return raw rows, truncated to max_tokens_hint if necessary"),
+		),
+		mcp.WithNumber("sample_rows",
+			mcp.Description("For SELECT queries, additionally return a deterministic random sample of this many underlying rows (FROM/WHERE part of the query, max 100) to sanity check aggregates; scans all matching rows"),
+		),
+		mcp.WithNumber("sample_seed",
+			mcp.Description("Random seed for sample_rows (default 42); the same seed returns the same sample while the data is unchanged"),
+		),
+		mcp.WithString("collation",
+			mcp.Description("Run the statement under this utf8mb4 session collation, e.g. utf8mb4_zh_0900_as_cs, or a locale alias: pinyin, zh, ja, de, es, fr, ru. Applies to string literals and expressions; to sort a column with it write ORDER BY col COLLATE <collation>"),
+		),
+		mcp.WithString("database",
+			mcp.Description("Run the statement with this database as the default (USE on a dedicated connection, restored afterwards), so unqualified table names resolve there. Not allowed together with transaction_id"),
+		),
+		mcp.WithString("transaction_id",
+			mcp.Description("Run the statement inside a transaction opened with begin_transaction"),
+		),
+		mcp.WithString("confirm",
+			mcp.Description("When DDL safe mode is on, DROP, TRUNCATE and ALTER only run if this is the name of the target table (or database, view, ...), comma separated for several targets. Ask the user to type the name; never fill it in on your own"),
+		),
+		mcp.WithString("store_as",
+			mcp.Description("Store the full result of a query under this handle name (kept 30 minutes) so read_result, explain_result and other tools can reuse it without re-running the SQL"),
+		),
+		mcp.WithBoolean("return_history_id",
+			mcp.Description("Also return the query history ID of this execution, as a separate content item after the result, for annotate_query_history (default false)"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description(fmt.Sprintf("Statement timeout in seconds (default %d, max %d). Raise it for analytical queries; lower it for interactive lookups that should fail fast",
+				int(Config.DB.QueryTimeout.Seconds()), int(Config.DB.QueryTimeoutMax.Seconds()))),
+		),
+	)
+
+	batchExecuteTool := mcp.NewTool("batch_execute",
+		mcp.WithDescription("Run several read-only queries (SELECT, SHOW, DESCRIBE, EXPLAIN) in one call and return each result in order; a failing query does not stop the others. Set snapshot=true for multi-part answers such as totals plus breakdowns so every query sees the same consistent data"),
+		mcp.WithArray("queries",
+			mcp.Required(),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+			mcp.Description("SQL queries to run, at most 20"),
+		),
+		mcp.WithBoolean("snapshot",
+			mcp.Description("Run all queries inside a single REPEATABLE READ read-only transaction started WITH CONSISTENT SNAPSHOT"),
+		),
+		mcp.WithNumber("max_tokens_hint",
+			mcp.Description("Approximate context budget in tokens for all results together; it is split evenly between the queries"),
+		),
+	)
+
+	executeDMLTool := mcp.NewTool("execute_dml",
+		mcp.WithDescription("Execute an INSERT, UPDATE, DELETE or REPLACE statement with ? placeholders as a prepared statement. Pass user-supplied values in args instead of interpolating them into the SQL"),
+		mcp.WithString("statement",
+			mcp.Required(),
+			mcp.Description("DML statement with ? placeholders, e.g. UPDATE users SET email = ? WHERE id = ?"),
+		),
+		mcp.WithString("args",
+			mcp.Description("JSON array of values bound to the placeholders in order, e.g. [\"a@example.com\", 42]; arrays and objects are bound as JSON text"),
+		),
+		mcp.WithString("transaction_id",
+			mcp.Description("Run the statement inside a transaction opened with begin_transaction"),
+		),
+		mcp.WithBoolean("return_history_id",
+			mcp.Description("Also return the query history ID of this execution, as a separate content item after the result, for annotate_query_history (default false)"),
+		),
+	)
+
+	exportQueryCSVTool := mcp.NewTool("export_query_csv",
+		mcp.WithDescription("Run a SELECT (or take a stored result handle) and export the rows as CSV, streamed without loading everything into memory. Small results (up to 64KB) are returned as CSV text; larger ones are written to a file in EXPORT_DIR and the path and row count are returned"),
+		mcp.WithString("query",
+			mcp.Description("SELECT statement to export"),
+		),
+		mcp.WithString("handle",
+			mcp.Description("Result handle stored by execute_sql with store_as; used instead of query"),
+		),
+		mcp.WithString("filename",
+			mcp.Description("File name inside EXPORT_DIR (default export_<timestamp>.csv); existing files are not overwritten"),
+		),
+		mcp.WithBoolean("to_file",
+			mcp.Description("Always write a file, even for small results"),
+		),
+	)
+
+	queryPageTool := mcp.NewTool("query_page",
+		mcp.WithDescription("Run a large SELECT one page at a time so results stay within message size limits. The first call takes query (and optionally page/limit) and returns the rows plus a next_cursor; pass that cursor to get the following page. Add ORDER BY for stable pages"),
+		mcp.WithString("query",
+			mcp.Description("SELECT statement to page through; not needed when cursor is given"),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("Opaque next_cursor returned by the previous page (kept 30 minutes)"),
+		),
+		mcp.WithNumber("page",
+			mcp.Description("1-based page number for the first call (default 1)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Rows per page (default 100, max 1000)"),
+		),
+	)
+
+	readResultTool := mcp.NewTool("read_result",
+		mcp.WithDescription("Page through a result stored by execute_sql with store_as, without re-running the SQL"),
+		mcp.WithString("handle",
+			mcp.Required(),
+			mcp.Description("Result handle name"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Index of the first row to return (default 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Number of rows to return (default 100)"),
+		),
+	)
+
+	beginTransactionTool := mcp.NewTool("begin_transaction",
+		mcp.WithDescription("Open a transaction and return its handle; pass it as transaction_id to execute_sql, then call commit or rollback. Idle transactions are rolled back automatically"),
+	)
+
+	commitTool := mcp.NewTool("commit",
+		mcp.WithDescription("Commit a transaction opened with begin_transaction"),
+		mcp.WithString("transaction_id",
+			mcp.Required(),
+			mcp.Description("Transaction handle returned by begin_transaction"),
+		),
+	)
+
+	rollbackTool := mcp.NewTool("rollback",
+		mcp.WithDescription("Roll back a transaction opened with begin_transaction"),
+		mcp.WithString("transaction_id",
+			mcp.Required(),
+			mcp.Description("Transaction handle returned by begin_transaction"),
+		),
+	)
+
+	sandboxExecuteTool := mcp.NewTool("sandbox_execute",
+		mcp.WithDescription("Dry-run a SQL statement against temporary copies of sampled rows from the target tables; all changes are rolled back. Use it before running risky statements with execute_sql"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL statement to try (SELECT/INSERT/UPDATE/DELETE/REPLACE)"),
+		),
+		mcp.WithString("tables",
+			mcp.Required(),
+			mcp.Description("Comma-separated names of the tables referenced by the statement"),
+		),
+		mcp.WithNumber("sample_size",
+			mcp.Description("Rows copied from each table into the sandbox (default 100, max 1000)"),
+		),
+	)
+
+	loadDataFileTool := mcp.NewTool("load_data_file",
+		mcp.WithDescription("Bulk-load a CSV file from the configured staging directory into a table via LOAD DATA LOCAL INFILE. The first line must be a header naming the target columns"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("File path, relative to or inside the LOAD_DATA_DIR staging directory"),
+		),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Target table name"),
+		),
+		mcp.WithString("delimiter",
+			mcp.Description("Field delimiter (default ',')"),
+		),
+	)
+
+	exportIndexStatusTool := mcp.NewTool("export_index_status",
+		mcp.WithDescription("Export the schema index status: every table with its schema hash, vector ID, embedding time and staleness flag (fresh, stale, not_indexed, dropped)"),
+		mcp.WithString("format",
+			mcp.Description("Output format"),
+			mcp.Enum("json", "csv"),
+			mcp.DefaultString("json"),
+		),
+	)
+
+	listIndexedTablesTool := mcp.NewTool("list_indexed_tables",
+		mcp.WithDescription("List the tables currently present in the vector index with their last-embedded time, to check whether a newly created table is discoverable yet"),
+		mcp.WithString("filter",
+			mcp.Description("Only list tables whose name contains this text"),
+		),
+	)
+
+	getSchemaStatsTool := mcp.NewTool("get_schema_stats",
+		mcp.WithDescription("Summarize the database schema: number of tables/views, total columns, how many tables are in the vector index, the largest tables and the percentage of tables/columns missing comments"),
+	)
+
+	getDBStatsTool := mcp.NewTool("get_db_stats",
+		mcp.WithDescription("Return database size and per-table engine, row count estimate, data size, index size and free space from information_schema.TABLES as JSON, largest tables first. Sizes and row counts are approximate for InnoDB"),
+	)
+
+	findSimilarQueriesTool := mcp.NewTool("find_similar_queries",
+		mcp.WithDescription("Find previously executed, successful SQL queries similar to a natural language request, to use as proven examples"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Natural language description of the data needed"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of queries to return (default 5, max 20)"),
+		),
+	)
+
+	annotateQueryHistoryTool := mcp.NewTool("annotate_query_history",
+		mcp.WithDescription("Attach a note and/or labels to an executed query (by the history_id returned by execute_sql or execute_dml with return_history_id, or found with search_query_history), e.g. \"monthly revenue report v2\", so it can be found and reused later"),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("History ID of the query"),
+		),
+		mcp.WithString("note",
+			mcp.Description("Free-form note describing what the query does; replaces the existing note"),
+		),
+		mcp.WithString("labels",
+			mcp.Description("Comma-separated labels to add, e.g. \"revenue,monthly\""),
+		),
+		mcp.WithString("remove_labels",
+			mcp.Description("Comma-separated labels to remove"),
+		),
+	)
+
+	searchQueryHistoryTool := mcp.NewTool("search_query_history",
+		mcp.WithDescription("Search the query history notebook by label and/or text (matched against SQL and notes); returns the most recent entries with their notes and labels"),
+		mcp.WithString("label",
+			mcp.Description("Only return queries carrying this label"),
+		),
+		mcp.WithString("text",
+			mcp.Description("Only return queries whose SQL or note contains this text"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of entries to return (default 20, max 200)"),
+		),
+	)
+
+	findDocumentsTool := mcp.NewTool("find_documents",
+		mcp.WithDescription("Query a MySQL document store collection (a table with a JSON doc column) by field equality and return the matching JSON documents"),
+		mcp.WithString("collection",
+			mcp.Required(),
+			mcp.Description("Collection name"),
+		),
+		mcp.WithString("filter",
+			mcp.Description(`JSON object of field paths to expected values, e.g. {"status": "active", "address.city": "Beijing"}`),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of documents to return (default 20, max 200)"),
+		),
+	)
+
+	describeCollectionTool := mcp.NewTool("describe_collection",
+		mcp.WithDescription("Describe a MySQL document store collection by sampling its documents; without a name, list all collections"),
+		mcp.WithString("collection",
+			mcp.Description("Collection name; omit to list collections"),
+		),
+	)
+
+	reloadConnectionsTool := mcp.NewTool("reload_connections",
+		mcp.WithDescription("Admin: re-read connection settings from .env and re-open the MySQL pool, Milvus client and SQLite handle, e.g. after credentials rotate or the network changes. Each new connection is verified before it replaces the old one; the MCP session is not interrupted"),
+	)
+
+	verifyBackupsTool := mcp.NewTool("verify_backups",
+		mcp.WithDescription("Admin: check whether logical backups in BACKUP_DIR are healthy: the latest (or named) mysqldump file or MySQL Shell dump directory is complete, recent enough and contains every table of the current database. Optionally triggers a new backup first and compares per-table row counts in the dump with the live database"),
+		mcp.WithString("name",
+			mcp.Description("File or directory name inside BACKUP_DIR to check; default is the most recent backup"),
+		),
+		mcp.WithBoolean("trigger",
+			mcp.Description("Run the configured BACKUP_COMMAND first and then check the newest backup"),
+		),
+		mcp.WithBoolean("verify_counts",
+			mcp.Description("Read the whole mysqldump file and compare each table's row count with COUNT(*) on the live database; slow for large dumps"),
+		),
+	)
+
+	forgetTableTool := mcp.NewTool("forget_table",
+		mcp.WithDescription("Admin: remove a deprecated table from the schema index so it is no longer suggested, even though it still exists in the database; it stays excluded from future re-indexing until restored"),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Table name"),
+		),
+		mcp.WithBoolean("restore",
+			mcp.Description("Undo a previous forget so the table is indexed again"),
+		),
+	)
+
+	setTableRankingTool := mcp.NewTool("set_table_ranking",
+		mcp.WithDescription("Admin: pin a core table so it is always appended to get_can_use_table results, or change its ranking weight (below 1 down-weights a noisy table, above 1 boosts it)"),
+		mcp.WithString("table",
+			mcp.Required(),
+			mcp.Description("Table name"),
+		),
+		mcp.WithBoolean("pinned",
+			mcp.Description("Always include this table in discovery results"),
+		),
+		mcp.WithNumber("weight",
+			mcp.Description("Score multiplier (default 1)"),
+		),
+		mcp.WithBoolean("reset",
+			mcp.Description("Remove the rule set by this tool and fall back to the environment configuration"),
+		),
+	)
+
+	listQueryTemplatesTool := mcp.NewTool("list_query_templates",
+		mcp.WithDescription("List the pre-registered query templates with their parameter slots; use run_query_template to execute one"),
+	)
+
+	runQueryTemplateTool := mcp.NewTool("run_query_template",
+		mcp.WithDescription("Execute a pre-registered query template; parameters are bound as prepared statement arguments"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Template name from list_query_templates"),
+		),
+		mcp.WithString("params",
+			mcp.Description(`JSON object of parameter values, e.g. {"user_id": 42, "since": "2024-01-01"}`),
+		),
+		mcp.WithNumber("max_tokens_hint",
+			mcp.Description("Approximate context budget in tokens for the result; large results are compacted or summarized to fit it"),
+		),
+	)
+
+	reindexSchemasTool := mcp.NewTool("reindex_schemas",
+		mcp.WithDescription("Rebuild the whole table schema index into a fresh Milvus collection and atomically switch to it when complete; searches keep using the old index meanwhile"),
+	)
+
+	compactVectorIndexTool := mcp.NewTool("compact_vector_index",
+		mcp.WithDescription("Maintenance: find duplicate schema vectors for the same table, keep the newest one per table, delete the rest and compact the collection"),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Only report duplicates without deleting anything"),
+		),
+	)
+
+	// Add tool handler
+	addTool(s, getCanUseTabletool, getCanUseTable)
+	addTool(s, listTablesTool, listTables)
+	addTool(s, estimateRowCountsTool, estimateRowCounts)
+	addTool(s, showGrantsTool, showGrants)
+	addTool(s, listRoutinesTool, listRoutines)
+	addTool(s, getPartitionsTool, getPartitions)
+	addTool(s, describeTableTool, describeTable)
+	addTool(s, columnProfileTool, columnProfile)
+	addTool(s, fetchCellTool, fetchCell)
+	addTool(s, getTableRelationshipsTool, getTableRelationships)
+	addTool(s, findColumnsTool, findColumns)
+	addTool(s, diffSchemasTool, diffSchemas)
+	addTool(s, whatChangedSinceTool, whatChangedSince)
+	addTool(s, explainRunningQueryTool, explainRunningQuery)
+	addTool(s, getSlowQueriesTool, getSlowQueries)
+	addTool(s, hotTablesTool, hotTables)
+	// 模板严格模式下不开放任何自由 SQL 工具，只能执行已登记的模板
+	if !service.Templates.Strict {
+		addTool(s, executeSqltool, executeSql)
+		if !Config.DB.ReadOnly {
+			addTool(s, executeDMLTool, executeDML)
+		}
+		addTool(s, batchExecuteTool, batchExecute)
+		addTool(s, sandboxExecuteTool, sandboxExecute)
+		addTool(s, readResultTool, readResult)
+		addTool(s, queryPageTool, queryPage)
+		addTool(s, exportQueryCSVTool, exportQueryCSV)
+		addTool(s, beginTransactionTool, beginTransaction)
+		addTool(s, commitTool, commitTransaction)
+		addTool(s, rollbackTool, rollbackTransaction)
+		addTool(s, explainQueryTool, explainQuery)
+		addTool(s, getTableSampleTool, getTableSample)
+	}
+	if len(service.Templates.Templates) > 0 {
+		addTool(s, listQueryTemplatesTool, listQueryTemplates)
+		addTool(s, runQueryTemplateTool, runQueryTemplate)
+	}
+	addTool(s, exportIndexStatusTool, exportIndexStatus)
+	addTool(s, listIndexedTablesTool, listIndexedTables)
+	addTool(s, getSchemaStatsTool, getSchemaStats)
+	addTool(s, getDBStatsTool, getDBStats)
+	addTool(s, reindexSchemasTool, reindexSchemas)
+	addTool(s, compactVectorIndexTool, compactVectorIndex)
+	addTool(s, findSimilarQueriesTool, findSimilarQueries)
+	if service.LLMEnabled() {
+		addTool(s, explainResultTool, explainResult)
+	}
+	addTool(s, annotateQueryHistoryTool, annotateQueryHistory)
+	addTool(s, searchQueryHistoryTool, searchQueryHistory)
+	addTool(s, findDocumentsTool, findDocuments)
+	addTool(s, describeCollectionTool, describeCollection)
+	if Config.LoadData.Dir != "" && !Config.DB.ReadOnly {
+		addTool(s, loadDataFileTool, loadDataFile)
+	}
+	if Config.Admin.KillQuery {
+		addTool(s, killQueryTool, killQuery)
+	}
+	if service.ConfirmApproverInUse() != nil {
+		addTool(s, confirmExecutionTool, confirmExecution)
+	}
+	if Config.Admin.Enabled {
+		addTool(s, forgetTableTool, forgetTable)
+		addTool(s, setTableRankingTool, setTableRanking)
+		addTool(s, reloadConnectionsTool, reloadConnections)
+		if Config.Backup.Dir != "" {
+			addTool(s, verifyBackupsTool, verifyBackups)
+		}
+	}
+	// 组织自定义的工具
+	for _, t := range service.RegisteredTools() {
+		if _, ok := toolHandlers[t.Name]; ok {
+			logger.Fatalf("自定义工具与内置工具重名: %s", t.Name)
+		}
+		// 模板严格模式下只能执行已登记的模板，执行 SQL 的自定义工具同样不开放
+		if service.Templates.Strict && t.ExecutesSQL {
+			logger.Warnw("模板严格模式下不注册执行 SQL 的自定义工具", "tool", t.Name)
+			continue
+		}
+		addTool(s, pluginTool(t), pluginHandler(t))
+	}
+
+	logger.Infow("启动MCP服务器...", "transport", Config.Transport.Type)
+	if err := serveMCP(s); err != nil {
+		logger.Errorf("服务器错误: %v", err)
+	}
+
+}
+
+// queryTimeout 返回 execute_sql 的超时：未指定时使用默认值，超过上限时取上限
+func queryTimeout(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return Config.DB.QueryTimeout
+	}
+	return min(time.Duration(seconds*float64(time.Second)), Config.DB.QueryTimeoutMax)
+}
+
+func executeSql(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	logger.Infof("执行查询: %s", query)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	// 危险语句先等待人工审批，审批时间不计入执行超时
+	ctx, err := service.RequestApproval(withLabel(ctx, "execute_sql"), query)
+	if err != nil {
+		logger.Errorw("SQL未获审批", "query", query, "error", err)
+		return nil, err
+	}
+
+	// 创建带超时的上下文，timeout_seconds 不能超过服务端配置的上限
+	timeoutSeconds, _ := request.Params.Arguments["timeout_seconds"].(float64)
+	queryCtx, cancel := context.WithTimeout(ctx, queryTimeout(timeoutSeconds))
+	defer cancel()
+
+	maxTokens, _ := request.Params.Arguments["max_tokens_hint"].(float64)
+	raw, _ := request.Params.Arguments["raw"].(bool)
+	sampleRows, _ := request.Params.Arguments["sample_rows"].(float64)
+	sampleSeed, _ := request.Params.Arguments["sample_seed"].(float64)
+	transactionID, _ := request.Params.Arguments["transaction_id"].(string)
+	storeAs, _ := request.Params.Arguments["store_as"].(string)
+	collation, _ := request.Params.Arguments["collation"].(string)
+	database, _ := request.Params.Arguments["database"].(string)
+	confirm, _ := request.Params.Arguments["confirm"].(string)
+	returnHistoryID, _ := request.Params.Arguments["return_history_id"].(bool)
+
+	opts := service.ExecOptions{
+		MaxTokens:  int(maxTokens),
+		Raw:        raw,
+		SampleRows: int(sampleRows),
+		SampleSeed: int64(sampleSeed),
+		Collation:  collation,
+		Database:   database,
+		Confirm:    confirm,
+	}
+	if storeAs != "" {
+		opts.Capture = &service.CapturedResult{}
+	}
+	start := time.Now()
+	var res string
+	if transactionID != "" {
+		res, err = service.ExecuteInTransaction(queryCtx, transactionID, query, opts)
+	} else {
+		res, err = service.ExecuteWithOptions(queryCtx, db, query, opts)
+	}
+	historyID := recordHistory(query, time.Since(start), err)
+	if err != nil {
+		logger.Errorw("SQL执行失败", "query", query, "error", err)
+		return nil, err
+	}
+
+	if storeAs != "" {
+		if err = service.StoreResultHandle(queryCtx, storeAs, query, opts.Capture); err != nil {
+			res += "\n\n" + service.Text("result_not_stored", err)
+		} else {
+			res += "\n\n" + service.Text("result_stored", storeAs, len(opts.Capture.Rows))
+		}
+	}
+
+	result := mcp.NewToolResultText(res)
+	// 按需返回历史记录ID，便于之后通过 annotate_query_history 为查询添加备注；单独作为一项内容，不影响结果 JSON 的解析
+	if returnHistoryID && historyID > 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("history_id: %d", historyID)))
+	}
+	return result, nil
+}
+
+func listTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger.Info("获取表列表")
+
+	listCtx, cancel := context.WithTimeout(withLabel(ctx, "list_tables"), 30*time.Second)
+	defer cancel()
+
+	tables, err := service.ListTables(listCtx, db)
+	if err != nil {
+		logger.Errorw("获取表列表失败", "error", err)
+		return nil, err
+	}
+	res, err := service.FormatTables(tables)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func estimateRowCounts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	exact, _ := request.Params.Arguments["exact"].(bool)
+	timeoutSeconds, _ := request.Params.Arguments["timeout_seconds"].(float64)
+	logger.Infof("估算表行数: table=%s exact=%v", table, exact)
+
+	countCtx, cancel := context.WithTimeout(withLabel(ctx, "estimate_row_counts"), 90*time.Second)
+	defer cancel()
+
+	report, err := service.EstimateRowCounts(countCtx, db, table, exact, time.Duration(timeoutSeconds*float64(time.Second)))
+	if err != nil {
+		logger.Errorw("估算表行数失败", "table", table, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatRowCounts(report)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func getPartitions(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	logger.Infof("获取分区信息: %s", table)
+	if table == "" {
+		return nil, fmt.Errorf("table is empty")
+	}
+
+	partCtx, cancel := context.WithTimeout(withLabel(ctx, "get_partitions"), 30*time.Second)
+	defer cancel()
+
+	info, err := service.GetPartitions(partCtx, db, table)
+	if err != nil {
+		logger.Errorw("获取分区信息失败", "table", table, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatPartitions(info)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func listRoutines(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.Params.Arguments["name"].(string)
+	includeBody, _ := request.Params.Arguments["include_body"].(bool)
+	logger.Infof("获取存储过程和函数: %s", name)
+
+	listCtx, cancel := context.WithTimeout(withLabel(ctx, "list_routines"), 30*time.Second)
+	defer cancel()
+
+	routines, err := service.ListRoutines(listCtx, db, name, includeBody)
+	if err != nil {
+		logger.Errorw("获取存储过程和函数失败", "name", name, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatRoutines(routines)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func showGrants(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	user, _ := request.Params.Arguments["user"].(string)
+	logger.Infof("查看账号权限: %s", user)
+
+	grantsCtx, cancel := context.WithTimeout(withLabel(ctx, "show_grants"), 30*time.Second)
+	defer cancel()
+
+	report, err := service.ShowGrants(grantsCtx, db, user)
+	if err != nil {
+		logger.Errorw("查看账号权限失败", "user", user, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatGrantReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func describeTable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	logger.Infof("获取表结构: %s", table)
+	if table == "" {
+		return nil, fmt.Errorf("table is empty")
+	}
+
+	describeCtx, cancel := context.WithTimeout(withLabel(ctx, "describe_table"), 30*time.Second)
+	defer cancel()
+
+	desc, err := service.DescribeTable(describeCtx, db, table)
+	if err != nil {
+		logger.Errorw("获取表结构失败", "table", table, "error", err)
+		return nil, err
+	}
+	if includeDDL, _ := request.Params.Arguments["include_ddl"].(bool); includeDDL {
+		if desc.DDL, err = service.TableDDL(describeCtx, db, table); err != nil {
+			logger.Errorw("获取建表语句失败", "table", table, "error", err)
+			return nil, err
+		}
+	}
+	res, err := service.FormatTableDescription(desc)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func columnProfile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	column, _ := request.Params.Arguments["column"].(string)
+	refresh, _ := request.Params.Arguments["refresh"].(bool)
+	logger.Infof("获取列统计: %s, 列: %s", table, column)
+	if table == "" {
+		return nil, fmt.Errorf("table is empty")
+	}
+
+	profileCtx, cancel := context.WithTimeout(withLabel(ctx, "column_profile"), 60*time.Second)
+	defer cancel()
+
+	res, err := service.ColumnProfile(profileCtx, db, table, column, refresh)
+	if err != nil {
+		logger.Errorw("获取列统计失败", "table", table, "column", column, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func fetchCell(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	pk, _ := request.Params.Arguments["pk"].(string)
+	column, _ := request.Params.Arguments["column"].(string)
+	offset, _ := request.Params.Arguments["offset"].(float64)
+	length, _ := request.Params.Arguments["length"].(float64)
+	logger.Infof("读取字段内容: %s.%s, 主键: %s, offset: %d, length: %d", table, column, pk, int64(offset), int64(length))
+	if table == "" || column == "" {
+		return nil, fmt.Errorf("table and column are required")
+	}
+
+	fetchCtx, cancel := context.WithTimeout(withLabel(ctx, "fetch_cell"), 30*time.Second)
+	defer cancel()
+
+	slice, err := service.FetchCell(fetchCtx, db, table, pk, column, int64(offset), int64(length))
+	if err != nil {
+		logger.Errorw("读取字段内容失败", "table", table, "column", column, "pk", pk, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatCellSlice(slice)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func findColumns(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pattern, _ := request.Params.Arguments["pattern"].(string)
+	like, _ := request.Params.Arguments["like"].(bool)
+	comment, _ := request.Params.Arguments["comment"].(string)
+	logger.Infof("查找列: %s, 注释: %s", pattern, comment)
+
+	findCtx, cancel := context.WithTimeout(withLabel(ctx, "find_columns"), 30*time.Second)
+	defer cancel()
+
+	matches, truncated, err := service.FindColumns(findCtx, db, pattern, like, comment)
+	if err != nil {
+		logger.Errorw("查找列失败", "pattern", pattern, "comment", comment, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatColumnMatches(matches, truncated)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func getTableRelationships(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	logger.Infof("获取外键关系: %s", table)
+
+	relCtx, cancel := context.WithTimeout(withLabel(ctx, "get_table_relationships"), 30*time.Second)
+	defer cancel()
+
+	edges, err := service.GetTableRelationships(relCtx, db, table)
+	if err != nil {
+		logger.Errorw("获取外键关系失败", "table", table, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatTableRelationships(edges)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func diffSchemas(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	schema, _ := request.Params.Arguments["schema"].(string)
+	target, _ := request.Params.Arguments["target"].(string)
+	logger.Infof("对比表结构, 库: %s, 目标: %s", schema, target)
+
+	diffCtx, cancel := context.WithTimeout(withLabel(ctx, "diff_schemas"), 60*time.Second)
+	defer cancel()
+
+	diff, err := service.DiffSchemas(diffCtx, db, schema, target)
+	if err != nil {
+		logger.Errorw("对比表结构失败", "schema", schema, "target", target, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatSchemaDiff(diff)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func whatChangedSince(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	value, _ := request.Params.Arguments["since"].(string)
+	logger.Infof("汇总变化: since=%s", value)
+	since, err := service.ParseSince(value, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	changeCtx, cancel := context.WithTimeout(withLabel(ctx, "what_changed_since"), 120*time.Second)
+	defer cancel()
+
+	report, err := service.WhatChangedSince(changeCtx, db, since)
+	if err != nil {
+		logger.Errorw("汇总变化失败", "since", value, "error", err)
+		return nil, err
+	}
+	res, err := service.FormatChangeReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func explainQuery(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	format, _ := request.Params.Arguments["format"].(string)
+	logger.Infof("获取执行计划: %s, 格式: %s", query, format)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	explainCtx, cancel := context.WithTimeout(withLabel(ctx, "explain_query"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.ExplainQuery(explainCtx, db, query, format)
+	if err != nil {
+		logger.Errorw("获取执行计划失败", "query", query, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func getTableSample(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	rows, _ := request.Params.Arguments["rows"].(float64)
+	maxTokens, _ := request.Params.Arguments["max_tokens_hint"].(float64)
+	logger.Infof("获取表样本: %s, 行数: %v", table, rows)
+	if table == "" {
+		return nil, fmt.Errorf("table is empty")
+	}
+
+	queryCtx, cancel := context.WithTimeout(withLabel(ctx, "get_table_sample"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.GetTableSample(queryCtx, db, table, int(rows), service.ExecOptions{MaxTokens: int(maxTokens)})
+	if err != nil {
+		logger.Errorw("获取表样本失败", "table", table, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func explainRunningQuery(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	connectionID, _ := request.Params.Arguments["connection_id"].(float64)
+	minSeconds, _ := request.Params.Arguments["min_seconds"].(float64)
+	logger.Infof("查看正在执行的语句, 连接: %d", int64(connectionID))
+
+	explainCtx, cancel := context.WithTimeout(withLabel(ctx, "explain_running_query"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.ExplainRunningQuery(explainCtx, db, int64(connectionID), int(minSeconds))
+	if err != nil {
+		logger.Errorw("查看正在执行的语句失败", "connection", int64(connectionID), "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func getSlowQueries(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	source, _ := request.Params.Arguments["source"].(string)
+	orderBy, _ := request.Params.Arguments["order_by"].(string)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("获取慢查询, 来源: %s, 排序: %s", source, orderBy)
+
+	slowCtx, cancel := context.WithTimeout(withLabel(ctx, "get_slow_queries"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.GetSlowQueries(slowCtx, db, source, orderBy, int(limit))
+	if err != nil {
+		logger.Errorw("获取慢查询失败", "source", source, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func hotTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	orderBy, _ := request.Params.Arguments["order_by"].(string)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("获取热点表, 排序: %s", orderBy)
+
+	report, err := service.GetHotTables(orderBy, int(limit))
+	if err != nil {
+		logger.Errorw("获取热点表失败", "error", err)
+		return nil, err
+	}
+	res, err := service.FormatHotTables(report)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func confirmExecution(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	token, _ := request.Params.Arguments["token"].(string)
+	logger.Infof("确认执行: %s", token)
+	confirmer := service.ConfirmApproverInUse()
+	if confirmer == nil {
+		return nil, fmt.Errorf("two-phase confirmation is not enabled")
+	}
+
+	confirmCtx, tool, arguments, err := confirmer.Confirm(withLabel(ctx, "confirm_execution"), token)
+	if err != nil {
+		logger.Errorw("确认执行失败", "token", token, "error", err)
+		return nil, err
+	}
+	handler, ok := toolHandlers[tool]
+	if !ok {
+		return nil, fmt.Errorf("tool %s is not available", tool)
+	}
+	replay := mcp.CallToolRequest{}
+	replay.Params.Name = tool
+	replay.Params.Arguments = arguments
+	return handler(confirmCtx, replay)
+}
+
+func killQuery(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	connectionID, _ := request.Params.Arguments["connection_id"].(float64)
+	minSeconds, _ := request.Params.Arguments["min_seconds"].(float64)
+	logger.Infof("终止语句, 连接: %d", int64(connectionID))
+
+	killCtx, cancel := context.WithTimeout(withLabel(ctx, "kill_query"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.KillQuery(killCtx, db, int64(connectionID), int(minSeconds))
+	if err != nil {
+		logger.Errorw("终止语句失败", "connection", int64(connectionID), "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func explainResult(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	question, _ := request.Params.Arguments["question"].(string)
+	result, _ := request.Params.Arguments["result"].(string)
+	handle, _ := request.Params.Arguments["handle"].(string)
+	historyID, _ := request.Params.Arguments["history_id"].(float64)
+	logger.Infof("生成结果说明: %s, 句柄: %s, 历史ID: %d", question, handle, int64(historyID))
+
+	// 需要调用LLM，可能还要重新执行查询，使用更宽松的超时
+	explainCtx, cancel := context.WithTimeout(withLabel(ctx, "explain_result"), 60*time.Second)
+	defer cancel()
+
+	res, err := service.ExplainResult(explainCtx, db, service.ExplainResultInput{
+		Question:  question,
+		Result:    result,
+		Handle:    handle,
+		HistoryID: int64(historyID),
+	})
+	if err != nil {
+		logger.Errorw("生成结果说明失败", "question", question, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+// pluginTool 根据自定义工具的定义构造 MCP 工具
+func pluginTool(t service.PluginTool) mcp.Tool {
+	opts := []mcp.ToolOption{mcp.WithDescription(t.Description)}
+	for _, p := range t.Params {
+		propOpts := []mcp.PropertyOption{mcp.Description(p.Description)}
+		if p.Required {
+			propOpts = append(propOpts, mcp.Required())
+		}
+		switch p.Type {
+		case "number":
+			opts = append(opts, mcp.WithNumber(p.Name, propOpts...))
+		case "boolean":
+			opts = append(opts, mcp.WithBoolean(p.Name, propOpts...))
+		default:
+			opts = append(opts, mcp.WithString(p.Name, propOpts...))
+		}
+	}
+	return mcp.NewTool(t.Name, opts...)
+}
+
+// pluginHandler 将自定义工具的处理函数适配为 MCP 工具处理函数
+func pluginHandler(t service.PluginTool) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		logger.Infof("执行自定义工具: %s", t.Name)
+		for _, p := range t.Params {
+			if _, ok := request.Params.Arguments[p.Name]; p.Required && !ok {
+				return nil, fmt.Errorf("%s is required", p.Name)
+			}
+		}
+
+		toolCtx, cancel := context.WithTimeout(withLabel(ctx, t.Name), 60*time.Second)
+		defer cancel()
+
+		res, err := t.Handler(toolCtx, service.PluginEnv{DB: db, Logger: logger}, request.Params.Arguments)
+		if err != nil {
+			logger.Errorw("自定义工具执行失败", "tool", t.Name, "error", err)
+			return nil, err
+		}
+		return mcp.NewToolResultText(res), nil
+	}
+}
+
+// addTool 注册工具，处理函数统一包裹 panic 恢复，并记录到最近调用列表中
+func addTool(s *server.MCPServer, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if !service.ToolEnabled(tool.Name) {
+		logger.Infow("工具已被权限配置禁用，不注册", "tool", tool.Name)
+		return
+	}
+	handler = pendingTool(tool.Name, authorizeTool(tool.Name, permitTool(tool.Name, handler)))
+	toolHandlers[tool.Name] = handler
+	service.MarkToolAvailable(tool.Name)
+	s.AddTool(tool, traceTool(tool.Name, recoverTool(tool.Name, handler)))
+}
+
+// toolHandlers 为已注册工具的处理函数，confirm_execution 按原参数重新调用
+var toolHandlers = map[string]server.ToolHandlerFunc{}
+
+// pendingTool 在语句需要两阶段确认时记录原调用的参数，并把待确认信息作为结果返回
+func pendingTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		var pending *service.ConfirmationRequiredError
+		if !errors.As(err, &pending) {
+			return result, err
+		}
+		if confirmer := service.ConfirmApproverInUse(); confirmer != nil {
+			confirmer.AttachCall(pending.Token, name, request.Params.Arguments)
+		}
+		res, _ := service.FormatPendingConfirmation(err)
+		return mcp.NewToolResultText(res), nil
+	}
+}
+
+// permitTool 在调用前按工具权限配置检查参数
+func permitTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := service.CheckToolArguments(name, request.Params.Arguments); err != nil {
+			logger.Warnw("工具调用参数不符合权限配置", "tool", name, "error", err)
+			return nil, err
+		}
+		return handler(ctx, request)
+	}
+}
+
+// traceTool 记录工具调用的参数、耗时和错误，供调试页面展示
+func traceTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, request)
+
+		call := service.ToolCall{
+			Tool:       name,
+			DurationMs: time.Since(start).Milliseconds(),
+			At:         start,
+		}
+		if cs := server.ClientSessionFromContext(ctx); cs != nil {
+			call.Session = cs.SessionID()
+		}
+		if args, marshalErr := json.Marshal(request.Params.Arguments); marshalErr == nil {
+			call.Arguments = string(args)
+		}
+		if err != nil {
+			call.Error = err.Error()
+		} else if result != nil && result.IsError {
+			call.Error = "tool returned an error result"
+		}
+		service.RecordToolCall(call)
+		return result, err
+	}
+}
+
+// recoverTool 捕获工具处理函数中的 panic，记录堆栈后返回结构化的内部错误结果，
+// 避免单个工具的异常导致整个 MCP 进程和客户端会话退出
+func recoverTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorw("工具执行发生panic", "tool", name, "panic", r, "stack", string(debug.Stack()))
+				data, _ := json.Marshal(map[string]string{
+					"error":   "internal_error",
+					"tool":    name,
+					"message": fmt.Sprint(r),
+				})
+				result = &mcp.CallToolResult{
+					Content: []mcp.Content{mcp.NewTextContent(string(data))},
+					IsError: true,
+				}
+				err = nil
+			}
+		}()
+		return handler(ctx, request)
+	}
+}
+
+// withLabel 在上下文中记录当前会话和工具名称，用于注入语句注释
+func withLabel(ctx context.Context, tool string) context.Context {
+	session := ""
+	if cs := server.ClientSessionFromContext(ctx); cs != nil {
+		session = cs.SessionID()
+	}
+	return service.WithStatementLabel(ctx, session, tool)
+}
+
+func readResult(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	handle, _ := request.Params.Arguments["handle"].(string)
+	offset, _ := request.Params.Arguments["offset"].(float64)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("读取结果句柄: %s, offset: %v, limit: %v", handle, offset, limit)
+	if handle == "" {
+		return nil, fmt.Errorf("handle is empty")
+	}
+
+	res, err := service.ReadResultHandle(withLabel(ctx, "read_result"), handle, int(offset), int(limit))
+	if err != nil {
+		logger.Errorw("读取结果句柄失败", "handle", handle, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func queryPage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	cursor, _ := request.Params.Arguments["cursor"].(string)
+	page, _ := request.Params.Arguments["page"].(float64)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("分页查询: %s, 游标: %s, 页: %v", query, cursor, page)
+
+	pageCtx, cancel := context.WithTimeout(withLabel(ctx, "query_page"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.QueryPage(pageCtx, db, query, cursor, int(page), int(limit), service.ExecOptions{Raw: true})
+	if err != nil {
+		logger.Errorw("分页查询失败", "query", query, "cursor", cursor, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func exportQueryCSV(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	handle, _ := request.Params.Arguments["handle"].(string)
+	filename, _ := request.Params.Arguments["filename"].(string)
+	toFile, _ := request.Params.Arguments["to_file"].(bool)
+	logger.Infof("导出CSV: %s, 句柄: %s", query, handle)
+
+	opts := service.CSVExportOptions{Dir: Config.Export.Dir, FileName: filename, ForceFile: toFile}
+	var (
+		res *service.CSVExportResult
+		err error
+	)
+	switch {
+	case handle != "":
+		res, err = service.ExportHandleCSV(withLabel(ctx, "export_query_csv"), handle, opts)
+	case query != "":
+		// 大结果集导出耗时较长，使用更宽松的超时
+		exportCtx, cancel := context.WithTimeout(withLabel(ctx, "export_query_csv"), 10*time.Minute)
+		defer cancel()
+		start := time.Now()
+		res, err = service.ExportQueryCSV(exportCtx, db, query, opts)
+		recordHistory(query, time.Since(start), err)
+	default:
+		return nil, fmt.Errorf("query or handle is required")
+	}
+	if err != nil {
+		logger.Errorw("导出CSV失败", "query", query, "handle", handle, "error", err)
+		return nil, err
+	}
+
+	note := ""
+	if res.Truncated {
+		note = service.Text("export_truncated")
+	}
+	if res.Path == "" {
+		return mcp.NewToolResultText(service.Text("export_inline", res.Rows, note) + "\n" + res.Inline), nil
+	}
+	return mcp.NewToolResultText(service.Text("export_file", res.Rows, note, res.Bytes, res.Path)), nil
+}
+
+func beginTransaction(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger.Info("开启事务")
+
+	beginCtx, cancel := context.WithTimeout(withLabel(ctx, "begin_transaction"), 30*time.Second)
+	defer cancel()
+
+	id, err := service.BeginTransaction(beginCtx, db)
+	if err != nil {
+		logger.Errorw("开启事务失败", "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(service.Text("tx_started", id, service.TransactionTimeout)), nil
+}
+
+func commitTransaction(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["transaction_id"].(string)
+	logger.Infof("提交事务: %s", id)
+	if id == "" {
+		return nil, fmt.Errorf("transaction_id is empty")
+	}
+
+	if err := service.CommitTransaction(withLabel(ctx, "commit"), id); err != nil {
+		logger.Errorw("提交事务失败", "transaction", id, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(service.Text("tx_committed", id)), nil
+}
+
+func rollbackTransaction(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["transaction_id"].(string)
+	logger.Infof("回滚事务: %s", id)
+	if id == "" {
+		return nil, fmt.Errorf("transaction_id is empty")
+	}
+
+	if err := service.RollbackTransaction(withLabel(ctx, "rollback"), id); err != nil {
+		logger.Errorw("回滚事务失败", "transaction", id, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(service.Text("tx_rolled_back", id)), nil
+}
+
+// splitList 按逗号拆分参数，去掉空白和空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// batchExecute 依次执行多条查询，可选在同一个一致性快照中执行
+func batchExecute(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	items, _ := request.Params.Arguments["queries"].([]interface{})
+	snapshot, _ := request.Params.Arguments["snapshot"].(bool)
+	maxTokens, _ := request.Params.Arguments["max_tokens_hint"].(float64)
+	logger.Infof("批量执行查询: %d 条, snapshot=%v", len(items), snapshot)
+
+	queries := make([]string, 0, len(items))
+	for _, item := range items {
+		if q, ok := item.(string); ok && strings.TrimSpace(q) != "" {
+			queries = append(queries, q)
+		}
+	}
+	opts := service.ExecOptions{}
+	if maxTokens > 0 && len(queries) > 0 {
+		opts.MaxTokens = int(maxTokens) / len(queries)
+	}
+
+	batchCtx, cancel := context.WithTimeout(withLabel(ctx, "batch_execute"), 60*time.Second)
+	defer cancel()
+
+	res, err := service.ExecuteBatch(batchCtx, db, queries, snapshot, opts)
+	if err != nil {
+		logger.Errorw("批量执行查询失败", "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+// executeDML 以预处理语句执行带占位符的 DML，参数不拼接进 SQL
+func executeDML(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	statement, _ := request.Params.Arguments["statement"].(string)
+	argsJSON, _ := request.Params.Arguments["args"].(string)
+	transactionID, _ := request.Params.Arguments["transaction_id"].(string)
+	returnHistoryID, _ := request.Params.Arguments["return_history_id"].(bool)
+	logger.Infof("执行DML: %s", statement)
+	if statement == "" {
+		return nil, fmt.Errorf("statement is empty")
+	}
+
+	args, err := service.PrepareDML(statement, argsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// 危险语句先等待人工审批，审批时间不计入执行超时
+	ctx, err = service.RequestApproval(withLabel(ctx, "execute_dml"), statement)
+	if err != nil {
+		logger.Errorw("DML未获审批", "statement", statement, "error", err)
+		return nil, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	opts := service.ExecOptions{Args: args}
+	start := time.Now()
+	var res string
+	if transactionID != "" {
+		res, err = service.ExecuteInTransaction(execCtx, transactionID, statement, opts)
+	} else {
+		res, err = service.ExecuteWithOptions(execCtx, db, statement, opts)
+	}
+	historyID := recordHistory(statement, time.Since(start), err)
+	if err != nil {
+		logger.Errorw("DML执行失败", "statement", statement, "error", err)
+		return nil, err
+	}
+	result := mcp.NewToolResultText(res)
+	if returnHistoryID && historyID > 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf("history_id: %d", historyID)))
+	}
+	return result, nil
+}
+
+// recordHistory 记录查询历史并返回历史记录ID（失败时为0），执行成功的查询会在后台向量化
+func recordHistory(query string, duration time.Duration, execErr error) int64 {
+	id, err := service.RecordQueryHistory(query, duration, execErr)
+	if err != nil {
+		logger.Warnw("记录查询历史失败", "error", err)
+		return 0
+	}
+	if execErr != nil {
+		return id
+	}
+
+	// 只使用 SQL 的场景不应因为记录历史而触发向量检索的初始化
+	if !vectorReady.Load() {
+		return id
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := service.IndexQueryHistory(ctx, vectorClient(), id, query); err != nil {
+			logger.Warnw("查询历史向量化失败", "id", id, "error", err)
+		}
+	}()
+	return id
+}
+
+func sandboxExecute(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	tablesArg, _ := request.Params.Arguments["tables"].(string)
+	sampleSize, _ := request.Params.Arguments["sample_size"].(float64)
+	logger.Infof("沙箱执行: %s, 表: %s", query, tablesArg)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	tables := splitList(tablesArg)
+
+	// 创建带超时的上下文
+	queryCtx, cancel := context.WithTimeout(withLabel(ctx, "sandbox_execute"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.SandboxExecute(queryCtx, db, query, tables, int(sampleSize))
+	if err != nil {
+		logger.Errorw("沙箱执行失败", "query", query, "error", err)
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(res), nil
+}
+
+func loadDataFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filePath, _ := request.Params.Arguments["file_path"].(string)
+	table, _ := request.Params.Arguments["table"].(string)
+	delimiter, _ := request.Params.Arguments["delimiter"].(string)
+	logger.Infof("批量导入: %s -> %s", filePath, table)
+	if filePath == "" || table == "" {
+		return nil, fmt.Errorf("file_path and table are required")
+	}
+
+	// 批量导入可能耗时较长，使用更宽松的超时
+	loadCtx, cancel := context.WithTimeout(withLabel(ctx, "load_data_file"), 5*time.Minute)
+	defer cancel()
+
+	res, err := service.LoadDataFile(loadCtx, db, Config.LoadData.Dir, filePath, table, delimiter)
+	if err != nil {
+		logger.Errorw("批量导入失败", "file", filePath, "table", table, "error", err)
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(res), nil
+}
+
+func exportIndexStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	format, _ := request.Params.Arguments["format"].(string)
+	logger.Infof("导出索引状态: %s", format)
+
+	// 创建带超时的上下文
+	statusCtx, cancel := context.WithTimeout(withLabel(ctx, "export_index_status"), 60*time.Second)
+	defer cancel()
+
+	statuses, err := service.GetIndexStatus(statusCtx, db)
+	if err != nil {
+		logger.Errorw("获取索引状态失败", "error", err)
+		return nil, err
+	}
+
+	res, err := service.ExportIndexStatus(statuses, format)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func listIndexedTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter, _ := request.Params.Arguments["filter"].(string)
+	logger.Infof("列出已索引的表: %s", filter)
+
+	tables, err := service.ListIndexedTables()
+	if err != nil {
+		logger.Errorw("获取已索引的表失败", "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(service.FormatIndexedTables(tables, filter)), nil
+}
+
+func forgetTable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	restore, _ := request.Params.Arguments["restore"].(bool)
+	logger.Infof("将表移出索引: %s, restore: %v", table, restore)
+
+	if restore {
+		res, err := service.RestoreTable(table)
+		if err != nil {
+			logger.Errorw("取消移出登记失败", "table", table, "error", err)
+			return nil, err
+		}
+		return mcp.NewToolResultText(res), nil
+	}
+
+	forgetCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	vc, err := getVectorClient()
+	if err != nil {
+		logger.Errorw("向量检索初始化失败", "error", err)
+		return nil, err
+	}
+	res, err := service.ForgetTable(forgetCtx, vc, table)
+	if err != nil {
+		logger.Errorw("将表移出索引失败", "table", table, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func verifyBackups(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.Params.Arguments["name"].(string)
+	trigger, _ := request.Params.Arguments["trigger"].(bool)
+	verifyCounts, _ := request.Params.Arguments["verify_counts"].(bool)
+	logger.Infof("检查备份: name=%s trigger=%v verify_counts=%v", name, trigger, verifyCounts)
+
+	report, err := service.VerifyBackups(withLabel(ctx, "verify_backups"), db, service.BackupOptions{
+		Dir:            Config.Backup.Dir,
+		MaxAge:         Config.Backup.MaxAge,
+		Command:        Config.Backup.Command,
+		CommandTimeout: Config.Backup.CommandTimeout,
+		Name:           name,
+		Trigger:        trigger,
+		VerifyCounts:   verifyCounts,
+	})
+	if err != nil {
+		logger.Errorw("检查备份失败", "error", err)
+		return nil, err
+	}
+	res, err := service.FormatBackupReport(report)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func setTableRanking(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	table, _ := request.Params.Arguments["table"].(string)
+	pinned, _ := request.Params.Arguments["pinned"].(bool)
+	reset, _ := request.Params.Arguments["reset"].(bool)
+	weight, ok := request.Params.Arguments["weight"].(float64)
+	if !ok {
+		weight = 1
+	}
+	logger.Infof("设置表排序规则: %s, pinned: %v, weight: %v, reset: %v", table, pinned, weight, reset)
+	if table == "" {
+		return nil, fmt.Errorf("table is empty")
+	}
+
+	if reset {
+		if err := service.ResetTableRanking(table); err != nil {
+			logger.Errorw("删除排序规则失败", "table", table, "error", err)
+			return nil, err
+		}
+		return mcp.NewToolResultText(service.Text("ranking_removed", table)), nil
+	}
+
+	if err := service.SetTableRanking(table, pinned, weight); err != nil {
+		logger.Errorw("设置排序规则失败", "table", table, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(service.Text("ranking_set", table, pinned, weight)), nil
+}
+
+func listQueryTemplates(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	res, err := service.ListQueryTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func runQueryTemplate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.Params.Arguments["name"].(string)
+	paramsArg, _ := request.Params.Arguments["params"].(string)
+	maxTokens, _ := request.Params.Arguments["max_tokens_hint"].(float64)
+	logger.Infof("执行查询模板: %s, 参数: %s", name, paramsArg)
+
+	params := map[string]any{}
+	if strings.TrimSpace(paramsArg) != "" {
+		if err := json.Unmarshal([]byte(paramsArg), &params); err != nil {
+			return nil, fmt.Errorf("invalid params JSON: %v", err)
+		}
+	}
+
+	queryCtx, cancel := context.WithTimeout(withLabel(ctx, "run_query_template"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.RunQueryTemplate(queryCtx, db, name, params, service.ExecOptions{MaxTokens: int(maxTokens)})
+	if err != nil {
+		logger.Errorw("查询模板执行失败", "name", name, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func reindexSchemas(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger.Info("开始全量重建表结构索引")
+
+	// 全量重建耗时较长，使用更宽松的超时
+	reindexCtx, cancel := context.WithTimeout(withLabel(ctx, "reindex_schemas"), 10*time.Minute)
+	defer cancel()
+
+	vc, err := getVectorClient()
+	if err != nil {
+		logger.Errorw("向量检索初始化失败", "error", err)
+		return nil, err
+	}
+	res, err := service.Reindex(reindexCtx, db, vc)
+	if err != nil {
+		logger.Errorw("全量重建索引失败", "error", err)
+		return nil, err
+	}
+	service.LogSchemaStats(reindexCtx, db)
+	return mcp.NewToolResultText(res), nil
+}
+
+func getSchemaStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger.Info("获取表结构统计摘要")
+
+	statsCtx, cancel := context.WithTimeout(withLabel(ctx, "get_schema_stats"), 30*time.Second)
+	defer cancel()
+
+	stats, err := service.GetSchemaStats(statsCtx, db)
+	if err != nil {
+		logger.Errorw("获取表结构统计摘要失败", "error", err)
+		return nil, err
+	}
+	res, err := service.FormatSchemaStats(stats)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func getDBStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger.Info("获取数据库容量统计")
+
+	statsCtx, cancel := context.WithTimeout(withLabel(ctx, "get_db_stats"), 30*time.Second)
+	defer cancel()
+
+	stats, err := service.GetDBStats(statsCtx, db)
+	if err != nil {
+		logger.Errorw("获取数据库容量统计失败", "error", err)
+		return nil, err
+	}
+	res, err := service.FormatDBStats(stats)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func compactVectorIndex(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dryRun, _ := request.Params.Arguments["dry_run"].(bool)
+	logger.Infof("向量集合去重压缩, dryRun: %v", dryRun)
+
+	compactCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	vc, err := getVectorClient()
+	if err != nil {
+		logger.Errorw("向量检索初始化失败", "error", err)
+		return nil, err
+	}
+	res, err := service.CompactVectorIndex(compactCtx, vc, dryRun)
+	if err != nil {
+		logger.Errorw("向量集合去重压缩失败", "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func findSimilarQueries(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("检索相似历史查询: %s", query)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > 20 {
+		limit = 20
+	}
 
 	// 创建带超时的上下文
 	searchCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 
-	vectors, err := service.EmbedQuery(query)
+	vc, err := getVectorClient()
+	if err != nil {
+		logger.Errorw("向量检索初始化失败", "error", err)
+		return nil, err
+	}
+	res, err := service.FindSimilarQueries(searchCtx, vc, query, int(limit))
+	if err != nil {
+		logger.Errorw("检索相似历史查询失败", "query", query, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func annotateQueryHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.Params.Arguments["id"].(float64)
+	note, _ := request.Params.Arguments["note"].(string)
+	labelsArg, _ := request.Params.Arguments["labels"].(string)
+	removeArg, _ := request.Params.Arguments["remove_labels"].(string)
+	logger.Infof("标注查询历史: %d, 标签: %s", int64(id), labelsArg)
+	if id <= 0 {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	if err := service.AnnotateQueryHistory(int64(id), note, splitList(labelsArg)); err != nil {
+		logger.Errorw("标注查询历史失败", "id", int64(id), "error", err)
+		return nil, err
+	}
+	if removeArg != "" {
+		if err := service.RemoveHistoryLabels(int64(id), splitList(removeArg)); err != nil {
+			logger.Errorw("删除查询历史标签失败", "id", int64(id), "error", err)
+			return nil, err
+		}
+	}
+	return mcp.NewToolResultText(service.Text("history_annotated", int64(id))), nil
+}
+
+func searchQueryHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	label, _ := request.Params.Arguments["label"].(string)
+	text, _ := request.Params.Arguments["text"].(string)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("检索查询历史: 标签=%s, 文本=%s", label, text)
+
+	res, err := service.SearchQueryHistory(label, text, int(limit))
+	if err != nil {
+		logger.Errorw("检索查询历史失败", "label", label, "text", text, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func findDocuments(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	collection, _ := request.Params.Arguments["collection"].(string)
+	filterArg, _ := request.Params.Arguments["filter"].(string)
+	limit, _ := request.Params.Arguments["limit"].(float64)
+	logger.Infof("查询文档集合: %s, 条件: %s", collection, filterArg)
+	if collection == "" {
+		return nil, fmt.Errorf("collection is empty")
+	}
+
+	filter := map[string]interface{}{}
+	if filterArg != "" {
+		if err := json.Unmarshal([]byte(filterArg), &filter); err != nil {
+			return nil, fmt.Errorf("filter must be a JSON object: %v", err)
+		}
+	}
+
+	// 创建带超时的上下文
+	queryCtx, cancel := context.WithTimeout(withLabel(ctx, "find_documents"), 30*time.Second)
+	defer cancel()
+
+	res, err := service.FindDocuments(queryCtx, db, collection, filter, int(limit))
+	if err != nil {
+		logger.Errorw("查询文档失败", "collection", collection, "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
+
+func describeCollection(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	collection, _ := request.Params.Arguments["collection"].(string)
+	logger.Infof("描述文档集合: %s", collection)
+
+	// 创建带超时的上下文
+	queryCtx, cancel := context.WithTimeout(withLabel(ctx, "describe_collection"), 30*time.Second)
+	defer cancel()
+
+	var result interface{}
+	if collection == "" {
+		collections, err := service.ListDocumentCollections(queryCtx, db)
+		if err != nil {
+			return nil, err
+		}
+		result = collections
+	} else {
+		fields, err := service.DescribeDocumentFields(queryCtx, db, collection)
+		if err != nil {
+			logger.Errorw("描述文档集合失败", "collection", collection, "error", err)
+			return nil, err
+		}
+		result = fields
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		logger.Errorw("向量嵌入失败", "query", query, "error", err)
-		return nil, fmt.Errorf("向量嵌入失败: %w", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func getCanUseTable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.Params.Arguments["query"].(string)
+	logger.Infof("执行相似度查询: %s", query)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	// 创建带超时的上下文
+	searchCtx, cancel := context.WithTimeout(withLabel(ctx, "get_can_use_table"), 20*time.Second)
+	defer cancel()
+
+	// 取回之前异步检索的结果
+	if token, _ := request.Params.Arguments["pending_token"].(string); token != "" {
+		res, err := service.AsyncDiscoveryResult(searchCtx, token)
+		if err != nil {
+			logger.Errorw("取回异步检索结果失败", "token", token, "error", err)
+			return nil, err
+		}
+		return mcp.NewToolResultText(res), nil
+	}
+
+	maxTokens, _ := request.Params.Arguments["max_tokens_hint"].(float64)
+	async, ok := request.Params.Arguments["async"].(bool)
+	if !ok {
+		async = Config.Discovery.Async
 	}
 
-	res, err := service.SimilaritySearch(searchCtx, cli, vectors)
+	vc, err := getVectorClient()
+	if err != nil {
+		logger.Errorw("向量检索初始化失败", "error", err)
+		return nil, err
+	}
+	opts := service.DiscoverOptions{
+		Translate: Config.Discovery.Translate,
+		MaxTokens: int(maxTokens),
+		DB:        db,
+		MinScore:  float32(Config.Discovery.MinScore),
+	}
+	var res string
+	if async {
+		res, err = service.DiscoverTablesAsync(searchCtx, vc, query, service.AsyncDiscoverOptions{
+			DiscoverOptions: opts,
+			Wait:            Config.Discovery.AsyncWait,
+			Notify:          discoveryNotifier(ctx),
+		})
+	} else {
+		res, err = service.DiscoverTables(searchCtx, vc, query, opts)
+	}
 	if err != nil {
-		logger.Errorw("相似度搜索失败", "query", query, "error", err)
-		return nil, fmt.Errorf("相似度搜索失败: %w", err)
+		logger.Errorw("表结构检索失败", "query", query, "error", err)
+		return nil, err
 	}
 
 	return mcp.NewToolResultText(res), nil
 }
+
+// discoveryNotifier 返回异步检索完成后向发起调用的客户端推送结果的函数，推送失败时客户端仍可通过 pending_token 取回
+func discoveryNotifier(ctx context.Context) func(token, query, result string, err error) {
+	srv := server.ServerFromContext(ctx)
+	return func(token, query, result string, err error) {
+		if srv == nil {
+			return
+		}
+		data := map[string]any{"tool": "get_can_use_table", "pending_token": token, "query": query}
+		level := "info"
+		if err != nil {
+			level, data["error"] = "error", err.Error()
+		} else {
+			data["result"] = result
+		}
+		if err := srv.SendNotificationToClient(ctx, "notifications/message", map[string]any{
+			"level":  level,
+			"logger": "get_can_use_table",
+			"data":   data,
+		}); err != nil {
+			logger.Warnw("推送异步检索结果失败", "token", token, "error", err)
+		}
+	}
+}
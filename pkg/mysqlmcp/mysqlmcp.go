@@ -0,0 +1,184 @@
+// Package mysqlmcp 以 Go 库的形式提供 mcp-mysql 的功能，其他程序可以直接嵌入，
+// 不需要通过 stdio 与 MCP 服务端通信。
+//
+// 基本用法：
+//
+//	client, err := mysqlmcp.New(ctx, mysqlmcp.Config{
+//		DSN:            "user:pass@tcp(127.0.0.1:3306)/app",
+//		MilvusAddress:  "127.0.0.1:19530",
+//		Collection:     "app_tables",
+//		EmbeddingURL:   "https://api.siliconflow.cn/v1/embeddings",
+//		EmbeddingToken: "sk-...",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close(ctx)
+//
+//	// 首次使用前建立表结构索引
+//	if _, err = client.Reindex(ctx); err != nil {
+//		return err
+//	}
+//	tables, err := client.DiscoverTables(ctx, "订单金额按月统计", mysqlmcp.DiscoverOptions{})
+//	res, err := client.Execute(ctx, "SELECT COUNT(*) FROM orders")
+//
+// 只需要执行 SQL 和查看表结构时可以不配置 Milvus 和嵌入接口。
+// 底层的 service 包使用进程级的全局配置，同一进程中只应创建一个 Client。
+package mysqlmcp
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+	"go.uber.org/zap"
+
+	"mcp-mysql/service"
+)
+
+// ExecOptions 为 ExecuteWithOptions 的执行选项
+type ExecOptions = service.ExecOptions
+
+// DiscoverOptions 为 DiscoverTables 的检索选项
+type DiscoverOptions = service.DiscoverOptions
+
+// TableDescription 为 DescribeTable 返回的表结构描述
+type TableDescription = service.TableDescription
+
+// Config 为 Client 的配置
+type Config struct {
+	// DSN 为 go-sql-driver/mysql 格式的连接串，与 DB 二选一
+	DSN string
+	// DB 为调用方已经打开的连接池，Close 时不会关闭它
+	DB *sql.DB
+
+	// MilvusAddress 为 Milvus 地址（host:port），为空时不启用表结构检索
+	MilvusAddress string
+	// Collection 为存放表结构向量的集合名称
+	Collection string
+
+	// EmbeddingURL、EmbeddingToken 为 OpenAI 兼容的嵌入接口地址和令牌，EmbeddingModel 默认为 BAAI/bge-m3
+	EmbeddingURL   string
+	EmbeddingToken string
+	EmbeddingModel string
+
+	// Logger 为空时不输出日志
+	Logger *zap.SugaredLogger
+}
+
+// Client 提供执行 SQL、检索和描述表结构以及重建索引的能力
+type Client struct {
+	db     *sql.DB
+	ownsDB bool
+	milvus *milvusclient.Client
+}
+
+// New 按配置连接 MySQL，配置了 MilvusAddress 时同时连接 Milvus
+func New(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Logger != nil {
+		service.Logger = cfg.Logger
+	} else if service.Logger == nil {
+		service.Logger = zap.NewNop().Sugar()
+	}
+
+	c := &Client{db: cfg.DB}
+	if c.db == nil {
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("需要提供 DSN 或 DB")
+		}
+		db, err := sql.Open("mysql", cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to MySQL: %v", err)
+		}
+		c.db, c.ownsDB = db, true
+	}
+	if err := c.db.PingContext(ctx); err != nil {
+		c.Close(ctx)
+		return nil, fmt.Errorf("failed to ping MySQL: %v", err)
+	}
+	if _, err := service.InitServerVersion(ctx, c.db, ""); err != nil {
+		service.Logger.Warnw("服务端版本检测失败，不启用兼容模式", "error", err)
+	}
+
+	if cfg.MilvusAddress == "" {
+		return c, nil
+	}
+	if cfg.Collection == "" {
+		c.Close(ctx)
+		return nil, fmt.Errorf("配置了 MilvusAddress 时需要提供 Collection")
+	}
+	if err := service.InitEmbeddingConfig(service.EmbeddingConfig{Model: cfg.EmbeddingModel}); err != nil {
+		c.Close(ctx)
+		return nil, err
+	}
+	service.SetEmbeddingEndpoint(cfg.EmbeddingURL, cfg.EmbeddingToken)
+	service.InitMilvusConfig(cfg.Collection)
+
+	milvus, err := milvusclient.New(ctx, &milvusclient.ClientConfig{Address: cfg.MilvusAddress})
+	if err != nil {
+		c.Close(ctx)
+		return nil, fmt.Errorf("failed to connect to Milvus: %v", err)
+	}
+	c.milvus = milvus
+	return c, nil
+}
+
+// DB 返回底层的 MySQL 连接池
+func (c *Client) DB() *sql.DB {
+	return c.db
+}
+
+// Execute 执行一条 SQL 语句，查询语句返回 JSON 格式的结果集，其他语句返回影响的行数
+func (c *Client) Execute(ctx context.Context, query string) (string, error) {
+	return service.Execute(ctx, c.db, query)
+}
+
+// ExecuteWithOptions 按给定选项执行一条 SQL 语句，如绑定 ? 占位符参数、按上下文预算压缩结果
+func (c *Client) ExecuteWithOptions(ctx context.Context, query string, opts ExecOptions) (string, error) {
+	return service.ExecuteWithOptions(ctx, c.db, query, opts)
+}
+
+// DescribeTable 返回表的列、索引和注释
+func (c *Client) DescribeTable(ctx context.Context, table string) (*TableDescription, error) {
+	return service.DescribeTable(ctx, c.db, table)
+}
+
+// DiscoverTables 根据自然语言描述检索相关的表结构，需要配置 Milvus 并已建立索引
+func (c *Client) DiscoverTables(ctx context.Context, query string, opts DiscoverOptions) (string, error) {
+	if c.milvus == nil {
+		return "", fmt.Errorf("未配置 Milvus，表结构检索不可用")
+	}
+	if opts.DB == nil {
+		opts.DB = c.db
+	}
+	return service.DiscoverTables(ctx, c.milvus, query, opts)
+}
+
+// Reindex 全量重建表结构索引，新索引构建完成后才替换旧索引
+func (c *Client) Reindex(ctx context.Context) (string, error) {
+	if c.milvus == nil {
+		return "", fmt.Errorf("未配置 Milvus，表结构检索不可用")
+	}
+	return service.Reindex(ctx, c.db, c.milvus)
+}
+
+// Close 关闭 Milvus 客户端、SQLite 元数据库以及由 Client 打开的 MySQL 连接池
+func (c *Client) Close(ctx context.Context) error {
+	var firstErr error
+	if c.milvus != nil {
+		if err := c.milvus.Close(ctx); err != nil {
+			firstErr = err
+		}
+		c.milvus = nil
+	}
+	service.CloseSQLite()
+	if c.ownsDB && c.db != nil {
+		if err := c.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		c.db = nil
+	}
+	return firstErr
+}
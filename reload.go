@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/joho/godotenv"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+
+	"mcp-mysql/service"
+)
+
+// milvusCloseDelay 替换 Milvus 客户端后，旧客户端延迟关闭，留给进行中的请求完成
+const milvusCloseDelay = time.Minute
+
+// MySQL 连接池的大小，initDB 和 reloadMySQL 共用
+const (
+	dbMaxOpenConns = 10
+	dbMaxIdleConns = 5
+)
+
+// mysqlConnector 包装 MySQL 驱动的连接器，可以在连接池使用中替换 DSN，
+// 之后新建立的连接使用新的地址和凭据
+type mysqlConnector struct {
+	mu        sync.RWMutex
+	connector driver.Connector
+}
+
+// newMySQLConnector 按 DSN 创建连接器
+func newMySQLConnector(dsn string, cfg *AppConfig) (*mysqlConnector, error) {
+	connector, err := connectorFromDSN(dsn, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlConnector{connector: connector}, nil
+}
+
+// connectorFromDSN 解析 DSN 并创建驱动的连接器，配置了 IAM 认证时每次建立连接前填入有效的令牌
+func connectorFromDSN(dsn string, cfg *AppConfig) (driver.Connector, error) {
+	mysqlCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %v", err)
+	}
+	if cfg.DB.IAMAuth != "" {
+		source, err := service.NewIAMTokenSource(cfg.DB.IAMAuth, cfg.DB.IAMRegion,
+			cfg.DB.Host, cfg.DB.Port, cfg.DB.User)
+		if err != nil {
+			return nil, err
+		}
+		if err = mysqlCfg.Apply(service.IAMBeforeConnect(source)); err != nil {
+			return nil, err
+		}
+	}
+	return mysql.NewConnector(mysqlCfg)
+}
+
+// Connect 使用当前的 DSN 建立连接
+func (c *mysqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.RLock()
+	connector := c.connector
+	c.mu.RUnlock()
+	return connector.Connect(ctx)
+}
+
+// Driver 返回 MySQL 驱动
+func (c *mysqlConnector) Driver() driver.Driver {
+	return &mysql.MySQLDriver{}
+}
+
+// reload 用新的 DSN 试连成功后才替换连接器，失败时保留原来的连接器
+func (c *mysqlConnector) reload(ctx context.Context, dsn string, cfg *AppConfig) error {
+	connector, err := connectorFromDSN(dsn, cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MySQL: %v", err)
+	}
+	conn.Close()
+
+	c.mu.Lock()
+	c.connector = connector
+	c.mu.Unlock()
+	return nil
+}
+
+// newMilvusClient 按 cfg 中的地址和凭据连接 Milvus
+func newMilvusClient(ctx context.Context, cfg *AppConfig) (*milvusclient.Client, error) {
+	connCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := milvusclient.New(connCtx, &milvusclient.ClientConfig{
+		Address:     cfg.Milvus.Host + ":" + cfg.Milvus.Port,
+		Username:    cfg.Milvus.Username,
+		Password:    cfg.Milvus.Password,
+		APIKey:      cfg.Milvus.APIKey,
+		DialOptions: service.MilvusDialOptions(cfg.Milvus.KeepaliveTime, cfg.Milvus.KeepaliveTimeout),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Milvus: %v", err)
+	}
+	return client, nil
+}
+
+// vectorClient 返回当前的 Milvus 客户端，尚未初始化时为 nil
+func vectorClient() *milvusclient.Client {
+	connMu.RLock()
+	defer connMu.RUnlock()
+	return cli
+}
+
+// connectionConfig 在 connMu 下复制当前配置，reload_connections 可能同时在替换其中的连接地址与凭据
+func connectionConfig() AppConfig {
+	connMu.RLock()
+	defer connMu.RUnlock()
+	return Config
+}
+
+// reopenConnections 重新读取 .env 中的连接配置，依次重建 MySQL、Milvus 和 SQLite 连接。
+// 每个连接都先建立新连接再替换旧连接，某一项失败时保留原连接并继续处理其余各项。
+// 新配置先读取到副本中，再在 connMu 下替换全局配置的连接部分
+func reopenConnections(ctx context.Context) (string, error) {
+	if err := godotenv.Overload(envFilePath()); err != nil {
+		logger.Warnw("重新加载.env文件失败，使用当前环境变量", "error", err)
+	}
+	next := connectionConfig()
+	if err := loadConnectionConfig(&next); err != nil {
+		return "", err
+	}
+	// 只替换连接地址与凭据，其余字段在启动后不再变化，工具调用可以不加锁读取
+	connMu.Lock()
+	Config.DB.User, Config.DB.Password = next.DB.User, next.DB.Password
+	Config.DB.Host, Config.DB.Port = next.DB.Host, next.DB.Port
+	Config.DB.Name, Config.DB.Params = next.DB.Name, next.DB.Params
+	Config.DB.OptionFile, Config.DB.OptionGroups = next.DB.OptionFile, next.DB.OptionGroups
+	Config.DB.IAMAuth, Config.DB.IAMRegion = next.DB.IAMAuth, next.DB.IAMRegion
+	Config.Milvus.Host, Config.Milvus.Port = next.Milvus.Host, next.Milvus.Port
+	Config.Milvus.Username, Config.Milvus.Password = next.Milvus.Username, next.Milvus.Password
+	Config.Milvus.APIKey = next.Milvus.APIKey
+	connMu.Unlock()
+
+	var (
+		lines  []string
+		failed int
+	)
+	report := func(name string, err error, ok string) {
+		if err != nil {
+			failed++
+			logger.Errorw("重新连接失败", "target", name, "error", err)
+			lines = append(lines, fmt.Sprintf("%s: failed, keeping the previous connection: %v", name, err))
+			return
+		}
+		logger.Infow("重新连接成功", "target", name)
+		lines = append(lines, fmt.Sprintf("%s: %s", name, ok))
+	}
+
+	report("MySQL", reloadMySQL(ctx, &next), "reconnected; idle connections were closed and in-flight queries finish on their existing connections")
+
+	if vectorReady.Load() {
+		report("Milvus", reloadMilvus(ctx, &next), "reconnected")
+	} else {
+		lines = append(lines, "Milvus: not initialized yet; it connects with the new settings on first use")
+	}
+
+	report("SQLite", service.ReloadSQLite(), "reopened")
+
+	res := strings.Join(lines, "\n")
+	if failed == 3 {
+		return "", fmt.Errorf("所有连接重新建立失败:\n%s", res)
+	}
+	return res, nil
+}
+
+// reloadMySQL 替换连接池的 DSN，并关闭使用旧凭据的空闲连接
+func reloadMySQL(ctx context.Context, cfg *AppConfig) error {
+	if db == nil || dbConnector == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+	if err := dbConnector.reload(ctx, buildDSNFromConfig(cfg), cfg); err != nil {
+		return err
+	}
+	// 先清空再恢复空闲连接上限，连接池会关闭现有的空闲连接
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(dbMaxIdleConns)
+
+	// 网络变化后可能连到了不同的实例，重新检测版本和代理
+	if _, err := service.InitServerVersion(ctx, db, cfg.DB.ServerVersion); err != nil {
+		logger.Warnw("服务端版本检测失败，不启用兼容模式", "error", err)
+	}
+	if _, err := service.InitServerProxy(ctx, db, cfg.DB.Proxy); err != nil {
+		logger.Warnw("数据库代理检测失败，按直连处理", "error", err)
+	}
+	return nil
+}
+
+// reloadMilvus 建立新的 Milvus 客户端并替换，旧客户端延迟关闭
+func reloadMilvus(ctx context.Context, cfg *AppConfig) error {
+	client, err := newMilvusClient(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	connMu.Lock()
+	old := cli
+	cli = client
+	connMu.Unlock()
+
+	if old != nil {
+		time.AfterFunc(milvusCloseDelay, func() {
+			if err := old.Close(context.Background()); err != nil {
+				logger.Warnw("关闭旧的Milvus客户端失败", "error", err)
+			}
+		})
+	}
+	return nil
+}
+
+// reloadConnections 处理 reload_connections 工具调用
+func reloadConnections(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	logger.Info("重新建立连接")
+
+	reloadCtx, cancel := context.WithTimeout(withLabel(ctx, "reload_connections"), 30*time.Second)
+	defer cancel()
+
+	res, err := reopenConnections(reloadCtx)
+	if err != nil {
+		logger.Errorw("重新建立连接失败", "error", err)
+		return nil, err
+	}
+	return mcp.NewToolResultText(res), nil
+}
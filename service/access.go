@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 内置角色
+const (
+	RoleReader = "reader"
+	RoleWriter = "writer"
+	RoleAdmin  = "admin"
+)
+
+// RolePolicy 为一个角色的权限：语句类型限制在连接策略之外额外生效，管理类工具只对 Admin 角色开放
+type RolePolicy struct {
+	// ReadOnly 为 true 时只允许执行查询语句
+	ReadOnly bool `yaml:"read_only" json:"read_only"`
+	// AllowedStatements 非空时只允许这些类型的语句
+	AllowedStatements []string `yaml:"allowed_statements" json:"allowed_statements,omitempty"`
+	// DeniedStatements 禁止执行的语句类型，优先于 AllowedStatements
+	DeniedStatements []string `yaml:"denied_statements" json:"denied_statements,omitempty"`
+	// Tools 非空时只能调用这些工具
+	Tools []string `yaml:"tools" json:"tools,omitempty"`
+	// Admin 为 true 时可以调用管理类工具
+	Admin bool `yaml:"admin" json:"admin"`
+}
+
+// defaultRoles 为内置角色的默认权限，访问控制文件中的同名角色整体覆盖
+var defaultRoles = map[string]RolePolicy{
+	RoleReader: {ReadOnly: true},
+	RoleWriter: {DeniedStatements: []string{"create", "alter", "drop", "truncate", "rename", "grant", "revoke"}},
+	RoleAdmin:  {Admin: true},
+}
+
+// AccessClient 为一个可识别的客户端，通过 Token 认证
+type AccessClient struct {
+	Name string `yaml:"name"`
+	// Token 为客户端在 Authorization: Bearer 中携带的令牌，可以写成 file:/path 或 vault:path#field 引用
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+// AccessConfig 为按客户端身份的访问控制配置
+type AccessConfig struct {
+	// StdioRole 为标准输入输出传输（启动进程的本地客户端）使用的角色，默认 admin
+	StdioRole string `yaml:"stdio_role"`
+	// AnonymousRole 为 SSE 传输中未携带令牌的客户端使用的角色，为空时拒绝连接
+	AnonymousRole string                `yaml:"anonymous_role"`
+	Roles         map[string]RolePolicy `yaml:"roles"`
+	Clients       []AccessClient        `yaml:"clients"`
+}
+
+// ClientIdentity 为发起工具调用的客户端身份
+type ClientIdentity struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+var (
+	// accessControl 为 nil 时不按客户端身份限制，行为与未开启时一致
+	accessControl *AccessConfig
+	// accessRoles 为合并内置角色后的角色权限
+	accessRoles map[string]RolePolicy
+)
+
+// InitAccessControl 从 YAML 文件加载按客户端身份的访问控制，path 为空时关闭：
+//
+//	stdio_role: admin
+//	anonymous_role: ""
+//	roles:
+//	  writer:
+//	    denied_statements: [drop, truncate, alter]
+//	  analyst:
+//	    read_only: true
+//	    tools: [get_can_use_table, describe_table, execute_sql]
+//	clients:
+//	  - name: report-bot
+//	    token: file:/run/secrets/report_bot_token
+//	    role: reader
+//	  - name: etl-agent
+//	    token: vault:secret/data/mcp#etl_token
+//	    role: writer
+func InitAccessControl(path string) error {
+	accessControl, accessRoles = nil, nil
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取访问控制文件失败: %v", err)
+	}
+	var cfg AccessConfig
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("解析访问控制文件失败: %v", err)
+	}
+
+	roles := make(map[string]RolePolicy, len(defaultRoles)+len(cfg.Roles))
+	for name, role := range defaultRoles {
+		roles[name] = role
+	}
+	for name, role := range cfg.Roles {
+		role.AllowedStatements = normalizeKeywords(role.AllowedStatements)
+		role.DeniedStatements = normalizeKeywords(role.DeniedStatements)
+		roles[name] = role
+	}
+	if cfg.StdioRole == "" {
+		cfg.StdioRole = RoleAdmin
+	}
+	for _, role := range []string{cfg.StdioRole, cfg.AnonymousRole} {
+		if _, ok := roles[role]; role != "" && !ok {
+			return fmt.Errorf("访问控制文件引用了未定义的角色: %s", role)
+		}
+	}
+
+	seen := make(map[string]bool, len(cfg.Clients))
+	for i, client := range cfg.Clients {
+		if client.Name == "" {
+			return fmt.Errorf("访问控制文件中第 %d 个客户端缺少 name", i+1)
+		}
+		if _, ok := roles[client.Role]; !ok {
+			return fmt.Errorf("客户端 %s 的角色未定义: %s", client.Name, client.Role)
+		}
+		token, err := ResolveSecretValue(client.Token)
+		if err != nil {
+			return fmt.Errorf("读取客户端 %s 的令牌失败: %v", client.Name, err)
+		}
+		if token == "" {
+			return fmt.Errorf("客户端 %s 缺少 token", client.Name)
+		}
+		if seen[token] {
+			return fmt.Errorf("客户端 %s 的令牌与其他客户端重复", client.Name)
+		}
+		seen[token] = true
+		cfg.Clients[i].Token = token
+	}
+
+	accessControl, accessRoles = &cfg, roles
+	Logger.Infow("客户端访问控制已开启", "clients", len(cfg.Clients), "stdio_role", cfg.StdioRole, "anonymous_role", cfg.AnonymousRole)
+	return nil
+}
+
+// AccessControlEnabled 判断是否按客户端身份限制权限
+func AccessControlEnabled() bool {
+	return accessControl != nil
+}
+
+// AuthenticateToken 按令牌识别客户端。token 为空时使用 anonymous_role，未配置匿名角色或令牌不匹配时返回 false
+func AuthenticateToken(token string) (ClientIdentity, bool) {
+	if accessControl == nil {
+		return ClientIdentity{}, false
+	}
+	if token == "" {
+		if accessControl.AnonymousRole == "" {
+			return ClientIdentity{}, false
+		}
+		return ClientIdentity{Name: "anonymous", Role: accessControl.AnonymousRole}, true
+	}
+	for _, client := range accessControl.Clients {
+		if subtle.ConstantTimeCompare([]byte(client.Token), []byte(token)) == 1 {
+			return ClientIdentity{Name: client.Name, Role: client.Role}, true
+		}
+	}
+	return ClientIdentity{}, false
+}
+
+type clientIdentityKey struct{}
+
+// WithClientIdentity 在上下文中记录发起调用的客户端身份
+func WithClientIdentity(ctx context.Context, identity ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// ClientIdentityFromContext 返回发起调用的客户端身份，上下文中没有身份时为标准输入输出的本地客户端
+func ClientIdentityFromContext(ctx context.Context) ClientIdentity {
+	if identity, ok := ctx.Value(clientIdentityKey{}).(ClientIdentity); ok {
+		return identity
+	}
+	if accessControl == nil {
+		return ClientIdentity{Name: "stdio", Role: RoleAdmin}
+	}
+	return ClientIdentity{Name: "stdio", Role: accessControl.StdioRole}
+}
+
+// CheckToolAccess 检查客户端的角色是否可以调用工具，admin 为 true 表示管理类工具
+func CheckToolAccess(ctx context.Context, tool string, admin bool) error {
+	if accessControl == nil {
+		return nil
+	}
+	identity := ClientIdentityFromContext(ctx)
+	role := accessRoles[identity.Role]
+	if admin && !role.Admin {
+		return fmt.Errorf("客户端 %s 的角色 %s 不能调用管理工具 %s", identity.Name, identity.Role, tool)
+	}
+	if len(role.Tools) > 0 && !containsFold(role.Tools, tool) {
+		return fmt.Errorf("客户端 %s 的角色 %s 不能调用工具 %s", identity.Name, identity.Role, tool)
+	}
+	return nil
+}
+
+// checkRoleStatement 按客户端角色检查语句，多条语句时逐条检查
+func checkRoleStatement(ctx context.Context, sql string) error {
+	if accessControl == nil {
+		return nil
+	}
+	identity := ClientIdentityFromContext(ctx)
+	role := accessRoles[identity.Role]
+	if role.ReadOnly && !isQueryStatement(sql) {
+		return fmt.Errorf("客户端 %s 的角色 %s 只允许执行查询语句，拒绝执行 %s 语句",
+			identity.Name, identity.Role, statementTypeName(sql))
+	}
+	rules := ConnectionPolicy{AllowedStatements: role.AllowedStatements, DeniedStatements: role.DeniedStatements}
+	for _, statementType := range classifyStatements(sql) {
+		if err := rules.checkStatementType(statementType, fmt.Sprintf("客户端 %s 的角色 %s", identity.Name, identity.Role)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var approvalTable = "approval_audit"
+
+// defaultApprovalStatements 默认需要人工审批的语句类型
+var defaultApprovalStatements = []string{"drop", "truncate", "delete", "update", "alter", "rename", "grant", "revoke"}
+
+// ApprovalRequest 为提交给审批方的一次危险语句执行请求
+type ApprovalRequest struct {
+	ID          string    `json:"id"`
+	SQL         string    `json:"sql"`
+	Statement   string    `json:"statement"`
+	Session     string    `json:"session,omitempty"`
+	Tool        string    `json:"tool,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// ApprovalDecision 为审批结果，Approver 记录审批人
+type ApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Approver string `json:"approver"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Approver 在危险语句执行前收集人工审批。实现方可以阻塞直到有人在带外渠道（IM、工单等）做出决定，
+// 超时或出错时语句不会执行
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+// ApprovalConfig 控制危险语句的人工审批
+type ApprovalConfig struct {
+	// Statements 为需要审批的语句类型（首个关键字），为空时使用默认列表
+	Statements []string
+	// Timeout 为等待审批的最长时间
+	Timeout time.Duration
+}
+
+var (
+	// 当前生效的审批实现，为 nil 时不需要审批
+	approver Approver
+	// 全局审批配置
+	Approval = ApprovalConfig{Statements: defaultApprovalStatements, Timeout: 5 * time.Minute}
+)
+
+// SetApprover 设置审批实现，传入 nil 时关闭审批
+func SetApprover(a Approver, cfg ApprovalConfig) {
+	cfg.Statements = normalizeKeywords(cfg.Statements)
+	if len(cfg.Statements) == 0 {
+		cfg.Statements = defaultApprovalStatements
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	approver, Approval = a, cfg
+}
+
+// needsApproval 判断语句是否需要人工审批
+func needsApproval(sql string) bool {
+	if approver == nil {
+		return false
+	}
+	for _, statementType := range classifyStatements(sql) {
+		for _, s := range Approval.Statements {
+			if s == statementType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// approvedStatementKey 是上下文中记录已审批语句的键
+type approvedStatementKey struct{}
+
+// RequestApproval 在语句需要审批时阻塞等待审批结果，通过后返回带有审批标记的上下文，
+// 之后在该上下文中执行同一语句时不再重复审批。
+// 调用方应在创建执行超时之前调用，避免等待审批的时间占用语句的执行时间
+func RequestApproval(ctx context.Context, sql string) (context.Context, error) {
+	if !needsApproval(sql) {
+		return ctx, nil
+	}
+	if err := collectApproval(ctx, sql); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, approvedStatementKey{}, sql), nil
+}
+
+// checkApproval 在执行前确认语句已审批，未经 RequestApproval 审批时就地发起审批
+func checkApproval(ctx context.Context, sql string) error {
+	if !needsApproval(sql) {
+		return nil
+	}
+	if approved, _ := ctx.Value(approvedStatementKey{}).(string); approved == sql {
+		return nil
+	}
+	return collectApproval(ctx, sql)
+}
+
+// collectApproval 提交审批请求并记录审计，审批失败或超时视为拒绝
+func collectApproval(ctx context.Context, sql string) error {
+	label, _ := ctx.Value(statementLabelKey{}).(statementLabel)
+	req := ApprovalRequest{
+		ID:          newApprovalID(),
+		SQL:         sql,
+		Statement:   classifyStatement(sql),
+		Session:     label.Session,
+		Tool:        label.Tool,
+		RequestedAt: time.Now(),
+	}
+
+	approveCtx, cancel := context.WithTimeout(ctx, Approval.Timeout)
+	defer cancel()
+
+	Logger.Infow("危险语句等待人工审批", "id", req.ID, "statement", req.Statement, "sql", sql)
+	decision, err := approver.Approve(approveCtx, req)
+	var pending *ConfirmationRequiredError
+	if errors.As(err, &pending) {
+		// 两阶段确认：语句未执行，等待用户通过 confirm_execution 确认
+		Logger.Infow("危险语句等待确认", "id", req.ID, "statement", req.Statement)
+		return err
+	}
+	if err != nil {
+		decision = ApprovalDecision{Reason: err.Error()}
+	}
+	recordApproval(req, decision)
+
+	if err != nil {
+		return fmt.Errorf("审批失败，语句未执行 (approval %s): %w", req.ID, err)
+	}
+	if !decision.Approved {
+		return fmt.Errorf("语句被 %s 拒绝执行 (approval %s): %s", decision.Approver, req.ID, decision.Reason)
+	}
+	Logger.Infow("危险语句已审批", "id", req.ID, "approver", decision.Approver)
+	return nil
+}
+
+// newApprovalID 生成审批请求ID
+func newApprovalID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("ap_%d", time.Now().UnixNano())
+	}
+	return "ap_" + hex.EncodeToString(b)
+}
+
+// createApprovalTable 创建审批审计表
+func createApprovalTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			query TEXT NOT NULL,
+			statement TEXT NOT NULL,
+			session TEXT NOT NULL DEFAULT '',
+			tool TEXT NOT NULL DEFAULT '',
+			approved INTEGER NOT NULL,
+			approver TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			requested_at INTEGER NOT NULL,
+			decided_at INTEGER NOT NULL
+		)`, approvalTable))
+	return err
+}
+
+// recordApproval 记录审批结果，失败时只记录日志
+func recordApproval(req ApprovalRequest, decision ApprovalDecision) {
+	Logger.Infow("审批结果", "id", req.ID, "approved", decision.Approved, "approver", decision.Approver,
+		"reason", decision.Reason, "session", req.Session, "tool", req.Tool, "sql", req.SQL)
+
+	if err := InitSQLite(); err != nil {
+		Logger.Warnw("记录审批审计失败", "id", req.ID, "error", err)
+		return
+	}
+	_, err := sqlite().Exec(fmt.Sprintf(`
+		INSERT INTO %s (id, query, statement, session, tool, approved, approver, reason, requested_at, decided_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, approvalTable),
+		req.ID, req.SQL, req.Statement, req.Session, req.Tool, decision.Approved, decision.Approver, decision.Reason,
+		req.RequestedAt.Unix(), time.Now().Unix())
+	if err != nil {
+		Logger.Warnw("记录审批审计失败", "id", req.ID, "error", err)
+	}
+}
+
+// WebhookApprover 通过 HTTP 回调收集审批。
+// 请求体为 ApprovalRequest 的 JSON，审批服务在有人做出决定后返回
+// {"approved": true, "approver": "alice", "reason": "..."}；连接会一直保持到审批完成或超时
+type WebhookApprover struct {
+	URL   string
+	Token string
+}
+
+// Approve 提交审批请求并等待结果
+func (a *WebhookApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	var decision ApprovalDecision
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return decision, fmt.Errorf("JSON 序列化失败: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return decision, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if a.Token != "" {
+		httpReq.Header.Add("Authorization", fmt.Sprintf("Bearer %s", a.Token))
+	}
+	httpReq.Header.Add("Content-Type", "application/json")
+
+	// 超时由上下文控制
+	res, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return decision, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return decision, fmt.Errorf("读取响应失败: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return decision, fmt.Errorf("请求失败，状态码: %d, 响应: %s", res.StatusCode, body)
+	}
+	if err = json.Unmarshal(body, &decision); err != nil {
+		return decision, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if decision.Approved && decision.Approver == "" {
+		return ApprovalDecision{}, fmt.Errorf("审批响应缺少 approver")
+	}
+	return decision, nil
+}
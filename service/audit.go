@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var auditTable = "statement_audit"
+
+// AuditConfig 控制语句审计日志
+type AuditConfig struct {
+	// Enabled 为 true 时把每条执行（包括被拒绝和执行失败）的语句写入 SQLite 的 statement_audit 表
+	Enabled bool
+	// File 非空时同时以 JSON Lines 追加写入该文件
+	File string
+}
+
+// AuditEntry 为一条语句审计记录
+type AuditEntry struct {
+	At         time.Time `json:"at"`
+	Session    string    `json:"session,omitempty"`
+	Client     string    `json:"client,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	Agent      string    `json:"agent,omitempty"`
+	Tool       string    `json:"tool,omitempty"`
+	Statement  string    `json:"statement"`
+	SQL        string    `json:"sql"`
+	Rows       int64     `json:"rows"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	// 全局审计配置
+	Audit AuditConfig
+
+	auditMu     sync.Mutex
+	auditFile   *os.File
+	auditClient string
+)
+
+// InitAudit 初始化语句审计，配置了文件时以只追加方式打开
+func InitAudit(cfg AuditConfig) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if auditFile != nil {
+		auditFile.Close()
+		auditFile = nil
+	}
+	if cfg.Enabled && cfg.File != "" {
+		f, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("打开审计日志文件失败: %v", err)
+		}
+		auditFile = f
+	}
+	Audit = cfg
+	if cfg.Enabled {
+		Logger.Infow("语句审计已开启", "table", auditTable, "file", cfg.File)
+	}
+	return nil
+}
+
+// SetAuditClient 记录 MCP 客户端软件的名称和版本。初始化回调拿不到连接，SSE 下多个客户端会互相覆盖，
+// 因此只写入 stdio 连接的审计记录，SSE 客户端以认证得到的身份区分
+func SetAuditClient(name, version string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditClient = name
+	if version != "" {
+		auditClient += "/" + version
+	}
+}
+
+// createAuditTable 创建审计表，并用触发器禁止修改和删除已有记录
+func createAuditTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			executed_at INTEGER NOT NULL,
+			session TEXT NOT NULL DEFAULT '',
+			client TEXT NOT NULL DEFAULT '',
+			tool TEXT NOT NULL DEFAULT '',
+			statement TEXT NOT NULL DEFAULT '',
+			query TEXT NOT NULL,
+			rows INTEGER NOT NULL DEFAULT 0,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			role TEXT NOT NULL DEFAULT '',
+			agent TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_executed_at ON %[1]s (executed_at);
+		CREATE TRIGGER IF NOT EXISTS %[1]s_no_update BEFORE UPDATE ON %[1]s
+		BEGIN SELECT RAISE(ABORT, '%[1]s is append-only'); END;
+		CREATE TRIGGER IF NOT EXISTS %[1]s_no_delete BEFORE DELETE ON %[1]s
+		BEGIN SELECT RAISE(ABORT, '%[1]s is append-only'); END`, auditTable))
+	if err != nil {
+		return err
+	}
+	// 旧版本的审计表没有客户端角色和客户端软件列
+	return addMissingColumns(db, auditTable, []sqliteColumn{
+		{"role", "TEXT NOT NULL DEFAULT ''"},
+		{"agent", "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// recordAudit 写入一条语句审计记录，失败时只记录日志，不影响语句的执行结果
+func recordAudit(ctx context.Context, sql string, rows int64, duration time.Duration, execErr error) {
+	if !Audit.Enabled {
+		return
+	}
+	label, _ := ctx.Value(statementLabelKey{}).(statementLabel)
+	// 客户端按连接的认证身份记录，SSE 下各连接的语句不会记到最后连接的客户端名下
+	identity := ClientIdentityFromContext(ctx)
+	_, authenticated := ctx.Value(clientIdentityKey{}).(ClientIdentity)
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	entry := AuditEntry{
+		At:         time.Now(),
+		Session:    label.Session,
+		Client:     identity.Name,
+		Role:       identity.Role,
+		Tool:       label.Tool,
+		Statement:  classifyStatement(sql),
+		SQL:        sql,
+		Rows:       rows,
+		DurationMs: duration.Milliseconds(),
+	}
+	if !authenticated {
+		entry.Agent = auditClient
+	}
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+
+	if err := InitSQLite(); err != nil {
+		Logger.Warnw("写入语句审计失败", "error", err)
+	} else {
+		_, err = sqlite().Exec(fmt.Sprintf(`
+			INSERT INTO %s (executed_at, session, client, role, agent, tool, statement, query, rows, duration_ms, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, auditTable),
+			entry.At.UnixMilli(), entry.Session, entry.Client, entry.Role, entry.Agent, entry.Tool, entry.Statement, entry.SQL,
+			entry.Rows, entry.DurationMs, entry.Error)
+		if err != nil {
+			Logger.Warnw("写入语句审计失败", "error", err)
+		}
+	}
+
+	if auditFile != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = auditFile.Write(append(line, '\n'))
+		}
+		if err != nil {
+			Logger.Warnw("写入审计日志文件失败", "file", Audit.File, "error", err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// autoLimit 大于0时，没有 LIMIT 的 SELECT 语句会被追加 LIMIT，避免一次读取整张大表
+var autoLimit int
+
+// InitAutoLimit 设置自动追加的 LIMIT 上限，不大于0时关闭
+func InitAutoLimit(limit int) {
+	autoLimit = limit
+	if limit > 0 {
+		Logger.Infow("已开启自动 LIMIT，没有 LIMIT 的 SELECT 语句最多返回该行数", "limit", limit)
+	}
+}
+
+// applyAutoLimit 在开启自动 LIMIT 且语句是没有 LIMIT 的单条 SELECT 时追加 LIMIT，返回改写后的语句和行数上限。
+// 实际追加的是上限加一，多读的一行只用于判断结果是否被截断，不会返回给调用方。
+// 带 INTO（导出文件或变量）、FOR UPDATE、LOCK IN SHARE MODE 等尾部子句的语句不改写，行数上限为0
+func applyAutoLimit(sql string) (string, int) {
+	if autoLimit <= 0 {
+		return sql, 0
+	}
+	statements := sqlTokens(sql)
+	// EXPLAIN ANALYZE SELECT 也归类为 select，追加 LIMIT 会改变被分析的语句
+	if len(statements) != 1 || classifyTokens(statements[0]) != "select" || statements[0][0] != "select" && statements[0][0] != "with" {
+		return sql, 0
+	}
+	for _, token := range statements[0] {
+		switch token {
+		case "limit", "into", "for", "lock", "procedure":
+			return sql, 0
+		}
+	}
+
+	trimmed := strings.TrimRight(strings.TrimSpace(sql), "; \t\r\n")
+	if strings.Contains(trimmed, ";") {
+		// 分号后只剩注释等情况无法安全地在末尾追加，保持原语句
+		return sql, 0
+	}
+	// 换行追加，避免被语句末尾的 -- 或 # 注释吞掉
+	return fmt.Sprintf("%s\nLIMIT %d", trimmed, autoLimit+1), autoLimit
+}
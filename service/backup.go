@@ -0,0 +1,496 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// backupKindMysqldump 为 mysqldump 生成的 .sql / .sql.gz 文件
+	backupKindMysqldump = "mysqldump"
+	// backupKindShell 为 MySQL Shell util.dumpInstance/dumpSchemas 生成的目录
+	backupKindShell = "mysqlsh"
+
+	// backupOutputLimit 备份命令输出保留的最大字节数（末尾部分）
+	backupOutputLimit = 4096
+	// dumpCompletedPrefix 为 mysqldump 在文件末尾写入的完成标记，
+	// 默认带有 " on <时间>"，使用 --skip-dump-date 时只有标记本身
+	dumpCompletedPrefix = "-- Dump completed"
+)
+
+// BackupOptions 备份检查的参数
+type BackupOptions struct {
+	// Dir 为存放逻辑备份的目录
+	Dir string
+	// MaxAge 为最新备份允许的最长时间，超过视为过期
+	MaxAge time.Duration
+	// Command 非空时 trigger 会通过 sh -c 执行该命令生成新的备份
+	Command string
+	// CommandTimeout 为备份命令的超时时间
+	CommandTimeout time.Duration
+	// Name 指定检查目录中的某个备份，为空时检查最新的备份
+	Name string
+	// Trigger 为 true 时先执行备份命令
+	Trigger bool
+	// VerifyCounts 为 true 时读取备份内容，与当前数据库的行数比较
+	VerifyCounts bool
+}
+
+// BackupTableCheck 备份中一张表与当前数据库的比较
+type BackupTableCheck struct {
+	Table      string `json:"table"`
+	BackupRows *int64 `json:"backup_rows,omitempty"`
+	LiveRows   *int64 `json:"live_rows,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// BackupReport 备份检查结果
+type BackupReport struct {
+	Healthy       bool               `json:"healthy"`
+	Problems      []string           `json:"problems,omitempty"`
+	Triggered     bool               `json:"triggered,omitempty"`
+	CommandOutput string             `json:"command_output,omitempty"`
+	Path          string             `json:"path,omitempty"`
+	Kind          string             `json:"kind,omitempty"`
+	Completed     *bool              `json:"completed,omitempty"`
+	CompletedAt   *time.Time         `json:"completed_at,omitempty"`
+	AgeHours      float64            `json:"age_hours,omitempty"`
+	SizeBytes     int64              `json:"size_bytes,omitempty"`
+	Backups       int                `json:"backups_found"`
+	MissingTables []string           `json:"missing_tables,omitempty"`
+	Tables        []BackupTableCheck `json:"tables,omitempty"`
+	Note          string             `json:"note,omitempty"`
+}
+
+// backupEntry 为备份目录中的一个备份
+type backupEntry struct {
+	path    string
+	kind    string
+	modTime time.Time
+	size    int64
+}
+
+// dumpContents 为从备份中读出的内容
+type dumpContents struct {
+	completed   *bool
+	completedAt *time.Time
+	// tables 为当前数据库在备份中的表，rows 为 nil 表示备份格式没有记录行数
+	tables map[string]*int64
+}
+
+// VerifyBackups 检查备份目录中最新（或指定）的逻辑备份：是否完整、是否过期、是否包含当前数据库的所有表，
+// VerifyCounts 为 true 时逐表比较备份中的行数与当前的行数
+func VerifyBackups(ctx context.Context, db *sql.DB, opts BackupOptions) (*BackupReport, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("未配置备份目录 BACKUP_DIR")
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	report := &BackupReport{}
+
+	if opts.Trigger {
+		if opts.Command == "" {
+			return nil, fmt.Errorf("未配置备份命令 BACKUP_COMMAND，不能触发备份")
+		}
+		output, err := runBackupCommand(ctx, opts.Command, opts.CommandTimeout)
+		report.Triggered = true
+		report.CommandOutput = output
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("backup command failed: %v", err))
+			return report, nil
+		}
+	}
+
+	entries, err := listBackups(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	report.Backups = len(entries)
+	if len(entries) == 0 {
+		report.Problems = append(report.Problems, "no backups found in "+opts.Dir)
+		return report, nil
+	}
+	entry := entries[0]
+	if opts.Name != "" {
+		if opts.Name != filepath.Base(opts.Name) {
+			return nil, fmt.Errorf("备份名称不能包含路径: %s", opts.Name)
+		}
+		found := false
+		for _, e := range entries {
+			if filepath.Base(e.path) == opts.Name {
+				entry, found = e, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("备份不存在: %s", opts.Name)
+		}
+	}
+	report.Path, report.Kind, report.SizeBytes = entry.path, entry.kind, entry.size
+
+	var database string
+	if err = db.QueryRowContext(ctx, "SELECT COALESCE(DATABASE(), '')").Scan(&database); err != nil {
+		return nil, fmt.Errorf("查询当前数据库失败: %v", err)
+	}
+	contents, err := readBackup(ctx, entry, database, opts.VerifyCounts)
+	if err != nil {
+		return nil, err
+	}
+	report.Completed = contents.completed
+	completedAt := entry.modTime
+	if contents.completedAt != nil {
+		completedAt = *contents.completedAt
+	}
+	report.CompletedAt = &completedAt
+	age := time.Since(completedAt)
+	report.AgeHours = float64(age.Round(time.Minute)) / float64(time.Hour)
+
+	if contents.completed != nil && !*contents.completed {
+		report.Problems = append(report.Problems, "the backup is incomplete (no completion marker); the dump may have been interrupted")
+	}
+	if opts.MaxAge > 0 && age > opts.MaxAge {
+		report.Problems = append(report.Problems, fmt.Sprintf("the backup is %.1f hours old, older than the allowed %s", report.AgeHours, opts.MaxAge))
+	}
+
+	if contents.tables != nil {
+		if err = compareBackupTables(ctx, db, contents, report); err != nil {
+			return nil, err
+		}
+	} else if entry.kind == backupKindMysqldump {
+		report.Note = "table contents were not checked; pass verify_counts to read the dump and compare row counts"
+		if contents.completed == nil {
+			report.Note += " (a compressed dump must be read in full to check its completion marker)"
+		}
+	}
+	report.Healthy = len(report.Problems) == 0
+	return report, nil
+}
+
+// runBackupCommand 执行备份命令，返回输出的末尾部分
+func runBackupCommand(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = time.Hour
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	Logger.Infow("执行备份命令", "command", command)
+	output, err := exec.CommandContext(cmdCtx, "sh", "-c", command).CombinedOutput()
+	if len(output) > backupOutputLimit {
+		output = output[len(output)-backupOutputLimit:]
+	}
+	if cmdCtx.Err() != nil {
+		err = fmt.Errorf("备份命令超时（%s）", timeout)
+	}
+	if err != nil {
+		Logger.Errorw("备份命令执行失败", "command", command, "error", err)
+	}
+	return strings.TrimSpace(string(output)), err
+}
+
+// listBackups 列出目录中的备份，按修改时间从新到旧排序
+func listBackups(dir string) ([]backupEntry, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取备份目录失败: %v", err)
+	}
+	var entries []backupEntry
+	for _, item := range items {
+		path := filepath.Join(dir, item.Name())
+		info, err := item.Info()
+		if err != nil {
+			continue
+		}
+		switch {
+		case item.IsDir():
+			if _, err := os.Stat(filepath.Join(path, "@.json")); err != nil {
+				continue
+			}
+			entries = append(entries, backupEntry{path: path, kind: backupKindShell, modTime: info.ModTime(), size: dirSize(path)})
+		case strings.HasSuffix(item.Name(), ".sql") || strings.HasSuffix(item.Name(), ".sql.gz"):
+			entries = append(entries, backupEntry{path: path, kind: backupKindMysqldump, modTime: info.ModTime(), size: info.Size()})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.After(entries[j].modTime) })
+	return entries, nil
+}
+
+// dirSize 统计目录下文件的总大小
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// readBackup 读取备份的完成状态，full 为 true 时同时读出备份中属于 database 的表和行数
+func readBackup(ctx context.Context, entry backupEntry, database string, full bool) (*dumpContents, error) {
+	if entry.kind == backupKindShell {
+		return readShellDump(entry.path, database)
+	}
+	if !full {
+		if strings.HasSuffix(entry.path, ".gz") {
+			// 压缩文件只能完整解压后才能看到末尾，不读取内容时完成状态未知
+			return &dumpContents{}, nil
+		}
+		return readDumpTail(entry.path)
+	}
+	return scanDump(ctx, entry.path, database)
+}
+
+// readDumpTail 只读取 mysqldump 文件末尾，检查完成标记
+func readDumpTail(path string) (*dumpContents, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取备份文件失败: %v", err)
+	}
+	offset := max(info.Size()-1024, 0)
+	tail := make([]byte, info.Size()-offset)
+	if _, err = f.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("读取备份文件失败: %v", err)
+	}
+	contents := &dumpContents{}
+	lines := strings.Split(strings.TrimSpace(string(tail)), "\n")
+	contents.setCompletion(lines[len(lines)-1])
+	return contents, nil
+}
+
+// setCompletion 根据 mysqldump 的最后一行设置完成状态
+func (c *dumpContents) setCompletion(lastLine string) {
+	completed := strings.HasPrefix(lastLine, dumpCompletedPrefix)
+	c.completed = &completed
+	if !completed {
+		return
+	}
+	date, ok := strings.CutPrefix(strings.TrimPrefix(lastLine, dumpCompletedPrefix), " on ")
+	if !ok {
+		return
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04:05", date, time.Local)
+	if err == nil {
+		c.completedAt = &at
+	}
+}
+
+// scanDump 完整读取 mysqldump 文件，统计 database 中每张表 INSERT 的行数
+func scanDump(ctx context.Context, path, database string) (*dumpContents, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("解压备份文件失败: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	contents := &dumpContents{tables: make(map[string]*int64)}
+	reader := bufio.NewReaderSize(r, 1<<20)
+	// dumpDatabase 为当前段落所属的数据库，单库备份没有 USE 语句时为空
+	var dumpDatabase, lastLine string
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		line, readErr := reader.ReadString('\n')
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lastLine = trimmed
+		}
+		switch {
+		case strings.HasPrefix(line, "USE "):
+			dumpDatabase = strings.Trim(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line[4:]), ";")), "`")
+		case strings.HasPrefix(line, "CREATE TABLE "):
+			if dumpDatabase == "" || dumpDatabase == database {
+				if table := dumpTableName(line[len("CREATE TABLE "):]); table != "" {
+					if _, ok := contents.tables[table]; !ok {
+						contents.tables[table] = new(int64)
+					}
+				}
+			}
+		case strings.HasPrefix(line, "INSERT INTO "):
+			if dumpDatabase == "" || dumpDatabase == database {
+				table := dumpTableName(line[len("INSERT INTO "):])
+				if _, ok := contents.tables[table]; !ok {
+					contents.tables[table] = new(int64)
+				}
+				*contents.tables[table] += countInsertRows(line)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("读取备份文件失败: %v", readErr)
+		}
+	}
+	contents.setCompletion(lastLine)
+	return contents, nil
+}
+
+// dumpTableName 取出语句开头的表名，跳过 IF NOT EXISTS
+func dumpTableName(s string) string {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "IF NOT EXISTS ")
+	if !strings.HasPrefix(s, "`") {
+		name, _, _ := strings.Cut(s, " ")
+		return name
+	}
+	end := strings.Index(s[1:], "`")
+	if end < 0 {
+		return ""
+	}
+	return s[1 : end+1]
+}
+
+// countInsertRows 统计一条 INSERT ... VALUES (...),(...) 语句中的行数，跳过字符串中的括号
+func countInsertRows(line string) int64 {
+	i := strings.Index(line, " VALUES ")
+	if i < 0 {
+		return 0
+	}
+	var (
+		rows  int64
+		depth int
+		quote byte
+	)
+	for j := i + len(" VALUES "); j < len(line); j++ {
+		c := line[j]
+		if quote != 0 {
+			switch c {
+			case '\\':
+				j++
+			case quote:
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '(':
+			if depth == 0 {
+				rows++
+			}
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return rows
+}
+
+// readShellDump 读取 MySQL Shell 备份目录的完成标记和包含的表。Shell 备份不记录行数，只比较表是否齐全
+func readShellDump(dir, database string) (*dumpContents, error) {
+	contents := &dumpContents{}
+	data, err := os.ReadFile(filepath.Join(dir, "@.done.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		completed := false
+		contents.completed = &completed
+		return contents, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取备份完成标记失败: %v", err)
+	}
+
+	var done struct {
+		End            string                      `json:"end"`
+		TableDataBytes map[string]map[string]int64 `json:"tableDataBytes"`
+	}
+	if err = json.Unmarshal(data, &done); err != nil {
+		return nil, fmt.Errorf("解析备份完成标记失败: %v", err)
+	}
+	completed := true
+	contents.completed = &completed
+	if at, err := time.ParseInLocation("2006-01-02 15:04:05", done.End, time.Local); err == nil {
+		contents.completedAt = &at
+	}
+
+	if tables, ok := done.TableDataBytes[database]; ok {
+		contents.tables = make(map[string]*int64, len(tables))
+		for table := range tables {
+			contents.tables[table] = nil
+		}
+	}
+	return contents, nil
+}
+
+// compareBackupTables 比较备份中的表与当前数据库：缺少的表计为问题，有行数时与当前行数比较
+func compareBackupTables(ctx context.Context, db *sql.DB, contents *dumpContents, report *BackupReport) error {
+	live, err := EstimateRowCounts(ctx, db, "", false, 0)
+	if err != nil {
+		return err
+	}
+	for _, t := range live.Tables {
+		rows, ok := contents.tables[t.Table]
+		if !ok {
+			report.MissingTables = append(report.MissingTables, t.Table)
+			continue
+		}
+		if rows == nil {
+			continue
+		}
+		check := BackupTableCheck{Table: t.Table, BackupRows: rows}
+		count, err := exactRowCount(ctx, db, t.Table, defaultExactCountTimeout)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+			estimate := t.Estimate
+			check.LiveRows = &estimate
+			check.Note = "COUNT(*) timed out; live_rows is an estimate"
+		case err != nil:
+			return err
+		default:
+			check.LiveRows = &count
+			if count != *rows {
+				check.Note = "row count differs; writes since the backup can explain small differences"
+			}
+		}
+		report.Tables = append(report.Tables, check)
+	}
+	if len(report.MissingTables) > 0 {
+		report.Problems = append(report.Problems,
+			fmt.Sprintf("%d table(s) in the database are not in the backup", len(report.MissingTables)))
+	}
+	if report.Kind == backupKindShell {
+		report.Note = "MySQL Shell dumps do not record row counts; only completion and table coverage were checked"
+	}
+	return nil
+}
+
+// FormatBackupReport 将备份检查结果序列化为 JSON
+func FormatBackupReport(report *BackupReport) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal backup report to JSON: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,25 @@
+package service
+
+import "testing"
+
+func TestDumpCompletion(t *testing.T) {
+	cases := []struct {
+		line      string
+		completed bool
+		dated     bool
+	}{
+		{"-- Dump completed on 2024-05-01 03:00:12", true, true},
+		{"-- Dump completed", true, false},
+		{"UNLOCK TABLES;", false, false},
+	}
+	for _, c := range cases {
+		var contents dumpContents
+		contents.setCompletion(c.line)
+		if *contents.completed != c.completed {
+			t.Errorf("setCompletion(%q) completed = %v, want %v", c.line, *contents.completed, c.completed)
+		}
+		if dated := contents.completedAt != nil; dated != c.dated {
+			t.Errorf("setCompletion(%q) has time = %v, want %v", c.line, dated, c.dated)
+		}
+	}
+}
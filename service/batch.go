@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxBatchQueries 一次批量执行的最大语句数
+const maxBatchQueries = 20
+
+// ExecuteBatch 依次执行多条查询语句并合并结果，单条语句失败不影响其余语句。
+// snapshot 为 true 时所有语句在同一个 REPEATABLE READ 只读事务的一致性快照中执行，
+// 用于总数与明细等需要相互对得上的多段查询
+func ExecuteBatch(ctx context.Context, db *sql.DB, queries []string, snapshot bool, opts ExecOptions) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if len(queries) == 0 {
+		return "", fmt.Errorf("queries is empty")
+	}
+	if len(queries) > maxBatchQueries {
+		return "", fmt.Errorf("一次最多执行 %d 条语句", maxBatchQueries)
+	}
+	for i, q := range queries {
+		if !isQueryStatement(q) {
+			return "", fmt.Errorf("第 %d 条语句不是查询语句，批量执行只支持 SELECT、SHOW、DESCRIBE 和 EXPLAIN", i+1)
+		}
+	}
+
+	return withBreaker(func() (string, error) {
+		conn, release, waited, err := acquireConn(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		if snapshot {
+			if _, err = conn.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+				return "", fmt.Errorf("设置隔离级别失败: %w", err)
+			}
+			if _, err = conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY"); err != nil {
+				return "", fmt.Errorf("开启快照事务失败: %w", err)
+			}
+			// 只读事务没有需要提交的修改，结束时提交仅用于释放快照
+			defer conn.ExecContext(context.Background(), "COMMIT")
+		}
+
+		var b strings.Builder
+		if snapshot {
+			b.WriteString(Text("batch_snapshot") + "\n\n")
+		}
+		for i, q := range queries {
+			fmt.Fprintf(&b, "-- [%d] %s\n", i+1, strings.TrimSpace(q))
+			res, err := runStatement(ctx, conn, q, opts)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", err
+				}
+				b.WriteString(Text("batch_error", err) + "\n\n")
+				continue
+			}
+			b.WriteString(res)
+			b.WriteString("\n\n")
+		}
+		return strings.TrimRight(b.String(), "\n") + queueNote(waited), nil
+	})
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultBenchK 评测文件未指定 k 时使用的截断位置
+const defaultBenchK = 5
+
+// BenchQuery 表示一条带标注的评测查询
+type BenchQuery struct {
+	Query    string   `yaml:"query" json:"query"`
+	Expected []string `yaml:"expected" json:"expected"`
+}
+
+// BenchSuite 表示一个检索评测文件
+//
+//	k: 5
+//	translate: false
+//	queries:
+//	  - query: 每个用户最近一个月的订单金额
+//	    expected: [orders, users]
+type BenchSuite struct {
+	K         int          `yaml:"k"`
+	Translate bool         `yaml:"translate"`
+	Queries   []BenchQuery `yaml:"queries"`
+}
+
+// BenchResult 表示单条查询的评测结果
+type BenchResult struct {
+	Query     string   `json:"query"`
+	Expected  []string `json:"expected"`
+	Retrieved []string `json:"retrieved"`
+	// Rank 为第一个命中的预期表的名次（从1开始），0 表示前 k 条中没有命中
+	Rank  int    `json:"rank"`
+	Error string `json:"error,omitempty"`
+}
+
+// BenchReport 表示一次检索评测的汇总结果
+type BenchReport struct {
+	K       int           `json:"k"`
+	Queries int           `json:"queries"`
+	HitAtK  float64       `json:"hit_at_k"`
+	MRR     float64       `json:"mrr"`
+	Errors  int           `json:"errors"`
+	Elapsed string        `json:"elapsed"`
+	Results []BenchResult `json:"results"`
+}
+
+// LoadBenchSuite 读取 YAML 格式的评测文件
+func LoadBenchSuite(path string) (*BenchSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取评测文件失败: %v", err)
+	}
+	var suite BenchSuite
+	if err = yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("解析评测文件失败: %v", err)
+	}
+	if len(suite.Queries) == 0 {
+		return nil, fmt.Errorf("评测文件中没有查询")
+	}
+	for i, q := range suite.Queries {
+		if q.Query == "" || len(q.Expected) == 0 {
+			return nil, fmt.Errorf("第 %d 条查询缺少 query 或 expected", i+1)
+		}
+	}
+	if suite.K <= 0 {
+		suite.K = defaultBenchK
+	}
+	return &suite, nil
+}
+
+// RunRetrievalBench 对当前索引逐条执行评测查询，计算 hit@k 和 MRR，
+// 用于客观比较不同的嵌入模型、重排序和排序规则配置。置顶的表不参与排名
+func RunRetrievalBench(ctx context.Context, cli *milvusclient.Client, suite *BenchSuite) BenchReport {
+	start := time.Now()
+	report := BenchReport{K: suite.K, Queries: len(suite.Queries)}
+	var hits int
+	var reciprocal float64
+
+	for _, q := range suite.Queries {
+		result := BenchResult{Query: q.Query, Expected: q.Expected}
+		ranked, _, err := discoverSchemaHits(ctx, cli, q.Query, DiscoverOptions{Translate: suite.Translate, Limit: suite.K})
+		if err != nil {
+			result.Error = err.Error()
+			report.Errors++
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		for i, hit := range ranked {
+			name, _ := tableNameFromSchema(hit.Schema)
+			result.Retrieved = append(result.Retrieved, name)
+			if result.Rank == 0 && containsFold(q.Expected, name) {
+				result.Rank = i + 1
+			}
+		}
+		if result.Rank > 0 {
+			hits++
+			reciprocal += 1 / float64(result.Rank)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	report.HitAtK = float64(hits) / float64(report.Queries)
+	report.MRR = reciprocal / float64(report.Queries)
+	report.Elapsed = time.Since(start).Round(time.Millisecond).String()
+	return report
+}
+
+// containsFold 判断列表中是否包含指定表名（忽略大小写）
+func containsFold(list []string, name string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDatabaseDegraded 表示熔断器处于打开状态，调用被快速失败
+var ErrDatabaseDegraded = errors.New("database degraded")
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	// FailureThreshold 连续失败（或连续慢查询）达到该次数时熔断，0 表示关闭熔断器
+	FailureThreshold int
+	// LatencyThreshold 单次执行耗时超过该值视为慢查询，0 表示不按延迟熔断
+	LatencyThreshold time.Duration
+	// Cooldown 熔断后快速失败的持续时间
+	Cooldown time.Duration
+}
+
+// CircuitBreaker 保护 MySQL 后端，避免在数据库异常时被代理的重试风暴继续冲击
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	cfg         BreakerConfig
+	failures    int
+	slowCalls   int
+	openUntil   time.Time
+	lastFailure string
+}
+
+// 全局 MySQL 熔断器
+var Breaker = NewCircuitBreaker(BreakerConfig{})
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// InitBreakerConfig 初始化全局熔断器
+func InitBreakerConfig(cfg BreakerConfig) {
+	Breaker = NewCircuitBreaker(cfg)
+}
+
+// Allow 判断当前是否允许访问数据库，熔断期间返回 ErrDatabaseDegraded
+func (b *CircuitBreaker) Allow() error {
+	if b.cfg.FailureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if remaining := time.Until(b.openUntil); remaining > 0 {
+		return fmt.Errorf("%w: recent failures (%s), retry after %s", ErrDatabaseDegraded,
+			b.lastFailure, remaining.Round(time.Second))
+	}
+	return nil
+}
+
+// Record 记录一次数据库调用的结果
+func (b *CircuitBreaker) Record(duration time.Duration, err error) {
+	if b.cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case isBackendFailure(err):
+		b.failures++
+		b.lastFailure = err.Error()
+	case b.cfg.LatencyThreshold > 0 && duration > b.cfg.LatencyThreshold:
+		b.slowCalls++
+		b.lastFailure = fmt.Sprintf("latency %s exceeds %s", duration.Round(time.Millisecond), b.cfg.LatencyThreshold)
+	default:
+		b.failures = 0
+		b.slowCalls = 0
+		return
+	}
+
+	if b.failures >= b.cfg.FailureThreshold || b.slowCalls >= b.cfg.FailureThreshold {
+		b.openUntil = time.Now().Add(b.cfg.Cooldown)
+		// 冷却结束后处于半开状态：下一次失败会立即再次熔断
+		b.failures = b.cfg.FailureThreshold - 1
+		b.slowCalls = b.cfg.FailureThreshold - 1
+		Logger.Warnw("MySQL熔断器已打开", "cooldown", b.cfg.Cooldown, "reason", b.lastFailure)
+	}
+}
+
+// isBackendFailure 判断错误是否反映后端故障。
+// 语法错误等服务端正常返回的语句错误是调用方的问题，不计入熔断
+func isBackendFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pending *ConfirmationRequiredError
+	if errors.As(err, &pending) {
+		return false
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, // Too many connections
+			1053, // Server shutdown in progress
+			1203: // User has exceeded max_user_connections
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// withBreaker 在熔断器保护下执行一次数据库调用
+func withBreaker(fn func() (string, error)) (string, error) {
+	if err := Breaker.Allow(); err != nil {
+		return "", err
+	}
+	start := time.Now()
+	res, err := fn()
+	Breaker.Record(time.Since(start), err)
+	return res, err
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// EstimateTokens 粗略估算文本的 token 数：ASCII 字符约 4 个一个 token，其他字符（如汉字）按一个 token 计
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// fitSchemaHitsToBudget 按分数顺序选取能放入预算的表结构，至少保留一条
+func fitSchemaHitsToBudget(hits []SchemaHit, maxTokens int) []SchemaHit {
+	used := 0
+	for i, hit := range hits {
+		used += EstimateTokens(hit.Schema)
+		if used > maxTokens && i > 0 {
+			return hits[:i]
+		}
+	}
+	return hits
+}
+
+// shapeRows 按上下文预算格式化结果集：预算充足时保持缩进格式，否则改用紧凑格式；
+// 仍然超出时默认返回服务端计算的统计摘要，raw 为 true 时截断行并附加说明
+func shapeRows(columns []string, resultSet []map[string]interface{}, maxTokens int, raw bool) (string, error) {
+	indented, err := json.MarshalIndent(resultSet, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	if maxTokens <= 0 || EstimateTokens(string(indented)) <= maxTokens {
+		return string(indented), nil
+	}
+
+	compact, err := json.Marshal(resultSet)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	if EstimateTokens(string(compact)) <= maxTokens {
+		return string(compact), nil
+	}
+
+	if !raw {
+		return summarizeRows(columns, resultSet, maxTokens)
+	}
+
+	// 逐行累加直到超出预算
+	used := 2 // 数组括号
+	kept := 0
+	for _, row := range resultSet {
+		rowJSON, err := json.Marshal(row)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+		}
+		used += EstimateTokens(string(rowJSON)) + 1
+		if used > maxTokens {
+			break
+		}
+		kept++
+	}
+
+	truncated, err := json.Marshal(resultSet[:kept])
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return fmt.Sprintf("%s\n\n%s", truncated, Text("budget_truncated", kept, len(resultSet), maxTokens)), nil
+}
@@ -0,0 +1,724 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	snapshotTable      = "schema_snapshots"
+	snapshotTableRows  = "schema_snapshot_tables"
+	snapshotDefinition = "schema_definitions"
+)
+
+const (
+	// minVolumeShiftRows 行数变化小于该值时不视为明显的数据量变化，避免小表的比例波动
+	minVolumeShiftRows = 1000
+	// maxChangelogStatements 返回的 DDL 语句条数上限
+	maxChangelogStatements = 50
+)
+
+// ChangelogConfig 控制表结构快照的采集和 what_changed_since 的汇总
+type ChangelogConfig struct {
+	// Interval 两次快照之间的间隔，不大于0时不定时采集
+	Interval time.Duration
+	// Retention 快照保留时长，更早的快照会被清理（至少保留最近一次）
+	Retention time.Duration
+	// VolumeShift 估算行数的相对变化达到该比例时视为明显的数据量变化
+	VolumeShift float64
+	// Binlog 为 true 时快照记录 binlog 位置，汇总时读取之后的 binlog 事件
+	Binlog bool
+	// BinlogMaxEvents 汇总时最多读取的 binlog 事件数
+	BinlogMaxEvents int
+}
+
+// 全局快照配置
+var ChangelogSettings = ChangelogConfig{Retention: 30 * 24 * time.Hour, VolumeShift: 0.2, BinlogMaxEvents: 10000}
+
+// InitChangelogConfig 初始化表结构快照配置
+func InitChangelogConfig(cfg ChangelogConfig) {
+	if cfg.Retention <= 0 {
+		cfg.Retention = 30 * 24 * time.Hour
+	}
+	if cfg.VolumeShift <= 0 {
+		cfg.VolumeShift = 0.2
+	}
+	if cfg.BinlogMaxEvents <= 0 {
+		cfg.BinlogMaxEvents = 10000
+	}
+	ChangelogSettings = cfg
+}
+
+// schemaSnapshot 为某一时刻所有表的定义、估算行数和 binlog 位置
+type schemaSnapshot struct {
+	takenAt    time.Time
+	binlogFile string
+	binlogPos  int64
+	tables     map[string]*snapshotTableState
+}
+
+// snapshotTableState 为快照中的一张表
+type snapshotTableState struct {
+	def       *tableDef
+	rows      int64
+	dataBytes int64
+}
+
+// VolumeShift 为一张表估算行数的明显变化
+type VolumeShift struct {
+	Table      string  `json:"table"`
+	RowsBefore int64   `json:"rows_before"`
+	RowsNow    int64   `json:"rows_now"`
+	Change     float64 `json:"change_percent"`
+	BytesNow   int64   `json:"bytes_now"`
+}
+
+// ChangelogStatement 为审计日志中的一条 DDL 语句
+type ChangelogStatement struct {
+	At      time.Time `json:"at"`
+	Session string    `json:"session,omitempty"`
+	Tool    string    `json:"tool,omitempty"`
+	SQL     string    `json:"sql"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ActivitySummary 为通过本服务执行的语句汇总，来自语句审计和查询历史
+type ActivitySummary struct {
+	Statements  map[string]int       `json:"statements_by_type,omitempty"`
+	Failed      int                  `json:"failed,omitempty"`
+	RowsWritten map[string]int64     `json:"rows_written_by_table,omitempty"`
+	DDL         []ChangelogStatement `json:"ddl,omitempty"`
+	Queries     int                  `json:"history_queries,omitempty"`
+}
+
+// BinlogSummary 为 binlog 中的变化汇总，包括不经过本服务的写入
+type BinlogSummary struct {
+	From         string           `json:"from"`
+	EventsRead   int              `json:"events_read"`
+	Truncated    bool             `json:"truncated,omitempty"`
+	RowEvents    map[string]int64 `json:"row_events_by_table,omitempty"`
+	DDL          []string         `json:"ddl,omitempty"`
+	Transactions int64            `json:"transactions"`
+}
+
+// ChangeReport 为 what_changed_since 的结果
+type ChangeReport struct {
+	Since         time.Time        `json:"since"`
+	BaselineAt    *time.Time       `json:"baseline_snapshot_at,omitempty"`
+	Now           time.Time        `json:"now"`
+	Summary       []string         `json:"summary"`
+	AddedTables   []string         `json:"added_tables,omitempty"`
+	RemovedTables []string         `json:"removed_tables,omitempty"`
+	ChangedTables []TableDiff      `json:"changed_tables,omitempty"`
+	VolumeShifts  []VolumeShift    `json:"volume_shifts,omitempty"`
+	Activity      *ActivitySummary `json:"activity,omitempty"`
+	Binlog        *BinlogSummary   `json:"binlog,omitempty"`
+	Notes         []string         `json:"notes,omitempty"`
+}
+
+// createSnapshotTables 创建快照表，表定义按内容哈希去重保存
+func createSnapshotTables(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			taken_at INTEGER NOT NULL,
+			binlog_file TEXT NOT NULL DEFAULT '',
+			binlog_pos INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_taken_at ON %[1]s (taken_at);
+		CREATE TABLE IF NOT EXISTS %[2]s (
+			snapshot_id INTEGER NOT NULL,
+			table_name TEXT NOT NULL,
+			definition_hash TEXT NOT NULL,
+			rows_estimate INTEGER NOT NULL DEFAULT 0,
+			data_bytes INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (snapshot_id, table_name)
+		);
+		CREATE TABLE IF NOT EXISTS %[3]s (
+			hash TEXT PRIMARY KEY,
+			definition TEXT NOT NULL
+		)`, snapshotTable, snapshotTableRows, snapshotDefinition))
+	return err
+}
+
+// RecordSchemaSnapshots 定时保存表结构快照，Interval 不大于0时直接返回。
+// 启动后先保存一次，之后按 Interval 重复，调度方式与增量索引相同，ctx 取消后返回
+func RecordSchemaSnapshots(ctx context.Context, db *sql.DB) {
+	if ChangelogSettings.Interval <= 0 {
+		return
+	}
+	runPeriodic(ctx, db, "snapshot", ChangelogSettings.Interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
+		if err := SaveSchemaSnapshot(ctx, db); err != nil {
+			Logger.Warnw("保存表结构快照失败", "error", err)
+		}
+	})
+}
+
+// SaveSchemaSnapshot 采集并保存一次表结构快照，并清理超过保留时长的快照
+func SaveSchemaSnapshot(ctx context.Context, db *sql.DB) error {
+	snap, err := captureSnapshot(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err = InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	tx, err := sqlite().Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (taken_at, binlog_file, binlog_pos) VALUES (?, ?, ?)", snapshotTable),
+		snap.takenAt.Unix(), snap.binlogFile, snap.binlogPos)
+	if err != nil {
+		return fmt.Errorf("保存快照失败: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("保存快照失败: %v", err)
+	}
+	for name, t := range snap.tables {
+		definition, err := json.Marshal(t.def)
+		if err != nil {
+			return fmt.Errorf("序列化表定义失败: %v", err)
+		}
+		sum := sha256.Sum256(definition)
+		hash := hex.EncodeToString(sum[:])
+		if _, err = tx.Exec(fmt.Sprintf("INSERT OR IGNORE INTO %s (hash, definition) VALUES (?, ?)", snapshotDefinition),
+			hash, string(definition)); err != nil {
+			return fmt.Errorf("保存表定义失败: %v", err)
+		}
+		if _, err = tx.Exec(fmt.Sprintf(`
+			INSERT INTO %s (snapshot_id, table_name, definition_hash, rows_estimate, data_bytes) VALUES (?, ?, ?, ?, ?)`, snapshotTableRows),
+			id, name, hash, t.rows, t.dataBytes); err != nil {
+			return fmt.Errorf("保存快照失败: %v", err)
+		}
+	}
+
+	// 清理过期快照，不再被引用的表定义一并删除
+	cutoff := snap.takenAt.Add(-ChangelogSettings.Retention).Unix()
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE snapshot_id IN (SELECT id FROM %s WHERE taken_at < ? AND id <> ?)",
+		snapshotTableRows, snapshotTable), cutoff, id); err != nil {
+		return fmt.Errorf("清理过期快照失败: %v", err)
+	}
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE taken_at < ? AND id <> ?", snapshotTable), cutoff, id); err != nil {
+		return fmt.Errorf("清理过期快照失败: %v", err)
+	}
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE hash NOT IN (SELECT definition_hash FROM %s)",
+		snapshotDefinition, snapshotTableRows)); err != nil {
+		return fmt.Errorf("清理过期快照失败: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("保存快照失败: %v", err)
+	}
+	Logger.Infow("表结构快照已保存", "tables", len(snap.tables), "binlog_file", snap.binlogFile)
+	return nil
+}
+
+// captureSnapshot 读取当前库所有表的定义、估算行数和（开启时）binlog 位置
+func captureSnapshot(ctx context.Context, db *sql.DB) (*schemaSnapshot, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	snap := &schemaSnapshot{takenAt: time.Now(), tables: make(map[string]*snapshotTableState)}
+	defs, err := loadTableDefs(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+	for name, def := range defs {
+		snap.tables[name] = &snapshotTableState{def: def}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'`)
+	if err != nil {
+		return nil, fmt.Errorf("查询表行数失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			name            string
+			rowCount, bytes int64
+		)
+		if err = rows.Scan(&name, &rowCount, &bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if t, ok := snap.tables[name]; ok {
+			t.rows, t.dataBytes = rowCount, bytes
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表行数失败: %v", err)
+	}
+
+	if ChangelogSettings.Binlog {
+		if snap.binlogFile, snap.binlogPos, err = binlogPosition(ctx, db); err != nil {
+			Logger.Warnw("读取 binlog 位置失败", "error", err)
+		}
+	}
+	return snap, nil
+}
+
+// binlogPosition 返回当前的 binlog 文件和位置，MySQL 8.4 起语句改名为 SHOW BINARY LOG STATUS
+func binlogPosition(ctx context.Context, db *sql.DB) (string, int64, error) {
+	var lastErr error
+	for _, stmt := range []string{"SHOW BINARY LOG STATUS", "SHOW MASTER STATUS"} {
+		rows, err := db.QueryContext(ctx, stmt)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		values, err := scanFirstRow(rows)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(values) < 2 {
+			return "", 0, fmt.Errorf("未开启 binlog")
+		}
+		pos, _ := strconv.ParseInt(values[1], 10, 64)
+		return values[0], pos, nil
+	}
+	return "", 0, lastErr
+}
+
+// scanFirstRow 以字符串读取结果集的第一行，没有结果时返回 nil
+func scanFirstRow(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err = rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = v.String
+	}
+	return result, nil
+}
+
+// loadSnapshotBefore 读取 at 之前最近的快照；没有时读取 at 之后最早的快照，都没有时返回 nil
+func loadSnapshotBefore(at time.Time) (*schemaSnapshot, error) {
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	var (
+		id, takenAt, pos int64
+		file             string
+	)
+	err := sqlite().QueryRow(fmt.Sprintf(`
+		SELECT id, taken_at, binlog_file, binlog_pos FROM %s
+		ORDER BY CASE WHEN taken_at <= ? THEN 0 ELSE 1 END,
+			CASE WHEN taken_at <= ? THEN -taken_at ELSE taken_at END
+		LIMIT 1`, snapshotTable), at.Unix(), at.Unix()).Scan(&id, &takenAt, &file, &pos)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询快照失败: %v", err)
+	}
+
+	snap := &schemaSnapshot{takenAt: time.Unix(takenAt, 0), binlogFile: file, binlogPos: pos, tables: make(map[string]*snapshotTableState)}
+	rows, err := sqlite().Query(fmt.Sprintf(`
+		SELECT t.table_name, d.definition, t.rows_estimate, t.data_bytes
+		FROM %s t JOIN %s d ON d.hash = t.definition_hash WHERE t.snapshot_id = ?`, snapshotTableRows, snapshotDefinition), id)
+	if err != nil {
+		return nil, fmt.Errorf("查询快照失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			name, definition string
+			t                snapshotTableState
+		)
+		if err = rows.Scan(&name, &definition, &t.rows, &t.dataBytes); err != nil {
+			return nil, fmt.Errorf("扫描快照失败: %v", err)
+		}
+		if err = json.Unmarshal([]byte(definition), &t.def); err != nil {
+			return nil, fmt.Errorf("解析表定义失败: %v", err)
+		}
+		snap.tables[name] = &t
+	}
+	return snap, rows.Err()
+}
+
+// ParseSince 解析 what_changed_since 的起始时间，支持 RFC3339、"2006-01-02 15:04:05"、"2006-01-02"，
+// 以及表示多久以前的时长，如 90m、24h、7d
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s，可使用 RFC3339、2006-01-02 15:04:05、2006-01-02 或 24h、7d 这样的时长", value)
+}
+
+// WhatChangedSince 汇总 since 之后的变化：对比当时的快照与当前的表结构和估算行数，
+// 汇总审计日志中通过本服务执行的语句，开启 binlog 时读取快照位置之后的 binlog 事件
+func WhatChangedSince(ctx context.Context, db *sql.DB, since time.Time) (*ChangeReport, error) {
+	current, err := captureSnapshot(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	report := &ChangeReport{Since: since, Now: current.takenAt, Summary: []string{}}
+
+	baseline, err := loadSnapshotBefore(since)
+	if err != nil {
+		return nil, err
+	}
+	if baseline == nil {
+		report.Notes = append(report.Notes, "no schema snapshots have been recorded yet, so schema and volume changes cannot be compared; enable SCHEMA_SNAPSHOT_INTERVAL_MINUTES")
+	} else {
+		report.BaselineAt = &baseline.takenAt
+		if baseline.takenAt.After(since) {
+			report.Notes = append(report.Notes, fmt.Sprintf("the earliest snapshot was taken at %s; schema and volume changes are compared from then",
+				baseline.takenAt.Format(time.RFC3339)))
+		}
+		compareSnapshots(baseline, current, report)
+	}
+
+	if report.Activity, err = summarizeActivity(since); err != nil {
+		return nil, err
+	}
+
+	if ChangelogSettings.Binlog {
+		switch {
+		case baseline == nil || baseline.binlogFile == "":
+			report.Notes = append(report.Notes, "no binlog position was recorded with the baseline snapshot; binlog summary skipped")
+		default:
+			summary, err := summarizeBinlog(ctx, db, baseline.binlogFile, baseline.binlogPos)
+			if err != nil {
+				report.Notes = append(report.Notes, fmt.Sprintf("binlog summary unavailable: %v", err))
+			} else {
+				report.Binlog = summary
+			}
+		}
+	}
+
+	narrateChanges(report)
+	return report, nil
+}
+
+// compareSnapshots 对比两次快照的表结构和估算行数
+func compareSnapshots(before, after *schemaSnapshot, report *ChangeReport) {
+	// 配置隐藏之前的快照中可能包含被隐藏的表
+	for name := range before.tables {
+		if IsHiddenTable(name) {
+			delete(before.tables, name)
+		}
+	}
+	for _, name := range sortedKeys(after.tables) {
+		if _, ok := before.tables[name]; !ok {
+			report.AddedTables = append(report.AddedTables, name)
+		}
+	}
+	for _, name := range sortedKeys(before.tables) {
+		now, ok := after.tables[name]
+		if !ok {
+			report.RemovedTables = append(report.RemovedTables, name)
+			continue
+		}
+		then := before.tables[name]
+		if d := diffTable(name, then.def, now.def); d != nil {
+			report.ChangedTables = append(report.ChangedTables, *d)
+		}
+		delta := now.rows - then.rows
+		if abs(delta) < minVolumeShiftRows {
+			continue
+		}
+		change := float64(delta) / float64(max(then.rows, 1))
+		if change >= ChangelogSettings.VolumeShift || change <= -ChangelogSettings.VolumeShift {
+			report.VolumeShifts = append(report.VolumeShifts, VolumeShift{
+				Table: name, RowsBefore: then.rows, RowsNow: now.rows,
+				Change: float64(int64(change*1000)) / 10, BytesNow: now.dataBytes,
+			})
+		}
+	}
+	sort.Slice(report.VolumeShifts, func(i, j int) bool {
+		return abs(report.VolumeShifts[i].RowsNow-report.VolumeShifts[i].RowsBefore) >
+			abs(report.VolumeShifts[j].RowsNow-report.VolumeShifts[j].RowsBefore)
+	})
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ddlStatements 为汇总时视为表结构变更的语句类型
+var ddlStatements = map[string]bool{"create": true, "alter": true, "drop": true, "rename": true, "truncate": true}
+
+// summarizeActivity 汇总 since 之后语句审计和查询历史中的记录
+func summarizeActivity(since time.Time) (*ActivitySummary, error) {
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	activity := &ActivitySummary{Statements: map[string]int{}, RowsWritten: map[string]int64{}}
+
+	rows, err := sqlite().Query(fmt.Sprintf(`
+		SELECT executed_at, session, tool, statement, query, rows, error FROM %s
+		WHERE executed_at >= ? ORDER BY executed_at`, auditTable), since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("查询语句审计失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			at                                     int64
+			session, tool, statement, query, fault string
+			affected                               int64
+		)
+		if err = rows.Scan(&at, &session, &tool, &statement, &query, &affected, &fault); err != nil {
+			return nil, fmt.Errorf("扫描语句审计失败: %v", err)
+		}
+		activity.Statements[statement]++
+		if fault != "" {
+			activity.Failed++
+		}
+		// 引用被隐藏的表的语句只计数，不列出语句和表名
+		if referencesHidden(query) {
+			continue
+		}
+		switch {
+		case ddlStatements[statement]:
+			if len(activity.DDL) < maxChangelogStatements {
+				activity.DDL = append(activity.DDL, ChangelogStatement{
+					At: time.UnixMilli(at), Session: session, Tool: tool, SQL: query, Error: fault,
+				})
+			}
+		case fault == "" && affected > 0 && !isQueryStatement(query):
+			if tables := referencedTables(query); len(tables) > 0 {
+				activity.RowsWritten[tables[0]] += affected
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询语句审计失败: %v", err)
+	}
+
+	if err = sqlite().QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE created_at >= ?", historyTable),
+		since.Unix()).Scan(&activity.Queries); err != nil {
+		return nil, fmt.Errorf("查询历史记录失败: %v", err)
+	}
+	return activity, nil
+}
+
+// summarizeBinlog 从 file/pos 开始读取 binlog 事件，统计当前库每张表的行变更事件和 DDL 语句，
+// 最多读取 BinlogMaxEvents 个事件
+func summarizeBinlog(ctx context.Context, db *sql.DB, file string, pos int64) (*BinlogSummary, error) {
+	var database string
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(DATABASE(), '')").Scan(&database); err != nil {
+		return nil, fmt.Errorf("查询当前数据库失败: %v", err)
+	}
+	files, err := binlogFiles(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	start := -1
+	for i, f := range files {
+		if f == file {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("binlog 文件 %s 已被清理", file)
+	}
+
+	summary := &BinlogSummary{From: fmt.Sprintf("%s:%d", file, pos), RowEvents: map[string]int64{}}
+	limit := ChangelogSettings.BinlogMaxEvents
+	for i := start; i < len(files) && summary.EventsRead < limit; i++ {
+		from := int64(4) // 每个 binlog 文件的第一个事件位置
+		if i == start {
+			from = pos
+		}
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SHOW BINLOG EVENTS IN '%s' FROM %d LIMIT %d",
+			strings.ReplaceAll(files[i], "'", "''"), from, limit-summary.EventsRead))
+		if err != nil {
+			return nil, fmt.Errorf("读取 binlog 事件失败: %v", err)
+		}
+		err = scanBinlogEvents(rows, database, summary)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	summary.Truncated = summary.EventsRead >= limit
+	return summary, nil
+}
+
+// binlogFiles 返回按顺序排列的 binlog 文件名
+func binlogFiles(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, fmt.Errorf("读取 binlog 文件列表失败: %v", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	values := make([]sql.RawBytes, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		files = append(files, string(values[0]))
+	}
+	return files, rows.Err()
+}
+
+// scanBinlogEvents 统计 SHOW BINLOG EVENTS 的结果：Table_map 事件按表计数，Query 事件中的 DDL 记录语句，
+// Xid 事件计为一个事务
+func scanBinlogEvents(rows *sql.Rows, database string, summary *BinlogSummary) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	// 列依次为 Log_name, Pos, Event_type, Server_id, End_log_pos, Info
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("failed to scan row: %v", err)
+		}
+		summary.EventsRead++
+		if len(values) < 6 {
+			continue
+		}
+		eventType, info := values[2].String, values[5].String
+		switch eventType {
+		case "Xid":
+			summary.Transactions++
+		case "Table_map":
+			// Info 形如 table_id: 108 (app.orders)
+			open, end := strings.LastIndex(info, "("), strings.LastIndex(info, ")")
+			if open < 0 || end < open {
+				continue
+			}
+			schema, table, ok := strings.Cut(info[open+1:end], ".")
+			if ok && (database == "" || schema == database) && !IsHiddenTable(table) && !IsHiddenSchema(schema) {
+				summary.RowEvents[table]++
+			}
+		case "Query":
+			// Info 形如 use `app`; ALTER TABLE ...
+			stmt := info
+			if strings.HasPrefix(stmt, "use ") {
+				use, rest, ok := strings.Cut(stmt, ";")
+				if !ok {
+					continue
+				}
+				if schema := strings.Trim(strings.TrimSpace(strings.TrimPrefix(use, "use ")), "`"); database != "" && schema != database || IsHiddenSchema(schema) {
+					continue
+				}
+				stmt = strings.TrimSpace(rest)
+			}
+			if ddlStatements[classifyStatement(stmt)] && !referencesHidden(stmt) && len(summary.DDL) < maxChangelogStatements {
+				summary.DDL = append(summary.DDL, stmt)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// narrateChanges 用几句话概括变化，放在结果的开头
+func narrateChanges(r *ChangeReport) {
+	say := func(key string, args ...any) { r.Summary = append(r.Summary, Text(key, args...)) }
+	if len(r.AddedTables) > 0 {
+		say("change_added_tables", len(r.AddedTables), strings.Join(r.AddedTables, ", "))
+	}
+	if len(r.RemovedTables) > 0 {
+		say("change_dropped_tables", len(r.RemovedTables), strings.Join(r.RemovedTables, ", "))
+	}
+	for _, d := range r.ChangedTables {
+		var parts []string
+		if len(d.AddedColumns) > 0 {
+			parts = append(parts, Text("change_added_columns", strings.Join(d.AddedColumns, ", ")))
+		}
+		if len(d.RemovedColumns) > 0 {
+			parts = append(parts, Text("change_removed_columns", strings.Join(d.RemovedColumns, ", ")))
+		}
+		if len(d.ChangedColumns) > 0 {
+			parts = append(parts, Text("change_changed_columns", len(d.ChangedColumns)))
+		}
+		if n := len(d.AddedIndexes) + len(d.RemovedIndexes) + len(d.ChangedIndexes); n > 0 {
+			parts = append(parts, Text("change_index_changes", n))
+		}
+		say("change_table", d.Table, strings.Join(parts, Text("change_separator")))
+	}
+	for _, v := range r.VolumeShifts {
+		key := "change_grew"
+		if v.RowsNow < v.RowsBefore {
+			key = "change_shrank"
+		}
+		say(key, v.Table, v.RowsBefore, v.RowsNow, v.Change)
+	}
+	if a := r.Activity; a != nil {
+		if len(a.DDL) > 0 {
+			say("change_ddl_activity", len(a.DDL))
+		}
+		total := 0
+		for _, n := range a.Statements {
+			total += n
+		}
+		if total > 0 {
+			say("change_statements", total, a.Failed)
+		}
+	}
+	if b := r.Binlog; b != nil {
+		say("change_binlog", b.Transactions, len(b.RowEvents), len(b.DDL))
+	}
+	if len(r.Summary) == 0 {
+		say("change_none")
+	}
+}
+
+// FormatChangeReport 将变化汇总序列化为 JSON
+func FormatChangeReport(report *ChangeReport) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal change report to JSON: %v", err)
+	}
+	return string(data), nil
+}
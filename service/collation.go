@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// collationAliases 常用语言环境对应的排序规则，方便调用方不必记住排序规则全名
+var collationAliases = map[string]string{
+	"pinyin": "utf8mb4_zh_0900_as_cs", // 中文按拼音排序，MySQL 8.0+
+	"zh":     "utf8mb4_zh_0900_as_cs",
+	"ja":     "utf8mb4_ja_0900_as_cs",
+	"de":     "utf8mb4_de_pb_0900_ai_ci",
+	"es":     "utf8mb4_es_0900_ai_ci",
+	"fr":     "utf8mb4_0900_ai_ci",
+	"ru":     "utf8mb4_ru_0900_ai_ci",
+}
+
+// ResolveCollation 将语言环境别名转换为排序规则名称
+func ResolveCollation(name string) string {
+	if c, ok := collationAliases[strings.ToLower(name)]; ok {
+		return c
+	}
+	return name
+}
+
+// applyCollation 在会话上切换连接排序规则（collation_connection），返回恢复原设置的函数。
+// 只允许 utf8mb4 的排序规则，客户端字符集保持不变，避免服务端按其他字符集解释驱动发送的 utf8mb4 字节。
+// 会话排序规则作用于字符串常量、表达式的比较和排序；列本身的排序仍使用列的排序规则，
+// 需要按该规则排序列时在 ORDER BY 中写 col COLLATE <collation>
+func applyCollation(ctx context.Context, conn sqlExecutor, collation string) (func(), error) {
+	collation = ResolveCollation(collation)
+	if err := ValidateIdentifier(collation); err != nil {
+		return nil, err
+	}
+
+	var charset string
+	err := conn.QueryRowContext(ctx,
+		"SELECT CHARACTER_SET_NAME FROM information_schema.COLLATIONS WHERE COLLATION_NAME = ?", collation).Scan(&charset)
+	if err != nil {
+		return nil, fmt.Errorf("服务端不支持排序规则 %s: %v", collation, err)
+	}
+	if !strings.EqualFold(charset, "utf8mb4") {
+		return nil, fmt.Errorf("排序规则 %s 属于字符集 %s，只支持 utf8mb4 的排序规则", collation, charset)
+	}
+
+	var oldCollation string
+	if err = conn.QueryRowContext(ctx, "SELECT @@collation_connection").Scan(&oldCollation); err != nil {
+		return nil, fmt.Errorf("查询会话排序规则失败: %v", err)
+	}
+	if _, err = conn.ExecContext(ctx, "SET SESSION collation_connection = "+collation); err != nil {
+		return nil, fmt.Errorf("设置排序规则失败: %v", err)
+	}
+
+	return func() {
+		// 连接会归还连接池，无论语句是否超时都要恢复
+		restoreCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(restoreCtx, "SET SESSION collation_connection = "+oldCollation); err != nil {
+			Logger.Warnw("恢复会话排序规则失败", "collation", oldCollation, "error", err)
+		}
+	}, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的列语义类型，帮助展示层和模型正确格式化取值
+var semanticTypes = map[string]bool{
+	"currency":     true, // 金额，配合 unit 给出币种，如 CNY
+	"percentage":   true, // 百分比，0.12 表示 12%，取值已乘以 100 时 unit 写 "%"
+	"timestamp_ms": true, // 毫秒级 Unix 时间戳
+	"timestamp_s":  true, // 秒级 Unix 时间戳
+	"duration_ms":  true, // 毫秒时长
+	"bytes":        true, // 字节数
+	"enum":         true, // 编码值，values 给出含义
+}
+
+// ColumnHint 描述一列的业务含义和语义类型
+type ColumnHint struct {
+	Description string            `yaml:"description" json:"description,omitempty"`
+	Semantic    string            `yaml:"semantic" json:"semantic,omitempty"`
+	Unit        string            `yaml:"unit" json:"unit,omitempty"`
+	Values      map[string]string `yaml:"values" json:"values,omitempty"`
+}
+
+// tableHints 为一张表的说明
+type tableHints struct {
+	Description string                `yaml:"description"`
+	Columns     map[string]ColumnHint `yaml:"columns"`
+}
+
+// 全局列说明配置：按表的说明，以及不区分表、按列名生效的说明
+var (
+	tableColumnHints  map[string]tableHints
+	globalColumnHints map[string]ColumnHint
+)
+
+// InitColumnHints 从 YAML 文件加载表和列的说明：
+//
+//	tables:
+//	  orders:
+//	    description: 订单表
+//	    columns:
+//	      amount: {description: 订单金额, semantic: currency, unit: CNY}
+//	      paid_at: {semantic: timestamp_ms}
+//	      status: {semantic: enum, values: {"1": 待支付, "2": 已支付}}
+//	columns:
+//	  created_ms: {semantic: timestamp_ms}
+func InitColumnHints(path string) error {
+	tableColumnHints, globalColumnHints = nil, nil
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取列说明文件失败: %v", err)
+	}
+	var file struct {
+		Tables  map[string]tableHints `yaml:"tables"`
+		Columns map[string]ColumnHint `yaml:"columns"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析列说明文件失败: %v", err)
+	}
+
+	check := func(where string, hints map[string]ColumnHint) error {
+		for col, hint := range hints {
+			if hint.Semantic != "" && !semanticTypes[hint.Semantic] {
+				return fmt.Errorf("%s.%s 的语义类型不支持: %s", where, col, hint.Semantic)
+			}
+		}
+		return nil
+	}
+	for table, t := range file.Tables {
+		if err = check(table, t.Columns); err != nil {
+			return err
+		}
+	}
+	if err = check("columns", file.Columns); err != nil {
+		return err
+	}
+
+	tableColumnHints, globalColumnHints = file.Tables, file.Columns
+	return nil
+}
+
+// tableDescription 返回配置中表的说明
+func tableDescription(table string) string {
+	return tableColumnHints[table].Description
+}
+
+// columnHint 返回指定表中列的说明，表内没有配置时使用按列名生效的说明
+func columnHint(table, column string) (ColumnHint, bool) {
+	if hint, ok := tableColumnHints[table].Columns[column]; ok {
+		return hint, true
+	}
+	hint, ok := globalColumnHints[column]
+	return hint, ok
+}
+
+// resultColumnHints 为结果集中的列查找说明。结果集不带来源表，按列名匹配：
+// 只有一张表配置了该列名，或多处配置的说明一致时才使用
+func resultColumnHints(columns []string) map[string]ColumnHint {
+	if len(tableColumnHints) == 0 && len(globalColumnHints) == 0 {
+		return nil
+	}
+
+	hints := make(map[string]ColumnHint)
+	for _, col := range columns {
+		var (
+			found     ColumnHint
+			matched   bool
+			ambiguous bool
+		)
+		candidates := make([]ColumnHint, 0, 1)
+		if hint, ok := globalColumnHints[col]; ok {
+			candidates = append(candidates, hint)
+		}
+		for _, t := range tableColumnHints {
+			if hint, ok := t.Columns[col]; ok {
+				candidates = append(candidates, hint)
+			}
+		}
+		for _, hint := range candidates {
+			if !matched {
+				found, matched = hint, true
+				continue
+			}
+			if !sameHint(found, hint) {
+				ambiguous = true
+				break
+			}
+		}
+		if matched && !ambiguous {
+			hints[col] = found
+		}
+	}
+	return hints
+}
+
+// sameHint 判断两条说明是否一致
+func sameHint(a, b ColumnHint) bool {
+	if a.Description != b.Description || a.Semantic != b.Semantic || a.Unit != b.Unit || len(a.Values) != len(b.Values) {
+		return false
+	}
+	for k, v := range a.Values {
+		if b.Values[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// formatColumnHints 将结果集的列说明格式化为附加在结果末尾的文本
+func formatColumnHints(columns []string) string {
+	hints := resultColumnHints(columns)
+	if len(hints) == 0 {
+		return ""
+	}
+	data, err := json.MarshalIndent(hints, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\n%s\n%s", Text("column_hints"), data)
+}
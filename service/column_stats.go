@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var columnStatsTable = "column_stats"
+
+// ColumnStatsConfig 控制列统计信息的定时采集
+type ColumnStatsConfig struct {
+	// Interval 两轮采集之间的间隔，不大于0时不定时采集
+	Interval time.Duration
+	// SampleRows 没有直方图时每张表最多读取的样本行数，用于估算空值比例和不同值数量
+	SampleRows int
+}
+
+// 全局列统计采集配置
+var ColumnStatsSettings = ColumnStatsConfig{SampleRows: 10000}
+
+// InitColumnStatsConfig 初始化列统计采集配置
+func InitColumnStatsConfig(cfg ColumnStatsConfig) {
+	if cfg.SampleRows <= 0 {
+		cfg.SampleRows = 10000
+	}
+	ColumnStatsSettings = cfg
+}
+
+// 统计值的来源
+const (
+	statsSourceSchema    = "schema"    // 列定义为 NOT NULL
+	statsSourceHistogram = "histogram" // information_schema.COLUMN_STATISTICS 中的直方图
+	statsSourceIndex     = "index"     // 以该列开头的索引的基数
+	statsSourceSample    = "sample"    // 读取前 SampleRows 行的样本
+)
+
+// ColumnStats 为保存在 SQLite 中的一列统计信息
+type ColumnStats struct {
+	Column string `json:"column,omitempty"`
+	// NullFraction 为空值比例（0-1）
+	NullFraction *float64 `json:"null_fraction,omitempty"`
+	NullSource   string   `json:"null_source,omitempty"`
+	// DistinctEstimate 为不同值数量的估算，来源为 sample 时是样本中的不同值数量
+	DistinctEstimate *int64 `json:"distinct_estimate,omitempty"`
+	DistinctSource   string `json:"distinct_source,omitempty"`
+	// SampleRows 为采样时实际读取的行数
+	SampleRows  int64     `json:"sample_rows,omitempty"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// createColumnStatsTable 创建列统计表
+func createColumnStatsTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			table_name TEXT NOT NULL,
+			column_name TEXT NOT NULL,
+			null_fraction REAL,
+			null_source TEXT NOT NULL DEFAULT '',
+			distinct_estimate INTEGER,
+			distinct_source TEXT NOT NULL DEFAULT '',
+			sample_rows INTEGER NOT NULL DEFAULT 0,
+			collected_at INTEGER NOT NULL,
+			PRIMARY KEY (table_name, column_name)
+		)`, columnStatsTable))
+	return err
+}
+
+// HarvestColumnStats 定时采集所有表的列统计信息写入 SQLite，Interval 不大于0时直接返回。
+// 启动后先采集一轮，之后按 Interval 重复，调度方式与增量索引相同，ctx 取消后返回
+func HarvestColumnStats(ctx context.Context, db *sql.DB) {
+	if ColumnStatsSettings.Interval <= 0 {
+		return
+	}
+	runPeriodic(ctx, db, "column-stats", ColumnStatsSettings.Interval, func(ctx context.Context) {
+		harvestAllColumnStats(ctx, db)
+	})
+}
+
+// harvestAllColumnStats 采集一轮所有表（不含视图）的列统计信息
+func harvestAllColumnStats(ctx context.Context, db *sql.DB) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	tables, err := ListTables(ctx, db)
+	if err != nil {
+		Logger.Warnw("获取表列表失败，跳过本轮列统计采集", "error", err)
+		return
+	}
+	start := time.Now()
+	collected := 0
+	for _, t := range tables {
+		if t.Type == "VIEW" {
+			continue
+		}
+		if _, err = CollectColumnStats(ctx, db, t.Name); err != nil {
+			Logger.Warnw("采集列统计失败", "table", t.Name, "error", err)
+			continue
+		}
+		collected++
+	}
+	Logger.Infow("列统计采集完成", "tables", collected, "elapsed", time.Since(start))
+}
+
+// CollectColumnStats 采集一张表的列统计信息并保存到 SQLite。
+// 优先使用直方图和索引基数等元数据，其余的列在采样时间窗口内读取前 SampleRows 行估算；
+// 不在时间窗口内时这些列沿用上一次的采样结果
+func CollectColumnStats(ctx context.Context, db *sql.DB, table string) ([]ColumnStats, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return nil, err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return nil, err
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE = 'YES'
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, table)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	var (
+		stats     []ColumnStats
+		dataTypes = make(map[string]string)
+		now       = time.Now()
+	)
+	for rows.Next() {
+		var (
+			s        = ColumnStats{CollectedAt: now}
+			dataType string
+			nullable bool
+		)
+		if err = rows.Scan(&s.Column, &dataType, &nullable); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !nullable {
+			zero := 0.0
+			s.NullFraction, s.NullSource = &zero, statsSourceSchema
+		}
+		dataTypes[s.Column] = strings.ToLower(dataType)
+		stats = append(stats, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("表不存在: %s", table)
+	}
+
+	applyHistogramStats(ctx, db, table, stats)
+	applyIndexCardinality(ctx, db, table, stats)
+	if LowPriority.samplingAllowed(now) {
+		applySampleStats(ctx, db, table, stats, dataTypes)
+	}
+
+	previous, err := LoadColumnStats(table)
+	if err != nil {
+		Logger.Warnw("读取已有列统计失败", "table", table, "error", err)
+	}
+	for i := range stats {
+		if p, ok := previous[stats[i].Column]; ok {
+			stats[i] = mergeColumnStats(stats[i], p)
+		}
+	}
+	if err = saveColumnStats(table, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// mergeColumnStats 本次没有采集到的统计值沿用上一次的结果
+func mergeColumnStats(current, previous ColumnStats) ColumnStats {
+	if current.NullFraction == nil {
+		current.NullFraction, current.NullSource = previous.NullFraction, previous.NullSource
+	}
+	if current.DistinctEstimate == nil {
+		current.DistinctEstimate, current.DistinctSource = previous.DistinctEstimate, previous.DistinctSource
+	}
+	if current.SampleRows == 0 {
+		current.SampleRows = previous.SampleRows
+	}
+	return current
+}
+
+// columnHistogram 为 COLUMN_STATISTICS.HISTOGRAM 中用到的字段
+type columnHistogram struct {
+	NullValues float64             `json:"null-values"`
+	Type       string              `json:"histogram-type"`
+	Buckets    [][]json.RawMessage `json:"buckets"`
+}
+
+// distinct 返回直方图记录的不同值数量：singleton 每个桶一个值，equi-height 每个桶的最后一项为桶内不同值数量
+func (h columnHistogram) distinct() int64 {
+	if h.Type == "singleton" {
+		return int64(len(h.Buckets))
+	}
+	var total int64
+	for _, bucket := range h.Buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+		var ndv int64
+		if err := json.Unmarshal(bucket[len(bucket)-1], &ndv); err == nil {
+			total += ndv
+		}
+	}
+	return total
+}
+
+// applyHistogramStats 使用 ANALYZE TABLE ... UPDATE HISTOGRAM 生成的直方图填充空值比例和不同值数量
+func applyHistogramStats(ctx context.Context, db *sql.DB, table string, stats []ColumnStats) {
+	if requireFeature(featureHistogram) != nil {
+		return
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT COLUMN_NAME, HISTOGRAM FROM information_schema.COLUMN_STATISTICS
+		WHERE SCHEMA_NAME = DATABASE() AND TABLE_NAME = ?`, table)
+	if err != nil {
+		Logger.Debugw("读取列直方图失败", "table", table, "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			column string
+			raw    []byte
+			h      columnHistogram
+		)
+		if err = rows.Scan(&column, &raw); err != nil || json.Unmarshal(raw, &h) != nil {
+			continue
+		}
+		for i := range stats {
+			if stats[i].Column != column {
+				continue
+			}
+			nullFraction, distinct := h.NullValues, h.distinct()
+			stats[i].NullFraction, stats[i].NullSource = &nullFraction, statsSourceHistogram
+			stats[i].DistinctEstimate, stats[i].DistinctSource = &distinct, statsSourceHistogram
+		}
+	}
+}
+
+// applyIndexCardinality 没有直方图的列使用以该列开头的索引的基数作为不同值数量
+func applyIndexCardinality(ctx context.Context, db *sql.DB, table string, stats []ColumnStats) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT COLUMN_NAME, MAX(CARDINALITY) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND SEQ_IN_INDEX = 1
+			AND COLUMN_NAME IS NOT NULL AND CARDINALITY IS NOT NULL
+		GROUP BY COLUMN_NAME`, table)
+	if err != nil {
+		Logger.Debugw("读取索引基数失败", "table", table, "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			column      string
+			cardinality int64
+		)
+		if err = rows.Scan(&column, &cardinality); err != nil {
+			continue
+		}
+		for i := range stats {
+			if stats[i].Column == column && stats[i].DistinctEstimate == nil {
+				stats[i].DistinctEstimate, stats[i].DistinctSource = &cardinality, statsSourceIndex
+			}
+		}
+	}
+}
+
+// distinctUnsupportedTypes 不统计不同值数量的类型，对它们执行 COUNT(DISTINCT) 代价高或不受支持
+var distinctUnsupportedTypes = map[string]bool{
+	"tinyblob": true, "blob": true, "mediumblob": true, "longblob": true, "json": true,
+	"geometry": true, "point": true, "linestring": true, "polygon": true,
+	"multipoint": true, "multilinestring": true, "multipolygon": true, "geometrycollection": true,
+}
+
+// applySampleStats 读取表的前 SampleRows 行，为元数据中没有的列估算空值比例和不同值数量
+func applySampleStats(ctx context.Context, db *sql.DB, table string, stats []ColumnStats, dataTypes map[string]string) {
+	var (
+		exprs   = []string{"COUNT(*)"}
+		columns []string
+		targets []int // exprs 中每一项对应的 stats 下标，负数表示不同值数量
+	)
+	for i, s := range stats {
+		if s.NullFraction != nil && (s.DistinctEstimate != nil || distinctUnsupportedTypes[dataTypes[s.Column]]) {
+			continue
+		}
+		quoted := quoteIdentifier(s.Column)
+		columns = append(columns, quoted)
+		if s.NullFraction == nil {
+			exprs = append(exprs, fmt.Sprintf("SUM(%s IS NULL)", quoted))
+			targets = append(targets, i)
+		}
+		if s.DistinctEstimate == nil && !distinctUnsupportedTypes[dataTypes[s.Column]] {
+			exprs = append(exprs, fmt.Sprintf("COUNT(DISTINCT %s)", quoted))
+			targets = append(targets, -i-1)
+		}
+	}
+	if len(columns) == 0 {
+		return
+	}
+
+	query := LowPriority.sampleStatement(fmt.Sprintf("SELECT %s FROM (SELECT %s FROM %s LIMIT %d) sample",
+		strings.Join(exprs, ", "), strings.Join(columns, ", "), quoteIdentifier(table), ColumnStatsSettings.SampleRows))
+	values := make([]sql.NullFloat64, len(exprs))
+	dest := make([]any, len(exprs))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := db.QueryRowContext(ctx, query).Scan(dest...); err != nil {
+		Logger.Warnw("采样列统计失败", "table", table, "error", err)
+		return
+	}
+
+	sampled := int64(values[0].Float64)
+	for j, target := range targets {
+		v := values[j+1]
+		if !v.Valid {
+			continue
+		}
+		if target >= 0 {
+			nullFraction := 0.0
+			if sampled > 0 {
+				nullFraction = v.Float64 / float64(sampled)
+			}
+			stats[target].NullFraction, stats[target].NullSource = &nullFraction, statsSourceSample
+		} else {
+			distinct := int64(v.Float64)
+			stats[-target-1].DistinctEstimate, stats[-target-1].DistinctSource = &distinct, statsSourceSample
+		}
+	}
+	for i := range stats {
+		if stats[i].NullSource == statsSourceSample || stats[i].DistinctSource == statsSourceSample {
+			stats[i].SampleRows = sampled
+		}
+	}
+}
+
+// saveColumnStats 覆盖保存一张表的列统计信息，已删除的列一并移除
+func saveColumnStats(table string, stats []ColumnStats) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	tx, err := sqlite().Begin()
+	if err != nil {
+		return fmt.Errorf("保存列统计失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", columnStatsTable), table); err != nil {
+		return fmt.Errorf("保存列统计失败: %v", err)
+	}
+	for _, s := range stats {
+		_, err = tx.Exec(fmt.Sprintf(`
+			INSERT INTO %s (table_name, column_name, null_fraction, null_source, distinct_estimate, distinct_source, sample_rows, collected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, columnStatsTable),
+			table, s.Column, s.NullFraction, s.NullSource, s.DistinctEstimate, s.DistinctSource, s.SampleRows, s.CollectedAt.Unix())
+		if err != nil {
+			return fmt.Errorf("保存列统计失败: %v", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("保存列统计失败: %v", err)
+	}
+	return nil
+}
+
+// LoadColumnStats 从 SQLite 读取一张表已采集的列统计信息，键为列名。SQLite 未初始化时返回空
+func LoadColumnStats(table string) (map[string]ColumnStats, error) {
+	db := sqlite()
+	if db == nil {
+		return nil, nil
+	}
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT column_name, null_fraction, null_source, distinct_estimate, distinct_source, sample_rows, collected_at
+		FROM %s WHERE table_name = ?`, columnStatsTable), table)
+	if err != nil {
+		return nil, fmt.Errorf("查询列统计失败: %v", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]ColumnStats)
+	for rows.Next() {
+		var (
+			s            ColumnStats
+			nullFraction sql.NullFloat64
+			distinct     sql.NullInt64
+			collectedAt  int64
+		)
+		if err = rows.Scan(&s.Column, &nullFraction, &s.NullSource, &distinct, &s.DistinctSource, &s.SampleRows, &collectedAt); err != nil {
+			return nil, fmt.Errorf("扫描列统计失败: %v", err)
+		}
+		if nullFraction.Valid {
+			s.NullFraction = &nullFraction.Float64
+		}
+		if distinct.Valid {
+			s.DistinctEstimate = &distinct.Int64
+		}
+		s.CollectedAt = time.Unix(collectedAt, 0)
+		stats[s.Column] = s
+	}
+	return stats, rows.Err()
+}
+
+// ColumnProfile 返回一张表（或其中一列）已采集的列统计信息，不查询业务数据。
+// refresh 为 true 或尚未采集过时先采集该表
+func ColumnProfile(ctx context.Context, db *sql.DB, table, column string, refresh bool) (string, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return "", err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return "", err
+	}
+	stored, err := LoadColumnStats(table)
+	if err != nil {
+		return "", err
+	}
+	if refresh || len(stored) == 0 {
+		if _, err = CollectColumnStats(ctx, db, table); err != nil {
+			return "", err
+		}
+		if stored, err = LoadColumnStats(table); err != nil {
+			return "", err
+		}
+	}
+
+	profile := struct {
+		Table   string        `json:"table"`
+		Columns []ColumnStats `json:"columns"`
+	}{Table: table, Columns: make([]ColumnStats, 0, len(stored))}
+	for name, s := range stored {
+		if column == "" || strings.EqualFold(name, column) {
+			profile.Columns = append(profile.Columns, s)
+		}
+	}
+	if column != "" && len(profile.Columns) == 0 {
+		return "", fmt.Errorf("列不存在或尚未采集统计信息: %s.%s", table, column)
+	}
+	sort.Slice(profile.Columns, func(i, j int) bool { return profile.Columns[i].Column < profile.Columns[j].Column })
+
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal column profile to JSON: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+const (
+	// compactPageSize 扫描集合时每页读取的行数
+	compactPageSize = 1000
+	// maxQueryWindow Milvus 查询 offset+limit 的上限
+	maxQueryWindow = 16384
+)
+
+// createTablePattern 从 SHOW CREATE TABLE 的结果中提取表名
+var createTablePattern = regexp.MustCompile("(?i)^\\s*CREATE\\s+TABLE\\s+`((?:[^`]|``)+)`")
+
+// CompactReport 表示一次去重压缩的结果
+type CompactReport struct {
+	Scanned      int            `json:"scanned"`
+	Tables       int            `json:"tables"`
+	Duplicates   int            `json:"duplicates"`
+	Deleted      int            `json:"deleted"`
+	DryRun       bool           `json:"dry_run"`
+	CompactionID int64          `json:"compaction_id,omitempty"`
+	PerTable     map[string]int `json:"duplicates_per_table,omitempty"`
+	Unparsed     int            `json:"unparsed"`
+}
+
+// tableNameFromSchema 解析表结构文本中的表名
+func tableNameFromSchema(schema string) (string, bool) {
+	m := createTablePattern.FindStringSubmatch(schema)
+	if m == nil {
+		return "", false
+	}
+	return strings.ReplaceAll(m[1], "``", "`"), true
+}
+
+// CompactVectorIndex 找出表结构集合中同一张表的重复向量（早期重启重复写入导致），
+// 每张表只保留 SQLite 中登记的向量，未登记时保留最新写入的向量，删除其余向量后触发集合压缩
+func CompactVectorIndex(ctx context.Context, cli *milvusclient.Client, dryRun bool) (string, error) {
+	if !dryRun {
+		if err := requireIndexWriter(); err != nil {
+			return "", err
+		}
+	}
+	if !indexMutex.TryLock() {
+		return "", fmt.Errorf("已有索引任务在进行中")
+	}
+	defer indexMutex.Unlock()
+
+	indexed, err := ListIndexedTables()
+	if err != nil {
+		return "", err
+	}
+	registered := make(map[string]int64, len(indexed))
+	for _, t := range indexed {
+		registered[t.TableName] = t.VectorID
+	}
+
+	idsByTable := make(map[string][]int64)
+	report := CompactReport{DryRun: dryRun, PerTable: map[string]int{}}
+	for offset := 0; offset < maxQueryWindow; offset += compactPageSize {
+		rs, err := cli.Query(ctx, milvusclient.NewQueryOption(Config.CollectionName).
+			WithFilter("my_id > 0").
+			WithOutputFields("my_id", "schema").
+			WithOffset(offset).
+			WithLimit(compactPageSize).
+			WithConsistencyLevel(entity.ClStrong))
+		if err != nil {
+			return "", fmt.Errorf("扫描向量集合失败: %w", err)
+		}
+
+		idColumn, schemaColumn := rs.GetColumn("my_id"), rs.GetColumn("schema")
+		if idColumn == nil || schemaColumn == nil || idColumn.Len() == 0 {
+			break
+		}
+		for i := 0; i < idColumn.Len(); i++ {
+			id, err := idColumn.GetAsInt64(i)
+			if err != nil {
+				return "", err
+			}
+			schema, err := schemaColumn.GetAsString(i)
+			if err != nil {
+				return "", err
+			}
+			report.Scanned++
+			tableName, ok := tableNameFromSchema(schema)
+			if !ok {
+				report.Unparsed++
+				continue
+			}
+			idsByTable[tableName] = append(idsByTable[tableName], id)
+		}
+		if idColumn.Len() < compactPageSize {
+			break
+		}
+		if offset+2*compactPageSize > maxQueryWindow {
+			Logger.Warnw("向量集合超过单次扫描上限，剩余数据将在下次压缩时处理", "scanned", report.Scanned)
+			break
+		}
+	}
+
+	var toDelete []int64
+	kept := make(map[string]int64, len(idsByTable))
+	for tableName, ids := range idsByTable {
+		report.Tables++
+		sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+		keep := ids[0]
+		if id, ok := registered[tableName]; ok && containsInt64(ids, id) {
+			keep = id
+		}
+		kept[tableName] = keep
+		for _, id := range ids {
+			if id != keep {
+				toDelete = append(toDelete, id)
+			}
+		}
+		if len(ids) > 1 {
+			report.PerTable[tableName] = len(ids) - 1
+		}
+	}
+	report.Duplicates = len(toDelete)
+
+	if !dryRun && len(toDelete) > 0 {
+		if report.Deleted, report.CompactionID, err = deleteAndCompact(ctx, cli, toDelete); err != nil {
+			return "", err
+		}
+		// 让 SQLite 中的向量 ID 与保留的向量一致
+		for tableName, keep := range kept {
+			if id, ok := registered[tableName]; ok && id != keep {
+				if err = UpdateIndexedVectorID(tableName, keep); err != nil {
+					Logger.Warnw("更新向量ID失败", "table", tableName, "error", err)
+				}
+			}
+		}
+	}
+
+	Logger.Infow("向量集合去重完成", "scanned", report.Scanned, "duplicates", report.Duplicates, "dryRun", dryRun)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal report to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// deleteAndCompact 删除指定向量，刷盘后触发压缩
+func deleteAndCompact(ctx context.Context, cli *milvusclient.Client, ids []int64) (int, int64, error) {
+	resp, err := cli.Delete(ctx, milvusclient.NewDeleteOption(Config.CollectionName).WithInt64IDs("my_id", ids))
+	if err != nil {
+		return 0, 0, fmt.Errorf("删除重复向量失败: %w", err)
+	}
+
+	flushTask, err := cli.Flush(ctx, milvusclient.NewFlushOption(Config.CollectionName))
+	if err != nil {
+		return int(resp.DeleteCount), 0, fmt.Errorf("刷盘失败: %w", err)
+	}
+	if err = flushTask.Await(ctx); err != nil {
+		return int(resp.DeleteCount), 0, fmt.Errorf("等待刷盘完成失败: %w", err)
+	}
+
+	compactionID, err := cli.Compact(ctx, milvusclient.NewCompactOption(Config.CollectionName))
+	if err != nil {
+		return int(resp.DeleteCount), 0, fmt.Errorf("触发压缩失败: %w", err)
+	}
+	return int(resp.DeleteCount), compactionID, nil
+}
+
+// containsInt64 判断切片中是否包含指定整数
+func containsInt64(list []int64, v int64) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
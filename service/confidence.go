@@ -0,0 +1,84 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// maxClosestCandidates 没有可信匹配时最多返回的候选表数量
+const maxClosestCandidates = 5
+
+// MatchCandidate 为没有可信匹配时返回的候选表
+type MatchCandidate struct {
+	Table string  `json:"table"`
+	Score float32 `json:"score"`
+}
+
+// NoConfidentMatch 为所有候选的相似度都低于阈值时返回给模型的结构化结果，
+// 代替拼接后的空字符串，提示模型不要基于不相关的表结构编写 SQL
+type NoConfidentMatch struct {
+	Status            string           `json:"status"`
+	Message           string           `json:"message"`
+	Threshold         float32          `json:"threshold"`
+	BestScore         float32          `json:"best_score"`
+	ClosestCandidates []MatchCandidate `json:"closest_candidates"`
+	Suggestion        string           `json:"suggestion"`
+}
+
+// noConfidentMatchError 表示检索结果的相似度都低于阈值，candidates 为按相似度降序的原始命中
+type noConfidentMatchError struct {
+	threshold  float32
+	candidates []SchemaHit
+}
+
+func (e *noConfidentMatchError) Error() string {
+	return fmt.Sprintf("没有相似度达到 %.2f 的表结构", e.threshold)
+}
+
+// checkConfidence 在 minScore 大于0且所有命中的相似度都低于它时返回 noConfidentMatchError。
+// 只检查向量检索的原始相似度，重排序和权重调整后的分数与阈值不可比
+func checkConfidence(hits []SchemaHit, minScore float32) error {
+	if minScore <= 0 {
+		return nil
+	}
+	for _, hit := range hits {
+		if hit.Score >= minScore {
+			return nil
+		}
+	}
+	candidates := append([]SchemaHit(nil), hits...)
+	sortSchemaHits(candidates)
+	return &noConfidentMatchError{threshold: minScore, candidates: candidates}
+}
+
+// formatNoConfidentMatch 将 noConfidentMatchError 格式化为返回给模型的 JSON
+func formatNoConfidentMatch(e *noConfidentMatchError) (string, error) {
+	result := NoConfidentMatch{
+		Status:            "no_confident_match",
+		Message:           Text("no_confident_match", e.threshold),
+		Threshold:         e.threshold,
+		ClosestCandidates: []MatchCandidate{},
+		Suggestion:        Text("no_confident_match_suggestion"),
+	}
+	seen := make(map[string]bool)
+	for _, hit := range e.candidates {
+		name, ok := tableNameFromSchema(hit.Schema)
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result.ClosestCandidates = append(result.ClosestCandidates, MatchCandidate{Table: name, Score: hit.Score})
+		if len(result.ClosestCandidates) >= maxClosestCandidates {
+			break
+		}
+	}
+	if len(e.candidates) > 0 {
+		result.BestScore = e.candidates[0].Score
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
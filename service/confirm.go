@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PendingConfirmation 为等待人工确认的语句，返回给模型代替执行结果
+type PendingConfirmation struct {
+	Status    string    `json:"status"`
+	Token     string    `json:"token"`
+	Statement string    `json:"statement"`
+	SQL       string    `json:"sql"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Message   string    `json:"message"`
+}
+
+// ConfirmationRequiredError 表示语句需要通过 confirm_execution 确认后才能执行
+type ConfirmationRequiredError struct {
+	Token     string
+	Statement string
+	SQL       string
+	ExpiresAt time.Time
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("%s 语句需要人工确认后才能执行，确认令牌: %s", e.Statement, e.Token)
+}
+
+// pendingCall 为等待确认的工具调用，确认后按原参数重新调用
+type pendingCall struct {
+	sql       string
+	session   string
+	tool      string
+	arguments map[string]any
+	expiresAt time.Time
+}
+
+// confirmedTokenKey 是上下文中记录已确认令牌的键
+type confirmedTokenKey struct{}
+
+// ConfirmApprover 以两阶段确认实现审批：第一次执行危险语句时登记待确认的调用并返回确认令牌，
+// 语句不会执行；用户审核后通过 confirm_execution 工具传入令牌，才按原参数真正执行。
+// 令牌只能使用一次，在 ApprovalConfig.Timeout 后过期，且只能由发起语句的会话确认
+type ConfirmApprover struct {
+	mu      sync.Mutex
+	pending map[string]*pendingCall
+}
+
+// NewConfirmApprover 创建两阶段确认的审批实现
+func NewConfirmApprover() *ConfirmApprover {
+	return &ConfirmApprover{pending: make(map[string]*pendingCall)}
+}
+
+// Approve 已确认的令牌与语句一致时通过，否则登记待确认的调用并返回 ConfirmationRequiredError
+func (a *ConfirmApprover) Approve(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if token, ok := ctx.Value(confirmedTokenKey{}).(string); ok {
+		if call, ok := a.pending[token]; ok && call.sql == req.SQL {
+			delete(a.pending, token)
+			return ApprovalDecision{Approved: true, Approver: "confirm_execution", Reason: "confirmed " + token}, nil
+		}
+	}
+
+	now := time.Now()
+	for token, call := range a.pending {
+		if now.After(call.expiresAt) {
+			delete(a.pending, token)
+		}
+	}
+	expiresAt := now.Add(Approval.Timeout)
+	a.pending[req.ID] = &pendingCall{sql: req.SQL, session: req.Session, tool: req.Tool, expiresAt: expiresAt}
+	return ApprovalDecision{}, &ConfirmationRequiredError{
+		Token:     req.ID,
+		Statement: req.Statement,
+		SQL:       req.SQL,
+		ExpiresAt: expiresAt,
+	}
+}
+
+// AttachCall 记录待确认令牌对应的工具调用参数，确认时按这些参数重新调用
+func (a *ConfirmApprover) AttachCall(token, tool string, arguments map[string]any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if call, ok := a.pending[token]; ok {
+		call.tool, call.arguments = tool, arguments
+	}
+}
+
+// Confirm 校验令牌，返回带有确认标记的上下文以及需要重新调用的工具和参数
+func (a *ConfirmApprover) Confirm(ctx context.Context, token string) (context.Context, string, map[string]any, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	call, ok := a.pending[token]
+	if !ok {
+		return ctx, "", nil, fmt.Errorf("确认令牌不存在或已使用: %s", token)
+	}
+	if time.Now().After(call.expiresAt) {
+		delete(a.pending, token)
+		return ctx, "", nil, fmt.Errorf("确认令牌已过期: %s，请重新提交语句", token)
+	}
+	if session := sessionFromContext(ctx); call.session != "" && session != call.session {
+		return ctx, "", nil, fmt.Errorf("确认令牌 %s 只能由发起语句的会话确认", token)
+	}
+	if call.arguments == nil {
+		return ctx, "", nil, fmt.Errorf("确认令牌 %s 没有对应的工具调用", token)
+	}
+	return context.WithValue(ctx, confirmedTokenKey{}, token), call.tool, call.arguments, nil
+}
+
+// ConfirmApproverInUse 返回当前生效的两阶段确认审批实现，未使用时返回 nil
+func ConfirmApproverInUse() *ConfirmApprover {
+	a, _ := approver.(*ConfirmApprover)
+	return a
+}
+
+// FormatPendingConfirmation 在 err 为 ConfirmationRequiredError 时返回给模型的 JSON，否则返回 false
+func FormatPendingConfirmation(err error) (string, bool) {
+	var pending *ConfirmationRequiredError
+	if !errors.As(err, &pending) {
+		return "", false
+	}
+	data, marshalErr := json.MarshalIndent(PendingConfirmation{
+		Status:    "pending_confirmation",
+		Token:     pending.Token,
+		Statement: pending.Statement,
+		SQL:       pending.SQL,
+		ExpiresAt: pending.ExpiresAt,
+		Message: "The statement was NOT executed. Show it to the user and ask for approval; only after the user approves, " +
+			"call confirm_execution with this token to run it. Do not confirm on the user's behalf.",
+	}, "", "  ")
+	if marshalErr != nil {
+		return "", false
+	}
+	return string(data), true
+}
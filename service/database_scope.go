@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// scopeDatabases 非空时 ExecOptions.Database 只能是其中的数据库
+var scopeDatabases []string
+
+// InitDatabaseScope 设置允许通过 database 参数切换到的数据库，为空时不限制（仍受账号权限约束）
+func InitDatabaseScope(databases []string) {
+	scopeDatabases = databases
+}
+
+// useDatabase 在固定的连接上执行 USE 切换默认数据库，返回恢复原数据库的函数。
+// 原连接没有默认数据库或恢复失败时，该连接会被连接池丢弃，避免之后的语句在错误的数据库中执行
+func useDatabase(ctx context.Context, conn *sql.Conn, database string) (func(), error) {
+	if err := ValidateIdentifier(database); err != nil {
+		return nil, err
+	}
+	if IsHiddenSchema(database) {
+		return nil, fmt.Errorf("不允许访问数据库 %s", database)
+	}
+	if len(scopeDatabases) > 0 && !containsFold(scopeDatabases, database) {
+		return nil, fmt.Errorf("不允许在数据库 %s 中执行语句，可用的数据库: %s", database, strings.Join(scopeDatabases, ", "))
+	}
+
+	var name string
+	err := conn.QueryRowContext(ctx,
+		"SELECT SCHEMA_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", database).Scan(&name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("数据库不存在或没有访问权限: %s", database)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %v", err)
+	}
+
+	var original sql.NullString
+	if err = conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&original); err != nil {
+		return nil, fmt.Errorf("查询当前数据库失败: %v", err)
+	}
+	if _, err = conn.ExecContext(ctx, "USE "+quoteIdentifier(name)); err != nil {
+		return nil, fmt.Errorf("切换数据库失败: %v", err)
+	}
+
+	return func() {
+		// 连接会归还连接池，无论语句是否超时都要恢复
+		restoreCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if original.Valid {
+			_, err := conn.ExecContext(restoreCtx, "USE "+quoteIdentifier(original.String))
+			if err == nil {
+				return
+			}
+			Logger.Warnw("恢复默认数据库失败，丢弃该连接", "database", original.String, "error", err)
+		}
+		// MySQL 无法把默认数据库恢复为空，返回 ErrBadConn 让连接池关闭该连接
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+// maxBudgetSearchLimit 指定上下文预算时最多检索的表结构数量
+const maxBudgetSearchLimit = 10
+
+// DiscoverOptions 控制表结构检索的行为
+type DiscoverOptions struct {
+	// Translate 为 true 且配置了LLM时，会把查询翻译为另一种语言（中文<->英文）再检索一次并合并结果，
+	// 以提高中英文混用时的召回率；翻译失败时退化为只用原始查询检索
+	Translate bool
+	// MaxTokens 为调用方的上下文预算，大于0时按预算决定返回多少表结构，否则返回固定的前 SearchLimit 条
+	MaxTokens int
+	// Limit 大于0时覆盖返回的表结构数量
+	Limit int
+	// DB 非空时在结果末尾附带每张表的数据新鲜度（最近写入时间），便于提示表可能已停止更新
+	DB *sql.DB
+	// MinScore 大于0时，所有候选的相似度都低于该值则返回 no_confident_match 结构化结果和最接近的候选表
+	MinScore float32
+}
+
+// DiscoverTables 根据自然语言描述检索相关表结构
+func DiscoverTables(ctx context.Context, cli *milvusclient.Client, query string, opts DiscoverOptions) (string, error) {
+	hits, pinned, err := discoverSchemaHits(ctx, cli, query, opts)
+	var noMatch *noConfidentMatchError
+	if errors.As(err, &noMatch) {
+		Logger.Infow("表结构检索没有可信匹配", "query", query, "threshold", noMatch.threshold, "candidates", len(noMatch.candidates))
+		return formatNoConfidentMatch(noMatch)
+	}
+	if err != nil {
+		return "", err
+	}
+	normalizeSchemaHits(hits)
+	normalizeSchemaHits(pinned)
+
+	if opts.MaxTokens > 0 {
+		// 置顶的表总会返回，预算先扣除它们的占用
+		budget := opts.MaxTokens
+		for _, hit := range pinned {
+			budget -= EstimateTokens(hit.Schema)
+		}
+		hits = fitSchemaHitsToBudget(hits, budget)
+	}
+
+	hits = append(hits, pinned...)
+	res := joinSchemaHits(hits)
+	if opts.DB != nil {
+		tables := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			if name, ok := tableNameFromSchema(hit.Schema); ok {
+				tables = append(tables, name)
+			}
+		}
+		res += formatTableFreshness(GetTableFreshness(ctx, opts.DB, tables))
+	}
+	return res, nil
+}
+
+// discoverSchemaHits 执行检索、重排序和排序规则，返回按相关度排序的结果以及需要附加的置顶表。
+// 设置了 MinScore 且没有候选达到时返回 noConfidentMatchError
+func discoverSchemaHits(ctx context.Context, cli *milvusclient.Client, query string, opts DiscoverOptions) ([]SchemaHit, []SchemaHit, error) {
+	field, vectors, err := embedForSearch(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("向量嵌入失败: %w", err)
+	}
+
+	limit := Config.SearchLimit
+	if opts.MaxTokens > 0 {
+		limit = maxBudgetSearchLimit
+	}
+	if opts.Limit > 0 {
+		limit = opts.Limit
+	}
+	// 多召回一些候选，被降权的表让出位置后由其余候选补上；启用重排序时由重排序决定最终保留哪些
+	candidates := limit * 2
+	if reranker != nil && candidates < rerankCandidateLimit {
+		candidates = rerankCandidateLimit
+	}
+
+	hits, err := searchSchemaField(ctx, cli, field, vectors, candidates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("相似度搜索失败: %w", err)
+	}
+
+	if opts.Translate && LLMEnabled() {
+		hits = append(hits, searchTranslated(ctx, cli, query, candidates)...)
+		hits = mergeSchemaHits(hits, candidates)
+	}
+	if err = checkConfidence(hits, opts.MinScore); err != nil {
+		return nil, nil, err
+	}
+
+	if reranker != nil {
+		hits = rerankHits(ctx, query, hits, candidates)
+	}
+	hits = preferSummaryTables(query, hits)
+
+	hits, pinned := applyRanking(ctx, cli, hits, limit)
+	return hits, pinned, nil
+}
+
+// searchTranslated 使用翻译后的查询检索，失败时只记录日志
+func searchTranslated(ctx context.Context, cli *milvusclient.Client, query string, limit int) []SchemaHit {
+	translated, lang, err := TranslateQuery(ctx, query)
+	if err != nil {
+		Logger.Warnw("查询翻译失败，仅使用原始查询", "query", query, "error", err)
+		return nil
+	}
+	Logger.Infow("查询翻译完成", "query", query, "translated", translated, "lang", lang)
+
+	field, vectors, err := embedForSearch(translated)
+	if err != nil {
+		Logger.Warnw("译文向量嵌入失败", "translated", translated, "error", err)
+		return nil
+	}
+
+	hits, err := searchSchemaField(ctx, cli, field, vectors, limit)
+	if err != nil {
+		Logger.Warnw("译文相似度搜索失败", "translated", translated, "error", err)
+		return nil
+	}
+	return hits
+}
+
+// mergeSchemaHits 合并多次检索的结果：同一张表只保留最高分，按分数降序取前 limit 条
+func mergeSchemaHits(hits []SchemaHit, limit int) []SchemaHit {
+	return truncateHits(dedupeSchemaHits(hits), limit)
+}
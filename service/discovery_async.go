@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+const (
+	// keywordIndexTTL 关键词索引的刷新间隔
+	keywordIndexTTL = 10 * time.Minute
+	// keywordMatchLimit 关键词匹配最多返回的表数
+	keywordMatchLimit = 5
+	// asyncDiscoveryTimeout 后台向量检索的超时
+	asyncDiscoveryTimeout = 60 * time.Second
+	// asyncResultTTL 后台检索结果保留的时长，过期后 pending_token 失效
+	asyncResultTTL = 10 * time.Minute
+)
+
+// keywordTable 为关键词索引中的一张表
+type keywordTable struct {
+	name    string
+	tokens  map[string]bool
+	columns map[string]bool
+	// comments 为表和列注释拼接后的小写文本，用于匹配中文等不按空格分词的查询
+	comments string
+}
+
+var (
+	keywordMu        sync.Mutex
+	keywordTables    []keywordTable
+	keywordRefreshed time.Time
+)
+
+// loadKeywordIndex 返回表名、列名和注释组成的关键词索引，超过 keywordIndexTTL 后从 information_schema 重新读取
+func loadKeywordIndex(ctx context.Context, db *sql.DB) ([]keywordTable, error) {
+	keywordMu.Lock()
+	defer keywordMu.Unlock()
+	if keywordTables != nil && time.Since(keywordRefreshed) < keywordIndexTTL {
+		return keywordTables, nil
+	}
+
+	forgotten, err := ListForgottenTables()
+	if err != nil {
+		Logger.Warnw("读取移出登记失败", "error", err)
+	}
+	tables := make(map[string]*keywordTable)
+	comments := make(map[string]*strings.Builder)
+	rows, err := db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COALESCE(TABLE_COMMENT, '') FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'`)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, comment string
+		if err = rows.Scan(&name, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if forgotten[name] || IsHiddenTable(name) {
+			continue
+		}
+		tables[name] = &keywordTable{name: name, tokens: keywordTokens(name), columns: make(map[string]bool)}
+		comments[name] = &strings.Builder{}
+		comments[name].WriteString(strings.ToLower(comment))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %v", err)
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COLUMN_NAME, COALESCE(COLUMN_COMMENT, '') FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()`)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column, comment string
+		if err = rows.Scan(&table, &column, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		t, ok := tables[table]
+		if !ok {
+			continue
+		}
+		for token := range keywordTokens(column) {
+			t.columns[token] = true
+		}
+		if comment != "" {
+			comments[table].WriteString(" " + strings.ToLower(comment))
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+
+	index := make([]keywordTable, 0, len(tables))
+	for name, t := range tables {
+		t.comments = comments[name].String()
+		index = append(index, *t)
+	}
+	keywordTables, keywordRefreshed = index, time.Now()
+	return index, nil
+}
+
+// keywordTokens 将文本按非字母数字字符（包括下划线）切分为小写词，并去掉英文复数的 s
+func keywordTokens(text string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tokens[word] = true
+		if len(word) > 3 && strings.HasSuffix(word, "s") {
+			tokens[strings.TrimSuffix(word, "s")] = true
+		}
+	}
+	return tokens
+}
+
+// KeywordMatchTables 按表名、列名和注释与查询的关键词重合程度返回最相关的几张表，不依赖嵌入服务
+func KeywordMatchTables(ctx context.Context, db *sql.DB, query string, limit int) ([]SchemaHit, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	index, err := loadKeywordIndex(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	words := keywordTokens(query)
+	lowerQuery := strings.ToLower(query)
+
+	type scored struct {
+		name  string
+		score float32
+	}
+	var matches []scored
+	for _, t := range index {
+		var score float32
+		for word := range words {
+			switch {
+			case t.tokens[word]:
+				score += 3
+			case t.columns[word]:
+				score += 1
+			}
+			if len(word) > 2 && strings.Contains(t.comments, word) {
+				score += 1
+			}
+		}
+		// 中文查询没有空格分词，整句出现在注释中，或注释中的词出现在查询里
+		if t.comments != "" && !isASCII(lowerQuery) {
+			for _, part := range strings.Fields(t.comments) {
+				if len([]rune(part)) >= 2 && strings.Contains(lowerQuery, part) {
+					score += 2
+				}
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{t.name, score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].name < matches[j].name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	hits := make([]SchemaHit, 0, len(matches))
+	for _, m := range matches {
+		ddl, err := TableDDL(ctx, db, m.name)
+		if err != nil {
+			Logger.Warnw("读取表结构失败", "table", m.name, "error", err)
+			continue
+		}
+		hits = append(hits, SchemaHit{Schema: ddl, Score: m.score / matches[0].score})
+	}
+	return hits, nil
+}
+
+// isASCII 判断文本是否只包含 ASCII 字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// asyncDiscovery 为一次后台执行的表结构检索
+type asyncDiscovery struct {
+	done    chan struct{}
+	result  string
+	err     error
+	expires time.Time
+	// finished 与 preliminary 在持有 asyncMu 时修改：检索先完成时直接返回结果，
+	// 已先返回关键词匹配时才在完成后推送通知
+	finished    bool
+	preliminary bool
+}
+
+var (
+	asyncMu       sync.Mutex
+	asyncSearches = map[string]*asyncDiscovery{}
+)
+
+// AsyncDiscoverOptions 控制异步检索
+type AsyncDiscoverOptions struct {
+	DiscoverOptions
+	// Wait 为等待向量检索完成的时长，超过后先返回关键词匹配的结果
+	Wait time.Duration
+	// Notify 在后台检索完成时调用，用于向客户端推送更新后的结果
+	Notify func(token, query, result string, err error)
+}
+
+// DiscoverTablesAsync 在后台执行嵌入和向量检索，Wait 时长内完成时直接返回检索结果；
+// 否则立即返回关键词匹配的表和 pending_token，检索完成后调用 Notify，并可通过 AsyncDiscoveryResult 取回
+func DiscoverTablesAsync(ctx context.Context, cli *milvusclient.Client, query string, opts AsyncDiscoverOptions) (string, error) {
+	token := newDiscoveryToken()
+	search := &asyncDiscovery{done: make(chan struct{}), expires: time.Now().Add(asyncResultTTL)}
+
+	asyncMu.Lock()
+	for t, s := range asyncSearches {
+		if time.Now().After(s.expires) {
+			delete(asyncSearches, t)
+		}
+	}
+	asyncSearches[token] = search
+	asyncMu.Unlock()
+
+	// 后台检索不随工具调用结束而取消，但保留上下文中的会话和标签
+	go func() {
+		searchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asyncDiscoveryTimeout)
+		defer cancel()
+		result, err := DiscoverTables(searchCtx, cli, query, opts.DiscoverOptions)
+
+		asyncMu.Lock()
+		search.result, search.err, search.finished = result, err, true
+		notify := search.preliminary
+		asyncMu.Unlock()
+		close(search.done)
+		if notify && opts.Notify != nil {
+			opts.Notify(token, query, result, err)
+		}
+	}()
+
+	select {
+	case <-search.done:
+	case <-time.After(opts.Wait):
+	case <-ctx.Done():
+	}
+	asyncMu.Lock()
+	if search.finished {
+		delete(asyncSearches, token)
+		asyncMu.Unlock()
+		return search.result, search.err
+	}
+	if ctx.Err() != nil {
+		delete(asyncSearches, token)
+		asyncMu.Unlock()
+		return "", ctx.Err()
+	}
+	search.preliminary = true
+	asyncMu.Unlock()
+
+	hits, err := KeywordMatchTables(ctx, opts.DB, query, keywordMatchLimit)
+	if err != nil {
+		Logger.Warnw("关键词匹配失败", "query", query, "error", err)
+	}
+	note := Text("discovery_pending", token)
+	if len(hits) == 0 {
+		return note + "\n\npending_token: " + token, nil
+	}
+	normalizeSchemaHits(hits)
+	return joinSchemaHits(hits) + "\n\n" + note + "\n\npending_token: " + token, nil
+}
+
+// AsyncDiscoveryResult 等待并返回 pending_token 对应的后台检索结果，结果取回后即失效
+func AsyncDiscoveryResult(ctx context.Context, token string) (string, error) {
+	asyncMu.Lock()
+	search, ok := asyncSearches[token]
+	asyncMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("pending_token 不存在或已过期: %s，请重新检索", token)
+	}
+
+	select {
+	case <-search.done:
+	case <-ctx.Done():
+		return "", fmt.Errorf("等待后台检索结果超时: %w", ctx.Err())
+	}
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	delete(asyncSearches, token)
+	return search.result, search.err
+}
+
+// newDiscoveryToken 生成后台检索的令牌
+func newDiscoveryToken() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("ds_%d", time.Now().UnixNano())
+	}
+	return "ds_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// dmlKeywords execute_dml 允许执行的语句类型
+var dmlKeywords = map[string]bool{
+	"insert":  true,
+	"update":  true,
+	"delete":  true,
+	"replace": true,
+}
+
+// PrepareDML 校验带 ? 占位符的 DML 语句，并把 JSON 数组形式的参数转换为预处理语句的参数。
+// 参数只作为预处理语句的绑定值传给服务端，不会拼接进 SQL
+func PrepareDML(statement, argsJSON string) ([]any, error) {
+	types := classifyStatements(statement)
+	if len(types) != 1 {
+		return nil, fmt.Errorf("execute_dml 每次只能执行一条语句，当前为 %d 条", len(types))
+	}
+	if !dmlKeywords[types[0]] {
+		return nil, fmt.Errorf("execute_dml 只支持 INSERT、UPDATE、DELETE 和 REPLACE 语句，当前为: %s", strings.ToUpper(types[0]))
+	}
+
+	var raw []json.RawMessage
+	if strings.TrimSpace(argsJSON) != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &raw); err != nil {
+			return nil, fmt.Errorf("args 必须是 JSON 数组: %v", err)
+		}
+	}
+	if n := countPlaceholders(statement); n != len(raw) {
+		return nil, fmt.Errorf("语句中有 %d 个占位符，但提供了 %d 个参数", n, len(raw))
+	}
+
+	args := make([]any, len(raw))
+	for i, r := range raw {
+		arg, err := dmlArg(r)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个参数无效: %v", i+1, err)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// dmlArg 将一个 JSON 值转换为绑定参数。整数保持为 int64，避免大数丢失精度；
+// 数组和对象按 JSON 文本绑定，用于 JSON 列
+func dmlArg(raw json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch val := v.(type) {
+	case nil, string, bool:
+		return val, nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n, nil
+		}
+		return val.Float64()
+	default:
+		return string(raw), nil
+	}
+}
+
+// countPlaceholders 统计语句中的 ? 占位符数量，按 lexSQL 的词法单元计数，字符串、带引号的标识符和注释中的问号不计入
+func countPlaceholders(sql string) int {
+	count := 0
+	for _, t := range lexSQL(sql) {
+		if t.kind == tokenPunct && t.text == "?" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,29 @@
+package service
+
+import "testing"
+
+func TestPrepareDML(t *testing.T) {
+	cases := []struct {
+		sql  string
+		args string
+		ok   bool
+	}{
+		{"UPDATE users SET email = ? WHERE id = ?", `["a@example.com", 42]`, true},
+		{"/* note */ DELETE FROM users WHERE id = ?", `[1]`, true},
+		{"WITH old AS (SELECT id FROM users WHERE created_at < ?) DELETE FROM users WHERE id IN (SELECT id FROM old)", `["2020-01-01"]`, true},
+		{"INSERT INTO logs (msg) VALUES (?);", `["x"]`, true},
+		{"INSERT INTO logs (msg) VALUES ('?')", ``, true},
+		{"UPDATE t SET a = ? --\tset b = ?\nWHERE id = ?", `[1, 2]`, true},
+		{"UPDATE t SET a = ? /* b = ? */ WHERE `c?` = ? # ?", `[1, 2]`, true},
+		{"UPDATE t SET a = ? WHERE id = ?", `[1]`, false},
+		{"SELECT * FROM users WHERE id = ?", `[1]`, false},
+		{"DELETE FROM a WHERE id = ?; DELETE FROM b", `[1]`, false},
+		{"DROP TABLE users", ``, false},
+	}
+	for _, c := range cases {
+		_, err := PrepareDML(c.sql, c.args)
+		if (err == nil) != c.ok {
+			t.Errorf("PrepareDML(%q, %s) = %v, want ok=%v", c.sql, c.args, err, c.ok)
+		}
+	}
+}
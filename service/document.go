@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// documentSampleSize 推断集合字段时采样的文档数量
+	documentSampleSize   = 100
+	defaultDocumentLimit = 20
+	maxDocumentLimit     = 200
+)
+
+// documentPathPattern 限制过滤条件中的字段路径，只允许以点分隔的普通标识符
+var documentPathPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// DocumentField 表示从集合文档中采样得到的一个顶层字段
+type DocumentField struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
+	// Count 为采样文档中包含该字段的数量
+	Count int `json:"count"`
+}
+
+// ListDocumentCollections 列出当前库中的文档集合。
+// 文档存储（X DevAPI）创建的集合是带有 JSON 类型 doc 列和生成列 _id 的普通表
+func ListDocumentCollections(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT c.TABLE_NAME FROM information_schema.COLUMNS c
+		JOIN information_schema.COLUMNS id ON id.TABLE_SCHEMA = c.TABLE_SCHEMA AND id.TABLE_NAME = c.TABLE_NAME
+		WHERE c.TABLE_SCHEMA = DATABASE() AND c.COLUMN_NAME = 'doc' AND c.DATA_TYPE = 'json'
+			AND id.COLUMN_NAME = '_id' AND id.EXTRA LIKE '%GENERATED%'
+		ORDER BY c.TABLE_NAME`))
+	if err != nil {
+		return nil, fmt.Errorf("查询文档集合失败: %v", err)
+	}
+	defer rows.Close()
+	return scanTables(rows)
+}
+
+// errOutsideSamplingWindow 表示当前不在采样时间窗口内，不读取业务数据
+var errOutsideSamplingWindow = errors.New("当前不在采样时间窗口内，暂不读取业务数据")
+
+// DescribeDocumentFields 采样集合中的文档，推断顶层字段及其 JSON 类型。
+// 采样属于读取业务数据，遵循 LowPriority 的时间窗口和执行时间限制
+func DescribeDocumentFields(ctx context.Context, db *sql.DB, collection string) ([]DocumentField, error) {
+	if err := ValidateIdentifier(collection); err != nil {
+		return nil, err
+	}
+	if !LowPriority.samplingAllowed(time.Now()) {
+		return nil, errOutsideSamplingWindow
+	}
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, LowPriority.sampleStatement(
+		fmt.Sprintf("SELECT doc FROM %s LIMIT %d", quoteIdentifier(collection), documentSampleSize))))
+	if err != nil {
+		return nil, fmt.Errorf("采样文档失败: %v", err)
+	}
+	defer rows.Close()
+
+	fields := make(map[string]*DocumentField)
+	for rows.Next() {
+		var raw []byte
+		if err = rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("扫描文档失败: %v", err)
+		}
+		var doc map[string]interface{}
+		if err = json.Unmarshal(raw, &doc); err != nil {
+			continue
+		}
+		for name, value := range doc {
+			field, ok := fields[name]
+			if !ok {
+				field = &DocumentField{Name: name}
+				fields[name] = field
+			}
+			field.Count++
+			if t := jsonTypeName(value); !containsString(field.Types, t) {
+				field.Types = append(field.Types, t)
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	result := make([]DocumentField, 0, len(fields))
+	for _, field := range fields {
+		sort.Strings(field.Types)
+		result = append(result, *field)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// describeDocumentSchema 生成附加在集合表结构后的字段说明，使向量检索能匹配到文档字段
+func describeDocumentSchema(fields []DocumentField) string {
+	var sb strings.Builder
+	sb.WriteString("\n-- MySQL document store collection; query with find_documents. Document fields:")
+	for _, f := range fields {
+		sb.WriteString(fmt.Sprintf("\n--   %s (%s)", f.Name, strings.Join(f.Types, "|")))
+	}
+	return sb.String()
+}
+
+// FindDocuments 按字段等值条件查询集合中的文档，filter 的键为字段路径（如 address.city）
+func FindDocuments(ctx context.Context, db *sql.DB, collection string, filter map[string]interface{}, limit int) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if err := ValidateIdentifier(collection); err != nil {
+		return "", err
+	}
+	if err := requireFeature(featureJSON); err != nil {
+		return "", err
+	}
+
+	collections, err := ListDocumentCollections(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if !containsString(collections, collection) {
+		return "", fmt.Errorf("不是文档集合: %s", collection)
+	}
+
+	if limit <= 0 {
+		limit = defaultDocumentLimit
+	}
+	if limit > maxDocumentLimit {
+		limit = maxDocumentLimit
+	}
+
+	// 对字段排序，保证生成的SQL稳定
+	paths := make([]string, 0, len(filter))
+	for path := range filter {
+		if !documentPathPattern.MatchString(path) {
+			return "", fmt.Errorf("无效的字段路径: %s", path)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	conditions := make([]string, 0, len(paths))
+	args := make([]any, 0, len(paths)*2)
+	for _, path := range paths {
+		value, err := json.Marshal(filter[path])
+		if err != nil {
+			return "", fmt.Errorf("无效的过滤值 %s: %v", path, err)
+		}
+		// 以 JSON 比较保证数字、字符串、布尔值按各自类型匹配
+		conditions = append(conditions, "JSON_EXTRACT(doc, ?) = CAST(? AS JSON)")
+		args = append(args, "$."+path, string(value))
+	}
+
+	query := fmt.Sprintf("SELECT doc FROM %s", quoteIdentifier(collection))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY _id LIMIT %d", limit)
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, query), args...)
+	if err != nil {
+		return "", fmt.Errorf("query execution failed: %v", err)
+	}
+	defer rows.Close()
+
+	docs := make([]json.RawMessage, 0)
+	for rows.Next() {
+		var raw []byte
+		if err = rows.Scan(&raw); err != nil {
+			return "", fmt.Errorf("failed to scan row: %v", err)
+		}
+		docs = append(docs, json.RawMessage(raw))
+	}
+	if err = rows.Err(); err != nil {
+		return "", fmt.Errorf("error during row iteration: %v", err)
+	}
+
+	resultJSON, err := json.MarshalIndent(docs, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(resultJSON), nil
+}
+
+// jsonTypeName 返回 JSON 值的类型名称
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
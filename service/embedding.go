@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+)
+
+// 主向量字段名称，存放主嵌入模型生成的向量
+const primaryVectorField = "vector"
+
+// EmbeddingConfig 存储嵌入模型的相关配置。
+// 配置了第二个模型时，每条表结构会同时存储两份向量，检索时按查询语言选择对应的向量字段，
+// 例如主模型针对中文优化、第二个模型针对英文优化，以提高中英文混用团队的召回率
+type EmbeddingConfig struct {
+	// Model 主嵌入模型，向量存放在 vector 字段，维度固定为 dim
+	Model string
+	// SecondaryModel 第二个嵌入模型，为空时不启用
+	SecondaryModel string
+	// SecondaryLang 使用第二个模型检索的查询语言（zh 或 en）
+	SecondaryLang string
+	// SecondaryDim 第二个模型输出的向量维度
+	SecondaryDim int
+}
+
+// 全局嵌入配置
+var Embedding = EmbeddingConfig{
+	Model: "BAAI/bge-m3",
+}
+
+// InitEmbeddingConfig 初始化嵌入配置
+func InitEmbeddingConfig(cfg EmbeddingConfig) error {
+	if cfg.Model == "" {
+		cfg.Model = "BAAI/bge-m3"
+	}
+	if cfg.SecondaryModel != "" {
+		if cfg.SecondaryLang != "zh" && cfg.SecondaryLang != "en" {
+			return fmt.Errorf("第二个嵌入模型的语言必须是 zh 或 en: %q", cfg.SecondaryLang)
+		}
+		if cfg.SecondaryDim <= 0 {
+			return fmt.Errorf("未配置第二个嵌入模型的向量维度")
+		}
+	}
+	Embedding = cfg
+	return nil
+}
+
+// SecondaryEmbeddingEnabled 判断是否启用了第二个嵌入模型
+func SecondaryEmbeddingEnabled() bool {
+	return Embedding.SecondaryModel != ""
+}
+
+// secondaryVectorField 返回第二个模型的向量字段名称，如 vector_en
+func secondaryVectorField() string {
+	return primaryVectorField + "_" + Embedding.SecondaryLang
+}
+
+// SchemaVectors 表示一条表结构的全部向量，未启用第二个模型时 Secondary 为空
+type SchemaVectors struct {
+	Primary   []float32
+	Secondary []float32
+}
+
+// embedSchema 使用所有已配置的模型对表结构进行向量嵌入
+func embedSchema(schema string) (SchemaVectors, error) {
+	var vectors SchemaVectors
+	var err error
+	if vectors.Primary, err = EmbedQuery(schema); err != nil {
+		return vectors, err
+	}
+	if SecondaryEmbeddingEnabled() {
+		if vectors.Secondary, err = embedWithModel(Embedding.SecondaryModel, schema); err != nil {
+			return vectors, fmt.Errorf("第二个模型向量嵌入失败: %w", err)
+		}
+		if len(vectors.Secondary) != Embedding.SecondaryDim {
+			return vectors, fmt.Errorf("第二个模型返回的向量维度为 %d，与配置的 %d 不一致",
+				len(vectors.Secondary), Embedding.SecondaryDim)
+		}
+	}
+	return vectors, nil
+}
+
+// embedForSearch 按查询语言选择模型进行向量嵌入，返回应检索的向量字段
+func embedForSearch(query string) (string, []float32, error) {
+	if SecondaryEmbeddingEnabled() && DetectLanguage(query) == Embedding.SecondaryLang {
+		vectors, err := embedWithModel(Embedding.SecondaryModel, query)
+		return secondaryVectorField(), vectors, err
+	}
+	vectors, err := EmbedQuery(query)
+	return primaryVectorField, vectors, err
+}
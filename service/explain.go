@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExplainQuery 返回语句的执行计划。format 为 traditional（默认，按行返回 JSON）、json（EXPLAIN FORMAT=JSON）
+// 或 tree（EXPLAIN FORMAT=TREE，需要 MySQL 8.0.16+），语句本身不会被执行
+func ExplainQuery(ctx context.Context, db *sql.DB, query, format string) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	if query == "" {
+		return "", fmt.Errorf("query is empty")
+	}
+	// EXPLAIN ANALYZE 会真正执行语句，这里只提供不执行的执行计划
+	switch keyword := statementKeyword(query); {
+	case keyword == "explain" || keyword == "describe" || keyword == "desc":
+		return "", fmt.Errorf("请直接传入需要分析的语句，不需要 EXPLAIN 前缀")
+	case keyword == "analyze" || strings.HasPrefix(keyword, "format"):
+		return "", fmt.Errorf("不支持 EXPLAIN ANALYZE 或自定义 FORMAT，请通过 format 参数指定格式")
+	}
+
+	switch strings.ToLower(format) {
+	case "", "traditional":
+		return ExecuteWithOptions(ctx, db, "EXPLAIN "+query, ExecOptions{})
+	case "json":
+		plan, err := explainFormatted(ctx, db, "EXPLAIN FORMAT=JSON "+query)
+		if err != nil {
+			return "", err
+		}
+		var indented bytes.Buffer
+		if err = json.Indent(&indented, []byte(plan), "", "  "); err != nil {
+			return plan, nil
+		}
+		return indented.String(), nil
+	case "tree":
+		if err := requireFeature(featureExplainTree); err != nil {
+			return "", err
+		}
+		return explainFormatted(ctx, db, "EXPLAIN FORMAT=TREE "+query)
+	default:
+		return "", fmt.Errorf("不支持的执行计划格式: %s，可选 traditional、json、tree", format)
+	}
+}
+
+// explainFormatted 执行返回单个文本列的 EXPLAIN 语句，与其他语句一样经过执行策略检查并写入审计
+func explainFormatted(ctx context.Context, db *sql.DB, stmt string) (string, error) {
+	return withBreaker(func() (string, error) {
+		conn, release, _, err := acquireConn(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		var plan CapturedResult
+		if _, err = runStatement(ctx, conn, stmt, ExecOptions{Capture: &plan}); err != nil {
+			return "", err
+		}
+		// 执行计划超出结果字节数上限时不会被保留
+		if len(plan.Columns) == 0 || len(plan.Rows) == 0 {
+			return "", fmt.Errorf("explain failed: 执行计划为空或超出结果大小上限")
+		}
+		// 语句已结束时 EXPLAIN FOR CONNECTION 返回 NULL
+		if value := plan.Rows[0][plan.Columns[0]]; value != nil {
+			return fmt.Sprint(value), nil
+		}
+		return "", nil
+	})
+}
+
+// RunningQuery 表示 processlist 中一条正在执行的语句
+type RunningQuery struct {
+	ID      int64  `json:"id"`
+	User    string `json:"user"`
+	Host    string `json:"host"`
+	DB      string `json:"db,omitempty"`
+	TimeSec int64  `json:"time_sec"`
+	State   string `json:"state,omitempty"`
+	Info    string `json:"info"`
+}
+
+// runningQueryLimit 列出的正在执行的语句数量上限
+const runningQueryLimit = 20
+
+// ListRunningQueries 列出正在执行的语句，按执行时长降序，排除本连接
+func ListRunningQueries(ctx context.Context, db *sql.DB, minSeconds int) ([]RunningQuery, error) {
+	if err := requireDirectConnection("processlist"); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT ID, USER, HOST, COALESCE(DB, ''), TIME, COALESCE(STATE, ''), INFO
+		FROM information_schema.PROCESSLIST
+		WHERE COMMAND = 'Query' AND INFO IS NOT NULL AND ID <> CONNECTION_ID() AND TIME >= ?
+		ORDER BY TIME DESC LIMIT ?`, minSeconds, runningQueryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("查询 processlist 失败: %v", err)
+	}
+	defer rows.Close()
+
+	queries := make([]RunningQuery, 0)
+	for rows.Next() {
+		var q RunningQuery
+		if err = rows.Scan(&q.ID, &q.User, &q.Host, &q.DB, &q.TimeSec, &q.State, &q.Info); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// ExplainRunningQuery 结合 processlist 与 EXPLAIN FOR CONNECTION 查看正在执行的语句的执行计划。
+// connectionID 为0时只列出正在执行的语句（执行时长不少于 minSeconds 秒），供调用方选择
+func ExplainRunningQuery(ctx context.Context, db *sql.DB, connectionID int64, minSeconds int) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+
+	if connectionID <= 0 {
+		queries, err := ListRunningQueries(ctx, db, minSeconds)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.MarshalIndent(map[string]any{"running_queries": queries}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+		}
+		return string(data), nil
+	}
+
+	if err := requireDirectConnection("EXPLAIN FOR CONNECTION"); err != nil {
+		return "", err
+	}
+	var q RunningQuery
+	err := db.QueryRowContext(ctx, `
+		SELECT ID, USER, HOST, COALESCE(DB, ''), TIME, COALESCE(STATE, ''), COALESCE(INFO, '')
+		FROM information_schema.PROCESSLIST WHERE ID = ?`, connectionID).
+		Scan(&q.ID, &q.User, &q.Host, &q.DB, &q.TimeSec, &q.State, &q.Info)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("连接 %d 不存在或已结束", connectionID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询 processlist 失败: %v", err)
+	}
+	if q.Info == "" {
+		return "", fmt.Errorf("连接 %d 当前没有正在执行的语句", connectionID)
+	}
+
+	// 旧版本不支持 EXPLAIN FOR CONNECTION，退化为只返回语句
+	if err = requireFeature(featureExplainForConnection); err != nil {
+		data, err := json.MarshalIndent(map[string]any{"query": q, "plan_unavailable": err.Error()}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+		}
+		return string(data), nil
+	}
+
+	// EXPLAIN FOR CONNECTION 需要 PROCESS 权限，查看其他用户的连接还需要 SUPER 或 CONNECTION_ADMIN
+	plan, err := explainFormatted(ctx, db, fmt.Sprintf("EXPLAIN FORMAT=JSON FOR CONNECTION %d", connectionID))
+	if err != nil {
+		return "", err
+	}
+	if plan == "" {
+		return "", fmt.Errorf("连接 %d 的语句已执行结束或不支持 EXPLAIN", connectionID)
+	}
+
+	data, err := json.MarshalIndent(map[string]any{"query": q, "plan": json.RawMessage(plan)}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(data), nil
+}
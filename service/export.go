@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// HistoryExporter 把查询历史事件投递到外部系统（日志平台、消息队列等），
+// 用于需要集中管理数据库访问日志的场景。实现方需要自行处理重试，返回错误时事件会被丢弃
+type HistoryExporter interface {
+	Export(ctx context.Context, entry HistoryEntry) error
+}
+
+const (
+	// historyExportQueueSize 待投递事件的缓冲大小，队列满时新事件会被丢弃，不阻塞SQL执行
+	historyExportQueueSize = 1000
+	historyExportTimeout   = 10 * time.Second
+)
+
+// 当前生效的导出实现及其事件队列，为 nil 时不导出
+var (
+	historyExporter HistoryExporter
+	historyExportCh chan HistoryEntry
+)
+
+// SetHistoryExporter 设置查询历史导出实现并启动后台投递协程，传入 nil 时关闭导出。
+// 只应在启动时调用一次
+func SetHistoryExporter(e HistoryExporter) {
+	historyExporter = e
+	if e == nil {
+		historyExportCh = nil
+		return
+	}
+
+	ch := make(chan HistoryEntry, historyExportQueueSize)
+	historyExportCh = ch
+	go func() {
+		for entry := range ch {
+			ctx, cancel := context.WithTimeout(context.Background(), historyExportTimeout)
+			if err := e.Export(ctx, entry); err != nil {
+				Logger.Warnw("导出查询历史失败", "id", entry.ID, "error", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// exportHistory 将查询历史事件放入投递队列
+func exportHistory(entry HistoryEntry) {
+	if historyExportCh == nil {
+		return
+	}
+	select {
+	case historyExportCh <- entry:
+	default:
+		Logger.Warnw("查询历史导出队列已满，丢弃事件", "id", entry.ID)
+	}
+}
+
+// NewHistoryExporter 根据类型创建内置的导出实现：
+//   - http: POST 单条事件的 JSON 到 target
+//   - kafka: 通过 Kafka REST Proxy 写入主题，target 为 REST Proxy 地址，topic 为主题名
+//   - syslog: 以 RFC 5424 格式发送到 target，格式为 udp://host:514 或 tcp://host:514
+func NewHistoryExporter(kind, target, token, topic string) (HistoryExporter, error) {
+	if target == "" {
+		return nil, fmt.Errorf("导出地址不能为空")
+	}
+	switch strings.ToLower(kind) {
+	case "http":
+		return &HTTPHistoryExporter{URL: target, Token: token}, nil
+	case "kafka":
+		if topic == "" {
+			return nil, fmt.Errorf("kafka 导出需要指定主题")
+		}
+		return &HTTPHistoryExporter{
+			URL:   strings.TrimRight(target, "/") + "/topics/" + url.PathEscape(topic),
+			Token: token,
+			Kafka: true,
+		}, nil
+	case "syslog":
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return nil, fmt.Errorf("syslog 地址格式应为 udp://host:port 或 tcp://host:port: %s", target)
+		}
+		return &SyslogHistoryExporter{Network: u.Scheme, Addr: u.Host}, nil
+	default:
+		return nil, fmt.Errorf("不支持的导出类型: %s", kind)
+	}
+}
+
+// HTTPHistoryExporter 通过 HTTP 投递事件。Kafka 为 true 时按 Kafka REST Proxy v2 的格式包装为
+// {"records": [{"value": {...}}]}
+type HTTPHistoryExporter struct {
+	URL   string
+	Token string
+	Kafka bool
+}
+
+// Export 实现 HistoryExporter 接口
+func (e *HTTPHistoryExporter) Export(ctx context.Context, entry HistoryEntry) error {
+	var (
+		payload     any = entry
+		contentType     = "application/json"
+	)
+	if e.Kafka {
+		payload = map[string]any{"records": []map[string]any{{"value": entry}}}
+		contentType = "application/vnd.kafka.json.v2+json"
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("JSON 序列化失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	if e.Token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", e.Token))
+	}
+	req.Header.Add("Content-Type", contentType)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("请求失败，状态码: %d, 响应: %s", res.StatusCode, body)
+	}
+	return nil
+}
+
+// SyslogHistoryExporter 以 RFC 5424 格式把事件发送到 syslog 服务，消息体为事件 JSON
+type SyslogHistoryExporter struct {
+	Network string
+	Addr    string
+}
+
+// syslogPriority local0.info，成功和失败的查询分别使用 info 与 warning 级别
+const (
+	syslogPriorityInfo    = 16*8 + 6
+	syslogPriorityWarning = 16*8 + 4
+)
+
+// Export 实现 HistoryExporter 接口
+func (e *SyslogHistoryExporter) Export(ctx context.Context, entry HistoryEntry) error {
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("JSON 序列化失败: %v", err)
+	}
+
+	priority := syslogPriorityInfo
+	if !entry.Success {
+		priority = syslogPriorityWarning
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s mcp-mysql %d query_history - %s",
+		priority, entry.CreatedAt.UTC().Format(time.RFC3339), hostname, os.Getpid(), jsonData)
+	// TCP 使用 octet counting 分帧
+	if e.Network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, e.Network, e.Addr)
+	if err != nil {
+		return fmt.Errorf("连接 syslog 失败: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err = conn.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("发送 syslog 失败: %v", err)
+	}
+	return nil
+}
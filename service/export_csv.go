@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// csvInlineLimit 导出结果不超过该字节数时直接以文本返回，不写文件
+const csvInlineLimit = 64 * 1024
+
+// CSVExportOptions 控制 CSV 导出的行为
+type CSVExportOptions struct {
+	// Dir 为导出目录，为空时只能导出不超过 csvInlineLimit 的结果
+	Dir string
+	// FileName 为导出文件名，为空时自动生成
+	FileName string
+	// ForceFile 为 true 时即便结果很小也写入文件
+	ForceFile bool
+}
+
+// CSVExportResult 为 CSV 导出的结果，Path 为空时结果在 Inline 中
+type CSVExportResult struct {
+	Rows      int
+	Bytes     int64
+	Path      string
+	Inline    string
+	Truncated bool
+}
+
+// spillWriter 先把内容写入内存，超过阈值或要求写文件时转存到文件
+type spillWriter struct {
+	buf   bytes.Buffer
+	limit int
+	file  *os.File
+	open  func() (*os.File, error)
+	n     int64
+}
+
+func (w *spillWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	if w.file == nil && w.buf.Len()+len(p) <= w.limit {
+		return w.buf.Write(p)
+	}
+	if w.file == nil {
+		if err := w.spill(); err != nil {
+			return 0, err
+		}
+	}
+	return w.file.Write(p)
+}
+
+// spill 将内存中的内容转存到文件
+func (w *spillWriter) spill() error {
+	f, err := w.open()
+	if err != nil {
+		return err
+	}
+	w.file = f
+	_, err = w.buf.WriteTo(f)
+	return err
+}
+
+// ExportQueryCSV 执行查询并以流式方式导出为 CSV，不会把结果集整体加载到内存
+func ExportQueryCSV(ctx context.Context, db *sql.DB, query string, opts CSVExportOptions) (*CSVExportResult, error) {
+	start := time.Now()
+	result, err := exportQueryCSV(ctx, db, query, opts)
+	var rows int64
+	if result != nil {
+		rows = int64(result.Rows)
+	}
+	recordAudit(ctx, query, rows, time.Since(start), err)
+	return result, err
+}
+
+// exportQueryCSV 为 ExportQueryCSV 的实现
+func exportQueryCSV(ctx context.Context, db *sql.DB, query string, opts CSVExportOptions) (*CSVExportResult, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	if !isQueryStatement(query) {
+		return nil, fmt.Errorf("只能导出查询语句的结果")
+	}
+	policy, err := guardStatement(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &spillWriter{limit: csvInlineLimit, open: func() (*os.File, error) {
+		return createExportFile(opts)
+	}}
+	if opts.ForceFile {
+		out.limit = 0
+	}
+
+	result := &CSVExportResult{}
+	_, err = withBreaker(func() (string, error) {
+		// 导出可能持续较长时间，同样占用会话的访问名额
+		release, err := acquireSlot(ctx)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		rows, err := db.QueryContext(ctx, labelStatement(ctx, query))
+		if err != nil {
+			return "", fmt.Errorf("query execution failed: %w", err)
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			return "", fmt.Errorf("failed to get column names: %v", err)
+		}
+
+		guard := newResultGuard(query, columns, policy)
+		w := csv.NewWriter(out)
+		if err = w.Write(columns); err != nil {
+			return "", err
+		}
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		record := make([]string, len(columns))
+		for rows.Next() {
+			if policy.MaxRows > 0 && result.Rows >= policy.MaxRows {
+				result.Truncated = true
+				break
+			}
+			if err = rows.Scan(pointers...); err != nil {
+				return "", fmt.Errorf("failed to scan row: %v", err)
+			}
+			if guard.skip(values) {
+				continue
+			}
+			for i := range columns {
+				record[i] = csvValue(guard.masker.apply(i, values[i]))
+			}
+			if err = w.Write(record); err != nil {
+				return "", fmt.Errorf("写入CSV失败: %w", err)
+			}
+			result.Rows++
+		}
+		if err = rows.Err(); err != nil {
+			return "", fmt.Errorf("error during row iteration: %w", err)
+		}
+		w.Flush()
+		return "", w.Error()
+	})
+	if out.file != nil {
+		if closeErr := out.file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("写入CSV文件失败: %w", closeErr)
+		}
+		if err != nil {
+			os.Remove(out.file.Name())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Bytes = out.n
+	if out.file != nil {
+		result.Path = out.file.Name()
+	} else {
+		result.Inline = out.buf.String()
+	}
+	return result, nil
+}
+
+// createExportFile 在导出目录中创建导出文件，文件名不能包含路径
+func createExportFile(opts CSVExportOptions) (*os.File, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("结果超过 %d 字节，需要配置 EXPORT_DIR 才能导出为文件", csvInlineLimit)
+	}
+	name := opts.FileName
+	if name == "" {
+		name = fmt.Sprintf("export_%s.csv", time.Now().Format("20060102_150405.000"))
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("导出文件名不能包含路径: %s", name)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".csv") {
+		name += ".csv"
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建导出目录失败: %v", err)
+	}
+
+	// 不覆盖已有文件
+	f, err := os.OpenFile(filepath.Join(opts.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %v", err)
+	}
+	return f, nil
+}
+
+// csvValue 将列值转换为CSV字段，NULL 输出为空字段
+func csvValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// ExportHandleCSV 将结果句柄中保存的结果导出为 CSV，无需重新执行查询
+func ExportHandleCSV(ctx context.Context, name string, opts CSVExportOptions) (*CSVExportResult, error) {
+	h, err := GetResultHandle(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &spillWriter{limit: csvInlineLimit, open: func() (*os.File, error) {
+		return createExportFile(opts)
+	}}
+	if opts.ForceFile {
+		out.limit = 0
+	}
+
+	w := csv.NewWriter(out)
+	err = w.Write(h.result.Columns)
+	record := make([]string, len(h.result.Columns))
+	for _, row := range h.result.Rows {
+		if err != nil {
+			break
+		}
+		for i, col := range h.result.Columns {
+			record[i] = csvValue(row[col])
+		}
+		err = w.Write(record)
+	}
+	if err == nil {
+		w.Flush()
+		err = w.Error()
+	}
+	if out.file != nil {
+		if closeErr := out.file.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(out.file.Name())
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("写入CSV失败: %w", err)
+	}
+
+	result := &CSVExportResult{Rows: len(h.result.Rows), Bytes: out.n, Truncated: h.Truncated}
+	if out.file != nil {
+		result.Path = out.file.Name()
+	} else {
+		result.Inline = out.buf.String()
+	}
+	return result, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FairScheduleConfig 控制多个会话共享服务时访问数据库的公平调度
+type FairScheduleConfig struct {
+	// Slots 为同时访问数据库的语句数上限，0 表示不调度
+	Slots int
+	// SessionSlots 为单个会话同时占用的上限，0 表示 Slots 的一半（至少为1）
+	SessionSlots int
+}
+
+// fairScheduler 按会话排队分配数据库访问名额。名额释放时优先分配给当前占用最少的会话，
+// 占用相同时按轮转顺序分配，单个会话的大查询或导出不会让其他会话的短查询一直排队
+type fairScheduler struct {
+	mu           sync.Mutex
+	slots        int
+	sessionSlots int
+	inUse        int
+	active       map[string]int
+	waiting      map[string][]*fairWaiter
+	// order 为有等待者的会话的轮转顺序
+	order []string
+}
+
+// fairWaiter 为一个等待名额的请求，granted 在持有 mu 时修改
+type fairWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// 全局公平调度器，为 nil 时不调度
+var fairQueue *fairScheduler
+
+// InitFairSchedule 初始化会话公平调度，Slots 为0时关闭
+func InitFairSchedule(cfg FairScheduleConfig) {
+	if cfg.Slots <= 0 {
+		fairQueue = nil
+		return
+	}
+	if cfg.SessionSlots <= 0 {
+		cfg.SessionSlots = max(cfg.Slots/2, 1)
+	}
+	fairQueue = &fairScheduler{
+		slots:        cfg.Slots,
+		sessionSlots: min(cfg.SessionSlots, cfg.Slots),
+		active:       make(map[string]int),
+		waiting:      make(map[string][]*fairWaiter),
+	}
+	Logger.Infow("会话公平调度已开启", "slots", cfg.Slots, "session_slots", fairQueue.sessionSlots)
+}
+
+// acquireSlot 为上下文所属的会话申请一个数据库访问名额，返回释放名额的函数。未开启调度时直接返回
+func acquireSlot(ctx context.Context) (func(), error) {
+	f := fairQueue
+	if f == nil {
+		return func() {}, nil
+	}
+	return f.acquire(ctx, sessionFromContext(ctx))
+}
+
+// acquire 申请名额，名额不足时在会话自己的队列中排队
+func (f *fairScheduler) acquire(ctx context.Context, session string) (func(), error) {
+	f.mu.Lock()
+	if len(f.waiting[session]) == 0 && f.available(session) {
+		f.take(session)
+		f.mu.Unlock()
+		return f.releaseFunc(session), nil
+	}
+	w := &fairWaiter{ready: make(chan struct{})}
+	if len(f.waiting[session]) == 0 {
+		f.order = append(f.order, session)
+	}
+	f.waiting[session] = append(f.waiting[session], w)
+	f.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return f.releaseFunc(session), nil
+	case <-ctx.Done():
+		f.mu.Lock()
+		granted := w.granted
+		if !granted {
+			f.removeWaiter(session, w)
+		}
+		f.mu.Unlock()
+		if granted {
+			// 取消的同时已分配到名额，归还给其他会话
+			f.release(session)
+		}
+		return nil, fmt.Errorf("等待数据库访问名额时取消: %w", ctx.Err())
+	}
+}
+
+// available 判断会话是否还能占用名额，调用时持有 mu
+func (f *fairScheduler) available(session string) bool {
+	return f.inUse < f.slots && f.active[session] < f.sessionSlots
+}
+
+// take 为会话占用一个名额，调用时持有 mu
+func (f *fairScheduler) take(session string) {
+	f.inUse++
+	f.active[session]++
+}
+
+// releaseFunc 返回只生效一次的释放函数
+func (f *fairScheduler) releaseFunc(session string) func() {
+	var once sync.Once
+	return func() { once.Do(func() { f.release(session) }) }
+}
+
+// release 归还名额，并把空出的名额分配给排队的会话
+func (f *fairScheduler) release(session string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.inUse--
+	if f.active[session]--; f.active[session] <= 0 {
+		delete(f.active, session)
+	}
+	f.dispatch()
+}
+
+// dispatch 在有空闲名额时，依次分配给当前占用最少的排队会话，调用时持有 mu
+func (f *fairScheduler) dispatch() {
+	for f.inUse < f.slots {
+		next := -1
+		for i, s := range f.order {
+			if f.active[s] >= f.sessionSlots {
+				continue
+			}
+			if next < 0 || f.active[s] < f.active[f.order[next]] {
+				next = i
+			}
+		}
+		if next < 0 {
+			return
+		}
+
+		session := f.order[next]
+		queue := f.waiting[session]
+		w := queue[0]
+		f.waiting[session] = queue[1:]
+		f.order = append(f.order[:next], f.order[next+1:]...)
+		if len(f.waiting[session]) > 0 {
+			// 仍有等待者的会话排到轮转顺序的末尾
+			f.order = append(f.order, session)
+		} else {
+			delete(f.waiting, session)
+		}
+
+		f.take(session)
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// removeWaiter 移除已取消的等待者，调用时持有 mu
+func (f *fairScheduler) removeWaiter(session string, w *fairWaiter) {
+	queue := f.waiting[session]
+	for i, item := range queue {
+		if item == w {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) > 0 {
+		f.waiting[session] = queue
+		return
+	}
+	delete(f.waiting, session)
+	for i, s := range f.order {
+		if s == session {
+			f.order = append(f.order[:i], f.order[i+1:]...)
+			break
+		}
+	}
+}
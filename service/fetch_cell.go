@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultCellFetchLength = 8000
+	maxCellFetchLength     = 65536
+)
+
+// CellSlice 为 FetchCell 返回的大字段片段
+type CellSlice struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// Unit 为 offset、length 的单位：TEXT 类型按字符，BLOB 等二进制类型按字节
+	Unit        string `json:"unit"`
+	Offset      int64  `json:"offset"`
+	Length      int64  `json:"length"`
+	TotalLength int64  `json:"total_length"`
+	HasMore     bool   `json:"has_more"`
+	NextOffset  int64  `json:"next_offset,omitempty"`
+	// Encoding 为 text 或 base64，二进制内容不是有效 UTF-8 时使用 base64
+	Encoding string `json:"encoding"`
+	Data     string `json:"data"`
+}
+
+// FetchCell 读取一行中大字段的一段内容（从 offset 开始的 length 个字符或字节），
+// 由服务端 SUBSTRING 截取，不会把整个值读入内存或上下文。
+// pk 为主键值，联合主键时为列名到取值的 JSON 对象
+func FetchCell(ctx context.Context, db *sql.DB, table, pk, column string, offset, length int64) (*CellSlice, error) {
+	if err := ValidateIdentifier(column); err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset 不能为负数: %d", offset)
+	}
+	if length <= 0 {
+		length = defaultCellFetchLength
+	}
+	if length > maxCellFetchLength {
+		length = maxCellFetchLength
+	}
+
+	desc, err := DescribeTable(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		columnType string
+		found      bool
+	)
+	for _, c := range desc.Columns {
+		if strings.EqualFold(c.Name, column) {
+			column, columnType, found = c.Name, strings.ToLower(c.Type), true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("列不存在: %s.%s", table, column)
+	}
+	var pkColumns []string
+	for _, idx := range desc.Indexes {
+		if idx.Name == "PRIMARY" {
+			pkColumns = idx.Columns
+		}
+	}
+	if len(pkColumns) == 0 {
+		return nil, fmt.Errorf("表 %s 没有主键，无法定位单行", table)
+	}
+	where, args, err := primaryKeyCondition(pkColumns, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	binary := strings.Contains(columnType, "blob") || strings.Contains(columnType, "binary")
+	lengthFunc, unit := "CHAR_LENGTH", "characters"
+	if binary {
+		lengthFunc, unit = "LENGTH", "bytes"
+	}
+	query := fmt.Sprintf("SELECT %s(%s), SUBSTRING(%s, ?, ?) FROM %s WHERE %s",
+		lengthFunc, quoteIdentifier(column), quoteIdentifier(column), quoteIdentifier(desc.Name), where)
+	if masker := newResultMasker(query, []string{column}, activePolicy()); masker.masked(0) {
+		return nil, fmt.Errorf("列 %s.%s 已配置脱敏，不能读取原始内容", table, column)
+	}
+
+	var (
+		total sql.NullInt64
+		data  []byte
+	)
+	args = append([]any{offset + 1, length}, args...)
+	start := time.Now()
+	err = db.QueryRowContext(ctx, labelStatement(ctx, query), args...).Scan(&total, &data)
+	switch err {
+	case nil:
+		recordAudit(ctx, query, 1, time.Since(start), nil)
+	case sql.ErrNoRows:
+		recordAudit(ctx, query, 0, time.Since(start), nil)
+	default:
+		recordAudit(ctx, query, 0, time.Since(start), err)
+	}
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("没有找到主键为 %s 的行", pk)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取字段内容失败: %v", err)
+	}
+
+	slice := &CellSlice{
+		Table: desc.Name, Column: column, Unit: unit, Offset: offset,
+		TotalLength: total.Int64, Encoding: "text", Data: string(data),
+	}
+	if binary {
+		slice.Length = int64(len(data))
+	} else {
+		slice.Length = int64(utf8.RuneCount(data))
+	}
+	if binary && !utf8.Valid(data) {
+		slice.Encoding, slice.Data = "base64", base64.StdEncoding.EncodeToString(data)
+	}
+	if end := offset + slice.Length; end < slice.TotalLength {
+		slice.HasMore, slice.NextOffset = true, end
+	}
+	return slice, nil
+}
+
+// primaryKeyCondition 根据主键列生成 WHERE 条件。单列主键时 pk 为取值本身，
+// 联合主键时为包含所有主键列的 JSON 对象
+func primaryKeyCondition(columns []string, pk string) (string, []any, error) {
+	if pk == "" {
+		return "", nil, fmt.Errorf("pk 不能为空")
+	}
+	values := make(map[string]any)
+	if len(columns) == 1 {
+		values[columns[0]] = pk
+	} else if err := json.Unmarshal([]byte(pk), &values); err != nil {
+		return "", nil, fmt.Errorf("联合主键 (%s) 的 pk 应为 JSON 对象，如 {\"%s\": ...}: %v",
+			strings.Join(columns, ", "), columns[0], err)
+	}
+
+	conditions := make([]string, 0, len(columns))
+	args := make([]any, 0, len(columns))
+	for _, c := range columns {
+		v, ok := values[c]
+		if !ok {
+			return "", nil, fmt.Errorf("pk 缺少主键列 %s", c)
+		}
+		conditions = append(conditions, quoteIdentifier(c)+" = ?")
+		args = append(args, v)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// FormatCellSlice 将字段片段格式化为 JSON
+func FormatCellSlice(slice *CellSlice) (string, error) {
+	data, err := json.MarshalIndent(slice, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cell slice to JSON: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+var forgottenTable = "forgotten_tables"
+
+// createForgottenTable 创建被移出索引的表的登记表。
+// 登记在其中的表即使仍存在于数据库中，也不会被定时更新或全量重建重新加入索引
+func createForgottenTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			table_name TEXT PRIMARY KEY,
+			forgotten_at INTEGER NOT NULL
+		)`, forgottenTable))
+	return err
+}
+
+// ListForgottenTables 返回所有被移出索引的表
+func ListForgottenTables() (map[string]bool, error) {
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	rows, err := sqlite().Query(fmt.Sprintf("SELECT table_name FROM %s", forgottenTable))
+	if err != nil {
+		return nil, fmt.Errorf("查询被移出索引的表失败: %v", err)
+	}
+	defer rows.Close()
+
+	forgotten := make(map[string]bool)
+	for rows.Next() {
+		var tableName string
+		if err = rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("扫描表名失败: %v", err)
+		}
+		forgotten[tableName] = true
+	}
+	return forgotten, rows.Err()
+}
+
+// ForgetTable 将一张表从向量索引和 SQLite 登记中移除，并登记为不再索引，
+// 适用于已废弃但仍保留在数据库中的表，避免模型继续推荐它们
+func ForgetTable(ctx context.Context, cli *milvusclient.Client, tableName string) (string, error) {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return "", err
+	}
+	if err := requireIndexWriter(); err != nil {
+		return "", err
+	}
+	if err := InitSQLite(); err != nil {
+		return "", fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	if !indexMutex.TryLock() {
+		return "", fmt.Errorf("已有索引任务在进行中")
+	}
+	defer indexMutex.Unlock()
+
+	ids, err := findTableVectorIDs(ctx, cli, tableName)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) > 0 {
+		if _, err = cli.Delete(ctx, milvusclient.NewDeleteOption(Config.CollectionName).WithInt64IDs("my_id", ids)); err != nil {
+			return "", fmt.Errorf("删除表结构向量失败: %w", err)
+		}
+	}
+
+	tx, err := sqlite().Begin()
+	if err != nil {
+		return "", fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", dbTable), tableName); err != nil {
+		return "", fmt.Errorf("删除索引元数据失败: %v", err)
+	}
+	if _, err = tx.Exec(fmt.Sprintf("INSERT OR REPLACE INTO %s (table_name, forgotten_at) VALUES (?, ?)", forgottenTable),
+		tableName, time.Now().Unix()); err != nil {
+		return "", fmt.Errorf("登记被移出索引的表失败: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return "", fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	Logger.Infow("已将表移出索引", "table", tableName, "vectors", len(ids))
+	return fmt.Sprintf("Removed %s from the index (%d vector(s) deleted). It will no longer be suggested or re-indexed; "+
+		"call forget_table with restore=true to index it again.", tableName, len(ids)), nil
+}
+
+// RestoreTable 取消表的移出登记，下一次定时更新时会重新索引该表
+func RestoreTable(tableName string) (string, error) {
+	if err := InitSQLite(); err != nil {
+		return "", fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	res, err := sqlite().Exec(fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", forgottenTable), tableName)
+	if err != nil {
+		return "", fmt.Errorf("取消移出登记失败: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Sprintf("%s was not removed from the index; nothing to restore.", tableName), nil
+	}
+	Logger.Infow("已取消表的移出登记", "table", tableName)
+	return fmt.Sprintf("%s will be indexed again on the next schema update (or call reindex_schemas).", tableName), nil
+}
+
+// tableVector 表示集合中属于某张表的一条向量记录
+type tableVector struct {
+	ID     int64
+	Schema string
+}
+
+// findTableVectorIDs 查找集合中属于指定表的所有向量，包括早期重复写入的向量
+func findTableVectorIDs(ctx context.Context, cli *milvusclient.Client, tableName string) ([]int64, error) {
+	vectors, err := queryTableVectors(ctx, cli, tableName)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(vectors))
+	for i, v := range vectors {
+		ids[i] = v.ID
+	}
+	return ids, nil
+}
+
+// queryTableVectors 查询集合中属于指定表的向量记录
+func queryTableVectors(ctx context.Context, cli *milvusclient.Client, tableName string) ([]tableVector, error) {
+	// 表结构文本以 CREATE TABLE `name` 开头，先按前缀粗筛，再解析表名精确比对
+	prefix := "CREATE TABLE `" + strings.ReplaceAll(tableName, "`", "``") + "`"
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	rs, err := cli.Query(ctx, milvusclient.NewQueryOption(Config.CollectionName).
+		WithFilter(fmt.Sprintf(`schema like "%s%%"`, escaper.Replace(prefix))).
+		WithOutputFields("my_id", "schema").
+		WithConsistencyLevel(entity.ClStrong))
+	if err != nil {
+		return nil, fmt.Errorf("查找表结构向量失败: %w", err)
+	}
+
+	idColumn, schemaColumn := rs.GetColumn("my_id"), rs.GetColumn("schema")
+	if idColumn == nil || schemaColumn == nil {
+		return nil, nil
+	}
+	var vectors []tableVector
+	for i := 0; i < idColumn.Len(); i++ {
+		schema, err := schemaColumn.GetAsString(i)
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := tableNameFromSchema(schema); !ok || name != tableName {
+			continue
+		}
+		id, err := idColumn.GetAsInt64(i)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, tableVector{ID: id, Schema: schema})
+	}
+	return vectors, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FreshnessConfig 控制检索结果中附带的表数据新鲜度
+type FreshnessConfig struct {
+	// Columns 为表名到更新时间列的映射，键为 "*" 时对所有包含该列的表生效
+	Columns map[string]string
+	// StaleDays 超过该天数没有写入的表标记为陈旧，不大于0时不标记
+	StaleDays int
+}
+
+// 全局数据新鲜度配置
+var Freshness = FreshnessConfig{StaleDays: 90}
+
+// InitFreshnessConfig 初始化数据新鲜度配置
+func InitFreshnessConfig(cfg FreshnessConfig) {
+	Freshness = cfg
+	freshnessMu.Lock()
+	freshnessCache = make(map[string]freshnessSample)
+	freshnessMu.Unlock()
+}
+
+// ParseFreshnessColumns 解析 "orders.updated_at,*.modified_at" 格式的更新时间列配置
+func ParseFreshnessColumns(value string) (map[string]string, error) {
+	columns := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		table, column, ok := strings.Cut(item, ".")
+		if !ok || table == "" || column == "" {
+			return nil, fmt.Errorf("格式应为 table.column: %s", item)
+		}
+		if table != "*" {
+			if err := ValidateIdentifier(table); err != nil {
+				return nil, err
+			}
+		}
+		if err := ValidateIdentifier(column); err != nil {
+			return nil, err
+		}
+		columns[table] = column
+	}
+	return columns, nil
+}
+
+// TableFreshness 描述一张表最近一次写入的时间
+type TableFreshness struct {
+	Table string
+	// UpdateTime 来自 information_schema.TABLES.UPDATE_TIME，InnoDB 在实例重启后可能为空
+	UpdateTime string
+	UpdateDays sql.NullInt64
+	// Column 为配置的更新时间列，MaxValue 为该列的最大值
+	Column   string
+	MaxValue string
+	MaxDays  sql.NullInt64
+}
+
+// daysSinceWrite 返回距离最近一次写入的天数，两种来源都有时取较近的一个
+func (f TableFreshness) daysSinceWrite() (int64, bool) {
+	switch {
+	case f.UpdateDays.Valid && f.MaxDays.Valid:
+		return min(f.UpdateDays.Int64, f.MaxDays.Int64), true
+	case f.UpdateDays.Valid:
+		return f.UpdateDays.Int64, true
+	case f.MaxDays.Valid:
+		return f.MaxDays.Int64, true
+	}
+	return 0, false
+}
+
+// GetTableFreshness 查询表的最近写入时间。单张表查询失败时只记录日志，不影响其余表
+func GetTableFreshness(ctx context.Context, db *sql.DB, tables []string) []TableFreshness {
+	if db == nil || len(tables) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tables)), ",")
+	args := make([]any, len(tables))
+	for i, t := range tables {
+		args[i] = t
+	}
+
+	result := make(map[string]*TableFreshness, len(tables))
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT TABLE_NAME, COALESCE(UPDATE_TIME, ''), TIMESTAMPDIFF(DAY, UPDATE_TIME, NOW())
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME IN (%s)`, placeholders), args...)
+	if err != nil {
+		Logger.Warnw("查询表更新时间失败", "error", err)
+		return nil
+	}
+	for rows.Next() {
+		f := &TableFreshness{}
+		if err = rows.Scan(&f.Table, &f.UpdateTime, &f.UpdateDays); err != nil {
+			Logger.Warnw("读取表更新时间失败", "error", err)
+			continue
+		}
+		result[f.Table] = f
+	}
+	rows.Close()
+
+	// 读取更新时间列属于业务数据采样，不在采样时间窗口内时只返回 information_schema 中的更新时间
+	// 和之前缓存的结果；每次检索都会调用，结果缓存 freshnessCacheTTL，避免反复对大表计算最大值
+	sampling := LowPriority.samplingAllowed(time.Now())
+	for table, f := range result {
+		sample, ok := cachedFreshnessSample(table)
+		if !ok {
+			if !sampling {
+				continue
+			}
+			if sample, ok = sampleFreshnessColumn(ctx, db, table); !ok {
+				continue
+			}
+		}
+		f.Column, f.MaxValue, f.MaxDays = sample.column, sample.maxValue, sample.maxDays
+	}
+
+	freshness := make([]TableFreshness, 0, len(result))
+	for _, t := range tables {
+		if f, ok := result[t]; ok {
+			freshness = append(freshness, *f)
+		}
+	}
+	return freshness
+}
+
+// freshnessCacheTTL 更新时间列最大值的缓存时间
+const freshnessCacheTTL = 10 * time.Minute
+
+// freshnessSample 为一张表更新时间列的采样结果，column 为空表示该表没有配置更新时间列
+type freshnessSample struct {
+	column   string
+	maxValue string
+	maxDays  sql.NullInt64
+	at       time.Time
+}
+
+var (
+	freshnessMu    sync.Mutex
+	freshnessCache = make(map[string]freshnessSample)
+)
+
+// cachedFreshnessSample 返回未过期的采样结果
+func cachedFreshnessSample(table string) (freshnessSample, bool) {
+	freshnessMu.Lock()
+	defer freshnessMu.Unlock()
+	sample, ok := freshnessCache[table]
+	if !ok || time.Since(sample.at) >= freshnessCacheTTL {
+		return freshnessSample{}, false
+	}
+	return sample, true
+}
+
+// sampleFreshnessColumn 查询表更新时间列的最大值并缓存，查询失败时不缓存
+func sampleFreshnessColumn(ctx context.Context, db *sql.DB, table string) (freshnessSample, bool) {
+	sample := freshnessSample{column: freshnessColumn(ctx, db, table)}
+	if sample.column != "" {
+		var maxValue sql.NullString
+		err := db.QueryRowContext(ctx, LowPriority.sampleStatement(fmt.Sprintf("SELECT MAX(%s), TIMESTAMPDIFF(DAY, MAX(%s), NOW()) FROM %s",
+			quoteIdentifier(sample.column), quoteIdentifier(sample.column), quoteIdentifier(table)))).Scan(&maxValue, &sample.maxDays)
+		if err != nil {
+			Logger.Warnw("查询表更新时间列失败", "table", table, "column", sample.column, "error", err)
+			return freshnessSample{}, false
+		}
+		sample.maxValue = maxValue.String
+	}
+	sample.at = time.Now()
+	freshnessMu.Lock()
+	freshnessCache[table] = sample
+	freshnessMu.Unlock()
+	return sample, true
+}
+
+// freshnessColumn 返回表配置的更新时间列，通配配置只在表中存在该列时生效
+func freshnessColumn(ctx context.Context, db *sql.DB, table string) string {
+	if column, ok := Freshness.Columns[table]; ok {
+		return column
+	}
+	column, ok := Freshness.Columns["*"]
+	if !ok {
+		return ""
+	}
+	var exists int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`, table, column).Scan(&exists)
+	if err != nil || exists == 0 {
+		return ""
+	}
+	return column
+}
+
+// formatTableFreshness 将数据新鲜度格式化为附加在检索结果末尾的文本
+func formatTableFreshness(freshness []TableFreshness) string {
+	if len(freshness) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\n" + Text("table_freshness"))
+	for _, f := range freshness {
+		parts := make([]string, 0, 3)
+		if f.UpdateTime != "" {
+			parts = append(parts, "update_time "+f.UpdateTime)
+		}
+		if f.Column != "" {
+			value := f.MaxValue
+			if value == "" {
+				value = "NULL"
+			}
+			parts = append(parts, fmt.Sprintf("max(%s) %s", f.Column, value))
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "unknown")
+		}
+		days, ok := f.daysSinceWrite()
+		if ok && Freshness.StaleDays > 0 && days >= int64(Freshness.StaleDays) {
+			parts = append(parts, fmt.Sprintf("STALE: no writes in %d days", days))
+		}
+		fmt.Fprintf(&b, "\n- %s: %s", f.Table, strings.Join(parts, ", "))
+	}
+	return b.String()
+}
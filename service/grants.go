@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// checkedPrivileges 汇总时关注的权限，决定代理能否执行相应的语句
+var checkedPrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "DROP", "INDEX", "EXECUTE", "PROCESS"}
+
+// grantPattern 匹配 GRANT <权限> ON <对象> TO 形式的授权语句
+var grantPattern = regexp.MustCompile(`(?is)^GRANT\s+(.+?)\s+ON\s+(\S+)\s+TO\s`)
+
+// GrantReport 描述账号的授权情况
+type GrantReport struct {
+	Account string   `json:"account"`
+	Grants  []string `json:"grants"`
+	// Privileges 是当前库上常用权限的汇总，来自全局授权（*.*）和库级授权，不含表级和列级授权
+	Privileges map[string]bool `json:"privileges_on_current_database"`
+	Database   string          `json:"database"`
+	Note       string          `json:"note,omitempty"`
+}
+
+// ShowGrants 返回账号的授权语句及当前库上的权限汇总。user 为空时查看当前连接的账号，
+// 否则按 name 或 name@host 查看其他账号，需要 mysql 库的 SELECT 权限
+func ShowGrants(ctx context.Context, db *sql.DB, user string) (*GrantReport, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	report := &GrantReport{Grants: make([]string, 0), Privileges: make(map[string]bool)}
+	var database sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT CURRENT_USER(), DATABASE()").Scan(&report.Account, &database); err != nil {
+		return nil, fmt.Errorf("查询当前账号失败: %v", err)
+	}
+	report.Database = database.String
+
+	query := "SHOW GRANTS"
+	if user != "" {
+		account, err := quoteAccount(user)
+		if err != nil {
+			return nil, err
+		}
+		report.Account = account
+		query = "SHOW GRANTS FOR " + account
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		if user != "" {
+			return nil, fmt.Errorf("查看账号 %s 的授权失败（查看其他账号需要 mysql 库的 SELECT 权限）: %v", user, err)
+		}
+		return nil, fmt.Errorf("查看授权失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var grant string
+		if err = rows.Scan(&grant); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		report.Grants = append(report.Grants, grant)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查看授权失败: %v", err)
+	}
+
+	for _, p := range checkedPrivileges {
+		report.Privileges[p] = false
+	}
+	roles := false
+	for _, grant := range report.Grants {
+		m := grantPattern.FindStringSubmatch(grant)
+		if m == nil {
+			// GRANT `role`@`%` TO ... 形式的角色授权
+			roles = true
+			continue
+		}
+		if !grantAppliesToDatabase(m[2], report.Database) {
+			continue
+		}
+		for _, p := range strings.Split(m[1], ",") {
+			p = strings.ToUpper(strings.TrimSpace(p))
+			if p == "ALL" || p == "ALL PRIVILEGES" {
+				for _, c := range checkedPrivileges {
+					report.Privileges[c] = true
+				}
+				continue
+			}
+			if _, ok := report.Privileges[p]; ok {
+				report.Privileges[p] = true
+			}
+		}
+	}
+	if roles {
+		report.Note = Text("grants_roles_note")
+	}
+	return report, nil
+}
+
+// grantAppliesToDatabase 判断授权对象是否覆盖整个库，*.* 和 `db`.* 覆盖，表级授权不算
+func grantAppliesToDatabase(target, database string) bool {
+	if target == "*.*" {
+		return true
+	}
+	schema, object, ok := strings.Cut(target, ".")
+	if !ok || object != "*" {
+		return false
+	}
+	schema = strings.Trim(schema, "`")
+	// 库名中的 _ 和 % 在授权中是通配符，转义后的 \_ 表示字面量
+	if strings.ContainsAny(schema, "%_") && !strings.Contains(schema, `\`) {
+		pattern := "^" + strings.NewReplacer("%", ".*", "_", ".").Replace(regexp.QuoteMeta(schema)) + "$"
+		matched, _ := regexp.MatchString(pattern, database)
+		return matched
+	}
+	return strings.ReplaceAll(schema, `\`, "") == database
+}
+
+// quoteAccount 将 name 或 name@host 转为带引号的账号名，host 缺省为 %
+func quoteAccount(user string) (string, error) {
+	name, host, ok := strings.Cut(user, "@")
+	if !ok {
+		host = "%"
+	}
+	name = strings.Trim(name, "'`\"")
+	host = strings.Trim(host, "'`\"")
+	if name == "" || len(name) > 255 || len(host) > 255 {
+		return "", fmt.Errorf("账号名无效: %s", user)
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return fmt.Sprintf("'%s'@'%s'", escape.Replace(name), escape.Replace(host)), nil
+}
+
+// FormatGrantReport 将授权情况序列化为 JSON
+func FormatGrantReport(report *GrantReport) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal grants to JSON: %v", err)
+	}
+	return string(data), nil
+}
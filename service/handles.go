@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// resultHandleTTL 结果句柄的保留时长
+	resultHandleTTL = 30 * time.Minute
+	// maxResultHandles 同时保留的结果句柄上限，超出时淘汰最早创建的句柄
+	maxResultHandles = 50
+	// maxHandleRows 单个句柄最多保存的行数
+	maxHandleRows = 10000
+	// defaultResultPageSize 分页读取结果句柄时默认的每页行数
+	defaultResultPageSize = 100
+)
+
+// CapturedResult 保存一次查询的完整结果集，供结果句柄复用
+type CapturedResult struct {
+	Columns []string
+	Rows    []map[string]interface{}
+	// Truncated 为 true 时结果集被执行策略或句柄行数上限截断
+	Truncated bool
+}
+
+// capture 记录查询结果，超出句柄行数上限的部分被丢弃
+func (c *CapturedResult) capture(columns []string, rows []map[string]interface{}, truncated bool) {
+	if len(rows) > maxHandleRows {
+		rows, truncated = rows[:maxHandleRows], true
+	}
+	c.Columns, c.Rows, c.Truncated = columns, rows, truncated
+}
+
+// ResultHandle 是以名称保存的查询结果，后续工具可以引用句柄而无需重新执行SQL
+type ResultHandle struct {
+	Name      string    `json:"name"`
+	SQL       string    `json:"sql"`
+	RowCount  int       `json:"row_count"`
+	Truncated bool      `json:"truncated,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	result  *CapturedResult
+	session string
+}
+
+// resultHandles 按 会话/名称 保存结果句柄
+var resultHandles = struct {
+	sync.Mutex
+	m map[string]*ResultHandle
+}{m: map[string]*ResultHandle{}}
+
+// handleKey 结果句柄只在创建它的会话中可见
+func handleKey(session, name string) string {
+	return session + "/" + name
+}
+
+// StoreResultHandle 以名称保存查询结果，同名句柄会被覆盖
+func StoreResultHandle(ctx context.Context, name, query string, result *CapturedResult) error {
+	if err := ValidateIdentifier(name); err != nil {
+		return fmt.Errorf("结果句柄名称不合法: %w", err)
+	}
+	if result == nil || result.Columns == nil {
+		return fmt.Errorf("只有查询语句的结果可以保存为句柄")
+	}
+
+	session := sessionFromContext(ctx)
+	h := &ResultHandle{
+		Name:      name,
+		SQL:       query,
+		RowCount:  len(result.Rows),
+		Truncated: result.Truncated,
+		CreatedAt: time.Now(),
+		result:    result,
+		session:   session,
+	}
+
+	resultHandles.Lock()
+	defer resultHandles.Unlock()
+	pruneResultHandles()
+	resultHandles.m[handleKey(session, name)] = h
+	return nil
+}
+
+// pruneResultHandles 清理过期句柄，并在超出上限时淘汰最早创建的句柄，调用方需持有锁
+func pruneResultHandles() {
+	handles := make([]*ResultHandle, 0, len(resultHandles.m))
+	for key, h := range resultHandles.m {
+		if time.Since(h.CreatedAt) > resultHandleTTL {
+			delete(resultHandles.m, key)
+			continue
+		}
+		handles = append(handles, h)
+	}
+	if len(handles) < maxResultHandles {
+		return
+	}
+	sort.Slice(handles, func(i, j int) bool { return handles[i].CreatedAt.Before(handles[j].CreatedAt) })
+	for _, h := range handles[:len(handles)-maxResultHandles+1] {
+		delete(resultHandles.m, handleKey(h.session, h.Name))
+	}
+}
+
+// GetResultHandle 返回当前会话中指定名称的结果句柄
+func GetResultHandle(ctx context.Context, name string) (*ResultHandle, error) {
+	resultHandles.Lock()
+	defer resultHandles.Unlock()
+	h, ok := resultHandles.m[handleKey(sessionFromContext(ctx), name)]
+	if !ok || time.Since(h.CreatedAt) > resultHandleTTL {
+		return nil, fmt.Errorf("结果句柄不存在或已过期: %s", name)
+	}
+	return h, nil
+}
+
+// Result 返回句柄保存的结果集
+func (h *ResultHandle) Result() *CapturedResult {
+	return h.result
+}
+
+// ReadResultHandle 分页读取结果句柄中的行
+func ReadResultHandle(ctx context.Context, name string, offset, limit int) (string, error) {
+	h, err := GetResultHandle(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = defaultResultPageSize
+	}
+
+	rows := h.result.Rows
+	end := offset + limit
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	page := struct {
+		Handle    *ResultHandle            `json:"handle"`
+		Offset    int                      `json:"offset"`
+		Rows      []map[string]interface{} `json:"rows"`
+		HasMore   bool                     `json:"has_more"`
+		NextStart int                      `json:"next_offset,omitempty"`
+	}{Handle: h, Offset: offset, Rows: rows[offset:end], HasMore: end < len(rows)}
+	if page.HasMore {
+		page.NextStart = end
+	}
+
+	data, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// formatHandleRows 将句柄中的结果集序列化为 JSON
+func formatHandleRows(h *ResultHandle) (string, error) {
+	data, err := json.Marshal(h.result.Rows)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	res := string(data)
+	if h.Truncated {
+		res += "\n\n" + Text("truncated_handle", h.RowCount)
+	}
+	return res, nil
+}
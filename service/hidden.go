@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+var (
+	// hiddenTables 为被隐藏的表名模式（小写，支持 * 和 ? 通配符），对所有数据库生效
+	hiddenTables []string
+	// hiddenSchemas 为被隐藏的数据库名模式
+	hiddenSchemas []string
+)
+
+// InitHiddenObjects 设置对模型完全不可见的表和数据库：不进入向量索引和检索结果，不出现在列表、
+// 描述类工具中，引用它们的语句会被拒绝执行。模式不区分大小写，支持 * 和 ? 通配符，如 audit_*
+func InitHiddenObjects(tables, schemas []string) error {
+	hiddenTables, hiddenSchemas = nil, nil
+	for _, pattern := range tables {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("隐藏表的模式不合法: %s", pattern)
+		}
+		hiddenTables = append(hiddenTables, strings.ToLower(pattern))
+	}
+	for _, pattern := range schemas {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("隐藏数据库的模式不合法: %s", pattern)
+		}
+		hiddenSchemas = append(hiddenSchemas, strings.ToLower(pattern))
+	}
+	if len(hiddenTables) > 0 || len(hiddenSchemas) > 0 {
+		Logger.Infow("隐藏表和数据库已配置", "tables", hiddenTables, "schemas", hiddenSchemas)
+	}
+	return nil
+}
+
+// matchHidden 判断名称是否匹配任一模式
+func matchHidden(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHiddenTable 判断表是否被隐藏
+func IsHiddenTable(table string) bool {
+	return matchHidden(hiddenTables, table)
+}
+
+// IsHiddenSchema 判断数据库是否被隐藏
+func IsHiddenSchema(schema string) bool {
+	return matchHidden(hiddenSchemas, schema)
+}
+
+// filterHiddenTables 去掉被隐藏的表名
+func filterHiddenTables(tables []string) []string {
+	if len(hiddenTables) == 0 {
+		return tables
+	}
+	visible := tables[:0:0]
+	for _, table := range tables {
+		if !IsHiddenTable(table) {
+			visible = append(visible, table)
+		}
+	}
+	return visible
+}
+
+// filterHiddenHits 去掉被隐藏的表的检索结果，配置隐藏之前写入向量索引的表结构也不会返回
+func filterHiddenHits(hits []SchemaHit) []SchemaHit {
+	if len(hiddenTables) == 0 {
+		return hits
+	}
+	visible := hits[:0]
+	for _, hit := range hits {
+		if name, ok := tableNameFromSchema(hit.Schema); ok && IsHiddenTable(name) {
+			continue
+		}
+		visible = append(visible, hit)
+	}
+	return visible
+}
+
+// systemSchemas 为可以查询到所有表名和表结构的系统库，配置了隐藏的表或数据库时不允许访问
+var systemSchemas = []string{"information_schema", "performance_schema", "mysql", "sys"}
+
+// checkHiddenObjects 拒绝引用被隐藏的表或数据库的语句。表名由 tableReferences 解析，另外检查任何 <库名>.<对象> 形式的限定名、
+// USE 和 SHOW 语句中出现的名称。配置了隐藏对象时，information_schema 等系统库中可以查到被隐藏的表，同样不允许访问
+func checkHiddenObjects(sql string) error {
+	if len(hiddenTables) == 0 && len(hiddenSchemas) == 0 {
+		return nil
+	}
+	checkSchema := func(schema string) error {
+		if IsHiddenSchema(schema) {
+			return fmt.Errorf("不允许访问数据库 %s", schema)
+		}
+		if containsFold(systemSchemas, schema) {
+			return fmt.Errorf("配置了隐藏的表或数据库，不允许访问系统库 %s，请使用 list_tables、describe_table 等工具查看表结构", schema)
+		}
+		return nil
+	}
+	checkTable := func(table string) error {
+		if IsHiddenTable(table) {
+			return fmt.Errorf("不允许访问表 %s", table)
+		}
+		return nil
+	}
+
+	for _, ref := range tableReferences(sql) {
+		if ref.schema != "" {
+			if err := checkSchema(ref.schema); err != nil {
+				return err
+			}
+		}
+		if err := checkTable(ref.name); err != nil {
+			return err
+		}
+	}
+
+	tokens := lexSQL(sql)
+	name := func(i int) bool {
+		return i < len(tokens) && (tokens[i].kind == tokenWord || tokens[i].kind == tokenIdent)
+	}
+	punct := func(i int, text string) bool {
+		return i < len(tokens) && tokens[i].kind == tokenPunct && tokens[i].text == text
+	}
+	show := make(map[int]bool)
+	for i, tok := range tokens {
+		if !name(i) {
+			continue
+		}
+		if tok.kind == tokenWord && (i == 0 || punct(i-1, ";")) && tok.text == "show" {
+			show[tok.stmt] = true
+		}
+		// 库名限定的对象，如 secrets.users、information_schema.columns.table_name
+		if punct(i+1, ".") && name(i+2) && (i == 0 || !punct(i-1, ".")) {
+			if err := checkSchema(tok.text); err != nil {
+				return err
+			}
+		}
+		switch {
+		case show[tok.stmt]:
+			// SHOW 语句中出现的任何名称，如 SHOW TABLES FROM secrets、SHOW CREATE TABLE audit_log
+			if err := checkSchema(tok.text); err != nil {
+				return err
+			}
+			if err := checkTable(tok.text); err != nil {
+				return err
+			}
+		case tok.kind == tokenWord && tok.text == "use" && name(i+1):
+			if err := checkSchema(tokens[i+1].text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hiddenRowFilter 返回 SHOW TABLES、SHOW TABLE STATUS、SHOW DATABASES 和 SHOW OPEN TABLES 结果中
+// 需要去掉的行的判断函数，其他语句或未配置隐藏对象时返回 nil
+func hiddenRowFilter(sql string) func(values []any) bool {
+	if len(hiddenTables) == 0 && len(hiddenSchemas) == 0 {
+		return nil
+	}
+	statements := sqlTokens(sql)
+	if len(statements) == 0 || statements[0][0] != "show" {
+		return nil
+	}
+	var words []string
+	for _, w := range statements[0][1:] {
+		if w != "full" && w != "extended" {
+			words = append(words, w)
+		}
+	}
+	column := func(values []any, i int) string {
+		if i >= len(values) {
+			return ""
+		}
+		if b, ok := values[i].([]byte); ok {
+			return string(b)
+		}
+		return fmt.Sprint(values[i])
+	}
+	switch {
+	case len(words) > 0 && words[0] == "tables", len(words) > 1 && words[0] == "table" && words[1] == "status":
+		return func(values []any) bool { return IsHiddenTable(column(values, 0)) }
+	case len(words) > 0 && (words[0] == "databases" || words[0] == "schemas"):
+		return func(values []any) bool { return IsHiddenSchema(column(values, 0)) }
+	case len(words) > 1 && words[0] == "open" && words[1] == "tables":
+		return func(values []any) bool {
+			return IsHiddenSchema(column(values, 0)) || IsHiddenTable(column(values, 1))
+		}
+	}
+	return nil
+}
+
+// referencesHidden 判断语句是否引用了被隐藏的表或数据库，用于从慢查询、审计和 binlog 汇总中去掉这些语句
+func referencesHidden(sql string) bool {
+	if len(hiddenTables) == 0 && len(hiddenSchemas) == 0 {
+		return false
+	}
+	for _, ref := range tableReferences(sql) {
+		if IsHiddenTable(ref.name) || ref.schema != "" && IsHiddenSchema(ref.schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// purgeHiddenTables 从向量索引和 SQLite 登记中删除配置隐藏之前已索引的表，调用方需持有 indexMutex
+func purgeHiddenTables(ctx context.Context, cli *milvusclient.Client) {
+	if len(hiddenTables) == 0 || cli == nil {
+		return
+	}
+	indexed, err := ListIndexedTables()
+	if err != nil {
+		Logger.Warnw("获取已索引的表失败，跳过清理被隐藏的表", "error", err)
+		return
+	}
+	for _, t := range indexed {
+		if !IsHiddenTable(t.TableName) {
+			continue
+		}
+		ids, err := findTableVectorIDs(ctx, cli, t.TableName)
+		if err != nil {
+			Logger.Warnw("查找被隐藏的表的向量失败", "table", t.TableName, "error", err)
+			continue
+		}
+		if len(ids) > 0 {
+			if _, err = cli.Delete(ctx, milvusclient.NewDeleteOption(Config.CollectionName).WithInt64IDs("my_id", ids)); err != nil {
+				Logger.Warnw("删除被隐藏的表的向量失败", "table", t.TableName, "error", err)
+				continue
+			}
+		}
+		if _, err = sqlite().Exec(fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", dbTable), t.TableName); err != nil {
+			Logger.Warnw("删除被隐藏的表的索引元数据失败", "table", t.TableName, "error", err)
+			continue
+		}
+		Logger.Infow("已从索引中删除被隐藏的表", "table", t.TableName, "vectors", len(ids))
+	}
+}
+
+// checkTableVisible 被隐藏的表按不存在处理，不向模型透露其是否存在
+func checkTableVisible(table string) error {
+	if IsHiddenTable(table) {
+		return fmt.Errorf("表不存在: %s", table)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckHiddenObjects(t *testing.T) {
+	if err := InitHiddenObjects([]string{"credentials", "audit_*"}, []string{"secrets"}); err != nil {
+		t.Fatal(err)
+	}
+	defer InitHiddenObjects(nil, nil)
+
+	denied := []string{
+		"SELECT * FROM credentials",
+		"SELECT 1 /*M! UNION SELECT * FROM credentials */",
+		"SELECT * FROM `Credentials` c",
+		"SELECT * FROM a JOIN credentials ON a.id = credentials.id",
+		"SELECT * FROM a STRAIGHT_JOIN credentials",
+		"SELECT * FROM a LEFT OUTER JOIN audit_log l USING (id)",
+		"SELECT * FROM a, b AS x, credentials",
+		"SELECT * FROM (a, credentials)",
+		"SELECT * FROM a USE INDEX (i), credentials",
+		"SELECT * FROM a PARTITION (p0) x, credentials",
+		"SELECT * FROM (SELECT * FROM credentials) t",
+		"SELECT * FROM a WHERE id IN (SELECT id FROM credentials)",
+		"DELETE FROM a USING a, credentials",
+		"UPDATE a, credentials SET a.x = 1",
+		"UPDATE LOW_PRIORITY credentials SET x = 1",
+		"INSERT INTO credentials VALUES (1)",
+		"INSERT credentials VALUES (1)",
+		"INSERT IGNORE credentials SET x = 1",
+		"REPLACE credentials VALUES (1)",
+		"INSERT INTO a SELECT * FROM credentials",
+		"CREATE INDEX i ON credentials (x)",
+		"CREATE UNIQUE INDEX i ON credentials (x)",
+		"DROP INDEX i ON credentials",
+		"CREATE TABLE copy LIKE credentials",
+		"RENAME TABLE credentials TO c2",
+		"RENAME TABLE a TO credentials",
+		"ALTER TABLE a RENAME TO credentials",
+		"HANDLER credentials OPEN",
+		"TRUNCATE credentials",
+		"TRUNCATE TABLE credentials",
+		"DESC credentials",
+		"LOCK TABLES a READ, credentials WRITE",
+		"TABLE credentials",
+		"SELECT * FROM secrets.users",
+		"SELECT secrets.users.id FROM secrets.users",
+		"USE secrets",
+		"SHOW TABLES FROM secrets",
+		"SHOW CREATE TABLE audit_log",
+		"SHOW COLUMNS FROM credentials",
+		"SELECT * FROM information_schema.columns WHERE table_name = 'credentials'",
+		"SELECT * FROM `information_schema`.`TABLES`",
+		"SELECT * FROM performance_schema.table_io_waits_summary_by_table",
+		"SELECT * FROM mysql.innodb_table_stats",
+		"SELECT * FROM sys.schema_table_statistics",
+		"USE information_schema",
+		"SHOW TABLES FROM information_schema",
+		"SELECT 1; SELECT * FROM credentials",
+		"/*!SELECT * FROM credentials*/",
+	}
+	for _, sql := range denied {
+		if err := checkHiddenObjects(sql); err == nil {
+			t.Errorf("checkHiddenObjects(%q) 应拒绝", sql)
+		}
+	}
+
+	allowed := []string{
+		"SELECT * FROM users",
+		"SELECT credentials_count FROM users",
+		"SELECT 'credentials' FROM users",
+		"SELECT * FROM users WHERE note = 'FROM credentials'",
+		"SELECT * FROM a JOIN b USING (credentials)",
+		"INSERT INTO a (x) VALUES (1) ON DUPLICATE KEY UPDATE x = 2",
+		"SELECT * FROM a FOR UPDATE",
+		"SHOW TABLES",
+		"SHOW TABLE STATUS",
+		"SHOW DATABASES",
+		"EXPLAIN SELECT * FROM users",
+		"CREATE INDEX i ON users (x)",
+	}
+	for _, sql := range allowed {
+		if err := checkHiddenObjects(sql); err != nil {
+			t.Errorf("checkHiddenObjects(%q) = %v，应允许", sql, err)
+		}
+	}
+}
+
+func TestCheckHiddenObjectsDisabled(t *testing.T) {
+	if err := checkHiddenObjects("SELECT * FROM information_schema.tables"); err != nil {
+		t.Errorf("未配置隐藏对象时不应限制系统库: %v", err)
+	}
+}
+
+func TestHiddenRowFilter(t *testing.T) {
+	if err := InitHiddenObjects([]string{"credentials"}, []string{"secrets"}); err != nil {
+		t.Fatal(err)
+	}
+	defer InitHiddenObjects(nil, nil)
+
+	tests := []struct {
+		sql    string
+		row    []any
+		hidden bool
+	}{
+		{"SHOW TABLES", []any{[]byte("credentials")}, true},
+		{"SHOW TABLES", []any{[]byte("users")}, false},
+		{"SHOW FULL TABLES", []any{"CREDENTIALS", "BASE TABLE"}, true},
+		{"SHOW TABLE STATUS", []any{[]byte("credentials"), []byte("InnoDB")}, true},
+		{"show databases", []any{[]byte("secrets")}, true},
+		{"SHOW OPEN TABLES", []any{[]byte("app"), []byte("credentials")}, true},
+		{"SHOW OPEN TABLES", []any{[]byte("app"), []byte("users")}, false},
+	}
+	for _, tt := range tests {
+		filter := hiddenRowFilter(tt.sql)
+		if filter == nil {
+			t.Errorf("hiddenRowFilter(%q) = nil", tt.sql)
+			continue
+		}
+		if got := filter(tt.row); got != tt.hidden {
+			t.Errorf("hiddenRowFilter(%q)(%v) = %v, want %v", tt.sql, tt.row, got, tt.hidden)
+		}
+	}
+	if hiddenRowFilter("SELECT * FROM users") != nil {
+		t.Errorf("非 SHOW 语句不应过滤结果")
+	}
+}
+
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT * FROM shop.orders o JOIN users u ON o.uid = u.id", []string{"orders", "users"}},
+		{"SELECT * FROM a, b WHERE a.id = b.id", []string{"a", "b"}},
+		{"SELECT EXTRACT(YEAR FROM NOW())", []string{"now"}},
+		{"INSERT INTO logs (x) VALUES (1) ON DUPLICATE KEY UPDATE x = 2", []string{"logs"}},
+		{"EXPLAIN FORMAT=JSON SELECT * FROM t", []string{"t"}},
+		{"SELECT 1 FROM dual", nil},
+	}
+	for _, tt := range tests {
+		if got := referencedTables(tt.sql); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("referencedTables(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestReferencesHidden(t *testing.T) {
+	if err := InitHiddenObjects([]string{"credentials"}, []string{"secrets"}); err != nil {
+		t.Fatal(err)
+	}
+	defer InitHiddenObjects(nil, nil)
+
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM `credentials` WHERE `id` = ?", true},
+		{"ALTER TABLE credentials ADD COLUMN x INT", true},
+		{"INSERT INTO secrets.t VALUES (?)", true},
+		{"SELECT * FROM information_schema.tables", false},
+		{"UPDATE users SET name = ?", false},
+	}
+	for _, tt := range tests {
+		if got := referencesHidden(tt.sql); got != tt.want {
+			t.Errorf("referencesHidden(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+var historyTable = "query_history"
+var historyLabelTable = "query_history_labels"
+
+// historyEmbeddedTable 记录已经向量化（或正在向量化）的语句哈希，主键保证同一语句只写入一次向量集合
+var historyEmbeddedTable = "query_history_embedded"
+
+// HistoryEntry 表示一条查询历史
+type HistoryEntry struct {
+	ID         int64     `json:"id"`
+	Query      string    `json:"query"`
+	Success    bool      `json:"success"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QueryHit 表示一条相似历史查询
+type QueryHit struct {
+	SQL   string  `json:"sql"`
+	Score float32 `json:"score"`
+}
+
+// createHistoryTable 创建查询历史表
+func createHistoryTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			query TEXT NOT NULL,
+			query_hash TEXT NOT NULL,
+			success INTEGER NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			embedded INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL
+		)`, historyTable))
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_hash ON %s (query_hash)", historyTable, historyTable))
+	if err != nil {
+		return err
+	}
+
+	// 备注与标签让历史记录可以作为可复用的查询笔记本
+	if err = addMissingColumns(db, historyTable, []sqliteColumn{{"note", "TEXT NOT NULL DEFAULT ''"}}); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			history_id INTEGER NOT NULL,
+			label TEXT NOT NULL,
+			PRIMARY KEY (history_id, label)
+		)`, historyLabelTable))
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_label ON %s (label)", historyLabelTable, historyLabelTable))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			query_hash TEXT PRIMARY KEY,
+			created_at INTEGER NOT NULL
+		)`, historyEmbeddedTable))
+	if err != nil {
+		return err
+	}
+	// 之前的版本只在历史记录上标记 embedded，迁移已向量化的语句
+	_, err = db.Exec(fmt.Sprintf(`
+		INSERT OR IGNORE INTO %s (query_hash, created_at)
+		SELECT query_hash, MIN(created_at) FROM %s WHERE embedded = 1 GROUP BY query_hash`, historyEmbeddedTable, historyTable))
+	return err
+}
+
+// normalizeQuery 规范化SQL文本用于去重：合并空白并去掉末尾分号
+func normalizeQuery(query string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(query), " "), ";")
+}
+
+// RecordQueryHistory 记录一次SQL执行，返回历史记录ID
+func RecordQueryHistory(query string, duration time.Duration, execErr error) (int64, error) {
+	if err := InitSQLite(); err != nil {
+		return 0, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	errMsg := ""
+	if execErr != nil {
+		errMsg = execErr.Error()
+	}
+
+	now := time.Now()
+	result, err := sqlite().Exec(fmt.Sprintf(
+		"INSERT INTO %s (query, query_hash, success, duration_ms, error, created_at) VALUES (?, ?, ?, ?, ?, ?)", historyTable),
+		query, hashText(normalizeQuery(query)), execErr == nil, duration.Milliseconds(), errMsg, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("记录查询历史失败: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	exportHistory(HistoryEntry{
+		ID:         id,
+		Query:      query,
+		Success:    execErr == nil,
+		DurationMs: duration.Milliseconds(),
+		Error:      errMsg,
+		CreatedAt:  now,
+	})
+	return id, nil
+}
+
+// EnsureQueryCollection 确保存放历史SQL向量的集合存在
+func EnsureQueryCollection(ctx context.Context, cli *milvusclient.Client) error {
+	has, err := cli.HasCollection(ctx, milvusclient.NewHasCollectionOption(Config.QueryCollectionName))
+	if err != nil {
+		Logger.Errorw("检查集合是否存在失败", "error", err, "collection", Config.QueryCollectionName)
+		return err
+	}
+	if has {
+		return nil
+	}
+
+	schema := entity.NewSchema().
+		WithField(entity.NewField().WithName("my_id").WithDataType(entity.FieldTypeInt64).WithIsPrimaryKey(true).WithIsAutoID(true)).
+		WithField(entity.NewField().WithName("vector").WithDim(dim).WithDataType(entity.FieldTypeFloatVector)).
+		WithField(entity.NewField().WithName("sql").WithDataType(entity.FieldTypeVarChar).WithMaxLength(10240))
+
+	return createAndLoadCollection(ctx, cli, Config.QueryCollectionName, schema)
+}
+
+// IndexQueryHistory 将执行成功的查询语句向量化，供相似查询检索使用。
+// 只处理查询类语句；写入向量集合前先以 INSERT OR IGNORE 占用语句哈希，并发执行相同语句时只有一个会写入，
+// 向量化失败时释放占用，之后再次执行可以重试
+func IndexQueryHistory(ctx context.Context, cli *milvusclient.Client, id int64, query string) error {
+	// 只读消费者不写入共享的历史查询集合
+	if !isQueryStatement(query) || !IsIndexWriter() {
+		return nil
+	}
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	normalized := normalizeQuery(query)
+	hash := hashText(normalized)
+	res, err := sqlite().Exec(fmt.Sprintf(
+		"INSERT OR IGNORE INTO %s (query_hash, created_at) VALUES (?, ?)", historyEmbeddedTable), hash, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("查询历史向量化状态失败: %v", err)
+	}
+	if claimed, err := res.RowsAffected(); err != nil || claimed == 0 {
+		return err
+	}
+	release := func() {
+		if _, err := sqlite().Exec(fmt.Sprintf("DELETE FROM %s WHERE query_hash = ?", historyEmbeddedTable), hash); err != nil {
+			Logger.Warnw("释放查询历史向量化状态失败", "error", err)
+		}
+	}
+
+	vectors, err := EmbedQuery(normalized)
+	if err != nil {
+		release()
+		return fmt.Errorf("向量嵌入失败: %w", err)
+	}
+
+	_, err = cli.Insert(ctx, milvusclient.NewColumnBasedInsertOption(Config.QueryCollectionName).
+		WithVarcharColumn("sql", []string{normalized}).
+		WithFloatVectorColumn("vector", dim, [][]float32{vectors}),
+	)
+	if err != nil {
+		release()
+		return fmt.Errorf("保存查询向量失败: %w", err)
+	}
+
+	if _, err = sqlite().Exec(fmt.Sprintf("UPDATE %s SET embedded = 1 WHERE id = ?", historyTable), id); err != nil {
+		return fmt.Errorf("更新查询历史向量化状态失败: %v", err)
+	}
+	return nil
+}
+
+// FindSimilarQueries 根据自然语言描述检索相似的历史成功查询
+func FindSimilarQueries(ctx context.Context, cli *milvusclient.Client, description string, limit int) (string, error) {
+	vectors, err := EmbedQuery(description)
+	if err != nil {
+		return "", fmt.Errorf("向量嵌入失败: %w", err)
+	}
+
+	var resultSets []milvusclient.ResultSet
+	err = withMilvusRetry(ctx, "Search", func(ctx context.Context) (err error) {
+		resultSets, err = cli.Search(ctx, milvusclient.NewSearchOption(
+			Config.QueryCollectionName,
+			limit,
+			[]entity.Vector{entity.FloatVector(vectors)},
+		).WithOutputFields("sql").WithConsistencyLevel(MilvusCall.ConsistencyLevel))
+		return err
+	})
+	if err != nil {
+		Logger.Errorw("检索相似查询失败", "error", err)
+		return "", fmt.Errorf("检索相似查询失败: %w", err)
+	}
+
+	hits := make([]QueryHit, 0, limit)
+	for _, resultSet := range resultSets {
+		sqlColumn := resultSet.GetColumn("sql")
+		if sqlColumn == nil {
+			continue
+		}
+		for i := 0; i < sqlColumn.Len(); i++ {
+			query, err := sqlColumn.GetAsString(i)
+			if err != nil {
+				return "", err
+			}
+			hit := QueryHit{SQL: query}
+			if i < len(resultSet.Scores) {
+				hit.Score = resultSet.Scores[i]
+			}
+			hits = append(hits, hit)
+		}
+	}
+
+	data, err := json.MarshalIndent(hits, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(data), nil
+}
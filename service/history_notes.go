@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHistorySearchLimit = 20
+	maxHistorySearchLimit     = 200
+)
+
+// AnnotateQueryHistory 为一条查询历史添加备注和标签。note 为空时保留原有备注，标签会追加到已有标签中
+func AnnotateQueryHistory(id int64, note string, labels []string) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	tx, err := sqlite().Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err = tx.QueryRow(fmt.Sprintf("SELECT COUNT(1) FROM %s WHERE id = ?", historyTable), id).Scan(&exists); err != nil {
+		return fmt.Errorf("查询历史记录失败: %v", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("查询历史不存在: %d", id)
+	}
+
+	if note != "" {
+		if _, err = tx.Exec(fmt.Sprintf("UPDATE %s SET note = ? WHERE id = ?", historyTable), note, id); err != nil {
+			return fmt.Errorf("更新备注失败: %v", err)
+		}
+	}
+	for _, label := range labels {
+		if label = strings.TrimSpace(label); label == "" {
+			continue
+		}
+		if _, err = tx.Exec(fmt.Sprintf("INSERT OR IGNORE INTO %s (history_id, label) VALUES (?, ?)", historyLabelTable),
+			id, label); err != nil {
+			return fmt.Errorf("添加标签失败: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveHistoryLabels 删除查询历史上的指定标签
+func RemoveHistoryLabels(id int64, labels []string) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	for _, label := range labels {
+		if _, err := sqlite().Exec(fmt.Sprintf("DELETE FROM %s WHERE history_id = ? AND label = ?", historyLabelTable),
+			id, strings.TrimSpace(label)); err != nil {
+			return fmt.Errorf("删除标签失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// SearchQueryHistory 按标签和文本检索查询历史，text 同时匹配 SQL 和备注，结果按时间倒序
+func SearchQueryHistory(label, text string, limit int) (string, error) {
+	entries, err := ListQueryHistory(label, text, limit)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal history to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// ListQueryHistory 按标签和文本检索查询历史，返回按时间倒序排列的记录
+func ListQueryHistory(label, text string, limit int) ([]HistoryEntry, error) {
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	if limit <= 0 {
+		limit = defaultHistorySearchLimit
+	}
+	if limit > maxHistorySearchLimit {
+		limit = maxHistorySearchLimit
+	}
+
+	var conditions []string
+	var args []any
+	if label != "" {
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT history_id FROM %s WHERE label = ?)", historyLabelTable))
+		args = append(args, label)
+	}
+	if text != "" {
+		conditions = append(conditions, "(query LIKE ? ESCAPE '\\' OR note LIKE ? ESCAPE '\\')")
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text) + "%"
+		args = append(args, pattern, pattern)
+	}
+	query := fmt.Sprintf("SELECT id, query, success, duration_ms, error, note, created_at FROM %s", historyTable)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := sqlite().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询历史失败: %v", err)
+	}
+	entries := make([]HistoryEntry, 0)
+	for rows.Next() {
+		var (
+			e         HistoryEntry
+			createdAt int64
+		)
+		if err = rows.Scan(&e.ID, &e.Query, &e.Success, &e.DurationMs, &e.Error, &e.Note, &createdAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("扫描查询历史失败: %v", err)
+		}
+		e.CreatedAt = time.Unix(createdAt, 0)
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询历史失败: %v", err)
+	}
+
+	for i := range entries {
+		if entries[i].Labels, err = historyLabels(entries[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// historyLabels 返回一条查询历史的所有标签
+func historyLabels(id int64) ([]string, error) {
+	rows, err := sqlite().Query(fmt.Sprintf("SELECT label FROM %s WHERE history_id = ? ORDER BY label", historyLabelTable), id)
+	if err != nil {
+		return nil, fmt.Errorf("查询标签失败: %v", err)
+	}
+	defer rows.Close()
+
+	var labels []string
+	for rows.Next() {
+		var label string
+		if err = rows.Scan(&label); err != nil {
+			return nil, fmt.Errorf("扫描标签失败: %v", err)
+		}
+		labels = append(labels, label)
+	}
+	return labels, rows.Err()
+}
+
+// GetQueryHistory 按ID获取一条查询历史
+func GetQueryHistory(id int64) (*HistoryEntry, error) {
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	var (
+		e         HistoryEntry
+		createdAt int64
+	)
+	err := sqlite().QueryRow(fmt.Sprintf(
+		"SELECT id, query, success, duration_ms, error, note, created_at FROM %s WHERE id = ?", historyTable), id).
+		Scan(&e.ID, &e.Query, &e.Success, &e.DurationMs, &e.Error, &e.Note, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("查询历史不存在: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询历史失败: %v", err)
+	}
+	e.CreatedAt = time.Unix(createdAt, 0)
+	if e.Labels, err = historyLabels(id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// RerunQueryHistory 重新执行一条历史查询。只允许重跑查询语句，模板严格模式下不允许执行任意SQL
+func RerunQueryHistory(ctx context.Context, db *sql.DB, id int64) (*HistoryEntry, string, error) {
+	if Templates.Strict {
+		return nil, "", fmt.Errorf("模板严格模式下不能重新执行历史SQL")
+	}
+	entry, err := GetQueryHistory(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !isQueryStatement(entry.Query) {
+		return entry, "", fmt.Errorf("历史记录 %d 不是查询语句，不能重新执行", id)
+	}
+	res, err := ExecuteWithOptions(ctx, db, entry.Query, ExecOptions{})
+	return entry, res, err
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hotTablesCacheTTL 表使用统计的缓存时间，检索排序每次都会用到，不必每次扫描查询历史
+	hotTablesCacheTTL = time.Minute
+	// defaultHotTablesLimit hot_tables 默认返回的表数
+	defaultHotTablesLimit = 20
+)
+
+// HotTableConfig 控制按查询历史统计的热点表
+type HotTableConfig struct {
+	// Window 为统计的时间范围
+	Window time.Duration
+	// Boost 为表结构检索中最热的表得到的最大加分比例，如 0.1 表示分数最多提高 10%，0 关闭
+	Boost float64
+	// SlowMs 为平均耗时达到该值（毫秒）的表列入索引优化的优先列表
+	SlowMs int64
+}
+
+// HotTableSettings 为全局的热点表配置
+var HotTableSettings = HotTableConfig{Window: 7 * 24 * time.Hour, Boost: 0.1, SlowMs: 1000}
+
+// InitHotTables 设置热点表的统计范围和在检索排序中的权重
+func InitHotTables(cfg HotTableConfig) {
+	if cfg.Window <= 0 {
+		cfg.Window = 7 * 24 * time.Hour
+	}
+	HotTableSettings = cfg
+	hotMu.Lock()
+	hotCache = nil
+	hotMu.Unlock()
+}
+
+// HotTable 为一张表在统计范围内的查询次数和耗时
+type HotTable struct {
+	Table   string `json:"table"`
+	Queries int64  `json:"queries"`
+	Failed  int64  `json:"failed,omitempty"`
+	AvgMs   int64  `json:"avg_ms"`
+	MaxMs   int64  `json:"max_ms"`
+	// TotalMs 为引用该表的语句的总耗时，一条语句引用多张表时每张表都计入
+	TotalMs     int64     `json:"total_ms"`
+	LastQueried time.Time `json:"last_queried"`
+}
+
+// HotTablesReport 为 hot_tables 的结果
+type HotTablesReport struct {
+	WindowDays float64    `json:"window_days"`
+	OrderBy    string     `json:"order_by"`
+	Tables     []HotTable `json:"tables"`
+	// IndexReview 为平均耗时达到 SlowMs 的表，按总耗时降序，是优先检查索引的候选
+	IndexReview []string `json:"index_review"`
+}
+
+var (
+	hotMu       sync.Mutex
+	hotCache    map[string]*HotTable
+	hotCachedAt time.Time
+)
+
+// TableUsage 从查询历史统计 HotTableSettings.Window 内每张表被查询的次数和耗时，结果缓存 hotTablesCacheTTL
+func TableUsage() (map[string]*HotTable, error) {
+	hotMu.Lock()
+	defer hotMu.Unlock()
+	if hotCache != nil && time.Since(hotCachedAt) < hotTablesCacheTTL {
+		return hotCache, nil
+	}
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	// 相同的语句只解析一次表名
+	rows, err := sqlite().Query(fmt.Sprintf(`
+		SELECT MIN(query), COUNT(*), SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END),
+			SUM(duration_ms), MAX(duration_ms), MAX(created_at)
+		FROM %s WHERE created_at >= ? GROUP BY query_hash`, historyTable),
+		time.Now().Add(-HotTableSettings.Window).Unix())
+	if err != nil {
+		return nil, fmt.Errorf("查询历史失败: %v", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]*HotTable)
+	for rows.Next() {
+		var (
+			query                       string
+			count, failed, total, maxMs int64
+			last                        int64
+		)
+		if err = rows.Scan(&query, &count, &failed, &total, &maxMs, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		for _, table := range referencedTables(query) {
+			if IsHiddenTable(table) {
+				continue
+			}
+			t, ok := usage[table]
+			if !ok {
+				t = &HotTable{Table: table}
+				usage[table] = t
+			}
+			t.Queries += count
+			t.Failed += failed
+			t.TotalMs += total
+			t.MaxMs = max(t.MaxMs, maxMs)
+			if at := time.Unix(last, 0); at.After(t.LastQueried) {
+				t.LastQueried = at
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询历史失败: %v", err)
+	}
+	for _, t := range usage {
+		t.AvgMs = t.TotalMs / t.Queries
+	}
+	hotCache, hotCachedAt = usage, time.Now()
+	return usage, nil
+}
+
+// GetHotTables 返回查询最多的表。orderBy 为 count（查询次数，默认）、total（总耗时）或 avg（平均耗时）
+func GetHotTables(orderBy string, limit int) (*HotTablesReport, error) {
+	if orderBy == "" {
+		orderBy = "count"
+	}
+	if orderBy != "count" && orderBy != "total" && orderBy != "avg" {
+		return nil, fmt.Errorf("不支持的排序方式: %s，可选 count、total、avg", orderBy)
+	}
+	if limit <= 0 {
+		limit = defaultHotTablesLimit
+	}
+	usage, err := TableUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]HotTable, 0, len(usage))
+	for _, t := range usage {
+		tables = append(tables, *t)
+	}
+	metric := func(t HotTable) int64 {
+		switch orderBy {
+		case "total":
+			return t.TotalMs
+		case "avg":
+			return t.AvgMs
+		}
+		return t.Queries
+	}
+	sortHotTables(tables, metric)
+
+	report := &HotTablesReport{
+		WindowDays:  HotTableSettings.Window.Hours() / 24,
+		OrderBy:     orderBy,
+		IndexReview: indexReviewTables(tables),
+	}
+	report.Tables = tables[:min(limit, len(tables))]
+	return report, nil
+}
+
+// sortHotTables 按 metric 降序排序，相同时按表名
+func sortHotTables(tables []HotTable, metric func(HotTable) int64) {
+	sort.Slice(tables, func(i, j int) bool {
+		if mi, mj := metric(tables[i]), metric(tables[j]); mi != mj {
+			return mi > mj
+		}
+		return tables[i].Table < tables[j].Table
+	})
+}
+
+// indexReviewTables 返回平均耗时达到 SlowMs 的表，按总耗时降序：查询频繁且慢的表优先检查索引
+func indexReviewTables(tables []HotTable) []string {
+	slow := make([]HotTable, 0)
+	for _, t := range tables {
+		if HotTableSettings.SlowMs > 0 && t.AvgMs >= HotTableSettings.SlowMs {
+			slow = append(slow, t)
+		}
+	}
+	sortHotTables(slow, func(t HotTable) int64 { return t.TotalMs })
+	names := make([]string, len(slow))
+	for i, t := range slow {
+		names[i] = t.Table
+	}
+	return names
+}
+
+// FormatHotTables 将热点表统计序列化为 JSON
+func FormatHotTables(report *HotTablesReport) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal hot tables to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// hotTableBoosts 返回表结构检索中各表的分数系数：按查询次数的对数相对最热的表计算，
+// 最热的表为 1+Boost，没有查询记录的表不调整
+func hotTableBoosts() map[string]float64 {
+	if HotTableSettings.Boost <= 0 {
+		return nil
+	}
+	usage, err := TableUsage()
+	if err != nil {
+		Logger.Warnw("统计热点表失败，检索排序不使用查询频率", "error", err)
+		return nil
+	}
+	var most int64
+	for _, t := range usage {
+		most = max(most, t.Queries)
+	}
+	if most == 0 {
+		return nil
+	}
+	boosts := make(map[string]float64, len(usage))
+	for name, t := range usage {
+		boosts[name] = 1 + HotTableSettings.Boost*math.Log1p(float64(t.Queries))/math.Log1p(float64(most))
+	}
+	return boosts
+}
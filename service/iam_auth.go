@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	IAMAuthAWS = "aws"
+	IAMAuthGCP = "gcp"
+
+	// rdsTokenTTL RDS IAM 认证令牌的有效期
+	rdsTokenTTL = 15 * time.Minute
+	// iamTokenRefreshMargin 令牌在过期前多久刷新，保证建立连接时令牌仍然有效
+	iamTokenRefreshMargin = 5 * time.Minute
+	// gcpMetadataTokenURL GCE/GKE/Cloud Run 元数据服务的访问令牌地址
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// IAMTokenSource 生成用于数据库登录的 IAM 令牌及其过期时间
+type IAMTokenSource interface {
+	Token(ctx context.Context) (string, time.Time, error)
+}
+
+// NewIAMTokenSource 按认证方式创建令牌来源。aws 使用 RDS IAM 认证令牌，凭据来自 AWS SDK 的默认凭据链
+// （环境变量、共享配置文件、Web Identity、ECS 任务角色和 EC2 实例角色）；gcp 从元数据服务获取
+// 服务账号的访问令牌，用于 Cloud SQL IAM 数据库登录
+func NewIAMTokenSource(provider, region, host, port, user string) (IAMTokenSource, error) {
+	switch strings.ToLower(provider) {
+	case IAMAuthAWS:
+		if region == "" {
+			return nil, fmt.Errorf("RDS IAM 认证需要指定区域")
+		}
+		if port == "" {
+			port = "3306"
+		}
+		return &rdsTokenSource{Region: region, Endpoint: host + ":" + port, User: user}, nil
+	case IAMAuthGCP:
+		return &gcpTokenSource{URL: gcpMetadataTokenURL}, nil
+	default:
+		return nil, fmt.Errorf("不支持的IAM认证方式: %s（支持 aws、gcp）", provider)
+	}
+}
+
+// IAMBeforeConnect 返回 MySQL 驱动建立每个连接前调用的钩子，用当前有效的令牌作为密码，
+// 令牌在过期前自动刷新，连接池中的新连接不会使用过期的令牌
+func IAMBeforeConnect(source IAMTokenSource) mysql.Option {
+	cache := &cachedIAMToken{source: source}
+	return mysql.BeforeConnect(func(ctx context.Context, cfg *mysql.Config) error {
+		token, err := cache.get(ctx)
+		if err != nil {
+			return fmt.Errorf("获取IAM认证令牌失败: %v", err)
+		}
+		cfg.Passwd = token
+		return nil
+	})
+}
+
+// cachedIAMToken 缓存令牌直到接近过期
+type cachedIAMToken struct {
+	source    IAMTokenSource
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (c *cachedIAMToken) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.expiresAt) > iamTokenRefreshMargin {
+		return c.token, nil
+	}
+	token, expiresAt, err := c.source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = token, expiresAt
+	Logger.Infow("IAM认证令牌已刷新", "expires_at", expiresAt)
+	return token, nil
+}
+
+// rdsTokenSource 使用 SigV4 预签名生成 RDS IAM 认证令牌，凭据来自 AWS SDK 的默认凭据链
+type rdsTokenSource struct {
+	Region   string
+	Endpoint string
+	User     string
+
+	// credentials 在首次生成令牌时加载，SDK 会缓存并在临时凭据过期前刷新；调用方 cachedIAMToken 已串行化
+	credentials aws.CredentialsProvider
+}
+
+// emptyPayloadHash 为空请求体的 SHA-256，预签名的 GET 请求没有请求体
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Token 生成 RDS IAM 认证令牌，令牌本身是对 connect 操作的预签名请求，有效期15分钟。
+// 凭据按默认凭据链查找：环境变量、共享配置文件（AWS_PROFILE、SSO）、Web Identity（EKS IRSA）、ECS 任务角色和 EC2 实例角色
+func (s *rdsTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	if s.credentials == nil {
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(s.Region))
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("加载 AWS 配置失败: %v", err)
+		}
+		s.credentials = cfg.Credentials
+	}
+	creds, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("获取 AWS 凭据失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+s.Endpoint+"/", nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("创建预签名请求失败: %v", err)
+	}
+	query := req.URL.Query()
+	query.Set("Action", "connect")
+	query.Set("DBUser", s.User)
+	query.Set("X-Amz-Expires", fmt.Sprint(int(rdsTokenTTL.Seconds())))
+	req.URL.RawQuery = query.Encode()
+
+	now := time.Now().UTC()
+	signed, _, err := v4.NewSigner().PresignHTTP(ctx, creds, req, emptyPayloadHash, "rds-db", s.Region, now)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("生成 RDS IAM 认证令牌失败: %v", err)
+	}
+
+	// 临时凭据先于令牌过期时，令牌随之失效
+	expiresAt := now.Add(rdsTokenTTL)
+	if creds.CanExpire && creds.Expires.Before(expiresAt) {
+		expiresAt = creds.Expires
+	}
+	return strings.TrimPrefix(signed, "https://"), expiresAt, nil
+}
+
+// gcpTokenSource 从 GCP 元数据服务获取服务账号的 OAuth2 访问令牌
+type gcpTokenSource struct {
+	URL string
+}
+
+// Token 获取访问令牌，Cloud SQL IAM 数据库登录以该令牌作为密码
+func (s *gcpTokenSource) Token(ctx context.Context) (string, time.Time, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("请求元数据服务失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("读取响应失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("元数据服务返回状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &token); err != nil {
+		return "", time.Time{}, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("元数据服务没有返回访问令牌")
+	}
+	return token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn) * time.Second), nil
+}
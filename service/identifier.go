@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxIdentifierLength MySQL 表名、列名的最大长度（字符数）
+const maxIdentifierLength = 64
+
+// quoteIdentifier 使用反引号引用标识符，并转义其中的反引号。
+// 引用后的标识符可以包含空格、关键字和任意 Unicode 字符，拼接进 SQL 是安全的
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// ValidateIdentifier 校验来自工具参数的表名、列名是否是合法的 MySQL 标识符，
+// 在拼接 SQL 之前尽早给出明确的错误，而不是等到执行时报出语法错误
+func ValidateIdentifier(name string) error {
+	if name == "" {
+		return fmt.Errorf("标识符不能为空")
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("标识符不是有效的 UTF-8 字符串: %q", name)
+	}
+	if n := utf8.RuneCountInString(name); n > maxIdentifierLength {
+		return fmt.Errorf("标识符长度为 %d，超过 MySQL 的上限 %d: %s", n, maxIdentifierLength, name)
+	}
+	if strings.HasSuffix(name, " ") {
+		return fmt.Errorf("标识符不能以空格结尾: %q", name)
+	}
+	for _, r := range name {
+		// MySQL 标识符只允许 U+0001 到 U+FFFF 范围内的字符
+		if r == 0 || r > 0xFFFF {
+			return fmt.Errorf("标识符包含不允许的字符 %U: %q", r, name)
+		}
+	}
+	return nil
+}
+
+// validateIdentifiers 依次校验多个标识符
+func validateIdentifiers(names ...string) error {
+	for _, name := range names {
+		if err := ValidateIdentifier(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+// 索引状态
+const (
+	IndexStatusFresh      = "fresh"       // 索引与当前表结构一致
+	IndexStatusStale      = "stale"       // 表结构已变化，索引过期
+	IndexStatusNotIndexed = "not_indexed" // 表存在但尚未索引
+	IndexStatusDropped    = "dropped"     // 表已不存在但仍在索引中
+)
+
+// autoIncrementPattern 匹配随写入不断变化的 AUTO_INCREMENT 计数器
+var autoIncrementPattern = regexp.MustCompile(`\s*AUTO_INCREMENT=\d+`)
+
+// SchemaHash 计算表结构的哈希，忽略 AUTO_INCREMENT 计数器以免每次写入都被判定为过期
+func SchemaHash(schema string) string {
+	return hashText(autoIncrementPattern.ReplaceAllString(schema, ""))
+}
+
+// hashText 计算文本的 sha256 十六进制摘要
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// IndexTableSchema 将一张表的结构向量化写入 Milvus，并在 SQLite 中记录索引元数据
+func IndexTableSchema(ctx context.Context, cli *milvusclient.Client, tableName, schema string) error {
+	vectors, err := embedSchema(schema)
+	if err != nil {
+		return fmt.Errorf("向量嵌入失败: %w", err)
+	}
+
+	ids, err := SaveToVDB(ctx, cli, []string{schema}, []SchemaVectors{vectors})
+	if err != nil {
+		return fmt.Errorf("保存向量失败: %w", err)
+	}
+
+	var vectorID int64
+	if len(ids) > 0 {
+		vectorID = ids[0]
+	}
+	return RecordIndexedTable(tableName, SchemaHash(schema), vectorID)
+}
+
+// IndexStatus 表示一张表在检索层中的索引状态
+type IndexStatus struct {
+	TableName   string    `json:"table_name"`
+	SchemaHash  string    `json:"schema_hash"`
+	CurrentHash string    `json:"current_hash"`
+	VectorID    int64     `json:"vector_id"`
+	EmbeddedAt  time.Time `json:"embedded_at"`
+	Status      string    `json:"status"`
+}
+
+// GetIndexStatus 对比 SQLite 中的索引元数据与数据库当前的表结构，得到每张表的索引状态
+func GetIndexStatus(ctx context.Context, db *sql.DB) ([]IndexStatus, error) {
+	indexed, err := ListIndexedTables()
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取当前所有表结构的哈希
+	currentHashes := make(map[string]string)
+	schemaChan := make(chan map[string]string, 10)
+	go streamTableSchemas(ctx, db, schemaChan, schemaBudget)
+	for tableMap := range schemaChan {
+		for tableName, schema := range tableMap {
+			currentHashes[tableName] = SchemaHash(schema)
+			schemaBudget.release(int64(len(schema)))
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]IndexStatus, 0, len(indexed)+len(currentHashes))
+	seen := make(map[string]bool, len(indexed))
+	for _, t := range indexed {
+		// 配置隐藏之前已索引的表在下一次定时更新时删除，此前也不列出
+		if IsHiddenTable(t.TableName) {
+			continue
+		}
+		seen[t.TableName] = true
+		status := IndexStatus{
+			TableName:   t.TableName,
+			SchemaHash:  t.SchemaHash,
+			CurrentHash: currentHashes[t.TableName],
+			VectorID:    t.VectorID,
+			EmbeddedAt:  t.EmbeddedAt,
+		}
+		switch current, ok := currentHashes[t.TableName]; {
+		case !ok:
+			status.Status = IndexStatusDropped
+		case current != t.SchemaHash:
+			status.Status = IndexStatusStale
+		default:
+			status.Status = IndexStatusFresh
+		}
+		statuses = append(statuses, status)
+	}
+
+	for tableName, hash := range currentHashes {
+		if seen[tableName] {
+			continue
+		}
+		statuses = append(statuses, IndexStatus{
+			TableName:   tableName,
+			CurrentHash: hash,
+			Status:      IndexStatusNotIndexed,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].TableName < statuses[j].TableName
+	})
+	return statuses, nil
+}
+
+// ExportIndexStatus 将索引状态导出为 json 或 csv 文本，不包含被隐藏的表
+func ExportIndexStatus(statuses []IndexStatus, format string) (string, error) {
+	visible := statuses[:0:0]
+	for _, s := range statuses {
+		if !IsHiddenTable(s.TableName) {
+			visible = append(visible, s)
+		}
+	}
+	statuses = visible
+	switch format {
+	case "", "json":
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal index status to JSON: %v", err)
+		}
+		return string(data), nil
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"table_name", "schema_hash", "current_hash", "vector_id", "embedded_at", "status"})
+		for _, s := range statuses {
+			embeddedAt := ""
+			if !s.EmbeddedAt.IsZero() {
+				embeddedAt = s.EmbeddedAt.Format(time.RFC3339)
+			}
+			_ = w.Write([]string{s.TableName, s.SchemaHash, s.CurrentHash,
+				strconv.FormatInt(s.VectorID, 10), embeddedAt, s.Status})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return "", fmt.Errorf("failed to write CSV: %v", err)
+		}
+		return buf.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// FormatIndexedTables 将已索引的表列表格式化为文本，filter 非空时只保留表名包含该子串的表，被隐藏的表不列出
+func FormatIndexedTables(tables []IndexedTable, filter string) string {
+	filter = strings.ToLower(filter)
+	var b strings.Builder
+	count := 0
+	for _, t := range tables {
+		if IsHiddenTable(t.TableName) || filter != "" && !strings.Contains(strings.ToLower(t.TableName), filter) {
+			continue
+		}
+		embeddedAt := Text("index_embedded_unknown")
+		if !t.EmbeddedAt.IsZero() {
+			embeddedAt = t.EmbeddedAt.Format(time.RFC3339)
+		}
+		b.WriteString(Text("index_table", t.TableName, embeddedAt) + "\n")
+		count++
+	}
+
+	if count == 0 {
+		if filter != "" {
+			return Text("index_no_match", filter)
+		}
+		return Text("index_empty")
+	}
+	return Text("index_tables", count, b.String())
+}
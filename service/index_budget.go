@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// IndexBudgetConfig 限制索引流水线的内存占用，避免为数千张表建索引时缓存过多表结构导致进程 OOM
+type IndexBudgetConfig struct {
+	// MaxBytes 为流水线中尚未处理完的表结构（排队中及正在向量化的）总字节数上限，
+	// 达到上限时读取表结构的一方阻塞等待，而不是继续缓存
+	MaxBytes int64
+	// Workers 为全量重建时并发向量化的协程数
+	Workers int
+}
+
+// 默认最多缓存 64MB 表结构，5 个并发向量化协程
+const (
+	defaultIndexBudgetBytes = 64 << 20
+	defaultIndexWorkers     = 5
+)
+
+var (
+	// 全局索引内存配置
+	IndexBudget = IndexBudgetConfig{MaxBytes: defaultIndexBudgetBytes, Workers: defaultIndexWorkers}
+	// schemaBudget 为索引流水线共用的内存预算
+	schemaBudget = newMemoryBudget(defaultIndexBudgetBytes)
+)
+
+// InitIndexBudget 初始化索引内存配置
+func InitIndexBudget(cfg IndexBudgetConfig) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultIndexBudgetBytes
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultIndexWorkers
+	}
+	IndexBudget = cfg
+	schemaBudget = newMemoryBudget(cfg.MaxBytes)
+}
+
+// memoryBudget 按字节计数的信号量
+type memoryBudget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	freed chan struct{}
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{limit: limit, freed: make(chan struct{})}
+}
+
+// clamp 超过上限的单个表结构按上限计算，保证它可以在流水线空闲时单独处理
+func (b *memoryBudget) clamp(n int64) int64 {
+	if n > b.limit {
+		return b.limit
+	}
+	return n
+}
+
+// acquire 占用 n 字节预算，预算不足时阻塞直到其他表结构处理完或上下文取消
+func (b *memoryBudget) acquire(ctx context.Context, n int64) error {
+	if b == nil {
+		return nil
+	}
+	n = b.clamp(n)
+	for {
+		b.mu.Lock()
+		if b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		freed := b.freed
+		b.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release 归还 n 字节预算并唤醒等待者
+func (b *memoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.used -= b.clamp(n)
+	close(b.freed)
+	b.freed = make(chan struct{})
+	b.mu.Unlock()
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// killOnTimeout 为 true 时，语句因客户端超时被取消后会在服务端执行 KILL QUERY，
+// 否则 MySQL 会继续执行已经没有人等待结果的语句
+var killOnTimeout bool
+
+// InitKillConfig 设置是否在超时后终止服务端的语句
+func InitKillConfig(enabled bool) {
+	killOnTimeout = enabled
+}
+
+// Connection 表示 processlist 中的一个连接
+type Connection struct {
+	ID      int64  `json:"id"`
+	User    string `json:"user"`
+	Host    string `json:"host"`
+	DB      string `json:"db,omitempty"`
+	Command string `json:"command"`
+	TimeSec int64  `json:"time_sec"`
+	State   string `json:"state,omitempty"`
+	Info    string `json:"info,omitempty"`
+}
+
+// ListConnections 列出本服务使用的账号下除空闲连接和本连接之外的活动连接，按持续时间降序
+func ListConnections(ctx context.Context, db *sql.DB, minSeconds int) ([]Connection, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	if err := requireDirectConnection("processlist"); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT ID, USER, HOST, COALESCE(DB, ''), COMMAND, TIME, COALESCE(STATE, ''), COALESCE(INFO, '')
+		FROM information_schema.PROCESSLIST
+		WHERE COMMAND <> 'Sleep' AND ID <> CONNECTION_ID() AND USER = SUBSTRING_INDEX(USER(), '@', 1) AND TIME >= ?
+		ORDER BY TIME DESC LIMIT ?`, minSeconds, runningQueryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("查询 processlist 失败: %v", err)
+	}
+	defer rows.Close()
+
+	connections := make([]Connection, 0)
+	for rows.Next() {
+		var c Connection
+		if err = rows.Scan(&c.ID, &c.User, &c.Host, &c.DB, &c.Command, &c.TimeSec, &c.State, &c.Info); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		connections = append(connections, c)
+	}
+	return connections, rows.Err()
+}
+
+// KillQuery 终止指定连接上正在执行的语句（KILL QUERY），连接本身保留。只能终止本服务使用的同一账号的连接，
+// 即便账号有 CONNECTION_ADMIN 权限也不会终止其他应用的语句。
+// connectionID 为0时只列出活动连接（持续时间不少于 minSeconds 秒），供调用方选择
+func KillQuery(ctx context.Context, db *sql.DB, connectionID int64, minSeconds int) (string, error) {
+	if connectionID <= 0 {
+		connections, err := ListConnections(ctx, db, minSeconds)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.MarshalIndent(map[string]any{"connections": connections}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+		}
+		return string(data), nil
+	}
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if err := requireDirectConnection("KILL QUERY"); err != nil {
+		return "", err
+	}
+
+	var (
+		c    Connection
+		self string
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT ID, USER, HOST, COALESCE(DB, ''), COMMAND, TIME, COALESCE(STATE, ''), COALESCE(INFO, ''),
+			SUBSTRING_INDEX(USER(), '@', 1)
+		FROM information_schema.PROCESSLIST WHERE ID = ?`, connectionID).
+		Scan(&c.ID, &c.User, &c.Host, &c.DB, &c.Command, &c.TimeSec, &c.State, &c.Info, &self)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("连接 %d 不存在或已结束", connectionID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询 processlist 失败: %v", err)
+	}
+	if c.User != self {
+		return "", fmt.Errorf("连接 %d 属于账号 %s，只能终止本服务使用的账号 %s 的语句", connectionID, c.User, self)
+	}
+	if c.Info == "" {
+		return "", fmt.Errorf("连接 %d 当前没有正在执行的语句", connectionID)
+	}
+
+	// KILL 不支持占位符，connectionID 为整数可以直接拼接
+	stmt := fmt.Sprintf("KILL QUERY %d", connectionID)
+	start := time.Now()
+	_, err = db.ExecContext(ctx, labelStatement(ctx, stmt))
+	recordAudit(ctx, stmt, 0, time.Since(start), err)
+	if err != nil {
+		return "", fmt.Errorf("终止语句失败: %v", err)
+	}
+	Logger.Infow("已终止语句", "connection", connectionID, "user", c.User, "time_sec", c.TimeSec, "sql", c.Info)
+
+	data, err := json.MarshalIndent(map[string]any{"killed": c}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// connectionID 返回会话的连接ID，未开启超时终止时不查询
+func connectionID(ctx context.Context, conn *sql.Conn) int64 {
+	// 经过代理时 CONNECTION_ID() 是后端连接的ID，可能已被其他客户端复用
+	if !killOnTimeout || currentProxy() != ProxyNone {
+		return 0
+	}
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		Logger.Warnw("获取连接ID失败", "error", err)
+		return 0
+	}
+	return id
+}
+
+// killTimedOut 在语句因超时被取消后终止服务端仍在执行的语句
+func killTimedOut(ctx context.Context, db *sql.DB, id int64) {
+	if id == 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+	// 原上下文已超时，使用独立的上下文
+	killCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stmt := fmt.Sprintf("KILL QUERY %d", id)
+	start := time.Now()
+	_, err := db.ExecContext(killCtx, stmt)
+	recordAudit(ctx, stmt, 0, time.Since(start), err)
+	if err != nil {
+		Logger.Warnw("终止超时语句失败", "connection", id, "error", err)
+		return
+	}
+	Logger.Infow("已终止超时语句", "connection", id)
+}
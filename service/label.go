@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"strings"
+)
+
+// defaultLabelTemplate 默认的语句注释模板，{session} 和 {tool} 会被替换
+const defaultLabelTemplate = "mcp-mysql session={session} tool={tool}"
+
+// LabelConfig 控制是否在执行的语句前注入标识注释
+type LabelConfig struct {
+	Enabled  bool
+	Template string
+}
+
+// 全局语句标识配置
+var Label = LabelConfig{Enabled: true, Template: defaultLabelTemplate}
+
+// InitLabelConfig 初始化语句标识配置，模板为空时使用默认模板
+func InitLabelConfig(enabled bool, template string) {
+	if template == "" {
+		template = defaultLabelTemplate
+	}
+	Label = LabelConfig{
+		Enabled:  enabled,
+		Template: template,
+	}
+}
+
+// statementLabelKey 是上下文中保存语句标识的键
+type statementLabelKey struct{}
+
+// statementLabel 描述语句的来源
+type statementLabel struct {
+	Session string
+	Tool    string
+}
+
+// WithStatementLabel 在上下文中记录发起语句的会话和工具，执行时会注入到语句注释中
+func WithStatementLabel(ctx context.Context, session, tool string) context.Context {
+	return context.WithValue(ctx, statementLabelKey{}, statementLabel{Session: session, Tool: tool})
+}
+
+// labelStatement 在语句前加上形如 /* mcp-mysql session=<id> tool=<tool> */ 的注释，
+// 便于 DBA 在 processlist 和慢日志中识别来源
+func labelStatement(ctx context.Context, sql string) string {
+	if !Label.Enabled {
+		return sql
+	}
+	label, _ := ctx.Value(statementLabelKey{}).(statementLabel)
+
+	comment := strings.NewReplacer(
+		"{session}", sanitizeComment(label.Session),
+		"{tool}", sanitizeComment(label.Tool),
+	).Replace(Label.Template)
+	return "/* " + sanitizeComment(comment) + " */ " + sql
+}
+
+// sanitizeComment 去掉可能提前结束注释或改变语句含义的字符。* 和 / 之间插入空格而不是删除，
+// 删除可能让两侧的字符拼出新的 */（如 **//），插入空格不会，因此结果再处理一次保持不变
+func sanitizeComment(s string) string {
+	s = strings.ReplaceAll(s, "*/", "* /")
+	s = strings.ReplaceAll(s, "/*", "/ *")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == 0 {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// sessionFromContext 返回上下文中记录的会话ID
+func sessionFromContext(ctx context.Context) string {
+	label, _ := ctx.Value(statementLabelKey{}).(statementLabel)
+	return label.Session
+}
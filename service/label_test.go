@@ -0,0 +1,18 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeComment(t *testing.T) {
+	for _, s := range []string{"*/", "/*", "**//", "/*/", "*/*/", "a*//*b", "x\n*/ DROP TABLE t; /*"} {
+		got := sanitizeComment(s)
+		if strings.Contains(got, "*/") || strings.Contains(got, "/*") || strings.ContainsAny(got, "\r\n") {
+			t.Errorf("sanitizeComment(%q) = %q", s, got)
+		}
+		if again := sanitizeComment(got); again != got {
+			t.Errorf("sanitizeComment is not idempotent for %q: %q then %q", s, got, again)
+		}
+	}
+}
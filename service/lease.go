@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotIndexWriter 表示当前实例没有持有索引租约，不能修改共享的向量集合
+var ErrNotIndexWriter = errors.New("当前实例未持有索引租约，向量集合为只读")
+
+// leaseCheckInterval 检查及尝试获取租约的间隔
+const leaseCheckInterval = 30 * time.Second
+
+// IndexLease 让多个实例安全地共享同一个向量集合：只有持有租约的实例会写入集合，
+// 其余实例作为只读消费者。租约基于 MySQL 咨询锁，持有者在一个固定的连接上长期持有锁，
+// 进程退出或连接断开时锁自动释放，其他实例在下一次检查时接管
+type IndexLease struct {
+	InstanceID string
+	LockName   string
+
+	mu   sync.Mutex
+	conn *sql.Conn
+	held atomic.Bool
+}
+
+// 当前实例的索引租约，为 nil 时不启用租约，实例总是可以写入
+var indexLease *IndexLease
+
+// DefaultInstanceID 返回默认的实例标识：主机名-进程号
+func DefaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// StartIndexLease 启用索引租约：立即尝试获取一次，之后在后台定期检查租约状态并在空闲时接管。
+// lockName 超过 MySQL 锁名长度上限时按 advisoryLockName 缩短
+func StartIndexLease(db *sql.DB, instanceID, lockName string) *IndexLease {
+	lease := &IndexLease{InstanceID: instanceID, LockName: advisoryLockName(lockName)}
+	indexLease = lease
+
+	lease.refresh(db)
+	go func() {
+		ticker := time.NewTicker(leaseCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			lease.refresh(db)
+		}
+	}()
+	return lease
+}
+
+// refresh 持有租约时确认锁仍然有效，未持有时尝试获取
+func (l *IndexLease) refresh(db *sql.DB) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if l.conn != nil {
+		var owned sql.NullInt64
+		err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", l.LockName).Scan(&owned)
+		if err == nil && owned.Valid && owned.Int64 == 1 {
+			return
+		}
+		Logger.Warnw("索引租约已失效", "instance", l.InstanceID, "lock", l.LockName, "error", err)
+		l.release()
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		Logger.Warnw("获取租约连接失败", "error", err)
+		return
+	}
+	var acquired sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.LockName).Scan(&acquired); err != nil {
+		Logger.Warnw("获取索引租约失败", "lock", l.LockName, "error", err)
+		conn.Close()
+		return
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return
+	}
+
+	l.conn = conn
+	l.held.Store(true)
+	Logger.Infow("已获得索引租约，本实例负责更新向量集合", "instance", l.InstanceID, "lock", l.LockName)
+}
+
+// release 释放租约连接，调用方需持有 l.mu
+func (l *IndexLease) release() {
+	l.held.Store(false)
+	if l.conn != nil {
+		// 关闭连接会释放连接上持有的咨询锁
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+// Close 主动释放租约，供进程退出时调用
+func (l *IndexLease) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.release()
+}
+
+// IsIndexWriter 判断当前实例是否可以写入共享的向量集合
+func IsIndexWriter() bool {
+	return indexLease == nil || indexLease.held.Load()
+}
+
+// requireIndexWriter 在修改向量集合前调用，非租约持有者返回 ErrNotIndexWriter
+func requireIndexWriter() error {
+	if IsIndexWriter() {
+		return nil
+	}
+	return fmt.Errorf("%w (instance %s)", ErrNotIndexWriter, indexLease.InstanceID)
+}
+
+// IndexLeaseStatus 返回租约状态的简要说明
+func IndexLeaseStatus() string {
+	if indexLease == nil {
+		return "Index lease disabled; this instance writes to the vector collection."
+	}
+	if indexLease.held.Load() {
+		return fmt.Sprintf("Instance %s holds the index lease (%s) and maintains the vector collection.",
+			indexLease.InstanceID, indexLease.LockName)
+	}
+	return fmt.Sprintf("Instance %s is a read-only consumer; another instance holds the index lease (%s).",
+		indexLease.InstanceID, indexLease.LockName)
+}
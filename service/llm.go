@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// LLMConfig 存储对话模型（OpenAI 兼容接口）的相关配置
+type LLMConfig struct {
+	URL   string
+	Token string
+	Model string
+}
+
+// 全局LLM配置
+var LLM LLMConfig
+
+// InitLLMConfig 初始化LLM配置
+func InitLLMConfig(url, token, model string) {
+	LLM = LLMConfig{
+		URL:   url,
+		Token: token,
+		Model: model,
+	}
+}
+
+// LLMEnabled 判断是否配置了LLM端点
+func LLMEnabled() bool {
+	return LLM.URL != "" && LLM.Token != "" && LLM.Model != ""
+}
+
+// ChatMessage 表示一条对话消息
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest 表示对话请求的结构
+type ChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+// ChatResponse 表示对话响应的结构
+type ChatResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// ChatComplete 调用配置的LLM端点完成一次对话
+func ChatComplete(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if !LLMEnabled() {
+		return "", fmt.Errorf("LLM配置不完整")
+	}
+
+	requestBody := ChatRequest{
+		Model: LLM.Model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
+		Temperature: 0,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("JSON 序列化失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", LLM.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", LLM.Token))
+	req.Header.Add("Content-Type", "application/json")
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("请求失败，状态码: %d, 响应: %s", res.StatusCode, body)
+	}
+
+	var response ChatResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("响应中没有数据")
+	}
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
+
+// DetectLanguage 粗略判断文本语言：汉字多于拉丁字母时认为是中文，否则为英文
+func DetectLanguage(text string) string {
+	var han, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Latin):
+			latin++
+		}
+	}
+	if han > 0 && han*2 >= latin {
+		return "zh"
+	}
+	return "en"
+}
+
+// TranslateQuery 将查询翻译为另一种语言（中文<->英文），返回译文及其语言
+func TranslateQuery(ctx context.Context, query string) (string, string, error) {
+	target, targetName := "en", "English"
+	if DetectLanguage(query) == "en" {
+		target, targetName = "zh", "Simplified Chinese"
+	}
+
+	systemPrompt := fmt.Sprintf("Translate the user's database search request into %s. "+
+		"Keep table names, column names and other identifiers unchanged. Reply with the translation only.", targetName)
+	translated, err := ChatComplete(ctx, systemPrompt, query)
+	if err != nil {
+		return "", "", fmt.Errorf("翻译查询失败: %w", err)
+	}
+	return translated, target, nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// loadDataSeq 用于生成唯一的 Reader 名称
+var loadDataSeq atomic.Int64
+
+// LoadDataFile 通过受控路径执行 LOAD DATA LOCAL INFILE。
+// 文件必须位于 allowedDir 目录下且首行为表头，表头会与目标表的列逐一校验；
+// 文件只在本次调用期间以 Reader 的形式注册给驱动，不会全局开放本地文件读取。
+func LoadDataFile(ctx context.Context, db *sql.DB, allowedDir, filePath, table, delimiter string) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if allowedDir == "" {
+		return "", fmt.Errorf("未配置 LOAD_DATA_DIR，批量导入功能未启用")
+	}
+	if err := activePolicy().checkStatement(ctx, "LOAD DATA"); err != nil {
+		return "", err
+	}
+	if err := ValidateIdentifier(table); err != nil {
+		return "", err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return "", err
+	}
+	if delimiter == "" {
+		delimiter = ","
+	}
+	if len([]rune(delimiter)) != 1 {
+		return "", fmt.Errorf("分隔符必须是单个字符: %q", delimiter)
+	}
+
+	path, err := resolveStagedPath(allowedDir, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	// 打开文件后整个导入过程都使用同一个文件句柄，保证校验过的内容就是导入的内容
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+
+	header, lineTerminator, err := readHeader(f, []rune(delimiter)[0])
+	if err != nil {
+		return "", err
+	}
+
+	if err = validateIdentifiers(header...); err != nil {
+		return "", fmt.Errorf("表头中的列名无效: %w", err)
+	}
+	columns, err := getTableColumns(ctx, db, table)
+	if err != nil {
+		return "", err
+	}
+	if err = validateHeader(header, columns); err != nil {
+		return "", err
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("重置文件读取位置失败: %v", err)
+	}
+
+	readerName := fmt.Sprintf("mcp_load_%d_%d", time.Now().UnixNano(), loadDataSeq.Add(1))
+	mysql.RegisterReaderHandler(readerName, func() io.Reader {
+		return f
+	})
+	defer mysql.DeregisterReaderHandler(readerName)
+
+	quotedColumns := make([]string, len(header))
+	for i, col := range header {
+		quotedColumns[i] = quoteIdentifier(col)
+	}
+
+	stmt := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s "+
+		"FIELDS TERMINATED BY '%s' OPTIONALLY ENCLOSED BY '\"' LINES TERMINATED BY '%s' IGNORE 1 LINES (%s)",
+		readerName, quoteIdentifier(table), escapeStringLiteral(delimiter),
+		escapeStringLiteral(lineTerminator), strings.Join(quotedColumns, ","))
+
+	start := time.Now()
+	result, err := db.ExecContext(ctx, labelStatement(ctx, stmt))
+	if err != nil {
+		recordAudit(ctx, stmt, 0, time.Since(start), err)
+		return "", fmt.Errorf("LOAD DATA 执行失败: %v", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	recordAudit(ctx, stmt, rowsAffected, time.Since(start), nil)
+	Logger.Infow("批量导入完成", "file", path, "table", table, "rowsAffected", rowsAffected)
+	return fmt.Sprintf("Loaded %s into %s. Rows affected: %d", filepath.Base(path), table, rowsAffected), nil
+}
+
+// resolveStagedPath 将文件路径解析为 allowedDir 下不含符号链接的绝对路径，拒绝目录穿越；
+// 目录和文件都先解析符号链接再比较，目录中指向外部的符号链接同样被拒绝
+func resolveStagedPath(allowedDir, filePath string) (string, error) {
+	baseDir, err := filepath.Abs(allowedDir)
+	if err == nil {
+		baseDir, err = filepath.EvalSymlinks(baseDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("解析 LOAD_DATA_DIR 失败: %v", err)
+	}
+
+	path := filePath
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	path, err = filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("读取文件信息失败: %v", err)
+	}
+
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("文件必须位于 LOAD_DATA_DIR(%s) 目录下: %s", baseDir, filePath)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("读取文件信息失败: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("不是普通文件: %s", filePath)
+	}
+	return path, nil
+}
+
+// readHeader 读取文件首行作为表头，并识别换行符类型
+func readHeader(r io.Reader, delimiter rune) ([]string, string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("读取表头失败: %v", err)
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil, "", fmt.Errorf("文件为空或缺少表头")
+	}
+
+	lineTerminator := "\n"
+	if strings.HasSuffix(line, "\r\n") {
+		lineTerminator = "\r\n"
+	}
+
+	csvReader := csv.NewReader(strings.NewReader(strings.TrimRight(line, "\r\n")))
+	csvReader.Comma = delimiter
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("解析表头失败: %v", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	return header, lineTerminator, nil
+}
+
+// validateHeader 校验表头中的每一列都存在于目标表中且不重复
+func validateHeader(header, columns []string) error {
+	known := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		known[strings.ToLower(col)] = true
+	}
+
+	seen := make(map[string]bool, len(header))
+	var unknown []string
+	for _, col := range header {
+		key := strings.ToLower(col)
+		if seen[key] {
+			return fmt.Errorf("表头中存在重复列: %s", col)
+		}
+		seen[key] = true
+		if !known[key] {
+			unknown = append(unknown, col)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("表头中的列在目标表中不存在: %s（可用列: %s）",
+			strings.Join(unknown, ", "), strings.Join(columns, ", "))
+	}
+	return nil
+}
+
+// escapeStringLiteral 转义单引号字符串字面量中的特殊字符
+func escapeStringLiteral(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return replacer.Replace(s)
+}
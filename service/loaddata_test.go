@@ -0,0 +1,61 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveStagedPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "staged")
+	outside := filepath.Join(root, "outside.csv")
+	for _, d := range []string{dir, filepath.Join(dir, "sub")} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{outside, filepath.Join(dir, "a.csv"), filepath.Join(dir, "sub", "b.csv"), filepath.Join(dir, "..c.csv")} {
+		if err := os.WriteFile(f, []byte("id\n1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(dir, "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "a.csv"), filepath.Join(dir, "alias.csv")); err != nil {
+		t.Fatal(err)
+	}
+	linkedDir := filepath.Join(root, "linked")
+	if err := os.Symlink(dir, linkedDir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir, file string
+		ok        bool
+	}{
+		{dir, "a.csv", true},
+		{dir, "sub/b.csv", true},
+		{dir, "..c.csv", true},
+		{dir, filepath.Join(dir, "a.csv"), true},
+		{dir, "alias.csv", true},
+		{linkedDir, "a.csv", true},
+		{dir, "../outside.csv", false},
+		{dir, outside, false},
+		{dir, "escape.csv", false},
+		{dir, "up/outside.csv", false},
+		{dir, "sub", false},
+		{dir, ".", false},
+		{dir, "missing.csv", false},
+	}
+	for _, tt := range tests {
+		_, err := resolveStagedPath(tt.dir, tt.file)
+		if (err == nil) != tt.ok {
+			t.Errorf("resolveStagedPath(%q, %q) error = %v, want ok=%v", tt.dir, tt.file, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LowPriorityConfig 控制为丰富表结构信息而读取业务数据的采样语句如何降低对生产流量的影响
+type LowPriorityConfig struct {
+	// MaxExecutionTime 大于0时为采样语句加上 MAX_EXECUTION_TIME 提示，服务端超时后自动终止语句
+	MaxExecutionTime time.Duration
+	// OffPeak 非空时只在该时间窗口（如 01:00-06:00，按本地时间，可跨零点）内执行采样语句
+	OffPeak string
+
+	offPeakStart, offPeakEnd time.Duration
+}
+
+// 全局采样语句配置，默认不限制
+var LowPriority LowPriorityConfig
+
+// InitLowPriority 初始化采样语句配置，时间窗口格式错误时返回错误
+func InitLowPriority(cfg LowPriorityConfig) error {
+	if cfg.OffPeak != "" {
+		start, end, ok := strings.Cut(cfg.OffPeak, "-")
+		if !ok {
+			return fmt.Errorf("时间窗口格式应为 HH:MM-HH:MM: %s", cfg.OffPeak)
+		}
+		var err error
+		if cfg.offPeakStart, err = parseClock(start); err != nil {
+			return err
+		}
+		if cfg.offPeakEnd, err = parseClock(end); err != nil {
+			return err
+		}
+	}
+	LowPriority = cfg
+	return nil
+}
+
+// parseClock 解析 HH:MM 为距零点的时长
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// samplingAllowed 判断当前是否允许执行采样语句，未配置时间窗口时总是允许
+func (c LowPriorityConfig) samplingAllowed(now time.Time) bool {
+	if c.OffPeak == "" {
+		return true
+	}
+	clock := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if c.offPeakStart <= c.offPeakEnd {
+		return clock >= c.offPeakStart && clock < c.offPeakEnd
+	}
+	// 跨零点的窗口，如 22:00-06:00
+	return clock >= c.offPeakStart || clock < c.offPeakEnd
+}
+
+// sampleStatement 为采样用的 SELECT 语句加上 MAX_EXECUTION_TIME 提示，服务端不支持该提示时原样返回
+func (c LowPriorityConfig) sampleStatement(sql string) string {
+	if c.MaxExecutionTime <= 0 || requireFeature(featureMaxExecutionTime) != nil {
+		return sql
+	}
+	trimmed := strings.TrimSpace(sql)
+	if len(trimmed) < 6 || !strings.EqualFold(trimmed[:6], "select") {
+		return sql
+	}
+	return fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */%s", c.MaxExecutionTime.Milliseconds(), trimmed[6:])
+}
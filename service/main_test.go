@@ -0,0 +1,13 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	Logger = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// 脱敏方式
+const (
+	maskRedact = "redact" // 替换为 ***
+	maskHash   = "hash"   // 替换为加盐的 SHA-256 摘要，相同取值的摘要相同，仍可用于分组和关联
+)
+
+// MaskRule 为一条列脱敏规则，表名和列名支持 path.Match 通配符，如 users.email、*.password
+type MaskRule struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Mode   string `json:"mode"`
+}
+
+// MaskingConfig 为列脱敏配置
+type MaskingConfig struct {
+	Rules []MaskRule
+	// HashSalt 为 hash 方式的盐，避免通过常见取值的摘要反查原值
+	HashSalt string
+}
+
+// 全局列脱敏配置
+var Masking MaskingConfig
+
+// InitMasking 初始化列脱敏配置
+func InitMasking(cfg MaskingConfig) {
+	Masking = cfg
+	if len(cfg.Rules) > 0 {
+		Logger.Infow("列脱敏已开启", "rules", cfg.Rules)
+	}
+}
+
+// ParseMaskRules 解析 "users.email:hash,*.password" 格式的脱敏规则，未指定方式时为 redact
+func ParseMaskRules(value string) ([]MaskRule, error) {
+	var rules []MaskRule
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		target, mode, _ := strings.Cut(item, ":")
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if mode == "" {
+			mode = maskRedact
+		}
+		if mode != maskRedact && mode != maskHash {
+			return nil, fmt.Errorf("脱敏方式应为 redact 或 hash: %s", item)
+		}
+		table, column, ok := strings.Cut(strings.TrimSpace(target), ".")
+		if !ok || table == "" || column == "" {
+			return nil, fmt.Errorf("格式应为 table.column[:redact|hash]: %s", item)
+		}
+		for _, pattern := range []string{table, column} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("通配符格式错误: %s", item)
+			}
+		}
+		rules = append(rules, MaskRule{Table: strings.ToLower(table), Column: strings.ToLower(column), Mode: mode})
+	}
+	return rules, nil
+}
+
+// resultMasker 记录结果集中每一列的脱敏方式，空字符串表示不脱敏
+type resultMasker struct {
+	modes []string
+}
+
+// newResultMasker 根据语句和结果列名确定需要脱敏的列。结果集不带来源表，
+// 规则的表名与语句中出现的任意表名匹配即生效；列名除了直接匹配，还会检查表达式列（如 LOWER(email)）
+// 和别名（如 email AS e）引用的列，别名按派生表、CTE 中各层 SELECT 列表逐层解析，避免通过改写列名绕过脱敏。
+// UNION 或派生表、CTE 的列名列表按位置改名，无法对应到来源列，此时只要语句引用了需要脱敏的列，所有结果列都脱敏
+func newResultMasker(sql string, columns []string, policy ConnectionPolicy) resultMasker {
+	m := resultMasker{modes: make([]string, len(columns))}
+	if len(Masking.Rules) == 0 && len(policy.MaskedColumns) == 0 {
+		return m
+	}
+
+	tokens := lexSQL(sql)
+	names := make(map[string]bool)
+	for _, t := range tokens {
+		if t.kind == tokenWord || t.kind == tokenIdent {
+			names[strings.ToLower(t.text)] = true
+		}
+	}
+	mode := func(source string) string {
+		if policy.masked(source) {
+			return maskRedact
+		}
+		return matchMaskRule(source, names)
+	}
+
+	if positionalColumns(tokens) {
+		fallback := ""
+		for _, t := range tokens {
+			if t.kind != tokenWord && t.kind != tokenIdent {
+				continue
+			}
+			if md := mode(t.text); md != "" && (fallback == "" || md == maskRedact) {
+				fallback = md
+			}
+		}
+		if fallback != "" {
+			for i := range m.modes {
+				m.modes[i] = fallback
+			}
+			return m
+		}
+	}
+
+	aliases := selectAliases(tokens)
+	for i, column := range columns {
+		for _, source := range columnSources(column, aliases) {
+			md := mode(source)
+			if md == "" {
+				continue
+			}
+			m.modes[i] = md
+			// 同一列匹配多个来源时 redact 优先
+			if md == maskRedact {
+				break
+			}
+		}
+	}
+	return m
+}
+
+// matchMaskRule 返回列匹配的脱敏方式，规则的表名需要出现在语句中
+func matchMaskRule(column string, names map[string]bool) string {
+	column = strings.ToLower(column)
+	mode := ""
+	for _, rule := range Masking.Rules {
+		if ok, _ := path.Match(rule.Column, column); !ok {
+			continue
+		}
+		if !maskTableReferenced(rule.Table, names) {
+			continue
+		}
+		// 同一列匹配多条规则时 redact 优先
+		if mode == "" || rule.Mode == maskRedact {
+			mode = rule.Mode
+		}
+	}
+	return mode
+}
+
+// maskTableReferenced 判断规则的表名是否出现在语句中
+func maskTableReferenced(pattern string, names map[string]bool) bool {
+	if pattern == "*" {
+		return true
+	}
+	for name := range names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// columnSources 返回结果列可能来自的列名：列名本身、表达式列中引用的标识符，以及别名对应表达式中的标识符；
+// 别名引用的标识符本身也可能是内层 SELECT 的别名，逐层展开直到不再出现新的名称
+func columnSources(column string, aliases map[string][]string) []string {
+	sources := []string{column}
+	seen := map[string]bool{strings.ToLower(column): true}
+	add := func(name string) {
+		if key := strings.ToLower(name); !seen[key] {
+			seen[key] = true
+			sources = append(sources, name)
+		}
+	}
+	for _, t := range lexSQL(column) {
+		if t.kind == tokenWord || t.kind == tokenIdent {
+			add(t.text)
+		}
+	}
+	for i := 0; i < len(sources); i++ {
+		for _, ref := range aliases[strings.ToLower(sources[i])] {
+			add(ref)
+		}
+	}
+	return sources
+}
+
+// selectListEnd 为 SELECT 列表之后可能出现的子句关键字
+var selectListEnd = map[string]bool{
+	"from": true, "into": true, "where": true, "group": true, "having": true, "window": true, "order": true,
+	"limit": true, "for": true, "lock": true, "union": true, "intersect": true, "except": true,
+}
+
+// selectAliases 解析语句中所有 SELECT 列表（包括派生表、CTE 和子查询中的）的别名，
+// 返回别名（小写）到表达式中标识符的映射；不同层级的同名别名合并
+func selectAliases(tokens []sqlToken) map[string][]string {
+	aliases := make(map[string][]string)
+	addItem := func(item []sqlToken) {
+		n := len(item)
+		if n < 2 {
+			return
+		}
+		last, prev := item[n-1], item[n-2]
+		if last.depth != item[0].depth || prev.kind == tokenPunct && prev.text == "." ||
+			last.kind != tokenWord && last.kind != tokenIdent && last.kind != tokenString {
+			return
+		}
+		var refs []string
+		for _, t := range item[:n-1] {
+			if t.kind == tokenWord || t.kind == tokenIdent {
+				refs = append(refs, t.text)
+			}
+		}
+		alias := strings.ToLower(last.text)
+		aliases[alias] = append(aliases[alias], refs...)
+	}
+
+	for i, t := range tokens {
+		if t.kind != tokenWord || t.text != "select" {
+			continue
+		}
+		var item []sqlToken
+		for _, u := range tokens[i+1:] {
+			// 括号中的 SELECT 在括号闭合（层数小于 SELECT 所在层）时结束
+			if u.stmt != t.stmt || u.depth < t.depth || u.depth == t.depth && u.kind == tokenWord && selectListEnd[u.text] {
+				break
+			}
+			if u.depth == t.depth && u.kind == tokenPunct && u.text == "," {
+				addItem(item)
+				item = nil
+				continue
+			}
+			item = append(item, u)
+		}
+		addItem(item)
+	}
+	return aliases
+}
+
+// positionalColumns 判断结果列是否可能按位置改名：UNION、INTERSECT、EXCEPT 取第一个查询的列名，
+// WITH c(a, b) AS (...) 和 (SELECT ...) AS t(a, b) 用列名列表替换内层的列名
+func positionalColumns(tokens []sqlToken) bool {
+	isName := func(t sqlToken) bool { return t.kind == tokenWord || t.kind == tokenIdent }
+	for i, t := range tokens {
+		if t.kind == tokenWord && (t.text == "union" || t.text == "intersect" || t.text == "except") {
+			return true
+		}
+		if t.kind != tokenPunct || t.text != "(" || i == 0 || !isName(tokens[i-1]) {
+			continue
+		}
+		// 括号中只有名称和逗号，即列名列表
+		j := i + 1
+		for j < len(tokens) && (isName(tokens[j]) || tokens[j].text == ",") {
+			j++
+		}
+		if j == i+1 || j >= len(tokens) || tokens[j].text != ")" {
+			continue
+		}
+		// CTE 的列名列表之后是 AS，派生表的列名列表紧跟在 ) 或 ) AS 之后的别名后面
+		if j+1 < len(tokens) && tokens[j+1].kind == tokenWord && tokens[j+1].text == "as" {
+			return true
+		}
+		if before := i - 2; before >= 0 && (tokens[before].text == ")" ||
+			tokens[before].kind == tokenWord && tokens[before].text == "as" && before > 0 && tokens[before-1].text == ")") {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 返回第 i 列脱敏后的取值，hash 方式下 NULL 保持不变
+func (m resultMasker) apply(i int, value any) any {
+	switch m.modes[i] {
+	case maskRedact:
+		return maskedValue
+	case maskHash:
+		if value == nil {
+			return nil
+		}
+		var text string
+		if b, ok := value.([]byte); ok {
+			text = string(b)
+		} else {
+			text = fmt.Sprint(value)
+		}
+		sum := sha256.Sum256([]byte(Masking.HashSalt + text))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return value
+}
+
+// masked 判断第 i 列是否需要脱敏
+func (m resultMasker) masked(i int) bool {
+	return m.modes[i] != ""
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewResultMasker(t *testing.T) {
+	defer func(saved MaskingConfig) { Masking = saved }(Masking)
+	Masking = MaskingConfig{Rules: []MaskRule{
+		{Table: "users", Column: "email", Mode: maskRedact},
+		{Table: "users", Column: "phone", Mode: maskHash},
+	}}
+
+	tests := []struct {
+		name    string
+		sql     string
+		columns []string
+		want    []string
+	}{
+		{"直接引用", "SELECT id, email FROM users", []string{"id", "email"}, []string{"", maskRedact}},
+		{"表达式列", "SELECT LOWER(email) FROM users", []string{"LOWER(email)"}, []string{maskRedact}},
+		{"外层别名", "SELECT email AS e, phone p FROM users", []string{"e", "p"}, []string{maskRedact, maskHash}},
+		{"派生表别名", "SELECT e FROM (SELECT email AS e FROM users) t", []string{"e"}, []string{maskRedact}},
+		{"派生表星号", "SELECT * FROM (SELECT id, email AS x FROM users) t", []string{"id", "x"}, []string{"", maskRedact}},
+		{"CTE 别名", "WITH c AS (SELECT email x FROM users) SELECT x FROM c", []string{"x"}, []string{maskRedact}},
+		{"多层别名", "SELECT z FROM (SELECT y AS z FROM (SELECT CONCAT(email, '') AS y FROM users) a) b", []string{"z"}, []string{maskRedact}},
+		{"标量子查询", "SELECT (SELECT email FROM users LIMIT 1) AS v", []string{"v"}, []string{maskRedact}},
+		{"UNION 按位置", "SELECT id FROM orders UNION SELECT email FROM users", []string{"id"}, []string{maskRedact}},
+		{"CTE 列名列表", "WITH c(x) AS (SELECT email FROM users) SELECT x FROM c", []string{"x"}, []string{maskRedact}},
+		{"派生表列名列表", "SELECT x FROM (SELECT email FROM users) AS t(x)", []string{"x"}, []string{maskRedact}},
+		{"其他表的同名列", "SELECT email FROM orders", []string{"email"}, []string{""}},
+		{"只在条件中引用", "SELECT id FROM users WHERE email = 'a'", []string{"id"}, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newResultMasker(tt.sql, tt.columns, ConnectionPolicy{})
+			if !reflect.DeepEqual(m.modes, tt.want) {
+				t.Errorf("newResultMasker(%q) = %v, want %v", tt.sql, m.modes, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResultMaskerPolicyColumns(t *testing.T) {
+	defer func(saved MaskingConfig) { Masking = saved }(Masking)
+	Masking = MaskingConfig{}
+
+	policy := ConnectionPolicy{MaskedColumns: []string{"salary"}}
+	m := newResultMasker("SELECT s FROM (SELECT salary AS s FROM emp) t", []string{"s"}, policy)
+	if !m.masked(0) {
+		t.Errorf("派生表中的策略脱敏列未脱敏")
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 工具结果中说明性文字的语言。模型通常会把这些文字原样转述给最终用户，因此与日志语言分开配置；
+// JSON 字段名以及 truncated:、pending_token: 等供程序解析的标记不随语言变化
+const (
+	LanguageEnglish = "en"
+	LanguageChinese = "zh"
+)
+
+// OutputLanguage 为当前的结果文字语言
+var OutputLanguage = LanguageEnglish
+
+// InitOutputLanguage 设置结果文字的语言，为空时使用英文
+func InitOutputLanguage(lang string) error {
+	switch strings.ToLower(lang) {
+	case "", "en", "english":
+		OutputLanguage = LanguageEnglish
+	case "zh", "zh-cn", "chinese":
+		OutputLanguage = LanguageChinese
+	default:
+		return fmt.Errorf("不支持的输出语言: %s，可选 en、zh", lang)
+	}
+	return nil
+}
+
+// message 为一条结果文字的各语言版本，均为 fmt 格式串
+type message struct {
+	en, zh string
+}
+
+// messages 为结果文字的目录
+var messages = map[string]message{
+	"exec_success": {
+		"Query executed successfully. Rows affected: %d",
+		"语句执行成功，影响行数: %d"},
+	"exec_last_insert_id": {
+		", Last insert ID: %d",
+		"，最后插入的 ID: %d"},
+	"truncated_auto_limit": {
+		"Result truncated to %d rows by the automatic LIMIT; add an explicit LIMIT (or narrower WHERE conditions) to control the rows returned.",
+		"结果已被自动追加的 LIMIT 截断为 %d 行；如需控制返回的行数，请显式添加 LIMIT（或收窄 WHERE 条件）。"},
+	"truncated_policy": {
+		"Result truncated to %d rows by the connection policy.",
+		"结果已按连接策略截断为 %d 行。"},
+	"truncated_limit": {
+		"Result truncated by %s (%d %s): returned %d rows, %s more rows were cut.",
+		"结果已被 %s（%d %s）截断：返回 %d 行，另有 %s 行未返回。"},
+	"unit_rows":  {"rows", "行"},
+	"unit_bytes": {"bytes", "字节"},
+	"at_least":   {"at least %s", "至少 %s"},
+	"truncated_handle": {
+		"Result truncated to %d rows.",
+		"结果已截断为 %d 行。"},
+	"warnings":        {"Warnings (%d):", "警告（%d 条）:"},
+	"column_hints":    {"Column hints:", "列说明:"},
+	"table_freshness": {"Table freshness:", "表数据新鲜度:"},
+	"row_sample_unavailable": {
+		"Row sample unavailable: %v",
+		"无法获取行样本: %v"},
+	"row_sample": {
+		"Deterministic sample of underlying rows (seed %d):",
+		"底层数据的确定性样本（种子 %d）:"},
+	"discovery_pending": {
+		"Semantic search is still running. These are preliminary keyword matches on table names, columns and comments. " +
+			"Better-ranked results will be pushed as a notification when ready; clients that do not show notifications can call " +
+			"get_can_use_table again with pending_token %q to wait for them.",
+		"语义检索仍在进行中，以上是按表名、列名和注释做关键词匹配的初步结果。检索完成后会通过通知推送排序更好的结果；" +
+			"不显示通知的客户端可以带 pending_token %q 再次调用 get_can_use_table 等待结果。"},
+	"result_stored": {
+		"Result stored as handle %q (%d rows).",
+		"结果已保存为句柄 %q（%d 行）。"},
+	"result_not_stored": {
+		"Result not stored: %v",
+		"结果未保存: %v"},
+	"export_inline": {"%d rows%s:", "%d 行%s:"},
+	"export_file": {
+		"Exported %d rows%s (%d bytes) to %s",
+		"已导出 %d 行%s（%d 字节）到 %s"},
+	"export_truncated": {" (truncated)", "（已截断）"},
+	"tx_started": {
+		"Transaction started. transaction_id: %s (rolled back automatically after %s idle)",
+		"事务已开始。transaction_id: %s（空闲 %s 后自动回滚）"},
+	"tx_committed":   {"Transaction %s committed.", "事务 %s 已提交。"},
+	"tx_rolled_back": {"Transaction %s rolled back.", "事务 %s 已回滚。"},
+	"ranking_removed": {
+		"Ranking rule for %s removed.",
+		"已删除 %s 的排序规则。"},
+	"ranking_set": {
+		"Ranking rule for %s set: pinned=%v, weight=%v.",
+		"已设置 %s 的排序规则: pinned=%v, weight=%v。"},
+	"batch_snapshot": {
+		"All queries ran against one consistent snapshot (REPEATABLE READ, read only).",
+		"所有查询都在同一个一致性快照中执行（REPEATABLE READ，只读）。"},
+	"batch_error": {"Error: %v", "错误: %v"},
+	"budget_truncated": {
+		"Showing %d of %d rows to fit max_tokens_hint=%d. Add a LIMIT or select fewer columns to see more.",
+		"为适应 max_tokens_hint=%[3]d，仅显示 %[2]d 行中的 %[1]d 行。如需查看更多，请添加 LIMIT 或减少查询的列。"},
+	"page_rows":  {"Rows %d-%d.", "第 %d-%d 行。"},
+	"page_empty": {"No rows at offset %d.", "偏移量 %d 处没有数据。"},
+	"page_last":  {" No more rows.", "没有更多数据。"},
+	"page_no_order": {
+		" Warning: the query has no ORDER BY, so rows may repeat or be skipped across pages.",
+		"注意：查询没有 ORDER BY，翻页时可能出现重复或遗漏的行。"},
+	"sandbox_result": {
+		"Sandbox result (sample of %d rows per table, all changes rolled back):",
+		"沙箱执行结果（每张表取样 %d 行，所有修改均已回滚）:"},
+	"sandbox_review": {
+		"Review the result above; call execute_sql to run the statement against the real tables.",
+		"请检查以上结果；确认后调用 execute_sql 在真实的表上执行该语句。"},
+	"history_annotated": {
+		"Query history %d annotated.",
+		"已为查询历史 %d 添加备注。"},
+	"index_table":            {"- %s (last embedded: %s)", "- %s（最后向量化: %s）"},
+	"index_embedded_unknown": {"unknown", "未知"},
+	"index_no_match": {
+		"No indexed table matches %q. New tables are indexed every 5 minutes; call reindex_schemas to index them now.",
+		"没有已索引的表与 %q 匹配。新表每 5 分钟索引一次，可调用 reindex_schemas 立即索引。"},
+	"index_empty": {
+		"The vector index is empty. Call reindex_schemas to build it.",
+		"向量索引为空，请调用 reindex_schemas 构建索引。"},
+	"index_tables": {
+		"%d table(s) in the vector index:\n%s",
+		"向量索引中共有 %d 张表:\n%s"},
+	"no_confident_match": {
+		"No indexed table schema reached the similarity threshold %.2f for this question; the candidates below are the closest but probably not relevant.",
+		"没有已索引的表结构达到相似度阈值 %.2f，以下候选表最接近，但很可能与问题无关。"},
+	"no_confident_match_suggestion": {
+		"Call list_tables to browse all tables (or find_columns to search by column name), then call get_can_use_table again with table or column names from the database.",
+		"请调用 list_tables 浏览所有表（或用 find_columns 按列名查找），再用数据库中的表名或列名重新调用 get_can_use_table。"},
+	"summary_note": {
+		"Result exceeded max_tokens_hint and was summarized; call execute_sql with raw=true to get the rows.",
+		"结果超出 max_tokens_hint，已替换为统计摘要；如需原始数据行，请以 raw=true 调用 execute_sql。"},
+	"grants_roles_note": {
+		"The account has roles; privileges granted through roles are not included in the summary. Check them with SHOW GRANTS FOR <account> USING <role>.",
+		"该账号带有角色，通过角色授予的权限不在汇总中，可用 SHOW GRANTS FOR <account> USING <role> 查看。"},
+	"proxy_vitess_stats": {
+		"Connected through Vitess: information_schema sizes and row counts may come from a single shard and undercount sharded keyspaces.",
+		"连接经过 Vitess：information_schema 中的大小和行数可能只来自单个分片，分片的 keyspace 会被低估。"},
+	"change_added_tables":    {"%d new table(s): %s", "新增 %d 张表: %s"},
+	"change_dropped_tables":  {"%d table(s) dropped: %s", "删除了 %d 张表: %s"},
+	"change_table":           {"%s: %s", "%s: %s"},
+	"change_separator":       {"; ", "；"},
+	"change_added_columns":   {"added columns %s", "新增列 %s"},
+	"change_removed_columns": {"removed columns %s", "删除列 %s"},
+	"change_changed_columns": {"changed %d column definition(s)", "修改了 %d 个列定义"},
+	"change_index_changes":   {"%d index change(s)", "%d 处索引变化"},
+	"change_grew": {
+		"%s grew from about %d to %d rows (%+.1f%%)",
+		"%s 从约 %d 行增长到 %d 行（%+.1f%%）"},
+	"change_shrank": {
+		"%s shrank from about %d to %d rows (%+.1f%%)",
+		"%s 从约 %d 行减少到 %d 行（%+.1f%%）"},
+	"change_ddl_activity": {
+		"%d DDL statement(s) were run through this server",
+		"通过本服务执行了 %d 条 DDL 语句"},
+	"change_statements": {
+		"%d statement(s) executed through this server (%d failed)",
+		"通过本服务执行了 %d 条语句（%d 条失败）"},
+	"change_binlog": {
+		"binlog: %d transaction(s), row changes on %d table(s), %d DDL statement(s)",
+		"binlog: %d 个事务，%d 张表有行变更，%d 条 DDL 语句"},
+	"change_none": {
+		"no schema changes or notable data volume shifts were found",
+		"没有发现表结构变化或明显的数据量变化"},
+}
+
+// Text 按当前的输出语言格式化结果文字，key 不存在时原样返回
+func Text(key string, args ...any) string {
+	m, ok := messages[key]
+	if !ok {
+		return key
+	}
+	format := m.en
+	if OutputLanguage == LanguageChinese && m.zh != "" {
+		format = m.zh
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
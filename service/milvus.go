@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	"github.com/milvus-io/milvus/client/v2/entity"
-	"github.com/milvus-io/milvus/client/v2/index"
 	"github.com/milvus-io/milvus/client/v2/milvusclient"
 	"go.uber.org/zap"
 )
@@ -20,25 +21,39 @@ var Logger *zap.SugaredLogger
 func CreateCollection(ctx context.Context, cli *milvusclient.Client, collectionName string) error {
 	schema := entity.NewSchema().
 		WithField(entity.NewField().WithName("my_id").WithDataType(entity.FieldTypeInt64).WithIsPrimaryKey(true).WithIsAutoID(true)).
-		WithField(entity.NewField().WithName("vector").WithDim(dim).WithDataType(entity.FieldTypeFloatVector)).
+		WithField(entity.NewField().WithName(primaryVectorField).WithDim(dim).WithDataType(vectorFieldType())).
 		WithField(entity.NewField().WithName("schema").WithDataType(entity.FieldTypeVarChar).WithMaxLength(10240))
+	if SecondaryEmbeddingEnabled() {
+		schema.WithField(entity.NewField().WithName(secondaryVectorField()).
+			WithDim(int64(Embedding.SecondaryDim)).WithDataType(vectorFieldType()))
+	}
+
+	return createAndLoadCollection(ctx, cli, collectionName, schema)
+}
 
-	err := cli.CreateCollection(ctx, milvusclient.NewCreateCollectionOption(collectionName, schema))
+// createAndLoadCollection 创建集合、为所有向量字段建立索引并加载集合
+func createAndLoadCollection(ctx context.Context, cli *milvusclient.Client, collectionName string, schema *entity.Schema) error {
+	err := cli.CreateCollection(ctx, milvusclient.NewCreateCollectionOption(collectionName, schema).
+		WithConsistencyLevel(MilvusCall.ConsistencyLevel))
 	if err != nil {
 		Logger.Errorw("创建集合失败", "error", err, "collection", collectionName)
 		return err
 	}
-	index := index.NewAutoIndex(entity.COSINE)
-	indexTask, err := cli.CreateIndex(ctx, milvusclient.NewCreateIndexOption(collectionName, "vector", index))
-	if err != nil {
-		Logger.Errorw("创建索引失败", "error", err, "collection", collectionName)
-		return err
-	}
+	for _, field := range schema.Fields {
+		if !isDenseVectorType(field.DataType) {
+			continue
+		}
+		indexTask, err := cli.CreateIndex(ctx, milvusclient.NewCreateIndexOption(collectionName, field.Name, buildVectorIndex()))
+		if err != nil {
+			Logger.Errorw("创建索引失败", "error", err, "collection", collectionName, "field", field.Name)
+			return err
+		}
 
-	err = indexTask.Await(ctx)
-	if err != nil {
-		Logger.Errorw("等待索引创建完成失败", "error", err, "collection", collectionName)
-		return err
+		err = indexTask.Await(ctx)
+		if err != nil {
+			Logger.Errorw("等待索引创建完成失败", "error", err, "collection", collectionName, "field", field.Name)
+			return err
+		}
 	}
 	loadTask, err := cli.LoadCollection(ctx, milvusclient.NewLoadCollectionOption(collectionName))
 	if err != nil {
@@ -60,6 +75,8 @@ func CreateCollection(ctx context.Context, cli *milvusclient.Client, collectionN
 // MilvusConfig 存储 Milvus 相关配置
 type MilvusConfig struct {
 	CollectionName string
+	// 存放历史SQL向量的集合名称
+	QueryCollectionName string
 	// 可以添加其他配置项，如维度、搜索限制等
 	Dimension   int
 	SearchLimit int
@@ -71,9 +88,10 @@ var Config MilvusConfig
 // 初始化配置
 func InitMilvusConfig(collectionName string) {
 	Config = MilvusConfig{
-		CollectionName: collectionName,
-		Dimension:      dim,
-		SearchLimit:    3,
+		CollectionName:      collectionName,
+		QueryCollectionName: collectionName + "_queries",
+		Dimension:           dim,
+		SearchLimit:         3,
 	}
 }
 
@@ -88,63 +106,174 @@ func CheckCollection(ctx context.Context, cli *milvusclient.Client) (has bool, e
 	return has, err
 }
 
-// SaveToVDB 保存数据到向量数据库
-func SaveToVDB(ctx context.Context, cli *milvusclient.Client, schemas []string, vector [][]float32) (err error) {
-	resp, err := cli.Insert(ctx, milvusclient.NewColumnBasedInsertOption(Config.CollectionName).
+// SaveToVDB 保存数据到向量数据库，返回自动生成的主键
+func SaveToVDB(ctx context.Context, cli *milvusclient.Client, schemas []string, vector []SchemaVectors) (ids []int64, err error) {
+	return saveSchemaVectors(ctx, cli, Config.CollectionName, schemas, vector)
+}
+
+// saveSchemaVectors 将表结构向量写入指定集合
+func saveSchemaVectors(ctx context.Context, cli *milvusclient.Client, collection string, schemas []string, vector []SchemaVectors) (ids []int64, err error) {
+	primary := make([][]float32, len(vector))
+	for i, v := range vector {
+		primary[i] = v.Primary
+	}
+	option := milvusclient.NewColumnBasedInsertOption(collection).
 		WithVarcharColumn("schema", schemas).
-		WithFloatVectorColumn("vector", dim, vector),
-	)
+		WithColumns(vectorColumn(primaryVectorField, dim, primary))
+	if SecondaryEmbeddingEnabled() {
+		secondary := make([][]float32, len(vector))
+		for i, v := range vector {
+			secondary[i] = v.Secondary
+		}
+		option = option.WithColumns(vectorColumn(secondaryVectorField(), Embedding.SecondaryDim, secondary))
+	}
+
+	resp, err := cli.Insert(ctx, option)
 	if err != nil {
 		Logger.Errorw("插入数据失败", "error", err)
-		return
+		return nil, err
 	}
 	Logger.Infow("数据插入成功", "insertCount", resp.InsertCount, "idsLen", resp.IDs.Len())
 
-	return nil
+	ids = make([]int64, 0, resp.IDs.Len())
+	for i := 0; i < resp.IDs.Len(); i++ {
+		id, err := resp.IDs.GetAsInt64(i)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SchemaHit 表示一条相似度搜索命中的表结构
+type SchemaHit struct {
+	Schema string  `json:"schema"`
+	Score  float32 `json:"score"`
+}
+
+// schemaHitKey 返回命中结果去重使用的键：能解析出表名时按表名，否则按表结构全文
+func schemaHitKey(hit SchemaHit) string {
+	if name, ok := tableNameFromSchema(hit.Schema); ok {
+		return name
+	}
+	return hit.Schema
+}
+
+// sortSchemaHits 按分数降序排序，分数相同时按表名和表结构全文排序，保证相同的输入总是得到相同的顺序
+func sortSchemaHits(hits []SchemaHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if ki, kj := schemaHitKey(hits[i]), schemaHitKey(hits[j]); ki != kj {
+			return ki < kj
+		}
+		return hits[i].Schema < hits[j].Schema
+	})
+}
+
+// dedupeSchemaHits 去掉同一张表的重复命中（如早期重启重复写入的向量），每张表只保留分数最高的一条，
+// 结果按 sortSchemaHits 的顺序排列
+func dedupeSchemaHits(hits []SchemaHit) []SchemaHit {
+	sorted := append([]SchemaHit(nil), hits...)
+	sortSchemaHits(sorted)
+	seen := make(map[string]bool, len(sorted))
+	deduped := sorted[:0]
+	for _, hit := range sorted {
+		key := schemaHitKey(hit)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, hit)
+	}
+	return deduped
 }
 
 // SimilaritySearch 执行相似度搜索
 func SimilaritySearch(ctx context.Context, cli *milvusclient.Client, queryVector []float32) (string, error) {
-	stats, err := cli.GetCollectionStats(ctx, milvusclient.NewGetCollectionStatsOption(Config.CollectionName))
+	hits, err := SearchSchemas(ctx, cli, queryVector, Config.SearchLimit)
 	if err != nil {
-		Logger.Errorw("获取集合统计信息失败", "error", err)
 		return "", err
 	}
+	normalizeSchemaHits(hits)
+	return joinSchemaHits(hits), nil
+}
+
+// SearchSchemas 执行相似度搜索，返回最多 limit 条命中的表结构及其相似度，同一张表只返回一次，按相似度降序排列
+func SearchSchemas(ctx context.Context, cli *milvusclient.Client, queryVector []float32, limit int) ([]SchemaHit, error) {
+	return searchSchemaField(ctx, cli, primaryVectorField, queryVector, limit)
+}
+
+// searchSchemaField 在指定的向量字段上执行相似度搜索，多召回一倍的候选用于补足去重后减少的结果
+func searchSchemaField(ctx context.Context, cli *milvusclient.Client, field string, queryVector []float32, limit int) ([]SchemaHit, error) {
+	var stats map[string]string
+	err := withMilvusRetry(ctx, "GetCollectionStats", func(ctx context.Context) (err error) {
+		stats, err = cli.GetCollectionStats(ctx, milvusclient.NewGetCollectionStatsOption(Config.CollectionName))
+		return err
+	})
+	if err != nil {
+		Logger.Errorw("获取集合统计信息失败", "error", err)
+		return nil, err
+	}
 	if stats["row_count"] == "0" {
 		loadTask, err := cli.LoadCollection(ctx, milvusclient.NewLoadCollectionOption(Config.CollectionName))
 		if err != nil {
 			Logger.Errorw("加载集合失败", "error", err)
-			return "", err
+			return nil, err
 		}
 
 		// sync wait collection to be loaded
 		err = loadTask.Await(ctx)
 		if err != nil {
 			Logger.Errorw("等待集合加载完成失败", "error", err)
-			return "", err
+			return nil, err
 		}
 	}
 
-	resultSets, err := cli.Search(ctx, milvusclient.NewSearchOption(
-		Config.CollectionName,
-		Config.SearchLimit,
-		[]entity.Vector{entity.FloatVector(queryVector)},
-	).WithOutputFields("schema"))
+	var resultSets []milvusclient.ResultSet
+	err = withMilvusRetry(ctx, "Search", func(ctx context.Context) (err error) {
+		resultSets, err = cli.Search(ctx, milvusclient.NewSearchOption(
+			Config.CollectionName,
+			limit*2,
+			[]entity.Vector{searchVector(queryVector)},
+		).WithANNSField(field).WithOutputFields("schema").WithConsistencyLevel(MilvusCall.ConsistencyLevel))
+		return err
+	})
 	if err != nil {
 		Logger.Errorw("执行相似度搜索失败", "error", err)
-		return "", err
+		return nil, err
 	}
 
-	res := ""
+	var hits []SchemaHit
 	for _, resultSet := range resultSets {
 		Logger.Debugw("搜索结果集", "idsLen", resultSet.IDs.Len(), "scores", resultSet.Scores)
-		for _, result := range resultSet.Fields {
-			fileData := result.FieldData().GetScalars().GetStringData().GetData()
-			for _, v := range fileData {
-				res += v
+		schemaColumn := resultSet.GetColumn("schema")
+		if schemaColumn == nil {
+			continue
+		}
+		for i := 0; i < schemaColumn.Len(); i++ {
+			schema, err := schemaColumn.GetAsString(i)
+			if err != nil {
+				return nil, err
+			}
+			hit := SchemaHit{Schema: schema}
+			if i < len(resultSet.Scores) {
+				hit.Score = resultSet.Scores[i]
 			}
+			hits = append(hits, hit)
 		}
 	}
 
-	return res, nil
+	return truncateHits(dedupeSchemaHits(filterHiddenHits(hits)), limit), nil
+}
+
+// joinSchemaHits 将命中的表结构拼接为返回给模型的文本
+func joinSchemaHits(hits []SchemaHit) string {
+	schemas := make([]string, len(hits))
+	for i, hit := range hits {
+		schemas[i] = hit.Schema
+	}
+	return strings.Join(schemas, "\n\n")
 }
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// MilvusCallConfig 控制 Milvus 读操作的一致性级别、超时与重试策略
+type MilvusCallConfig struct {
+	ConsistencyLevel entity.ConsistencyLevel
+	// SearchTimeout 单次检索的超时时间，0 表示只受调用方上下文控制
+	SearchTimeout time.Duration
+	// RetryAttempts 读操作的最大尝试次数（含首次），小于1时按1处理
+	RetryAttempts int
+	// RetryBackoff 首次重试前的等待时间，之后每次翻倍
+	RetryBackoff time.Duration
+}
+
+// 全局 Milvus 调用配置，默认与客户端库的默认行为一致
+var MilvusCall = MilvusCallConfig{
+	ConsistencyLevel: entity.ClBounded,
+	RetryAttempts:    1,
+	RetryBackoff:     200 * time.Millisecond,
+}
+
+// InitMilvusCallConfig 初始化 Milvus 调用配置
+func InitMilvusCallConfig(cfg MilvusCallConfig) {
+	if cfg.RetryAttempts < 1 {
+		cfg.RetryAttempts = 1
+	}
+	MilvusCall = cfg
+}
+
+// ParseConsistencyLevel 解析一致性级别名称（Strong/Bounded/Session/Eventually），为空时返回 Bounded
+func ParseConsistencyLevel(name string) (entity.ConsistencyLevel, error) {
+	switch strings.ToLower(name) {
+	case "", "bounded":
+		return entity.ClBounded, nil
+	case "strong":
+		return entity.ClStrong, nil
+	case "session":
+		return entity.ClSession, nil
+	case "eventually":
+		return entity.ClEventually, nil
+	default:
+		return entity.ClBounded, fmt.Errorf("未知的一致性级别: %s", name)
+	}
+}
+
+// withMilvusRetry 按配置的超时和重试策略执行只读调用。
+// 写操作重试可能导致重复数据，因此只用于检索、统计等读操作。
+// 长时间空闲后底层连接可能已被对端关闭，首次调用会因此失败；gRPC 会在后台重新建连，
+// 所以遇到连接类错误时即使未配置重试也会再尝试一次，避免空闲后的第一次检索直接报错
+func withMilvusRetry(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	attempts := MilvusCall.RetryAttempts
+	backoff := MilvusCall.RetryBackoff
+	redialed := false
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = func() error {
+			callCtx := ctx
+			if MilvusCall.SearchTimeout > 0 {
+				var cancel context.CancelFunc
+				callCtx, cancel = context.WithTimeout(ctx, MilvusCall.SearchTimeout)
+				defer cancel()
+			}
+			return fn(callCtx)
+		}()
+		if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+			return err
+		}
+		if attempt == attempts && !redialed && isConnectionError(err) {
+			redialed = true
+			attempts++
+		}
+		if attempt < attempts {
+			Logger.Warnw("Milvus调用失败，准备重试", "op", op, "attempt", attempt, "error", err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+// isConnectionError 判断错误是否由连接断开引起
+func isConnectionError(err error) bool {
+	if st, ok := status.FromError(err); ok && st.Code() == codes.Unavailable {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection closed") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "transport is closing") ||
+		strings.Contains(msg, "broken pipe")
+}
+
+// MilvusDialOptions 在客户端库默认的 gRPC 选项基础上覆盖 keepalive 参数。
+// 空闲期间按 keepaliveTime 发送探活，对端在 keepaliveTimeout 内未响应则判定连接失效并重新建连
+func MilvusDialOptions(keepaliveTime, keepaliveTimeout time.Duration) []grpc.DialOption {
+	options := append([]grpc.DialOption{}, milvusclient.DefaultGrpcOpts...)
+	if keepaliveTime <= 0 {
+		return options
+	}
+	if keepaliveTimeout <= 0 {
+		keepaliveTimeout = 10 * time.Second
+	}
+	// 后设置的选项会覆盖默认的 keepalive 参数
+	return append(options, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:                keepaliveTime,
+		Timeout:             keepaliveTimeout,
+		PermitWithoutStream: true,
+	}))
+}
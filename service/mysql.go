@@ -4,36 +4,202 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 )
 
+// Warning 表示 SHOW WARNINGS 返回的一条警告
+type Warning struct {
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// sqlExecutor 抽象 *sql.Conn 与 *sql.Tx 的共同方法，便于在固定会话或事务中执行语句
+type sqlExecutor interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ExecOptions 控制单次SQL执行的行为
+type ExecOptions struct {
+	// MaxTokens 为调用方的上下文预算，大于0时按预算调整结果集的返回形式
+	MaxTokens int
+	// Raw 为 true 时超出预算的结果集只截断，不替换为统计摘要
+	Raw bool
+	// Args 为语句中 ? 占位符对应的参数
+	Args []any
+	// SampleRows 大于0时额外返回查询底层行的确定性样本，用于核对聚合结果
+	SampleRows int
+	// SampleSeed 抽样使用的随机种子，为0时使用默认值
+	SampleSeed int64
+	// Capture 非空时保存查询语句的完整结果集，用于结果句柄
+	Capture *CapturedResult
+	// Collation 非空时语句在该排序规则（或 pinyin 等语言环境别名）下执行，执行后恢复会话设置
+	Collation string
+	// Database 非空时语句在该数据库中执行（在固定连接上 USE），执行后恢复连接的默认数据库；不能用于事务
+	Database string
+	// Confirm 为 DDL 安全模式下用户输入确认的目标对象名，多个对象用逗号分隔
+	Confirm string
+}
+
 func Execute(ctx context.Context, db *sql.DB, sql string) (string, error) {
+	return ExecuteWithOptions(ctx, db, sql, ExecOptions{})
+}
+
+// ExecuteWithOptions 按给定选项执行SQL语句
+func ExecuteWithOptions(ctx context.Context, db *sql.DB, sql string, opts ExecOptions) (string, error) {
 	// 检查数据库连接是否可用
 	if db == nil {
 		return "", fmt.Errorf("database connection not initialized")
 	}
 
-	// 判断SQL语句类型（简单判断，实际应用中可能需要更复杂的解析）
-	queryLower := strings.ToLower(strings.TrimSpace(sql))
-	isQuery := strings.HasPrefix(queryLower, "select") || strings.HasPrefix(queryLower, "show") ||
-		strings.HasPrefix(queryLower, "describe") || strings.HasPrefix(queryLower, "explain")
+	return withBreaker(func() (string, error) {
+		// 固定一个连接，保证 SHOW WARNINGS 与语句在同一会话中执行
+		// 连接池或服务端连接数已满时短暂排队，排队时间会附加在结果中
+		conn, release, waited, err := acquireConn(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+		if opts.Database != "" {
+			restore, err := useDatabase(ctx, conn, opts.Database)
+			if err != nil {
+				return "", err
+			}
+			defer restore()
+		}
+
+		id := connectionID(ctx, conn)
+		res, err := runStatement(ctx, conn, sql, opts)
+		if err != nil {
+			killTimedOut(ctx, db, id)
+			return res, err
+		}
+		if opts.SampleRows > 0 && isQueryStatement(sql) {
+			res = appendRowSample(ctx, conn, sql, res, opts)
+		}
+		if !opts.Raw {
+			res += queueNote(waited)
+		}
+		return res, nil
+	})
+}
+
+// queryStatementTypes 返回结果集的语句类型
+var queryStatementTypes = map[string]bool{"select": true, "show": true, "describe": true, "explain": true}
+
+// isQueryStatement 判断SQL语句是否只读取数据，多条语句时要求每条都是查询语句。
+// EXPLAIN ANALYZE 按被分析的语句判断，加锁读取不算查询语句
+func isQueryStatement(sql string) bool {
+	types := classifyStatements(sql)
+	for _, t := range types {
+		if !queryStatementTypes[t] {
+			return false
+		}
+	}
+	return len(types) > 0 && !lockingRead(sql)
+}
+
+// returnsRows 判断语句是否返回结果集：查询语句、加锁读取和 EXPLAIN ANALYZE，多条语句时要求每条都返回结果集
+func returnsRows(sql string) bool {
+	statements := sqlTokens(sql)
+	for _, tokens := range statements {
+		keyword := tokens[0]
+		if alias, ok := statementAliases[keyword]; ok {
+			keyword = alias
+		}
+		if keyword != "explain" && keyword != "describe" && !queryStatementTypes[classifyTokens(tokens)] {
+			return false
+		}
+	}
+	return len(statements) > 0
+}
+
+// runStatement 在给定会话上执行语句，并把结果和警告格式化为文本。被拒绝或执行失败的语句同样写入审计日志
+func runStatement(ctx context.Context, conn sqlExecutor, sql string, opts ExecOptions) (string, error) {
+	start := time.Now()
+	res, rows, err := executeStatement(ctx, conn, sql, opts)
+	recordAudit(ctx, sql, rows, time.Since(start), err)
+	return res, err
+}
+
+// guardStatement 执行语句前的结构检查和连接策略检查，返回当前生效的连接策略。
+// 所有读取业务数据的入口共用，与 resultGuard 一起保证各入口的检查一致
+func guardStatement(ctx context.Context, sql string) (ConnectionPolicy, error) {
+	if err := checkPayload(sql); err != nil {
+		return ConnectionPolicy{}, err
+	}
+	policy := activePolicy()
+	if err := policy.checkStatement(ctx, sql); err != nil {
+		return ConnectionPolicy{}, err
+	}
+	return policy, nil
+}
+
+// resultGuard 读取结果集时对需要脱敏的列脱敏，并去掉 SHOW TABLES 等列表中被隐藏的表和数据库
+type resultGuard struct {
+	masker resultMasker
+	hidden func(values []any) bool
+}
+
+// newResultGuard 按原始语句和结果列创建结果集检查
+func newResultGuard(sql string, columns []string, policy ConnectionPolicy) resultGuard {
+	return resultGuard{masker: newResultMasker(sql, columns, policy), hidden: hiddenRowFilter(sql)}
+}
+
+// skip 判断一行是否属于被隐藏的对象，需要从结果中去掉
+func (g resultGuard) skip(values []any) bool {
+	return g.hidden != nil && g.hidden(values)
+}
+
+// executeStatement 执行语句，同时返回结果行数（查询）或影响行数（非查询）
+func executeStatement(ctx context.Context, conn sqlExecutor, sql string, opts ExecOptions) (string, int64, error) {
+	policy, err := guardStatement(ctx, sql)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := checkTypedConfirmation(sql, opts.Confirm); err != nil {
+		return "", 0, err
+	}
+	if err := checkApproval(ctx, sql); err != nil {
+		return "", 0, err
+	}
+	if opts.Collation != "" {
+		restore, err := applyCollation(ctx, conn, opts.Collation)
+		if err != nil {
+			return "", 0, err
+		}
+		defer restore()
+	}
+
+	// 先按原始语句判断类型，再追加自动 LIMIT 并注入标识注释
+	isQuery := returnsRows(sql)
+	original := sql
+	sql, rowLimit := applyAutoLimit(sql)
+	sql = labelStatement(ctx, sql)
 
 	// 如果是查询语句
 	if isQuery {
 		// 执行查询
-		rows, err := db.QueryContext(ctx, sql)
+		rows, err := conn.QueryContext(ctx, sql, opts.Args...)
 		if err != nil {
-			return "", fmt.Errorf("query execution failed: %v", err)
+			return "", 0, withSuggestions(fmt.Errorf("query execution failed: %w", err), executionErrorSuggestions(original, err))
 		}
 		defer rows.Close()
 
 		// 获取列名
 		columns, err := rows.Columns()
 		if err != nil {
-			return "", fmt.Errorf("failed to get column names: %v", err)
+			return "", 0, fmt.Errorf("failed to get column names: %v", err)
 		}
 
+		// 按语句引用的表和结果列确定需要脱敏的列，脱敏在序列化之前完成；SHOW TABLES 等列表中去掉被隐藏的表和数据库
+		guard := newResultGuard(original, columns, policy)
+		masker := guard.masker
+
 		// 准备结果集
 		resultSet := make([]map[string]interface{}, 0)
 		colValues := make([]interface{}, len(columns))
@@ -45,15 +211,36 @@ func Execute(ctx context.Context, db *sql.DB, sql string) (string, error) {
 		}
 
 		// 遍历结果集
+		truncated, autoLimited := false, false
+		limiter := newResultLimiter()
 		for rows.Next() {
+			if policy.MaxRows > 0 && len(resultSet) >= policy.MaxRows {
+				truncated = true
+				break
+			}
+			if rowLimit > 0 && len(resultSet) >= rowLimit {
+				truncated, autoLimited = true, true
+				break
+			}
+			if limiter.rowsFull(len(resultSet)) {
+				truncated = true
+				break
+			}
 			err = rows.Scan(colPointers...)
 			if err != nil {
-				return "", fmt.Errorf("failed to scan row: %v", err)
+				return "", 0, fmt.Errorf("failed to scan row: %v", err)
+			}
+			if guard.skip(colValues) {
+				continue
 			}
 
 			// 创建行数据映射
 			rowData := make(map[string]interface{})
 			for i, colName := range columns {
+				if masker.masked(i) {
+					rowData[colName] = masker.apply(i, *colPointers[i].(*interface{}))
+					continue
+				}
 				val := colPointers[i].(*interface{})
 				// 处理特殊类型，如时间和二进制数据
 				switch v := (*val).(type) {
@@ -65,40 +252,118 @@ func Execute(ctx context.Context, db *sql.DB, sql string) (string, error) {
 				}
 			}
 
+			if !limiter.fits(len(resultSet), rowData) {
+				truncated = true
+				break
+			}
 			resultSet = append(resultSet, rowData)
 		}
+		if limiter.truncation != nil {
+			limiter.countOmitted(rows)
+		}
 
 		// 检查遍历过程中是否有错误
 		if err = rows.Err(); err != nil {
-			return "", fmt.Errorf("error during row iteration: %v", err)
+			return "", 0, fmt.Errorf("error during row iteration: %w", err)
+		}
+		rows.Close() // 释放结果集后才能在同一连接上查询警告
+		if opts.Capture != nil {
+			opts.Capture.capture(columns, resultSet, truncated)
 		}
 
 		// 将结果转换为JSON
-		resultJSON, err := json.MarshalIndent(resultSet, "", "  ")
+		resultJSON, err := shapeRows(columns, resultSet, opts.MaxTokens, opts.Raw)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+			return "", 0, err
+		}
+		if limiter.cfg.MaxBytes > 0 && len(resultJSON) > limiter.cfg.MaxBytes {
+			// 缩进后超出字节数上限时改为紧凑格式，紧凑格式的大小已在读取时控制
+			compact, err := json.Marshal(resultSet)
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to marshal result to JSON: %v", err)
+			}
+			resultJSON = string(compact)
+		}
+		if autoLimited {
+			resultJSON += "\n\n" + Text("truncated_auto_limit", rowLimit)
+			resultJSON += formatSuggestions(truncationSuggestions(original, rowLimit, true))
+		} else if limiter.truncation != nil {
+			resultJSON += limiter.note()
+			resultJSON += formatSuggestions(truncationSuggestions(original, max(limiter.truncation.ReturnedRows, 1), false))
+		} else if truncated {
+			resultJSON += "\n\n" + Text("truncated_policy", policy.MaxRows)
+			resultJSON += formatSuggestions(truncationSuggestions(original, policy.MaxRows, false))
 		}
-		return string(resultJSON), nil
+		return resultJSON + formatColumnHints(columns) + formatWarnings(fetchWarnings(ctx, conn)), int64(len(resultSet)), nil
 	} else {
 		// 执行非查询语句（如INSERT, UPDATE, DELETE等）
-		result, err := db.ExecContext(ctx, sql)
+		result, err := conn.ExecContext(ctx, sql, opts.Args...)
 		if err != nil {
-			return "", fmt.Errorf("non-query execution failed: %v", err)
+			return "", 0, withSuggestions(fmt.Errorf("non-query execution failed: %w", err), executionErrorSuggestions(original, err))
 		}
 
 		rowsAffected, _ := result.RowsAffected()
 		lastInsertID, _ := result.LastInsertId()
 
-		response := fmt.Sprintf("Query executed successfully. Rows affected: %d", rowsAffected)
+		response := Text("exec_success", rowsAffected)
 		if lastInsertID > 0 {
-			response += fmt.Sprintf(", Last insert ID: %d", lastInsertID)
+			response += Text("exec_last_insert_id", lastInsertID)
 		}
 
-		return response, nil
+		return response + formatWarnings(fetchWarnings(ctx, conn)), rowsAffected, nil
 	}
 }
 
+// fetchWarnings 在语句执行后读取当前会话的警告，警告数为0时不做额外查询
+func fetchWarnings(ctx context.Context, conn sqlExecutor) []Warning {
+	var count int
+	// SHOW COUNT(*) WARNINGS 属于诊断语句，不会清空警告区
+	if err := conn.QueryRowContext(ctx, "SHOW COUNT(*) WARNINGS").Scan(&count); err != nil {
+		Logger.Warnw("获取警告数量失败", "error", err)
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		Logger.Warnw("获取警告失败", "error", err)
+		return nil
+	}
+	defer rows.Close()
+
+	warnings := make([]Warning, 0, count)
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			Logger.Warnw("扫描警告失败", "error", err)
+			return warnings
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
+
+// formatWarnings 将警告格式化为附加在结果末尾的文本
+func formatWarnings(warnings []Warning) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	data, err := json.MarshalIndent(warnings, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\n%s\n%s", Text("warnings", len(warnings)), data)
+}
+
 func GetAllTableSchema(ctx context.Context, db *sql.DB, ch chan map[string]string) {
+	streamTableSchemas(ctx, db, ch, nil)
+}
+
+// streamTableSchemas 逐表读取表结构发送到通道。budget 非空时每个表结构发送前先占用等量的内存预算，
+// 预算不足时阻塞，接收方处理完一个表结构后需要调用 budget.release 归还
+func streamTableSchemas(ctx context.Context, db *sql.DB, ch chan map[string]string, budget *memoryBudget) {
 	defer close(ch) // 确保函数结束时关闭通道
 
 	if db == nil {
@@ -121,51 +386,134 @@ func GetAllTableSchema(ctx context.Context, db *sql.DB, ch chan map[string]strin
 		Logger.Errorw("扫描表失败", "error", err)
 		return
 	}
+	tables = filterHiddenTables(tables)
+
+	// 文档集合的表结构只有 doc 列，需要补充采样得到的字段说明
+	collections, err := ListDocumentCollections(ctx, db)
+	if err != nil {
+		Logger.Warnw("查询文档集合失败", "error", err)
+	}
 
 	// 处理每个表的结构
+	failed := 0
 	for _, table := range tables {
-		select {
-		case <-ctx.Done():
+		if ctx.Err() != nil {
 			Logger.Info("上下文取消，停止获取表结构")
 			return
-		default:
-			// 在每次循环中创建新的查询
-			tableRows, err := db.QueryContext(ctx, "show create table "+table)
-			if err != nil {
-				// 记录错误但继续处理其他表
-				Logger.Warnw("无法获取表结构", "table", table, "error", err)
-				continue
+		}
+
+		createTableStmt, err := tableSchema(ctx, db, table)
+		if errors.Is(err, errViewSchema) {
+			continue
+		}
+		if err != nil {
+			// 记录错误但继续处理其他表
+			Logger.Warnw("无法获取表结构", "table", table, "error", err)
+			failed++
+			continue
+		}
+		if containsString(collections, table) {
+			fields, err := DescribeDocumentFields(ctx, db, table)
+			if errors.Is(err, errOutsideSamplingWindow) {
+				Logger.Debugw("不在采样时间窗口内，跳过推断文档字段", "collection", table)
+			} else if err != nil {
+				Logger.Warnw("无法推断文档字段", "collection", table, "error", err)
+			} else {
+				createTableStmt += describeDocumentSchema(fields)
 			}
+		}
+		tableMap := map[string]string{
+			table: createTableStmt,
+		}
 
-			// 使用闭包立即处理结果并关闭资源
-			func() {
-				defer tableRows.Close() // 在闭包内使用defer是安全的
-
-				if tableRows.Next() {
-					var tableName, createTableStmt string
-					if err = tableRows.Scan(&tableName, &createTableStmt); err != nil {
-						Logger.Warnw("无法扫描表结构", "table", table, "error", err)
-						return
-					}
-					tableMap := map[string]string{
-						tableName: createTableStmt,
-					}
-
-					select {
-					case ch <- tableMap:
-						// 成功发送到通道
-					case <-ctx.Done():
-						Logger.Info("上下文取消，停止发送表结构")
-						return
-					}
-				}
-			}()
+		if err = budget.acquire(ctx, int64(len(createTableStmt))); err != nil {
+			Logger.Info("上下文取消，停止发送表结构")
+			return
+		}
+		select {
+		case ch <- tableMap:
+			// 成功发送到通道
+		case <-ctx.Done():
+			budget.release(int64(len(createTableStmt)))
+			Logger.Info("上下文取消，停止发送表结构")
+			return
 		}
 	}
 
+	// 部分表读取失败时明确记录，避免检索层在数据不完整时看起来一切正常
+	if failed > 0 {
+		Logger.Errorw("部分表结构获取失败，检索结果可能不完整", "tables", len(tables), "failed", failed, "proxy", currentProxy())
+	}
 	Logger.Info("所有表结构获取完成")
 }
 
+// tableSchema 返回表的建表语句。SHOW CREATE TABLE 失败时（如经过 Vitess 等代理时部分语句行为不同），
+// 退化为根据 information_schema 拼出的近似建表语句
+func tableSchema(ctx context.Context, db *sql.DB, table string) (string, error) {
+	createTableStmt, err := showCreateTable(ctx, db, table)
+	if err == nil || errors.Is(err, errViewSchema) {
+		return createTableStmt, err
+	}
+	stmt, fallbackErr := createTableFromColumns(ctx, db, table)
+	if fallbackErr != nil {
+		return "", fmt.Errorf("%v; 从 information_schema 读取也失败: %v", err, fallbackErr)
+	}
+	Logger.Warnw("SHOW CREATE TABLE 失败，改用 information_schema 生成表结构", "table", table, "error", err)
+	return stmt, nil
+}
+
+// errViewSchema 表示对象是视图，视图不进入检索
+var errViewSchema = errors.New("视图不建立索引")
+
+// showCreateTable 执行 SHOW CREATE TABLE，视图返回 errViewSchema
+func showCreateTable(ctx context.Context, db *sql.DB, table string) (string, error) {
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, "SHOW CREATE TABLE "+quoteIdentifier(table)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	// 视图返回 View、Create View、character_set_client、collation_connection 四列
+	if len(columns) != 2 {
+		return "", errViewSchema
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return "", err
+		}
+		return "", sql.ErrNoRows
+	}
+	var tableName, createTableStmt string
+	if err = rows.Scan(&tableName, &createTableStmt); err != nil {
+		return "", err
+	}
+	return createTableStmt, nil
+}
+
+// getTableColumns 按定义顺序获取当前库中指定表的列名
+func getTableColumns(ctx context.Context, db *sql.DB, table string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		table)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := scanTables(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("表不存在或没有列: %s", table)
+	}
+	return columns, nil
+}
+
 // 辅助函数：扫描表名
 func scanTables(rows *sql.Rows) ([]string, error) {
 	var tables []string
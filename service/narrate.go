@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// narrateMaxTokens 按历史ID重新查询时结果集的上下文预算
+	narrateMaxTokens = 4000
+	// narrateMaxResultChars 传给LLM的结果文本上限
+	narrateMaxResultChars = 24000
+)
+
+// ExplainResultInput 为 ExplainResult 的输入，Result、Handle 与 HistoryID 三选一
+type ExplainResultInput struct {
+	Question  string
+	Result    string
+	Handle    string
+	HistoryID int64
+}
+
+const explainResultPrompt = `You explain SQL query results to business users.
+Write a concise narrative summary (a few sentences or short bullets) that answers the user's question from the result.
+Use only numbers that appear in the result; do not invent data.
+End with a "Caveats" line covering anything that limits the answer, such as truncated or summarized results,
+empty results, data that may be stale relative to the time it was fetched, or a failed query.
+Reply in the same language as the question.`
+
+// ExplainResult 调用配置的LLM为结果集生成自然语言说明。指定历史ID时会重新执行该查询获取当前结果
+func ExplainResult(ctx context.Context, db *sql.DB, in ExplainResultInput) (string, error) {
+	if !LLMEnabled() {
+		return "", fmt.Errorf("未配置LLM，无法生成结果说明")
+	}
+	if strings.TrimSpace(in.Question) == "" {
+		return "", fmt.Errorf("question is empty")
+	}
+
+	var details strings.Builder
+	result := in.Result
+	failed := false
+	if in.Handle != "" && result == "" {
+		h, err := GetResultHandle(ctx, in.Handle)
+		if err != nil {
+			return "", err
+		}
+		if result, err = formatHandleRows(h); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&details, "SQL: %s\nFetched at: %s\n", h.SQL, h.CreatedAt.Format(time.RFC3339))
+	}
+	if in.HistoryID > 0 && result == "" {
+		entry, err := GetQueryHistory(in.HistoryID)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&details, "SQL: %s\nOriginally executed at: %s\n", entry.Query, entry.CreatedAt.Format(time.RFC3339))
+		if entry.Note != "" {
+			fmt.Fprintf(&details, "Note: %s\n", entry.Note)
+		}
+		switch {
+		case !entry.Success:
+			failed = true
+			fmt.Fprintf(&details, "The query failed: %s\n", entry.Error)
+		case result == "" && isQueryStatement(entry.Query):
+			// 历史记录只保存SQL，重新执行以获取结果
+			if result, err = ExecuteWithOptions(ctx, db, entry.Query, ExecOptions{MaxTokens: narrateMaxTokens}); err != nil {
+				return "", fmt.Errorf("重新执行历史查询失败: %w", err)
+			}
+			fmt.Fprintf(&details, "Result re-fetched at: %s\n", time.Now().Format(time.RFC3339))
+		case result == "":
+			return "", fmt.Errorf("历史记录 %d 不是查询语句，没有可说明的结果集", in.HistoryID)
+		}
+	}
+	if result == "" && !failed {
+		return "", fmt.Errorf("需要提供 result、handle 或 history_id")
+	}
+
+	if len(result) > narrateMaxResultChars {
+		result = result[:narrateMaxResultChars]
+		details.WriteString("The result text was cut off before being passed to you.\n")
+	}
+	userPrompt := fmt.Sprintf("Question: %s\n%s\nResult:\n%s", in.Question, details.String(), result)
+	return ChatComplete(ctx, explainResultPrompt, userPrompt)
+}
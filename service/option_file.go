@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadOptionFile 读取 MySQL 选项文件（如 ~/.my.cnf）中指定分组的选项，后出现的分组覆盖先出现的同名选项。
+// 选项名统一为小写并把 - 替换为 _，值两端的引号会被去掉；!include 和 !includedir 指令会被忽略
+func ReadOptionFile(path string, groups ...string) (map[string]string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("获取用户主目录失败: %v", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开选项文件失败: %v", err)
+	}
+	defer f.Close()
+
+	wanted := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		wanted[strings.ToLower(g)] = true
+	}
+
+	options := make(map[string]string)
+	inGroup := false
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", line[0] == '#', line[0] == ';', line[0] == '!':
+			continue
+		case line[0] == '[':
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("选项文件 %s 第 %d 行分组格式错误", path, lineNo)
+			}
+			inGroup = wanted[strings.ToLower(strings.TrimSpace(line[1:end]))]
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+		options[key] = optionValue(strings.TrimSpace(value))
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取选项文件失败: %v", err)
+	}
+	return options, nil
+}
+
+// optionValue 去掉选项值两端的引号；未加引号的值去掉行尾注释
+func optionValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
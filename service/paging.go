@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// pageCursorTTL 分页游标的保留时长
+	pageCursorTTL = 30 * time.Minute
+	// maxPageCursors 同时保留的游标上限，超出时淘汰最早创建的游标
+	maxPageCursors = 200
+	// defaultPageSize、maxPageSize 每页的默认与最大行数
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// pageCursor 记录下一页的位置，每页生成新的游标，重复使用同一游标会得到同一页
+type pageCursor struct {
+	query     string
+	limit     int
+	offset    int
+	session   string
+	createdAt time.Time
+}
+
+// pageCursors 按游标令牌保存分页状态
+var pageCursors = struct {
+	sync.Mutex
+	m map[string]*pageCursor
+}{m: map[string]*pageCursor{}}
+
+// QueryPage 分页执行 SELECT 语句，每次只返回一页。首次调用传入 query（可指定 page，从1开始），
+// 之后传入上一页返回的 cursor 继续读取。语句在服务端被包装为带 LIMIT/OFFSET 的子查询重新执行，
+// 没有 ORDER BY 时不同页之间的顺序不保证稳定
+func QueryPage(ctx context.Context, db *sql.DB, query, cursor string, page, limit int, opts ExecOptions) (string, error) {
+	var c pageCursor
+	if cursor != "" {
+		found, err := lookupPageCursor(ctx, cursor)
+		if err != nil {
+			return "", err
+		}
+		c = *found
+	} else {
+		// 语句会被包装为子查询，末尾的注释会吞掉之后拼接的右括号和 LIMIT
+		query = trimStatementEnd(query)
+		if query == "" {
+			return "", fmt.Errorf("请指定 query 或 cursor")
+		}
+		if types := classifyStatements(query); len(types) != 1 || types[0] != "select" {
+			return "", fmt.Errorf("只支持分页读取单条 SELECT 语句")
+		}
+		if limit <= 0 {
+			limit = defaultPageSize
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+		if page <= 0 {
+			page = 1
+		}
+		c = pageCursor{query: query, limit: limit, offset: (page - 1) * limit, session: sessionFromContext(ctx)}
+	}
+
+	opts.Args = []any{c.limit, c.offset}
+	opts.Capture = &CapturedResult{}
+	pageSQL := fmt.Sprintf("SELECT * FROM (%s) AS mcp_page LIMIT ? OFFSET ?", c.query)
+	res, err := ExecuteWithOptions(ctx, db, pageSQL, opts)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1060 { // Duplicate column name
+		return "", fmt.Errorf("分页会把语句包装为子查询，子查询的结果列名不能重复（%s），请为同名的列指定不同的别名，如 b.id AS b_id", mysqlErr.Message)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	rows := len(opts.Capture.Rows)
+	summary := Text("page_rows", c.offset+1, c.offset+rows)
+	if rows == 0 {
+		summary = Text("page_empty", c.offset)
+	}
+	if rows < c.limit {
+		summary += Text("page_last")
+	} else {
+		next := c
+		next.offset += c.limit
+		next.createdAt = time.Now()
+		summary += fmt.Sprintf(" next_cursor: %s", storePageCursor(&next))
+	}
+	if !strings.Contains(strings.ToLower(c.query), "order by") {
+		summary += Text("page_no_order")
+	}
+	return res + "\n\n" + summary, nil
+}
+
+// storePageCursor 保存游标并返回令牌
+func storePageCursor(c *pageCursor) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	token := "pg_" + hex.EncodeToString(b)
+
+	pageCursors.Lock()
+	defer pageCursors.Unlock()
+	prunePageCursors()
+	pageCursors.m[token] = c
+	return token
+}
+
+// prunePageCursors 清理过期游标，并在超出上限时淘汰最早创建的游标，调用方需持有锁
+func prunePageCursors() {
+	tokens := make([]string, 0, len(pageCursors.m))
+	for token, c := range pageCursors.m {
+		if time.Since(c.createdAt) > pageCursorTTL {
+			delete(pageCursors.m, token)
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) < maxPageCursors {
+		return
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return pageCursors.m[tokens[i]].createdAt.Before(pageCursors.m[tokens[j]].createdAt)
+	})
+	for _, token := range tokens[:len(tokens)-maxPageCursors+1] {
+		delete(pageCursors.m, token)
+	}
+}
+
+// lookupPageCursor 返回当前会话中的游标
+func lookupPageCursor(ctx context.Context, token string) (*pageCursor, error) {
+	pageCursors.Lock()
+	defer pageCursors.Unlock()
+	c, ok := pageCursors.m[token]
+	if !ok || c.session != sessionFromContext(ctx) || time.Since(c.createdAt) > pageCursorTTL {
+		return nil, fmt.Errorf("游标不存在或已过期: %s", token)
+	}
+	return c, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Partition 描述表的一个分区或子分区
+type Partition struct {
+	Name         string `json:"name"`
+	SubPartition string `json:"subpartition,omitempty"`
+	Position     int64  `json:"position"`
+	// Description 为 RANGE 分区的上界（VALUES LESS THAN）或 LIST 分区的取值（VALUES IN）
+	Description string `json:"description,omitempty"`
+	// RowsEstimate 来自 information_schema.PARTITIONS，InnoDB 下为估算值
+	RowsEstimate int64  `json:"rows_estimate"`
+	DataBytes    int64  `json:"data_bytes"`
+	IndexBytes   int64  `json:"index_bytes"`
+	Comment      string `json:"comment,omitempty"`
+}
+
+// PartitionInfo 表的分区方案和各分区统计
+type PartitionInfo struct {
+	Table       string `json:"table"`
+	Partitioned bool   `json:"partitioned"`
+	// Method 为 RANGE、LIST、HASH、KEY 等，Expression 为分区表达式或列
+	Method        string      `json:"method,omitempty"`
+	Expression    string      `json:"expression,omitempty"`
+	SubMethod     string      `json:"subpartition_method,omitempty"`
+	SubExpression string      `json:"subpartition_expression,omitempty"`
+	Partitions    []Partition `json:"partitions"`
+}
+
+// GetPartitions 从 information_schema.PARTITIONS 读取表的分区方案和每个分区的行数与大小，
+// 用于编写能命中分区裁剪的查询
+func GetPartitions(ctx context.Context, db *sql.DB, table string) (*PartitionInfo, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return nil, err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return nil, err
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT COALESCE(PARTITION_NAME, ''), COALESCE(SUBPARTITION_NAME, ''),
+			COALESCE(PARTITION_ORDINAL_POSITION, 0), COALESCE(SUBPARTITION_ORDINAL_POSITION, 0),
+			COALESCE(PARTITION_METHOD, ''), COALESCE(PARTITION_EXPRESSION, ''),
+			COALESCE(SUBPARTITION_METHOD, ''), COALESCE(SUBPARTITION_EXPRESSION, ''),
+			COALESCE(PARTITION_DESCRIPTION, ''), COALESCE(TABLE_ROWS, 0),
+			COALESCE(DATA_LENGTH, 0), COALESCE(INDEX_LENGTH, 0), COALESCE(PARTITION_COMMENT, '')
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY PARTITION_ORDINAL_POSITION, SUBPARTITION_ORDINAL_POSITION`), table)
+	if err != nil {
+		return nil, fmt.Errorf("查询分区信息失败: %v", err)
+	}
+	defer rows.Close()
+
+	info := &PartitionInfo{Table: table, Partitions: make([]Partition, 0)}
+	found := false
+	for rows.Next() {
+		var (
+			p           Partition
+			subPosition int64
+		)
+		if err = rows.Scan(&p.Name, &p.SubPartition, &p.Position, &subPosition,
+			&info.Method, &info.Expression, &info.SubMethod, &info.SubExpression,
+			&p.Description, &p.RowsEstimate, &p.DataBytes, &p.IndexBytes, &p.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		found = true
+		// 未分区的表只有一行，PARTITION_NAME 为 NULL
+		if p.Name == "" {
+			continue
+		}
+		info.Partitioned = true
+		info.Partitions = append(info.Partitions, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询分区信息失败: %v", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("表不存在: %s", table)
+	}
+	return info, nil
+}
+
+// FormatPartitions 将分区信息序列化为 JSON
+func FormatPartitions(info *PartitionInfo) (string, error) {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal partitions to JSON: %v", err)
+	}
+	return string(data), nil
+}
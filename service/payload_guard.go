@@ -0,0 +1,81 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PayloadGuardConfig 控制执行前对语句结构的检查，默认全部拒绝
+type PayloadGuardConfig struct {
+	// AllowMultiStatements 为 true 时允许一次提交以分号分隔的多条语句
+	AllowMultiStatements bool
+	// AllowFileAccess 为 true 时允许 LOAD_FILE、INTO OUTFILE/DUMPFILE、LOAD DATA 等读写服务端文件的语句
+	AllowFileAccess bool
+	// AllowExecutableComments 为 true 时允许 /*! ... */ 和 MariaDB 的 /*M! ... */ 可执行注释
+	AllowExecutableComments bool
+}
+
+// 全局语句结构检查配置
+var PayloadGuard PayloadGuardConfig
+
+// InitPayloadGuard 初始化语句结构检查配置
+func InitPayloadGuard(cfg PayloadGuardConfig) {
+	PayloadGuard = cfg
+}
+
+// dangerousFunctions 读取服务端文件或执行外部命令的函数，出现函数调用时拒绝执行
+var dangerousFunctions = map[string]bool{
+	"load_file": true,
+	"sys_exec":  true, // lib_mysqludf_sys
+	"sys_eval":  true,
+}
+
+// checkPayload 基于词法分析检查语句结构：拒绝多条语句和连续的分号、/*! */ 和 /*M! */ 可执行注释，
+// 以及读写服务端文件的函数和子句，除非配置中显式允许。字符串和普通注释中的内容不会误判
+func checkPayload(sql string) error {
+	tokens := lexSQL(sql)
+
+	semicolons := 0
+	for i, t := range tokens {
+		switch {
+		case t.kind == tokenPunct && t.text == ";":
+			semicolons++
+			if PayloadGuard.AllowMultiStatements {
+				continue
+			}
+			if semicolons > 1 || (i+1 < len(tokens) && tokens[i+1].text == ";") {
+				return fmt.Errorf("语句中包含连续或多余的分号，请一次只提交一条语句")
+			}
+			if i+1 < len(tokens) {
+				return fmt.Errorf("不允许一次执行多条语句，请拆分后逐条执行")
+			}
+		case t.kind == tokenPunct && t.text == "/*!":
+			if !PayloadGuard.AllowExecutableComments {
+				return fmt.Errorf("不允许使用 /*! ... */ 或 /*M! ... */ 可执行注释，请直接写出语句")
+			}
+		case t.kind == tokenWord && !PayloadGuard.AllowFileAccess:
+			if err := checkFileAccess(tokens, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkFileAccess 检查第 i 个关键字是否为读写服务端文件的函数调用或子句
+func checkFileAccess(tokens []sqlToken, i int) error {
+	word := tokens[i].text
+	next := ""
+	if i+1 < len(tokens) {
+		next = tokens[i+1].text
+	}
+	switch {
+	case dangerousFunctions[word] && next == "(":
+		return fmt.Errorf("不允许调用 %s，该函数会访问服务端文件或执行外部命令", strings.ToUpper(word))
+	case word == "into" && (next == "outfile" || next == "dumpfile"):
+		return fmt.Errorf("不允许使用 INTO %s 把结果写入服务端文件，请使用 export_query_csv 导出", strings.ToUpper(next))
+	case word == "load" && (next == "data" || next == "xml"):
+		return fmt.Errorf("不允许通过 SQL 执行 LOAD %s，请使用 load_data_file 工具", strings.ToUpper(next))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import "testing"
+
+func TestCheckPayload(t *testing.T) {
+	InitPayloadGuard(PayloadGuardConfig{})
+
+	allowed := []string{
+		"SELECT 1",
+		"SELECT 1;",
+		"SELECT 1;  -- trailing comment",
+		"SELECT ';;' FROM a",
+		"SELECT * FROM a -- ; DROP TABLE a",
+		"SELECT * FROM a /* ; DROP TABLE a */",
+		"SELECT load_file FROM a",
+		"SELECT 'LOAD DATA INFILE x' FROM a",
+		"SELECT \"/*!50000 x */\" FROM a",
+		"SELECT * FROM a /* M! not executable */",
+		"INSERT INTO a (into_outfile) VALUES (1)",
+	}
+	for _, sql := range allowed {
+		if err := checkPayload(sql); err != nil {
+			t.Errorf("checkPayload(%q) = %v, want nil", sql, err)
+		}
+	}
+
+	denied := []string{
+		"SELECT 1; DROP TABLE a",
+		"SELECT 1;;",
+		"; SELECT 1",
+		"SELECT /*!50000 1 */",
+		"SELECT * FROM a /*!80000 ; DROP TABLE a */",
+		"SELECT * FROM t /*M! INTO OUTFILE '/tmp/x' */",
+		"SELECT 1 /*M!100100 UNION SELECT * FROM b */",
+		"SELECT * FROM t /*!50000 INTO OUTFILE '/tmp/x' */",
+		"SELECT LOAD_FILE('/etc/passwd')",
+		"SELECT load_file ('/etc/passwd')",
+		"SELECT sys_exec('id')",
+		"SELECT * FROM a INTO OUTFILE '/tmp/a'",
+		"SELECT * INTO DUMPFILE '/tmp/a' FROM a",
+		"LOAD DATA INFILE '/tmp/a' INTO TABLE a",
+		"LOAD XML INFILE '/tmp/a' INTO TABLE a",
+	}
+	for _, sql := range denied {
+		if err := checkPayload(sql); err == nil {
+			t.Errorf("checkPayload(%q) = nil, want error", sql)
+		}
+	}
+}
+
+func TestCheckPayloadAllowed(t *testing.T) {
+	InitPayloadGuard(PayloadGuardConfig{AllowMultiStatements: true, AllowFileAccess: true, AllowExecutableComments: true})
+	defer InitPayloadGuard(PayloadGuardConfig{})
+
+	for _, sql := range []string{
+		"SELECT 1; SELECT 2",
+		"SELECT /*!50000 1 */",
+		"SELECT LOAD_FILE('/tmp/a')",
+		"SELECT * FROM a INTO OUTFILE '/tmp/a'",
+	} {
+		if err := checkPayload(sql); err != nil {
+			t.Errorf("checkPayload(%q) = %v, want nil", sql, err)
+		}
+	}
+}
+
+func TestMariaDBExecutableComment(t *testing.T) {
+	InitPayloadGuard(PayloadGuardConfig{AllowFileAccess: false, AllowExecutableComments: true})
+	defer InitPayloadGuard(PayloadGuardConfig{})
+
+	// 允许可执行注释时，其中的内容仍按正常语句检查
+	if err := checkPayload("SELECT * FROM t /*M! INTO OUTFILE '/tmp/x' */"); err == nil {
+		t.Error("INTO OUTFILE inside /*M! */ was not rejected")
+	}
+	if got := classifyStatement("SELECT 1 /*M! ; DELETE FROM a */"); got != "select" {
+		t.Errorf("classifyStatement = %q", got)
+	}
+	if types := classifyStatements("SELECT 1 /*M! ; DELETE FROM a */"); len(types) != 2 || types[1] != "delete" {
+		t.Errorf("classifyStatements = %v", types)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// PluginParam 描述自定义工具的一个参数
+type PluginParam struct {
+	Name        string `yaml:"name" json:"name"`
+	Type        string `yaml:"type" json:"type"` // string、number、boolean，默认 string
+	Description string `yaml:"description" json:"description,omitempty"`
+	Required    bool   `yaml:"required" json:"required,omitempty"`
+}
+
+// PluginEnv 为自定义工具提供服务端已有的能力，执行 SQL 同样受连接策略、熔断器和语句标识的约束
+type PluginEnv struct {
+	DB     *sql.DB
+	Logger *zap.SugaredLogger
+}
+
+// Execute 按服务端的执行策略执行 SQL，模板严格模式下不允许执行自由 SQL
+func (e PluginEnv) Execute(ctx context.Context, query string, args ...any) (string, error) {
+	if Templates.Strict {
+		return "", fmt.Errorf("模板严格模式下自定义工具不能执行 SQL")
+	}
+	return ExecuteWithOptions(ctx, e.DB, query, ExecOptions{Args: args})
+}
+
+// PluginHandler 自定义工具的处理函数，args 为调用方传入的参数，返回给模型的文本
+type PluginHandler func(ctx context.Context, env PluginEnv, args map[string]any) (string, error)
+
+// PluginTool 表示一个组织自定义的工具（如 create_jira_from_slow_query）
+type PluginTool struct {
+	Name        string
+	Description string
+	Params      []PluginParam
+	Handler     PluginHandler
+	// ExecutesSQL 为 true 表示工具会通过 PluginEnv.Execute 执行 SQL，模板严格模式下不注册
+	ExecutesSQL bool
+}
+
+var (
+	pluginMu    sync.Mutex
+	pluginTools []PluginTool
+)
+
+// RegisterTool 注册自定义工具，需要在服务启动注册工具之前调用（例如在 main 包的 init 中）。
+// 工具名称不能与已注册的自定义工具重复；与内置工具重名时服务启动注册工具时报错
+func RegisterTool(tool PluginTool) error {
+	if tool.Name == "" || tool.Handler == nil {
+		return fmt.Errorf("自定义工具缺少 name 或 handler")
+	}
+	pluginMu.Lock()
+	defer pluginMu.Unlock()
+	for _, t := range pluginTools {
+		if t.Name == tool.Name {
+			return fmt.Errorf("自定义工具重复: %s", tool.Name)
+		}
+	}
+	pluginTools = append(pluginTools, tool)
+	return nil
+}
+
+// RegisteredTools 返回所有已注册的自定义工具
+func RegisteredTools() []PluginTool {
+	pluginMu.Lock()
+	defer pluginMu.Unlock()
+	return append([]PluginTool(nil), pluginTools...)
+}
+
+// externalTool 为外部工具文件中的一项，调用时把参数 POST 到 URL
+type externalTool struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	URL         string        `yaml:"url"`
+	Token       string        `yaml:"token"`
+	TimeoutMs   int           `yaml:"timeout_ms"`
+	Params      []PluginParam `yaml:"params"`
+	// ReturnsSQL 为 false 时声明外部工具不会在响应中返回 SQL，未设置时视为可能返回
+	ReturnsSQL *bool `yaml:"returns_sql"`
+}
+
+// returnsSQL 判断外部工具是否可能在响应中返回需要执行的 SQL
+func (t externalTool) returnsSQL() bool {
+	return t.ReturnsSQL == nil || *t.ReturnsSQL
+}
+
+// externalToolResponse 外部工具的响应。SQL 非空时由服务端按执行策略执行，
+// 执行结果附加在 Result 之后返回，外部服务无需直接访问数据库
+type externalToolResponse struct {
+	Result string `json:"result"`
+	SQL    string `json:"sql"`
+	Error  string `json:"error"`
+}
+
+// LoadExternalTools 从 YAML 文件加载通过 HTTP 桥接的外部工具并注册：
+//
+//	tools:
+//	  - name: create_jira_from_slow_query
+//	    description: 为慢查询创建 Jira 工单
+//	    url: http://tools.internal/jira
+//	    params:
+//	      - {name: sql, type: string, required: true}
+//
+// 调用时 POST {"tool": "...", "arguments": {...}}，响应为 {"result": "...", "sql": "...", "error": "..."}
+func LoadExternalTools(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取外部工具文件失败: %v", err)
+	}
+	var file struct {
+		Tools []externalTool `yaml:"tools"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析外部工具文件失败: %v", err)
+	}
+
+	for _, t := range file.Tools {
+		if t.URL == "" {
+			return fmt.Errorf("外部工具缺少 url: %s", t.Name)
+		}
+		if err = RegisterTool(PluginTool{
+			Name:        t.Name,
+			Description: t.Description,
+			Params:      t.Params,
+			Handler:     t.call,
+			ExecutesSQL: t.returnsSQL(),
+		}); err != nil {
+			return err
+		}
+	}
+	Logger.Infow("外部工具加载完成", "path", path, "count", len(file.Tools))
+	return nil
+}
+
+// call 调用外部工具
+func (t externalTool) call(ctx context.Context, env PluginEnv, args map[string]any) (string, error) {
+	jsonData, err := json.Marshal(map[string]any{"tool": t.Name, "arguments": args})
+	if err != nil {
+		return "", fmt.Errorf("JSON 序列化失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	if t.Token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", t.Token))
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	timeout := time.Duration(t.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("请求失败，状态码: %d, 响应: %s", res.StatusCode, body)
+	}
+
+	var response externalToolResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("%s", response.Error)
+	}
+	if response.SQL == "" {
+		return response.Result, nil
+	}
+	if !t.returnsSQL() {
+		return "", fmt.Errorf("外部工具 %s 声明了 returns_sql: false，但响应中包含 SQL", t.Name)
+	}
+
+	env.Logger.Infow("执行外部工具返回的SQL", "tool", t.Name, "sql", response.SQL)
+	sqlResult, err := env.Execute(ctx, response.SQL)
+	if err != nil {
+		return "", err
+	}
+	if response.Result == "" {
+		return sqlResult, nil
+	}
+	return response.Result + "\n\n" + sqlResult, nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConnectionName 未配置连接名称时使用的名称
+const DefaultConnectionName = "default"
+
+// ConnectionPolicy 描述对一个数据库连接生效的执行策略
+type ConnectionPolicy struct {
+	// ReadOnly 为 true 时只允许执行查询类语句
+	ReadOnly bool `json:"read_only"`
+	// MaxRows 大于0时查询结果最多返回的行数
+	MaxRows int `json:"max_rows,omitempty"`
+	// AllowedStatements 非空时只允许这些类型的语句，如 select、show
+	AllowedStatements []string `json:"allowed_statements,omitempty"`
+	// DeniedStatements 禁止执行的语句类型，如 drop、truncate，优先于 AllowedStatements
+	DeniedStatements []string `json:"denied_statements,omitempty"`
+	// MaskedColumns 结果中需要脱敏的列名，不区分大小写
+	MaskedColumns []string `json:"masked_columns,omitempty"`
+}
+
+// policyOverride 为策略文件中的一段配置，未设置的字段沿用默认策略
+type policyOverride struct {
+	ReadOnly          *bool    `yaml:"read_only"`
+	MaxRows           *int     `yaml:"max_rows"`
+	AllowedStatements []string `yaml:"allowed_statements"`
+	DeniedStatements  []string `yaml:"denied_statements"`
+	MaskedColumns     []string `yaml:"masked_columns"`
+}
+
+// apply 将覆盖项合并到策略上
+func (o policyOverride) apply(p ConnectionPolicy) ConnectionPolicy {
+	if o.ReadOnly != nil {
+		p.ReadOnly = *o.ReadOnly
+	}
+	if o.MaxRows != nil {
+		p.MaxRows = *o.MaxRows
+	}
+	if o.AllowedStatements != nil {
+		p.AllowedStatements = normalizeKeywords(o.AllowedStatements)
+	}
+	if o.DeniedStatements != nil {
+		p.DeniedStatements = normalizeKeywords(o.DeniedStatements)
+	}
+	if o.MaskedColumns != nil {
+		p.MaskedColumns = o.MaskedColumns
+	}
+	return p
+}
+
+// PolicyConfig 存储各连接的执行策略
+type PolicyConfig struct {
+	// Connection 当前使用的连接名称
+	Connection  string
+	Default     ConnectionPolicy
+	Connections map[string]ConnectionPolicy
+}
+
+// 全局执行策略配置
+var Policies = PolicyConfig{Connection: DefaultConnectionName}
+
+// readOnlyMode 为 true 时所有连接都只允许执行查询语句，优先于策略文件
+var readOnlyMode bool
+
+// InitReadOnlyMode 设置全局只读模式
+func InitReadOnlyMode(enabled bool) {
+	readOnlyMode = enabled
+	if enabled {
+		Logger.Info("只读模式已开启，只允许执行 SELECT、SHOW、DESCRIBE、EXPLAIN 语句")
+	}
+}
+
+// ReadOnlyMode 返回是否开启了全局只读模式
+func ReadOnlyMode() bool {
+	return readOnlyMode
+}
+
+// statementRules 为全局的语句类型允许/禁止列表，对所有连接生效，与策略文件中的设置同时检查
+var statementRules ConnectionPolicy
+
+// InitStatementRules 设置全局允许和禁止执行的语句类型，allowed 为空时不限制
+func InitStatementRules(allowed, denied []string) {
+	statementRules = ConnectionPolicy{
+		AllowedStatements: normalizeKeywords(allowed),
+		DeniedStatements:  normalizeKeywords(denied),
+	}
+	if len(statementRules.AllowedStatements) > 0 || len(statementRules.DeniedStatements) > 0 {
+		Logger.Infow("语句类型限制已开启", "allowed", statementRules.AllowedStatements, "denied", statementRules.DeniedStatements)
+	}
+}
+
+// InitPolicies 从 YAML 文件加载执行策略，connection 为当前连接的名称。
+// defaults 对所有连接生效，connections 下按连接名称覆盖其中的部分设置：
+//
+//	defaults:
+//	  max_rows: 1000
+//	connections:
+//	  prod_replica:
+//	    read_only: true
+//	    max_rows: 200
+//	    masked_columns: [phone, id_card]
+//	  dev:
+//	    allowed_statements: [select, show, insert, update, delete]
+//	    denied_statements: [drop, truncate]
+func InitPolicies(path, connection string) error {
+	if connection == "" {
+		connection = DefaultConnectionName
+	}
+	Policies = PolicyConfig{Connection: connection, Connections: map[string]ConnectionPolicy{}}
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取执行策略文件失败: %v", err)
+	}
+	var file struct {
+		Defaults    policyOverride            `yaml:"defaults"`
+		Connections map[string]policyOverride `yaml:"connections"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析执行策略文件失败: %v", err)
+	}
+
+	Policies.Default = file.Defaults.apply(ConnectionPolicy{})
+	for name, override := range file.Connections {
+		Policies.Connections[name] = override.apply(Policies.Default)
+	}
+	if _, ok := Policies.Connections[connection]; !ok && len(file.Connections) > 0 {
+		Logger.Warnw("执行策略文件中没有当前连接的配置，使用默认策略", "connection", connection)
+	}
+	Logger.Infow("执行策略加载完成", "connection", connection, "policy", PolicyFor(connection))
+	return nil
+}
+
+// PolicyFor 返回指定连接生效的执行策略
+func PolicyFor(connection string) ConnectionPolicy {
+	if p, ok := Policies.Connections[connection]; ok {
+		return p
+	}
+	return Policies.Default
+}
+
+// activePolicy 返回当前连接生效的执行策略，全局只读模式下总是只读
+func activePolicy() ConnectionPolicy {
+	p := PolicyFor(Policies.Connection)
+	if readOnlyMode {
+		p.ReadOnly = true
+	}
+	return p
+}
+
+// checkStatement 检查语句是否被策略和发起调用的客户端角色允许执行，多条语句时逐条检查
+func (p ConnectionPolicy) checkStatement(ctx context.Context, sql string) error {
+	if err := checkHiddenObjects(sql); err != nil {
+		return err
+	}
+	if p.ReadOnly && !isQueryStatement(sql) {
+		if readOnlyMode {
+			return fmt.Errorf("服务处于只读模式（READONLY=true），拒绝执行 %s 语句，只允许不加锁的 SELECT、SHOW、DESCRIBE、EXPLAIN",
+				statementTypeName(sql))
+		}
+		return fmt.Errorf("连接 %s 为只读，只允许执行查询语句", Policies.Connection)
+	}
+	for _, statementType := range classifyStatements(sql) {
+		if err := p.checkStatementType(statementType, "连接 "+Policies.Connection); err != nil {
+			return err
+		}
+		if err := statementRules.checkStatementType(statementType, "服务配置"); err != nil {
+			return err
+		}
+	}
+	return checkRoleStatement(ctx, sql)
+}
+
+// checkStatementType 按允许和禁止列表检查一种语句类型，scope 用于错误信息中说明限制的来源
+func (p ConnectionPolicy) checkStatementType(statementType, scope string) error {
+	for _, denied := range p.DeniedStatements {
+		if statementType == denied {
+			return fmt.Errorf("%s 禁止执行 %s 语句", scope, strings.ToUpper(statementType))
+		}
+	}
+	if len(p.AllowedStatements) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedStatements {
+		if statementType == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s 不允许执行 %s 语句，允许的语句: %s",
+		scope, strings.ToUpper(statementType), strings.Join(p.AllowedStatements, ", "))
+}
+
+// masked 判断列是否需要脱敏
+func (p ConnectionPolicy) masked(column string) bool {
+	for _, c := range p.MaskedColumns {
+		if strings.EqualFold(c, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedValue 脱敏后的列值
+const maskedValue = "***"
+
+// statementKeyword 返回语句的首个关键字（小写）
+func statementKeyword(sql string) string {
+	fields := strings.Fields(strings.TrimLeft(strings.TrimSpace(sql), "("))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimRight(fields[0], ";("))
+}
+
+// normalizeKeywords 将关键字统一为小写并去掉空白
+func normalizeKeywords(keywords []string) []string {
+	normalized := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			normalized = append(normalized, k)
+		}
+	}
+	return normalized
+}
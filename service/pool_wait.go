@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// poolRetryBackoff 服务端连接数已满时首次重试的等待时间，之后每次翻倍
+	poolRetryBackoff = 100 * time.Millisecond
+	// poolRetryMaxBackoff 重试等待时间的上限
+	poolRetryMaxBackoff = time.Second
+	// queueReportThreshold 排队超过该时长时在结果中注明排队时间
+	queueReportThreshold = 100 * time.Millisecond
+)
+
+// poolWaitBudget 获取连接时最多排队等待的时长，0 表示不限制排队时间也不重试，直接使用调用方的超时
+var poolWaitBudget = 5 * time.Second
+
+// InitPoolWait 设置获取连接的排队时长
+func InitPoolWait(budget time.Duration) {
+	if budget < 0 {
+		budget = 0
+	}
+	poolWaitBudget = budget
+}
+
+// acquireConn 先按会话公平调度申请访问名额，再从连接池获取一个连接。本地连接池已满时排队等待，
+// 服务端返回连接数已满（Too many connections、max_user_connections）时退避重试，总等待时间不超过 poolWaitBudget。
+// 返回关闭连接并归还名额的函数，以及排队花费的时间
+func acquireConn(ctx context.Context, db *sql.DB) (*sql.Conn, func(), time.Duration, error) {
+	start := time.Now()
+	release, err := acquireSlot(ctx)
+	if err != nil {
+		return nil, nil, time.Since(start), err
+	}
+	conn, err := openConn(ctx, db)
+	if err != nil {
+		release()
+		return nil, nil, time.Since(start), err
+	}
+	return conn, func() {
+		conn.Close()
+		release()
+	}, time.Since(start), nil
+}
+
+// openConn 从连接池获取一个连接，按 poolWaitBudget 排队和重试
+func openConn(ctx context.Context, db *sql.DB) (*sql.Conn, error) {
+	if poolWaitBudget == 0 {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connection: %w", err)
+		}
+		return conn, nil
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, poolWaitBudget)
+	defer cancel()
+
+	backoff := poolRetryBackoff
+	for {
+		conn, err := db.Conn(waitCtx)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to get connection: %w", err)
+		}
+		if waitCtx.Err() != nil {
+			return nil, fmt.Errorf("failed to get connection: connection pool exhausted, no connection became available within %s", poolWaitBudget)
+		}
+		if !isConnectionLimit(err) {
+			return nil, fmt.Errorf("failed to get connection: %w", err)
+		}
+
+		Logger.Debugw("服务端连接数已满，等待后重试", "backoff", backoff, "error", err)
+		select {
+		case <-waitCtx.Done():
+			return nil, fmt.Errorf("failed to get connection: server connection limit reached, still failing after waiting %s: %w", poolWaitBudget, err)
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > poolRetryMaxBackoff {
+			backoff = poolRetryMaxBackoff
+		}
+	}
+}
+
+// isConnectionLimit 判断错误是否为服务端连接数已满
+func isConnectionLimit(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == 1040 || mysqlErr.Number == 1203
+}
+
+// queueNote 排队时间较长时返回附加在结果末尾的说明
+func queueNote(waited time.Duration) string {
+	if waited < queueReportThreshold {
+		return ""
+	}
+	return fmt.Sprintf("\n\nqueue_wait_ms: %d", waited.Milliseconds())
+}
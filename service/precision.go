@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/milvus-io/milvus/client/v2/column"
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/index"
+)
+
+// 向量存储精度
+const (
+	PrecisionFloat32  = "float32"  // 默认，4 字节/维
+	PrecisionFloat16  = "float16"  // 半精度存储，2 字节/维
+	PrecisionBFloat16 = "bfloat16" // bfloat16 存储，2 字节/维
+	PrecisionInt8     = "int8"     // 以 float32 存储，使用 IVF_SQ8 索引在内存中量化为 8 位
+)
+
+// VectorIndexConfig 存储表结构向量的存储精度与索引参数。
+// 表结构数量非常多时，降低精度可以显著减少 Milvus 的内存占用，代价是召回率略有下降
+type VectorIndexConfig struct {
+	Precision string
+	// IndexType 索引类型：AUTOINDEX、HNSW、IVF_FLAT、IVF_SQ8
+	IndexType string
+	// NList IVF 类索引的聚类数
+	NList int
+	// HNSWM、HNSWEfConstruction 为 HNSW 索引的构建参数
+	HNSWM              int
+	HNSWEfConstruction int
+}
+
+// 全局向量索引配置
+var VectorIndex = VectorIndexConfig{
+	Precision: PrecisionFloat32,
+	IndexType: "AUTOINDEX",
+}
+
+// InitVectorIndexConfig 校验并初始化向量索引配置
+func InitVectorIndexConfig(cfg VectorIndexConfig) error {
+	cfg.Precision = strings.ToLower(cfg.Precision)
+	if cfg.Precision == "" {
+		cfg.Precision = PrecisionFloat32
+	}
+	cfg.IndexType = strings.ToUpper(cfg.IndexType)
+	if cfg.IndexType == "" {
+		cfg.IndexType = "AUTOINDEX"
+	}
+
+	switch cfg.Precision {
+	case PrecisionFloat32, PrecisionFloat16, PrecisionBFloat16:
+	case PrecisionInt8:
+		// INT8 通过标量量化索引实现，只能与 IVF_SQ8 搭配
+		if cfg.IndexType == "AUTOINDEX" {
+			cfg.IndexType = "IVF_SQ8"
+		}
+		if cfg.IndexType != "IVF_SQ8" {
+			return fmt.Errorf("int8 精度只支持 IVF_SQ8 索引，当前为 %s", cfg.IndexType)
+		}
+	default:
+		return fmt.Errorf("不支持的向量精度: %s", cfg.Precision)
+	}
+
+	switch cfg.IndexType {
+	case "AUTOINDEX", "HNSW", "IVF_FLAT", "IVF_SQ8":
+	default:
+		return fmt.Errorf("不支持的索引类型: %s", cfg.IndexType)
+	}
+	if cfg.NList <= 0 {
+		cfg.NList = 128
+	}
+	if cfg.HNSWM <= 0 {
+		cfg.HNSWM = 16
+	}
+	if cfg.HNSWEfConstruction <= 0 {
+		cfg.HNSWEfConstruction = 200
+	}
+
+	VectorIndex = cfg
+	return nil
+}
+
+// vectorFieldType 返回表结构向量字段的数据类型
+func vectorFieldType() entity.FieldType {
+	switch VectorIndex.Precision {
+	case PrecisionFloat16:
+		return entity.FieldTypeFloat16Vector
+	case PrecisionBFloat16:
+		return entity.FieldTypeBFloat16Vector
+	default:
+		return entity.FieldTypeFloatVector
+	}
+}
+
+// isDenseVectorType 判断字段是否为需要建立向量索引的稠密向量
+func isDenseVectorType(fieldType entity.FieldType) bool {
+	switch fieldType {
+	case entity.FieldTypeFloatVector, entity.FieldTypeFloat16Vector, entity.FieldTypeBFloat16Vector:
+		return true
+	}
+	return false
+}
+
+// vectorColumn 按配置的精度构建写入用的向量列
+func vectorColumn(name string, dim int, data [][]float32) column.Column {
+	switch VectorIndex.Precision {
+	case PrecisionFloat16, PrecisionBFloat16:
+		encoded := make([][]byte, len(data))
+		for i, v := range data {
+			if VectorIndex.Precision == PrecisionFloat16 {
+				encoded[i] = entity.FloatVector(v).ToFloat16Vector()
+			} else {
+				encoded[i] = entity.FloatVector(v).ToBFloat16Vector()
+			}
+		}
+		if VectorIndex.Precision == PrecisionFloat16 {
+			return column.NewColumnFloat16Vector(name, dim, encoded)
+		}
+		return column.NewColumnBFloat16Vector(name, dim, encoded)
+	default:
+		return column.NewColumnFloatVector(name, dim, data)
+	}
+}
+
+// searchVector 将查询向量转换为与存储精度一致的类型
+func searchVector(v []float32) entity.Vector {
+	switch VectorIndex.Precision {
+	case PrecisionFloat16:
+		return entity.FloatVector(v).ToFloat16Vector()
+	case PrecisionBFloat16:
+		return entity.FloatVector(v).ToBFloat16Vector()
+	default:
+		return entity.FloatVector(v)
+	}
+}
+
+// buildVectorIndex 按配置构建向量索引
+func buildVectorIndex() index.Index {
+	switch VectorIndex.IndexType {
+	case "HNSW":
+		return index.NewHNSWIndex(entity.COSINE, VectorIndex.HNSWM, VectorIndex.HNSWEfConstruction)
+	case "IVF_FLAT":
+		return index.NewIvfFlatIndex(entity.COSINE, VectorIndex.NList)
+	case "IVF_SQ8":
+		return index.NewIvfSQ8Index(entity.COSINE, VectorIndex.NList)
+	default:
+		return index.NewAutoIndex(entity.COSINE)
+	}
+}
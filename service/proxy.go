@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 支持识别的数据库代理
+const (
+	ProxyNone     = "none"
+	ProxyProxySQL = "proxysql"
+	ProxyVitess   = "vitess" // 包括 PlanetScale
+)
+
+var (
+	// 当前连接经过的代理，影响 processlist、KILL 和表结构读取的方式；reload_connections 会重新检测，读写需持有 proxyMu
+	serverProxy = ProxyNone
+	proxyMu     sync.RWMutex
+)
+
+// currentProxy 返回当前连接经过的代理
+func currentProxy() string {
+	proxyMu.RLock()
+	defer proxyMu.RUnlock()
+	return serverProxy
+}
+
+// setProxy 记录检测到的代理
+func setProxy(proxy string) string {
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+	serverProxy = proxy
+	return proxy
+}
+
+// InitServerProxy 识别连接是否经过 ProxySQL 或 Vitess（PlanetScale）。
+// override 为 none、proxysql、vitess 时不检测，为空或 auto 时自动检测
+func InitServerProxy(ctx context.Context, db *sql.DB, override string) (string, error) {
+	switch override = strings.ToLower(override); override {
+	case ProxyNone, ProxyProxySQL, ProxyVitess:
+		return setProxy(override), nil
+	case "", "auto":
+	default:
+		return "", fmt.Errorf("不支持的代理类型: %s，可选 auto、none、proxysql、vitess", override)
+	}
+
+	// vtgate 的版本号带有 -Vitess 后缀
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT @@version").Scan(&version); err != nil {
+		return "", fmt.Errorf("查询服务端版本失败: %v", err)
+	}
+	if strings.Contains(strings.ToLower(version), "vitess") {
+		return setProxy(ProxyVitess), nil
+	}
+
+	// ProxySQL 会拦截这条语句（文本需完全一致）并返回 (ProxySQL)
+	var comment sql.NullString
+	if err := db.QueryRowContext(ctx, "select @@version_comment limit 1").Scan(&comment); err == nil &&
+		strings.Contains(strings.ToLower(comment.String), "proxysql") {
+		return setProxy(ProxyProxySQL), nil
+	}
+	return setProxy(ProxyNone), nil
+}
+
+// requireDirectConnection 经过代理时 processlist 只反映代理或单个后端/分片的连接，
+// 连接ID也与后端不一致，依赖它们的功能返回说明性的错误，而不是返回不完整的数据
+func requireDirectConnection(feature string) error {
+	switch currentProxy() {
+	case ProxyProxySQL:
+		return fmt.Errorf("连接经过 ProxySQL，%s 看到的是后端连接池的会话，连接ID与客户端不对应，请直连 MySQL 使用该功能", feature)
+	case ProxyVitess:
+		return fmt.Errorf("连接经过 Vitess/PlanetScale，vtgate 不提供各分片的 processlist，%s 不可用", feature)
+	}
+	return nil
+}
+
+// proxyStatsNote 返回代理下统计信息的注意事项
+func proxyStatsNote() string {
+	if currentProxy() == ProxyVitess {
+		return Text("proxy_vitess_stats")
+	}
+	return ""
+}
+
+// createTableFromColumns 在 SHOW CREATE TABLE 不可用时（如部分 Vitess 版本），
+// 根据 information_schema 拼出近似的建表语句，保证表结构仍能进入检索
+func createTableFromColumns(ctx context.Context, db *sql.DB, table string) (string, error) {
+	desc, err := DescribeTable(ctx, db, table)
+	if err != nil {
+		return "", err
+	}
+	if len(desc.Columns) == 0 {
+		return "", fmt.Errorf("表不存在或没有列: %s", table)
+	}
+
+	lines := make([]string, 0, len(desc.Columns)+len(desc.Indexes))
+	for _, c := range desc.Columns {
+		line := fmt.Sprintf("  %s %s", quoteIdentifier(c.Name), c.Type)
+		if !c.Nullable {
+			line += " NOT NULL"
+		}
+		if c.Default != nil {
+			line += " DEFAULT '" + strings.ReplaceAll(*c.Default, "'", "''") + "'"
+		}
+		if c.Extra != "" {
+			line += " " + c.Extra
+		}
+		if c.Comment != "" {
+			line += " COMMENT '" + strings.ReplaceAll(c.Comment, "'", "''") + "'"
+		}
+		lines = append(lines, line)
+	}
+	for _, idx := range desc.Indexes {
+		columns := make([]string, len(idx.Columns))
+		for i, col := range idx.Columns {
+			columns[i] = quoteIdentifier(col)
+		}
+		switch {
+		case idx.Name == "PRIMARY":
+			lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", strings.Join(columns, ",")))
+		case idx.Unique:
+			lines = append(lines, fmt.Sprintf("  UNIQUE KEY %s (%s)", quoteIdentifier(idx.Name), strings.Join(columns, ",")))
+		default:
+			lines = append(lines, fmt.Sprintf("  KEY %s (%s)", quoteIdentifier(idx.Name), strings.Join(columns, ",")))
+		}
+	}
+
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", quoteIdentifier(table), strings.Join(lines, ",\n"))
+	if desc.Comment != "" {
+		stmt += " COMMENT='" + strings.ReplaceAll(desc.Comment, "'", "''") + "'"
+	}
+	return stmt, nil
+}
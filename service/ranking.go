@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+var rankingTable = "table_ranking"
+
+// RankingConfig 控制检索结果的最终排序：置顶的表总会附加在结果中，
+// 权重会与相似度相乘，小于1的权重用于压低噪声表，大于1则提升核心表
+type RankingConfig struct {
+	Pinned  []string
+	Weights map[string]float64
+}
+
+// 通过环境变量配置的排序规则，管理工具写入 SQLite 的规则优先级更高
+var Ranking RankingConfig
+
+// InitRankingConfig 初始化排序配置
+func InitRankingConfig(pinned []string, weights map[string]float64) {
+	Ranking = RankingConfig{Pinned: pinned, Weights: weights}
+}
+
+// ParseTableWeights 解析 "table:weight" 以逗号分隔的权重配置
+func ParseTableWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("权重配置格式应为 table:weight: %s", item)
+		}
+		weight, err := strconv.ParseFloat(item[i+1:], 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("无效的表权重: %s", item)
+		}
+		weights[strings.TrimSpace(item[:i])] = weight
+	}
+	return weights, nil
+}
+
+// createRankingTable 创建管理工具设置的排序规则表
+func createRankingTable(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			table_name TEXT PRIMARY KEY,
+			pinned INTEGER NOT NULL DEFAULT 0,
+			weight REAL NOT NULL DEFAULT 1
+		)`, rankingTable))
+	return err
+}
+
+// SetTableRanking 设置一张表的置顶状态和权重
+func SetTableRanking(tableName string, pinned bool, weight float64) error {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return err
+	}
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	if weight < 0 {
+		return fmt.Errorf("权重不能为负数: %v", weight)
+	}
+	_, err := sqlite().Exec(fmt.Sprintf(`
+		INSERT INTO %s (table_name, pinned, weight) VALUES (?, ?, ?)
+		ON CONFLICT(table_name) DO UPDATE SET pinned = excluded.pinned, weight = excluded.weight`, rankingTable),
+		tableName, pinned, weight)
+	if err != nil {
+		return fmt.Errorf("保存排序规则失败: %v", err)
+	}
+	return nil
+}
+
+// ResetTableRanking 删除管理工具为一张表设置的排序规则，恢复为环境变量中的配置
+func ResetTableRanking(tableName string) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	if _, err := sqlite().Exec(fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", rankingTable), tableName); err != nil {
+		return fmt.Errorf("删除排序规则失败: %v", err)
+	}
+	return nil
+}
+
+// loadRanking 合并环境变量和 SQLite 中的排序规则
+func loadRanking() (map[string]bool, map[string]float64, error) {
+	pinned := make(map[string]bool, len(Ranking.Pinned))
+	for _, t := range Ranking.Pinned {
+		pinned[t] = true
+	}
+	weights := make(map[string]float64, len(Ranking.Weights))
+	for t, w := range Ranking.Weights {
+		weights[t] = w
+	}
+
+	if err := InitSQLite(); err != nil {
+		return nil, nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	rows, err := sqlite().Query(fmt.Sprintf("SELECT table_name, pinned, weight FROM %s", rankingTable))
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询排序规则失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tableName string
+			isPinned  bool
+			weight    float64
+		)
+		if err = rows.Scan(&tableName, &isPinned, &weight); err != nil {
+			return nil, nil, fmt.Errorf("扫描排序规则失败: %v", err)
+		}
+		pinned[tableName] = isPinned
+		weights[tableName] = weight
+	}
+	return pinned, weights, rows.Err()
+}
+
+// applyRanking 按权重调整命中结果的分数并重新排序，取前 limit 条；
+// 返回的 pinned 为置顶但不在结果中的表，需要调用方附加在结果之后
+func applyRanking(ctx context.Context, cli *milvusclient.Client, hits []SchemaHit, limit int) ([]SchemaHit, []SchemaHit) {
+	pinned, weights, err := loadRanking()
+	if err != nil {
+		Logger.Warnw("加载排序规则失败，使用原始排序", "error", err)
+		return truncateHits(hits, limit), nil
+	}
+
+	// 经常被查询的表略微加分，与人工设置的权重相乘
+	boosts := hotTableBoosts()
+	if len(weights) > 0 || len(boosts) > 0 {
+		for i := range hits {
+			if name, ok := tableNameFromSchema(hits[i].Schema); ok {
+				if w, ok := weights[name]; ok {
+					hits[i].Score *= float32(w)
+				}
+				if b, ok := boosts[name]; ok {
+					hits[i].Score *= float32(b)
+				}
+			}
+		}
+		sortSchemaHits(hits)
+	}
+	hits = truncateHits(hits, limit)
+
+	present := make(map[string]bool, len(hits))
+	for _, hit := range hits {
+		if name, ok := tableNameFromSchema(hit.Schema); ok {
+			present[name] = true
+		}
+	}
+	names := make([]string, 0, len(pinned))
+	for name, isPinned := range pinned {
+		if isPinned && !present[name] && !IsHiddenTable(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var pinnedHits []SchemaHit
+	for _, name := range names {
+		vectors, err := queryTableVectors(ctx, cli, name)
+		if err != nil {
+			Logger.Warnw("获取置顶表结构失败", "table", name, "error", err)
+			continue
+		}
+		if len(vectors) == 0 {
+			Logger.Warnw("置顶的表不在向量索引中", "table", name)
+			continue
+		}
+		pinnedHits = append(pinnedHits, SchemaHit{Schema: vectors[0].Schema})
+	}
+	return hits, pinnedHits
+}
+
+// truncateHits 截取前 limit 条命中结果
+func truncateHits(hits []SchemaHit, limit int) []SchemaHit {
+	if limit > 0 && len(hits) > limit {
+		return hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+// indexMutex 保证全量重建与增量更新不会同时写入索引
+var indexMutex sync.Mutex
+
+// Reindex 以蓝绿方式全量重建表结构索引：先把所有表结构写入新的集合，
+// 完成后原子地将别名 Config.CollectionName 切换到新集合，再删除旧集合，
+// 检索过程中永远不会命中构建了一半的索引
+func Reindex(ctx context.Context, db *sql.DB, cli *milvusclient.Client) (string, error) {
+	if err := requireIndexWriter(); err != nil {
+		return "", err
+	}
+	if !indexMutex.TryLock() {
+		return "", fmt.Errorf("已有索引任务在进行中")
+	}
+	defer indexMutex.Unlock()
+
+	start := time.Now()
+	newCollection := fmt.Sprintf("%s_%d", Config.CollectionName, start.Unix())
+	if err := CreateCollection(ctx, cli, newCollection); err != nil {
+		return "", fmt.Errorf("创建新集合失败: %w", err)
+	}
+
+	records, failed, err := embedAllTables(ctx, db, cli, newCollection)
+	if err != nil {
+		dropCollection(cli, newCollection)
+		return "", err
+	}
+
+	oldCollection, err := switchAlias(ctx, cli, Config.CollectionName, newCollection)
+	if err != nil {
+		dropCollection(cli, newCollection)
+		return "", fmt.Errorf("切换集合别名失败: %w", err)
+	}
+
+	if err = ReplaceIndexedTables(records); err != nil {
+		Logger.Errorw("更新索引元数据失败", "error", err)
+	}
+	if oldCollection != "" {
+		dropCollection(cli, oldCollection)
+	}
+
+	Logger.Infow("全量重建索引完成", "collection", newCollection, "tables", len(records), "failed", failed)
+	return fmt.Sprintf("Reindexed %d tables into %s in %s (%d failed); alias %s now points to it.",
+		len(records), newCollection, time.Since(start).Round(time.Second), failed, Config.CollectionName), nil
+}
+
+// embedAllTables 并发地把所有表结构向量化写入指定集合，返回写入成功的索引元数据和失败数量
+func embedAllTables(ctx context.Context, db *sql.DB, cli *milvusclient.Client, collection string) ([]IndexedTable, int, error) {
+	// 创建子上下文用于控制goroutine生命周期
+	workCtx, workCancel := context.WithCancel(ctx)
+	defer workCancel()
+
+	forgotten, err := ListForgottenTables()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	schemaChan := make(chan map[string]string, 10)
+	go streamTableSchemas(workCtx, db, schemaChan, schemaBudget)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		records []IndexedTable
+		failed  int
+	)
+
+	// 信号量控制并发数
+	semaphore := make(chan struct{}, IndexBudget.Workers)
+	for tableMap := range schemaChan {
+		for tableName, schema := range tableMap {
+			if forgotten[tableName] {
+				schemaBudget.release(int64(len(schema)))
+				continue
+			}
+			semaphore <- struct{}{}
+			wg.Add(1)
+			go func(tableName, schema string) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				defer schemaBudget.release(int64(len(schema)))
+
+				record, err := embedTable(workCtx, cli, collection, tableName, schema)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					Logger.Errorw("表结构索引失败", "table", tableName, "error", err)
+					failed++
+					return
+				}
+				records = append(records, record)
+			}(tableName, schema)
+		}
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, failed, fmt.Errorf("重建索引被取消: %w", err)
+	}
+	return records, failed, nil
+}
+
+// embedTable 将一张表的结构向量化写入指定集合
+func embedTable(ctx context.Context, cli *milvusclient.Client, collection, tableName, schema string) (IndexedTable, error) {
+	vectors, err := embedSchema(schema)
+	if err != nil {
+		return IndexedTable{}, fmt.Errorf("向量嵌入失败: %w", err)
+	}
+
+	ids, err := saveSchemaVectors(ctx, cli, collection, []string{schema}, []SchemaVectors{vectors})
+	if err != nil {
+		return IndexedTable{}, fmt.Errorf("保存向量失败: %w", err)
+	}
+
+	record := IndexedTable{
+		TableName:  tableName,
+		SchemaHash: SchemaHash(schema),
+		EmbeddedAt: time.Now(),
+	}
+	if len(ids) > 0 {
+		record.VectorID = ids[0]
+	}
+	return record, nil
+}
+
+// switchAlias 将别名指向新集合，返回之前指向的旧集合名称，由调用方在切换完成后删除。
+// 旧版本直接以别名作为集合名，而别名不能与集合同名：迁移时先把旧集合改名，创建别名后再由调用方删除，
+// 创建别名失败时改回原名，任何一步失败都不会丢失旧集合
+func switchAlias(ctx context.Context, cli *milvusclient.Client, alias, collection string) (string, error) {
+	current, err := cli.DescribeAlias(ctx, milvusclient.NewDescribeAliasOption(alias))
+	if err == nil && current != nil {
+		if err = cli.AlterAlias(ctx, milvusclient.NewAlterAliasOption(alias, collection)); err != nil {
+			return "", err
+		}
+		return current.CollectionName, nil
+	}
+
+	has, err := cli.HasCollection(ctx, milvusclient.NewHasCollectionOption(alias))
+	if err != nil {
+		return "", err
+	}
+	if !has {
+		return "", cli.CreateAlias(ctx, milvusclient.NewCreateAliasOption(collection, alias))
+	}
+
+	legacy := fmt.Sprintf("%s_legacy_%d", alias, time.Now().Unix())
+	Logger.Warnw("检测到旧版本的同名集合，改名后创建别名", "collection", alias, "renamed", legacy)
+	if err = cli.RenameCollection(ctx, milvusclient.NewRenameCollectionOption(alias, legacy)); err != nil {
+		return "", err
+	}
+	if err = cli.CreateAlias(ctx, milvusclient.NewCreateAliasOption(collection, alias)); err != nil {
+		if renameErr := cli.RenameCollection(ctx, milvusclient.NewRenameCollectionOption(legacy, alias)); renameErr != nil {
+			Logger.Errorw("恢复旧集合名称失败", "collection", legacy, "error", renameErr)
+		}
+		return "", err
+	}
+	return legacy, nil
+}
+
+// dropCollection 删除集合，失败时只记录日志
+func dropCollection(cli *milvusclient.Client, collection string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := cli.DropCollection(ctx, milvusclient.NewDropCollectionOption(collection)); err != nil {
+		Logger.Warnw("删除集合失败", "collection", collection, "error", err)
+	}
+}
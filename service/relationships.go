@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// ForeignKeyEdge 描述外键关系图中的一条边：FromTable.FromColumns 引用 ToTable.ToColumns
+type ForeignKeyEdge struct {
+	Constraint  string   `json:"constraint"`
+	FromTable   string   `json:"from_table"`
+	FromColumns []string `json:"from_columns"`
+	// ToSchema 仅在被引用的表位于其他库时设置
+	ToSchema  string   `json:"to_schema,omitempty"`
+	ToTable   string   `json:"to_table"`
+	ToColumns []string `json:"to_columns"`
+}
+
+// GetTableRelationships 从 information_schema.KEY_COLUMN_USAGE 读取外键关系。
+// table 为空时返回整个库的外键关系，否则返回该表引用其他表以及被其他表引用的关系
+func GetTableRelationships(ctx context.Context, db *sql.DB, table string) ([]ForeignKeyEdge, error) {
+	if table != "" {
+		if err := ValidateIdentifier(table); err != nil {
+			return nil, err
+		}
+		if err := checkTableVisible(table); err != nil {
+			return nil, err
+		}
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	query := `
+		SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME,
+			IF(REFERENCED_TABLE_SCHEMA = DATABASE(), '', REFERENCED_TABLE_SCHEMA),
+			REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL`
+	args := []any{}
+	if table != "" {
+		query += ` AND (TABLE_NAME = ? OR (REFERENCED_TABLE_NAME = ? AND REFERENCED_TABLE_SCHEMA = DATABASE()))`
+		args = append(args, table, table)
+	}
+	query += ` ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询外键关系失败: %v", err)
+	}
+	defer rows.Close()
+
+	edges := make([]ForeignKeyEdge, 0)
+	// 约束名只在表内唯一，按 表名+约束名 合并复合外键的多列
+	positions := make(map[string]int)
+	for rows.Next() {
+		var constraint, fromTable, fromColumn, toSchema, toTable, toColumn string
+		if err = rows.Scan(&constraint, &fromTable, &fromColumn, &toSchema, &toTable, &toColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if IsHiddenTable(fromTable) || IsHiddenTable(toTable) || IsHiddenSchema(toSchema) {
+			continue
+		}
+		key := fromTable + "." + constraint
+		i, ok := positions[key]
+		if !ok {
+			i = len(edges)
+			positions[key] = i
+			edges = append(edges, ForeignKeyEdge{
+				Constraint: constraint,
+				FromTable:  fromTable,
+				ToSchema:   toSchema,
+				ToTable:    toTable,
+			})
+		}
+		edges[i].FromColumns = append(edges[i].FromColumns, fromColumn)
+		edges[i].ToColumns = append(edges[i].ToColumns, toColumn)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询外键关系失败: %v", err)
+	}
+	return edges, nil
+}
+
+// FormatTableRelationships 将外键关系序列化为 JSON
+func FormatTableRelationships(edges []ForeignKeyEdge) (string, error) {
+	data, err := json.MarshalIndent(map[string]any{"edges": edges}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal relationships to JSON: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Reranker 对检索得到的候选表结构重新打分。
+// 实现方接收原始查询和候选列表，返回重新打分后的结果，可以丢弃不相关的候选；
+// 返回结果会按分数降序排列后截取所需数量
+type Reranker interface {
+	Rerank(ctx context.Context, query string, hits []SchemaHit) ([]SchemaHit, error)
+}
+
+// 当前生效的重排序实现，为 nil 时保持向量相似度排序
+var reranker Reranker
+
+// SetReranker 设置重排序实现，传入 nil 时关闭重排序
+func SetReranker(r Reranker) {
+	reranker = r
+}
+
+// rerankCandidateLimit 启用重排序时从向量库召回的候选数量
+const rerankCandidateLimit = 20
+
+// rerankHits 调用重排序实现并截取前 limit 条，失败时退化为原始排序
+func rerankHits(ctx context.Context, query string, hits []SchemaHit, limit int) []SchemaHit {
+	if reranker == nil || len(hits) == 0 {
+		return hits
+	}
+
+	reranked, err := reranker.Rerank(ctx, query, hits)
+	if err != nil {
+		Logger.Warnw("重排序失败，使用向量相似度排序", "query", query, "error", err)
+		return hits
+	}
+	sortSchemaHits(reranked)
+	if limit > 0 && len(reranked) > limit {
+		reranked = reranked[:limit]
+	}
+	return reranked
+}
+
+// HTTPReranker 通过外部 HTTP 服务重排序。
+// 请求体为 {"query": "...", "candidates": [{"schema": "...", "score": 0.8}]}，
+// 响应体为 {"results": [{"index": 0, "score": 0.95}]}，index 对应候选在请求中的下标，
+// 未出现在响应中的候选会被丢弃
+type HTTPReranker struct {
+	URL     string
+	Token   string
+	Timeout time.Duration
+}
+
+type rerankRequest struct {
+	Query      string      `json:"query"`
+	Candidates []SchemaHit `json:"candidates"`
+}
+
+type rerankResponse struct {
+	Results []struct {
+		Index int     `json:"index"`
+		Score float32 `json:"score"`
+	} `json:"results"`
+}
+
+// Rerank 实现 Reranker 接口
+func (r *HTTPReranker) Rerank(ctx context.Context, query string, hits []SchemaHit) ([]SchemaHit, error) {
+	jsonData, err := json.Marshal(rerankRequest{Query: query, Candidates: hits})
+	if err != nil {
+		return nil, fmt.Errorf("JSON 序列化失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.URL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if r.Token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", r.Token))
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求失败，状态码: %d, 响应: %s", res.StatusCode, body)
+	}
+
+	var response rerankResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	reranked := make([]SchemaHit, 0, len(response.Results))
+	seen := make(map[int]bool, len(response.Results))
+	for _, result := range response.Results {
+		if result.Index < 0 || result.Index >= len(hits) {
+			return nil, fmt.Errorf("响应中的候选下标越界: %d", result.Index)
+		}
+		if seen[result.Index] {
+			continue
+		}
+		seen[result.Index] = true
+		reranked = append(reranked, SchemaHit{Schema: hits[result.Index].Schema, Score: result.Score})
+	}
+	return reranked, nil
+}
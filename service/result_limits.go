@@ -0,0 +1,111 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// maxOmittedCount 截断后继续统计剩余行数的上限，超过后只报告下限。统计时每一行仍要从服务端传输过来，
+// 上限保持较小，避免截断后的查询仍然读完超大的结果集
+const maxOmittedCount = 1000
+
+// ResultLimitConfig 控制单次查询返回给模型的结果大小，防止超大结果耗尽内存或撑满客户端上下文
+type ResultLimitConfig struct {
+	// MaxRows 大于0时最多返回的行数
+	MaxRows int
+	// MaxBytes 大于0时返回的 JSON 结果最多的字节数
+	MaxBytes int
+}
+
+// 全局结果大小限制
+var ResultLimits ResultLimitConfig
+
+// InitResultLimits 设置查询结果的行数和字节数上限，不大于0的项不限制
+func InitResultLimits(cfg ResultLimitConfig) {
+	ResultLimits = cfg
+	Logger.Infow("查询结果大小限制", "max_rows", cfg.MaxRows, "max_bytes", cfg.MaxBytes)
+}
+
+// ResultTruncation 为附加在截断结果末尾的截断标记，说明触发的上限以及被截掉的部分
+type ResultTruncation struct {
+	Limit         string `json:"limit"`
+	Max           int    `json:"max"`
+	ReturnedRows  int    `json:"returned_rows"`
+	ReturnedBytes int    `json:"returned_bytes"`
+	OmittedRows   int64  `json:"omitted_rows"`
+	// OmittedAtLeast 为 true 时剩余行数超过统计上限，OmittedRows 只是下限
+	OmittedAtLeast bool `json:"omitted_rows_at_least,omitempty"`
+}
+
+// resultLimiter 在读取结果集时累计行数和紧凑 JSON 的字节数，超出上限时记录截断原因
+type resultLimiter struct {
+	cfg   ResultLimitConfig
+	bytes int
+	// truncation 非 nil 时结果已被截断
+	truncation *ResultTruncation
+}
+
+// newResultLimiter 按当前配置创建结果大小限制
+func newResultLimiter() *resultLimiter {
+	return &resultLimiter{cfg: ResultLimits, bytes: 2} // 数组括号
+}
+
+// rowsFull 在已读取 count 行时判断是否达到行数上限
+func (l *resultLimiter) rowsFull(count int) bool {
+	if l.cfg.MaxRows <= 0 || count < l.cfg.MaxRows {
+		return false
+	}
+	l.truncation = &ResultTruncation{Limit: "MAX_RESULT_ROWS", Max: l.cfg.MaxRows, ReturnedRows: count, ReturnedBytes: l.bytes}
+	return true
+}
+
+// fits 判断加入 row 后是否仍在字节数上限之内，放不下时记录截断，该行计入被截掉的行
+func (l *resultLimiter) fits(count int, row map[string]interface{}) bool {
+	if l.cfg.MaxBytes <= 0 {
+		return true
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		// 序列化失败留给后续的结果序列化报告
+		return true
+	}
+	size := len(data)
+	if count > 0 {
+		size++ // 行之间的逗号
+	}
+	if l.bytes+size > l.cfg.MaxBytes {
+		l.truncation = &ResultTruncation{Limit: "MAX_RESULT_BYTES", Max: l.cfg.MaxBytes, ReturnedRows: count, ReturnedBytes: l.bytes, OmittedRows: 1}
+		return false
+	}
+	l.bytes += size
+	return true
+}
+
+// countOmitted 截断后继续遍历（不读取列值）统计剩余的行数，最多统计 maxOmittedCount 行
+func (l *resultLimiter) countOmitted(rows *sql.Rows) {
+	for l.truncation.OmittedRows < maxOmittedCount && rows.Next() {
+		l.truncation.OmittedRows++
+	}
+	if l.truncation.OmittedRows >= maxOmittedCount {
+		l.truncation.OmittedAtLeast = true
+	}
+}
+
+// note 返回附加在结果末尾的截断说明和 JSON 格式的截断标记
+func (l *resultLimiter) note() string {
+	t := l.truncation
+	omitted := fmt.Sprintf("%d", t.OmittedRows)
+	if t.OmittedAtLeast {
+		omitted = Text("at_least", omitted)
+	}
+	unit := Text("unit_rows")
+	if t.Limit == "MAX_RESULT_BYTES" {
+		unit = Text("unit_bytes")
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\n%s\n\ntruncated: %s", Text("truncated_limit", t.Limit, t.Max, unit, t.ReturnedRows, omitted), data)
+}
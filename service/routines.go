@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RoutineParam 存储过程或函数的一个参数
+type RoutineParam struct {
+	Mode string `json:"mode,omitempty"` // IN、OUT、INOUT，函数参数为空
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Routine 描述一个存储过程或函数
+type Routine struct {
+	Name          string         `json:"name"`
+	Type          string         `json:"type"` // PROCEDURE 或 FUNCTION
+	Signature     string         `json:"signature"`
+	Params        []RoutineParam `json:"params"`
+	Returns       string         `json:"returns,omitempty"`
+	Deterministic bool           `json:"deterministic"`
+	DataAccess    string         `json:"data_access"`
+	Security      string         `json:"security"`
+	Comment       string         `json:"comment,omitempty"`
+	// Body 只在请求时返回；没有权限查看定义时为空
+	Body string `json:"body,omitempty"`
+}
+
+// ListRoutines 从 information_schema.ROUTINES 和 PARAMETERS 读取当前库的存储过程和函数及参数签名。
+// name 非空时只返回该名称的例程，includeBody 为 true 时同时返回例程体
+func ListRoutines(ctx context.Context, db *sql.DB, name string, includeBody bool) ([]Routine, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	query := `SELECT ROUTINE_NAME, ROUTINE_TYPE, IS_DETERMINISTIC = 'YES', SQL_DATA_ACCESS, SECURITY_TYPE,
+			COALESCE(ROUTINE_COMMENT, ''), COALESCE(ROUTINE_DEFINITION, '')
+		FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = DATABASE()`
+	var args []any
+	if name != "" {
+		query += " AND ROUTINE_NAME = ?"
+		args = append(args, name)
+	}
+	query += " ORDER BY ROUTINE_TYPE, ROUTINE_NAME"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询存储过程和函数失败: %v", err)
+	}
+	routines := make([]Routine, 0)
+	index := make(map[string]int)
+	for rows.Next() {
+		var (
+			r    Routine
+			body string
+		)
+		if err = rows.Scan(&r.Name, &r.Type, &r.Deterministic, &r.DataAccess, &r.Security, &r.Comment, &body); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if includeBody {
+			r.Body = body
+		}
+		r.Params = make([]RoutineParam, 0)
+		index[r.Type+"."+r.Name] = len(routines)
+		routines = append(routines, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询存储过程和函数失败: %v", err)
+	}
+	if len(routines) == 0 {
+		if name != "" {
+			return nil, fmt.Errorf("存储过程或函数不存在: %s", name)
+		}
+		return routines, nil
+	}
+
+	// ORDINAL_POSITION 为0的行是函数的返回值类型
+	query = `SELECT SPECIFIC_NAME, ROUTINE_TYPE, ORDINAL_POSITION, COALESCE(PARAMETER_MODE, ''),
+			COALESCE(PARAMETER_NAME, ''), DTD_IDENTIFIER
+		FROM information_schema.PARAMETERS WHERE SPECIFIC_SCHEMA = DATABASE()`
+	if name != "" {
+		query += " AND SPECIFIC_NAME = ?"
+	}
+	query += " ORDER BY SPECIFIC_NAME, ORDINAL_POSITION"
+	rows, err = db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询例程参数失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			specific, routineType string
+			position              int
+			p                     RoutineParam
+		)
+		if err = rows.Scan(&specific, &routineType, &position, &p.Mode, &p.Name, &p.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		i, ok := index[routineType+"."+specific]
+		if !ok {
+			continue
+		}
+		if position == 0 {
+			routines[i].Returns = p.Type
+			continue
+		}
+		routines[i].Params = append(routines[i].Params, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询例程参数失败: %v", err)
+	}
+
+	for i := range routines {
+		routines[i].Signature = routineSignature(routines[i])
+	}
+	return routines, nil
+}
+
+// routineSignature 生成形如 name(IN a INT, OUT b VARCHAR(10)) 或 name(a INT) RETURNS INT 的签名
+func routineSignature(r Routine) string {
+	params := make([]string, len(r.Params))
+	for i, p := range r.Params {
+		params[i] = strings.TrimSpace(strings.Join([]string{p.Mode, p.Name, p.Type}, " "))
+	}
+	signature := fmt.Sprintf("%s(%s)", r.Name, strings.Join(params, ", "))
+	if r.Returns != "" {
+		signature += " RETURNS " + r.Returns
+	}
+	return signature
+}
+
+// FormatRoutines 将存储过程和函数列表序列化为 JSON
+func FormatRoutines(routines []Routine) (string, error) {
+	data, err := json.MarshalIndent(routines, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal routines to JSON: %v", err)
+	}
+	return string(data), nil
+}
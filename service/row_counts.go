@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultExactCountTimeout 精确计数的默认超时
+	defaultExactCountTimeout = 10 * time.Second
+	// maxExactCountTimeout 精确计数允许的最长超时
+	maxExactCountTimeout = 60 * time.Second
+)
+
+// RowCount 一张表的行数，Estimate 来自 information_schema.TABLES，Exact 只在精确计数成功时返回
+type RowCount struct {
+	Table    string `json:"table"`
+	Estimate int64  `json:"rows_estimate"`
+	Exact    *int64 `json:"exact_count,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// RowCountReport 行数统计结果
+type RowCountReport struct {
+	Approximate bool       `json:"approximate"`
+	TotalRows   int64      `json:"total_rows_estimate"`
+	Tables      []RowCount `json:"tables"`
+}
+
+// EstimateRowCounts 返回所有表的估算行数，按行数从多到少排序；指定 table 时只返回该表。
+// exact 为 true 时对指定的表执行 COUNT(*)，超过 timeout 后终止计数并只返回估算值
+func EstimateRowCounts(ctx context.Context, db *sql.DB, table string, exact bool, timeout time.Duration) (*RowCountReport, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	if exact && table == "" {
+		return nil, fmt.Errorf("精确计数需要指定 table")
+	}
+	if table != "" {
+		if err := ValidateIdentifier(table); err != nil {
+			return nil, err
+		}
+		if err := checkTableVisible(table); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'`
+	var args []any
+	if table != "" {
+		query += " AND TABLE_NAME = ?"
+		args = append(args, table)
+	}
+	query += " ORDER BY TABLE_ROWS DESC, TABLE_NAME"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询表行数失败: %v", err)
+	}
+	report := &RowCountReport{Approximate: true, Tables: make([]RowCount, 0)}
+	for rows.Next() {
+		var c RowCount
+		if err = rows.Scan(&c.Table, &c.Estimate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if IsHiddenTable(c.Table) {
+			continue
+		}
+		report.TotalRows += c.Estimate
+		report.Tables = append(report.Tables, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表行数失败: %v", err)
+	}
+	if table != "" && len(report.Tables) == 0 {
+		return nil, fmt.Errorf("表不存在: %s", table)
+	}
+
+	if exact {
+		if timeout <= 0 {
+			timeout = defaultExactCountTimeout
+		}
+		if timeout > maxExactCountTimeout {
+			timeout = maxExactCountTimeout
+		}
+		c := &report.Tables[0]
+		count, err := exactRowCount(ctx, db, table, timeout)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			c.Note = fmt.Sprintf("COUNT(*) did not finish within %s; only the estimate is returned", timeout)
+		case err != nil:
+			return nil, err
+		default:
+			c.Exact = &count
+			report.Approximate = false
+		}
+	}
+	return report, nil
+}
+
+// exactRowCount 在超时时间内对表执行 COUNT(*)，超时后终止服务端仍在执行的计数
+func exactRowCount(ctx context.Context, db *sql.DB, table string, timeout time.Duration) (int64, error) {
+	countCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := db.Conn(countCtx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	id := connectionID(countCtx, conn)
+	var count int64
+	err = conn.QueryRowContext(countCtx, labelStatement(ctx, "SELECT COUNT(*) FROM "+quoteIdentifier(table))).Scan(&count)
+	if err != nil {
+		killTimedOut(countCtx, db, id)
+		if countCtx.Err() != nil {
+			return 0, countCtx.Err()
+		}
+		return 0, fmt.Errorf("COUNT(*) 执行失败: %v", err)
+	}
+	return count, nil
+}
+
+// FormatRowCounts 将行数统计序列化为 JSON
+func FormatRowCounts(report *RowCountReport) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal row counts to JSON: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// safeDDL 为 true 时 DROP、TRUNCATE、ALTER 需要在 confirm 参数中输入目标对象名才会执行
+var safeDDL bool
+
+// InitSafeDDL 开启或关闭破坏性 DDL 的输入确认
+func InitSafeDDL(enabled bool) {
+	safeDDL = enabled
+	if enabled {
+		Logger.Info("DDL 安全模式已开启，DROP、TRUNCATE、ALTER 需要输入目标对象名确认")
+	}
+}
+
+// safeDDLStatements 安全模式下需要确认的语句类型
+var safeDDLStatements = map[string]bool{"drop": true, "truncate": true, "alter": true}
+
+// ddlModifiers 语句关键字与对象类型之间可能出现的修饰词
+var ddlModifiers = map[string]bool{"temporary": true, "online": true, "offline": true, "ignore": true, "undefined": true}
+
+// ddlObjectKinds DROP、ALTER 之后的对象类型
+var ddlObjectKinds = map[string]bool{
+	"table": true, "tables": true, "view": true, "database": true, "schema": true, "event": true,
+	"procedure": true, "function": true, "trigger": true, "tablespace": true, "user": true, "role": true,
+}
+
+// checkTypedConfirmation 安全模式下检查 DROP、TRUNCATE、ALTER 语句的每个目标对象都在 confirm 中（逗号分隔，不区分大小写，
+// 带库名的对象也可以只写对象名），与 gh、kubectl 删除资源前要求输入名称的做法一致
+func checkTypedConfirmation(sql, confirm string) error {
+	if !safeDDL {
+		return nil
+	}
+	var targets []string
+	var statement string
+	for _, tokens := range statementTokens(sql) {
+		if len(tokens) == 0 || tokens[0].kind != tokenWord || !safeDDLStatements[tokens[0].text] {
+			continue
+		}
+		statement = tokens[0].text
+		targets = append(targets, ddlTargets(tokens)...)
+	}
+	if statement == "" {
+		return nil
+	}
+
+	confirmed := make(map[string]bool)
+	for _, name := range strings.Split(confirm, ",") {
+		if name = strings.ToLower(strings.Trim(strings.TrimSpace(name), "`")); name != "" {
+			confirmed[name] = true
+		}
+	}
+	var missing []string
+	for _, target := range targets {
+		short := target[strings.LastIndex(target, ".")+1:]
+		if !confirmed[strings.ToLower(target)] && !confirmed[strings.ToLower(short)] {
+			missing = append(missing, short)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	// 只有 execute_sql 接受 confirm 参数，batch_execute、sandbox_execute 等工具传来的语句在这里总是被拒绝，
+	// 错误中说明改用 execute_sql，避免模型在原工具上反复重试
+	if confirm == "" {
+		return fmt.Errorf("DDL 安全模式：%s 需要确认目标对象，只能通过 execute_sql 执行（其他工具没有 confirm 参数）。"+
+			"请先向用户说明影响并征得同意，再在 execute_sql 的 confirm 参数中传入 %q",
+			strings.ToUpper(statement), strings.Join(missing, ","))
+	}
+	return fmt.Errorf("DDL 安全模式：execute_sql 的 confirm 参数 %q 与语句的目标对象不符，需要 %q", confirm, strings.Join(missing, ","))
+}
+
+// statementTokens 按分号拆分语句，返回每条语句顶层（不在括号内）的词法单元，跳过可执行注释的标记
+func statementTokens(sql string) [][]sqlToken {
+	var statements [][]sqlToken
+	for _, t := range lexSQL(sql) {
+		for len(statements) <= t.stmt {
+			statements = append(statements, nil)
+		}
+		if t.depth == 0 && t.text != ";" && t.text != "/*!" {
+			statements[t.stmt] = append(statements[t.stmt], t)
+		}
+	}
+	return statements
+}
+
+// ddlTargets 返回 DROP、TRUNCATE、ALTER 语句的目标对象名，带库名时保留 db.name 形式：
+// DROP INDEX idx ON t 的目标为表 t；无法识别对象名时（如 ALTER INSTANCE）返回对象类型
+func ddlTargets(tokens []sqlToken) []string {
+	name := func(i int) bool {
+		return i < len(tokens) && (tokens[i].kind == tokenWord || tokens[i].kind == tokenIdent)
+	}
+	i := 1
+	for name(i) && tokens[i].kind == tokenWord && ddlModifiers[tokens[i].text] {
+		i++
+	}
+	if !name(i) {
+		return []string{tokens[0].text}
+	}
+	if tokens[i].kind == tokenWord && tokens[i].text == "index" {
+		// DROP INDEX idx ON t 以表作为确认对象
+		for ; i < len(tokens) && !(tokens[i].kind == tokenWord && tokens[i].text == "on"); i++ {
+		}
+		i++
+	} else if tokens[i].kind == tokenWord && ddlObjectKinds[tokens[i].text] {
+		i++
+	}
+	if name(i) && tokens[i].kind == tokenWord && tokens[i].text == "if" {
+		for name(i) && tokens[i].kind == tokenWord && (tokens[i].text == "if" || tokens[i].text == "not" || tokens[i].text == "exists") {
+			i++
+		}
+	}
+
+	var targets []string
+	for name(i) {
+		target := tokens[i].text
+		for i+2 < len(tokens) && tokens[i+1].text == "." && name(i+2) {
+			target += "." + tokens[i+2].text
+			i += 2
+		}
+		targets = append(targets, target)
+		// ALTER、TRUNCATE 只有一个目标，DROP TABLE a, b 可以有多个
+		if tokens[0].text != "drop" || i+2 >= len(tokens) || tokens[i+1].text != "," {
+			break
+		}
+		i += 2
+	}
+	if len(targets) == 0 {
+		return []string{tokens[min(1, len(tokens)-1)].text}
+	}
+	return targets
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckTypedConfirmation(t *testing.T) {
+	InitSafeDDL(true)
+	defer InitSafeDDL(false)
+
+	cases := []struct {
+		sql, confirm string
+		ok           bool
+	}{
+		{"SELECT * FROM orders", "", true},
+		{"DELETE FROM orders WHERE id = 1", "", true},
+		{"CREATE TABLE t (id INT)", "", true},
+
+		{"DROP TABLE orders", "", false},
+		{"DROP TABLE orders", "orders", true},
+		{"DROP TABLE orders", "ORDERS", true},
+		{"DROP TABLE orders", "`orders`", true},
+		{"DROP TABLE orders", "order", false},
+		{"DROP TABLE IF EXISTS shop.orders", "orders", true},
+		{"DROP TABLE IF EXISTS shop.orders", "shop.orders", true},
+		{"DROP TEMPORARY TABLE IF EXISTS tmp", "tmp", true},
+		{"DROP TABLE a, b", "a", false},
+		{"DROP TABLE a, b", "a, b", true},
+		{"DROP TABLE `my table`", "my table", true},
+		{"DROP INDEX idx_user ON users", "idx_user", false},
+		{"DROP INDEX idx_user ON users", "users", true},
+		{"TRUNCATE TABLE logs", "logs", true},
+		{"TRUNCATE logs", "logs", true},
+		{"TRUNCATE logs", "log", false},
+		{"ALTER TABLE users ADD COLUMN age INT", "", false},
+		{"ALTER TABLE users ADD COLUMN age INT", "users", true},
+		{"ALTER ONLINE TABLE users ADD INDEX (age)", "users", true},
+		{"DROP DATABASE shop", "shop", true},
+		{"ALTER INSTANCE ROTATE INNODB MASTER KEY", "", false},
+		{"ALTER INSTANCE ROTATE INNODB MASTER KEY", "instance", true},
+		{"SELECT 1; DROP TABLE orders", "", false},
+		{"SELECT 'DROP TABLE orders'", "", true},
+		{"/* DROP TABLE orders */ SELECT 1", "", true},
+	}
+	for _, c := range cases {
+		err := checkTypedConfirmation(c.sql, c.confirm)
+		if (err == nil) != c.ok {
+			t.Errorf("checkTypedConfirmation(%q, %q) = %v, want ok=%v", c.sql, c.confirm, err, c.ok)
+		}
+		// 其他工具没有 confirm 参数，错误需要指明改用 execute_sql
+		if err != nil && !strings.Contains(err.Error(), "execute_sql") {
+			t.Errorf("checkTypedConfirmation(%q, %q) = %v, want a hint to use execute_sql", c.sql, c.confirm, err)
+		}
+	}
+}
+
+func TestCheckTypedConfirmationDisabled(t *testing.T) {
+	InitSafeDDL(false)
+	if err := checkTypedConfirmation("DROP TABLE orders", ""); err != nil {
+		t.Errorf("safe mode disabled: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxSampleRows 单次最多返回的样本行数
+	maxSampleRows = 100
+	// defaultSampleSeed 未指定随机种子时使用的默认值，保证同样的数据得到同样的样本
+	defaultSampleSeed = 42
+)
+
+// sampleEndKeywords 出现在顶层时标志着 FROM ... WHERE 部分结束的关键字
+var sampleEndKeywords = []string{"group by", "having", "order by", "limit", "window", "for update", "lock in share mode"}
+
+// deriveSampleQuery 从查询中提取顶层的 FROM ... WHERE 部分，生成对底层行的确定性抽样语句。
+// 抽样使用 ORDER BY RAND(seed)，数据不变时结果稳定，但需要扫描全部匹配行
+func deriveSampleQuery(query string, rows int, seed int64) (string, error) {
+	text := strings.TrimRight(strings.TrimSpace(query), ";")
+	lower := strings.ToLower(text)
+	if !strings.HasPrefix(lower, "select") {
+		return "", fmt.Errorf("行抽样仅支持 SELECT 语句")
+	}
+
+	from, end := -1, len(text)
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			continue
+		case '(':
+			depth++
+			continue
+		case ')':
+			depth--
+			continue
+		}
+		if depth != 0 || (i > 0 && isIdentPart(text[i-1])) {
+			continue
+		}
+		if from < 0 {
+			if hasKeywordAt(lower, i, "from") {
+				from = i
+			}
+			continue
+		}
+		if hasKeywordAt(lower, i, "union") {
+			return "", fmt.Errorf("行抽样不支持 UNION 查询")
+		}
+		for _, kw := range sampleEndKeywords {
+			if hasKeywordAt(lower, i, kw) {
+				end = i
+				break
+			}
+		}
+		if end != len(text) {
+			break
+		}
+	}
+	if from < 0 {
+		return "", fmt.Errorf("查询中没有 FROM 子句，无法抽样")
+	}
+
+	if rows > maxSampleRows {
+		rows = maxSampleRows
+	}
+	return fmt.Sprintf("SELECT * %s ORDER BY RAND(%d) LIMIT %d", strings.TrimSpace(text[from:end]), seed, rows), nil
+}
+
+// hasKeywordAt 判断 lower[i:] 是否以完整的关键字开头，关键字中的空格可以匹配任意空白
+func hasKeywordAt(lower string, i int, keyword string) bool {
+	for _, word := range strings.Split(keyword, " ") {
+		if !strings.HasPrefix(lower[i:], word) {
+			return false
+		}
+		i += len(word)
+		if i < len(lower) && isIdentPart(lower[i]) {
+			return false
+		}
+		for i < len(lower) && (lower[i] == ' ' || lower[i] == '\t' || lower[i] == '\n' || lower[i] == '\r') {
+			i++
+		}
+	}
+	return true
+}
+
+// appendRowSample 在同一会话中执行抽样语句，把样本附加在结果之后；抽样失败不影响主查询结果
+func appendRowSample(ctx context.Context, conn sqlExecutor, query, res string, opts ExecOptions) string {
+	seed := opts.SampleSeed
+	if seed == 0 {
+		seed = defaultSampleSeed
+	}
+	sampleSQL, err := deriveSampleQuery(query, opts.SampleRows, seed)
+	if err != nil {
+		return res + "\n\n" + Text("row_sample_unavailable", err)
+	}
+
+	sample, err := runStatement(ctx, conn, sampleSQL, ExecOptions{MaxTokens: opts.MaxTokens, Raw: true})
+	if err != nil {
+		Logger.Warnw("行抽样失败", "sql", sampleSQL, "error", err)
+		return res + "\n\n" + Text("row_sample_unavailable", err)
+	}
+	return res + fmt.Sprintf("\n\n%s\n%s\n%s", Text("row_sample", seed), sampleSQL, sample)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultSandboxSampleSize = 100
+	maxSandboxSampleSize     = 1000
+)
+
+// sandboxStatements 沙箱中允许执行的语句类型（按 classifyStatements 解析，WITH 按主语句判断）。DDL 会隐式提交事务，因此不允许
+var sandboxStatements = map[string]bool{"select": true, "insert": true, "update": true, "delete": true, "replace": true}
+
+// SandboxExecute 将目标表的少量样本复制到同名临时表中，再在临时表上试运行语句。
+// 同名临时表会在当前会话中遮蔽真实表，语句本身无需改写；语句在事务中执行并最终回滚，
+// 即便引用了未复制的表也不会修改真实数据。
+func SandboxExecute(ctx context.Context, db *sql.DB, query string, tables []string, sampleSize int) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if len(tables) == 0 {
+		return "", fmt.Errorf("沙箱执行至少需要指定一张目标表")
+	}
+	if err := validateIdentifiers(tables...); err != nil {
+		return "", err
+	}
+	for _, table := range tables {
+		if err := checkTableVisible(table); err != nil {
+			return "", err
+		}
+	}
+
+	types := classifyStatements(query)
+	if len(types) == 0 {
+		return "", fmt.Errorf("query is empty")
+	}
+	for _, t := range types {
+		if !sandboxStatements[t] {
+			return "", fmt.Errorf("沙箱仅支持 SELECT/INSERT/UPDATE/DELETE/REPLACE 语句，不支持 %s", strings.ToUpper(t))
+		}
+	}
+
+	if sampleSize <= 0 {
+		sampleSize = defaultSandboxSampleSize
+	}
+	if sampleSize > maxSandboxSampleSize {
+		sampleSize = maxSandboxSampleSize
+	}
+
+	return withBreaker(func() (string, error) {
+		return sandboxRun(ctx, db, query, tables, sampleSize)
+	})
+}
+
+// sandboxRun 在固定连接上创建样本临时表并在回滚的事务中执行语句
+func sandboxRun(ctx context.Context, db *sql.DB, query string, tables []string, sampleSize int) (string, error) {
+	// 临时表只在当前会话可见，必须固定连接
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	// 非事务引擎的表（如 MyISAM）不会随事务回滚，语句引用的表和目标表都必须是 InnoDB
+	refs := tableReferences(query)
+	for _, table := range tables {
+		refs = append(refs, tableRef{name: table})
+	}
+	if err = checkSandboxEngines(ctx, conn, refs); err != nil {
+		return "", err
+	}
+
+	created := make([]string, 0, len(tables))
+	defer func() {
+		// 使用独立的上下文清理，避免调用方超时后临时表残留在连接池中
+		for _, table := range created {
+			if _, err := conn.ExecContext(context.Background(), "DROP TEMPORARY TABLE IF EXISTS "+quoteIdentifier(table)); err != nil {
+				Logger.Warnw("删除沙箱临时表失败", "table", table, "error", err)
+			}
+		}
+	}()
+
+	for _, table := range tables {
+		stmt := fmt.Sprintf("CREATE TEMPORARY TABLE %s ENGINE=InnoDB SELECT * FROM %s LIMIT %d",
+			quoteIdentifier(table), quoteIdentifier(table), sampleSize)
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return "", fmt.Errorf("创建沙箱临时表 %s 失败: %v", table, err)
+		}
+		created = append(created, table)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("开启沙箱事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := runStatement(ctx, tx, query, ExecOptions{})
+	if err != nil {
+		return "", fmt.Errorf("沙箱执行失败: %v", err)
+	}
+
+	Logger.Infow("沙箱执行完成", "tables", tables, "sampleSize", sampleSize)
+	return fmt.Sprintf("%s\n%s\n\n%s", Text("sandbox_result", sampleSize), res, Text("sandbox_review")), nil
+}
+
+// checkSandboxEngines 检查表的存储引擎都支持事务，不存在的表留给语句执行时报错，视图没有存储引擎
+func checkSandboxEngines(ctx context.Context, conn *sql.Conn, refs []tableRef) error {
+	for _, ref := range refs {
+		var engine sql.NullString
+		err := conn.QueryRowContext(ctx, `
+			SELECT ENGINE FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?`, ref.schema, ref.name).Scan(&engine)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("查询表 %s 的存储引擎失败: %v", ref.name, err)
+		}
+		if engine.Valid && !strings.EqualFold(engine.String, "InnoDB") {
+			return fmt.Errorf("表 %s 的存储引擎为 %s，修改不会随事务回滚，沙箱只支持 InnoDB 表", ref.name, engine.String)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// SchedulerConfig 控制定时增量索引的节奏
+type SchedulerConfig struct {
+	// Interval 两次增量索引之间的基础间隔
+	Interval time.Duration
+	// Jitter 每次在基础间隔上额外等待 [0, Jitter) 的随机时长，
+	// 避免多个实例同时启动后在同一时刻重复向量化
+	Jitter time.Duration
+	// LockName 为空时不使用 MySQL 咨询锁；否则只有拿到该锁的实例执行本轮增量索引
+	LockName string
+}
+
+// 全局定时任务配置
+var Scheduler = SchedulerConfig{Interval: 5 * time.Minute}
+
+// InitSchedulerConfig 初始化定时任务配置
+func InitSchedulerConfig(cfg SchedulerConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.Jitter < 0 {
+		cfg.Jitter = 0
+	}
+	Scheduler = cfg
+}
+
+// nextDelay 返回距离下一次执行的等待时长
+func (c SchedulerConfig) nextDelay() time.Duration {
+	return c.Interval + c.jitter()
+}
+
+// jitter 返回 [0, Jitter) 的随机时长
+func (c SchedulerConfig) jitter() time.Duration {
+	if c.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(c.Jitter)))
+}
+
+// runPeriodic 每隔 interval 加上随机抖动执行一次 task，启动后先等待一段抖动再执行第一轮，ctx 取消后返回。
+// 与增量索引一样只由持有索引租约的实例执行，配置了咨询锁时多个实例中只有拿到锁的一个执行本轮
+func runPeriodic(ctx context.Context, db *sql.DB, name string, interval time.Duration, task func(ctx context.Context)) {
+	timer := time.NewTimer(Scheduler.jitter())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		runLeasedTask(ctx, db, name, task)
+		timer.Reset(interval + Scheduler.jitter())
+	}
+}
+
+// runLeasedTask 在索引租约和咨询锁的保护下执行一轮定时任务，每个任务使用独立的锁，互不阻塞
+func runLeasedTask(ctx context.Context, db *sql.DB, name string, task func(ctx context.Context)) {
+	if !IsIndexWriter() {
+		Logger.Debugw("未持有索引租约，跳过本轮定时任务", "task", name)
+		return
+	}
+	if Scheduler.LockName == "" {
+		task(ctx)
+		return
+	}
+	lock := Scheduler.LockName + ":" + name
+	acquired, err := withAdvisoryLock(ctx, db, lock, func() {
+		task(ctx)
+	})
+	if err != nil {
+		Logger.Warnw("获取咨询锁失败，跳过本轮定时任务", "task", name, "lock", lock, "error", err)
+		return
+	}
+	if !acquired {
+		Logger.Infow("其他实例正在执行定时任务，跳过本轮", "task", name, "lock", lock)
+	}
+}
+
+// maxLockNameLength 为 MySQL 5.7 起 GET_LOCK 锁名的最大长度，超过时报错
+const maxLockNameLength = 64
+
+// advisoryLockName 返回实际使用的锁名：超过 maxLockNameLength 时保留前缀，
+// 末尾换成完整名称的 SHA-256 摘要，较长的集合名也能得到稳定且不冲突的锁名
+func advisoryLockName(name string) string {
+	if len(name) <= maxLockNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	digest := hex.EncodeToString(sum[:8])
+	return name[:maxLockNameLength-len(digest)-1] + ":" + digest
+}
+
+// withAdvisoryLock 在 MySQL 咨询锁保护下执行 fn，锁已被其他实例持有时不执行并返回 false。
+// GET_LOCK 与会话绑定，因此在整个执行期间固定一个连接
+func withAdvisoryLock(ctx context.Context, db *sql.DB, name string, fn func()) (bool, error) {
+	name = advisoryLockName(name)
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&acquired); err != nil {
+		return false, fmt.Errorf("获取咨询锁失败: %v", err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return false, nil
+	}
+	defer func() {
+		// 任务的上下文可能已取消，释放锁使用独立的上下文
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(releaseCtx, "DO RELEASE_LOCK(?)", name); err != nil {
+			Logger.Warnw("释放咨询锁失败", "lock", name, "error", err)
+		}
+	}()
+
+	fn()
+	return true, nil
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdvisoryLockName(t *testing.T) {
+	if got := advisoryLockName("mcp-mysql:index:schemas"); got != "mcp-mysql:index:schemas" {
+		t.Errorf("short name changed: %q", got)
+	}
+	long := "mcp-mysql:index:" + strings.Repeat("collection", 6)
+	got := advisoryLockName(long)
+	if len(got) != maxLockNameLength || !strings.HasPrefix(got, "mcp-mysql:index:") {
+		t.Errorf("advisoryLockName(%q) = %q", long, got)
+	}
+	if advisoryLockName(long) != got {
+		t.Error("advisoryLockName is not stable")
+	}
+	if other := advisoryLockName(long + ":column-stats"); other == got {
+		t.Errorf("names sharing a prefix map to the same lock %q", got)
+	}
+}
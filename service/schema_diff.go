@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 可以与当前库对比的其他数据库，键为名称，值为 DSN
+var schemaDiffTargets map[string]string
+
+// ParseSchemaDiffTargets 解析 "staging=user:pass@tcp(host:3306)/db;prod=..." 格式的对比目标配置
+func ParseSchemaDiffTargets(value string) (map[string]string, error) {
+	targets := make(map[string]string)
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, dsn, ok := strings.Cut(item, "=")
+		name, dsn = strings.TrimSpace(name), strings.TrimSpace(dsn)
+		if !ok || name == "" || dsn == "" {
+			return nil, fmt.Errorf("格式应为 name=dsn: %s", item)
+		}
+		targets[name] = dsn
+	}
+	return targets, nil
+}
+
+// InitSchemaDiffTargets 设置可以对比的其他数据库
+func InitSchemaDiffTargets(targets map[string]string) {
+	schemaDiffTargets = targets
+}
+
+// SchemaDiffTargetNames 返回已配置的对比目标名称
+func SchemaDiffTargetNames() []string {
+	names := make([]string, 0, len(schemaDiffTargets))
+	for name := range schemaDiffTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// columnDef 为对比用的列定义
+type columnDef struct {
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default,omitempty"`
+	Extra    string  `json:"extra,omitempty"`
+}
+
+// indexDef 为对比用的索引定义
+type indexDef struct {
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+}
+
+// tableDef 为对比用的表定义
+type tableDef struct {
+	Columns map[string]columnDef
+	Indexes map[string]*indexDef
+}
+
+// DefinitionChange 描述一处定义变化
+type DefinitionChange struct {
+	Name string `json:"name"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// TableDiff 描述一张表的差异
+type TableDiff struct {
+	Table          string             `json:"table"`
+	AddedColumns   []string           `json:"added_columns,omitempty"`
+	RemovedColumns []string           `json:"removed_columns,omitempty"`
+	ChangedColumns []DefinitionChange `json:"changed_columns,omitempty"`
+	AddedIndexes   []string           `json:"added_indexes,omitempty"`
+	RemovedIndexes []string           `json:"removed_indexes,omitempty"`
+	ChangedIndexes []DefinitionChange `json:"changed_indexes,omitempty"`
+}
+
+// SchemaDiff 为两个库之间的表结构差异，added 表示只存在于 Target，removed 表示只存在于 Source
+type SchemaDiff struct {
+	Source        string      `json:"source"`
+	Target        string      `json:"target"`
+	AddedTables   []string    `json:"added_tables"`
+	RemovedTables []string    `json:"removed_tables"`
+	ChangedTables []TableDiff `json:"changed_tables"`
+}
+
+// DiffSchemas 对比当前库（source）与另一个库（target）的列和索引定义。
+// otherSchema 非空时对比同一实例上的另一个库，否则 target 为已配置的对比目标名称
+func DiffSchemas(ctx context.Context, db *sql.DB, otherSchema, target string) (*SchemaDiff, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+	if (otherSchema == "") == (target == "") {
+		return nil, fmt.Errorf("请指定 schema 或 target 中的一个")
+	}
+
+	diff := &SchemaDiff{AddedTables: []string{}, RemovedTables: []string{}, ChangedTables: []TableDiff{}}
+	if err := db.QueryRowContext(ctx, labelStatement(ctx, "SELECT DATABASE()")).Scan(&diff.Source); err != nil {
+		return nil, fmt.Errorf("查询当前库失败: %v", err)
+	}
+	source, err := loadTableDefs(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var other map[string]*tableDef
+	if otherSchema != "" {
+		if err = ValidateIdentifier(otherSchema); err != nil {
+			return nil, err
+		}
+		if IsHiddenSchema(otherSchema) {
+			return nil, fmt.Errorf("不允许访问数据库 %s", otherSchema)
+		}
+		diff.Target = otherSchema
+		other, err = loadTableDefs(ctx, db, otherSchema)
+	} else {
+		dsn, ok := schemaDiffTargets[target]
+		if !ok {
+			return nil, fmt.Errorf("未配置的对比目标: %s，可选: %s", target, strings.Join(SchemaDiffTargetNames(), ", "))
+		}
+		var targetDB *sql.DB
+		if targetDB, err = sql.Open("mysql", dsn); err != nil {
+			return nil, fmt.Errorf("连接对比目标失败: %v", err)
+		}
+		defer targetDB.Close()
+		diff.Target = target
+		other, err = loadTableDefs(ctx, targetDB, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range sortedKeys(other) {
+		if _, ok := source[name]; !ok {
+			diff.AddedTables = append(diff.AddedTables, name)
+		}
+	}
+	for _, name := range sortedKeys(source) {
+		to, ok := other[name]
+		if !ok {
+			diff.RemovedTables = append(diff.RemovedTables, name)
+			continue
+		}
+		if t := diffTable(name, source[name], to); t != nil {
+			diff.ChangedTables = append(diff.ChangedTables, *t)
+		}
+	}
+	return diff, nil
+}
+
+// loadTableDefs 读取库中所有表的列和索引定义，schema 为空时读取连接的当前库
+func loadTableDefs(ctx context.Context, db *sql.DB, schema string) (map[string]*tableDef, error) {
+	where, args := "TABLE_SCHEMA = DATABASE()", []any{}
+	if schema != "" {
+		where, args = "TABLE_SCHEMA = ?", []any{schema}
+	}
+
+	tables := make(map[string]*tableDef)
+	table := func(name string) *tableDef {
+		t, ok := tables[name]
+		if !ok {
+			t = &tableDef{Columns: make(map[string]columnDef), Indexes: make(map[string]*indexDef)}
+			tables[name] = t
+		}
+		return t
+	}
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES', COLUMN_DEFAULT, EXTRA
+		FROM information_schema.COLUMNS WHERE `+where+` ORDER BY TABLE_NAME, ORDINAL_POSITION`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tableName, column string
+			c                 columnDef
+			defValue          sql.NullString
+		)
+		if err = rows.Scan(&tableName, &column, &c.Type, &c.Nullable, &defValue, &c.Extra); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if defValue.Valid {
+			c.Default = &defValue.String
+		}
+		table(tableName).Columns[column] = c
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE = 0, COLUMN_NAME
+		FROM information_schema.STATISTICS WHERE `+where+` ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询索引信息失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tableName, name string
+			unique          bool
+			column          sql.NullString
+		)
+		if err = rows.Scan(&tableName, &name, &unique, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		t := table(tableName)
+		idx, ok := t.Indexes[name]
+		if !ok {
+			idx = &indexDef{Unique: unique, Columns: []string{}}
+			t.Indexes[name] = idx
+		}
+		// 函数索引没有对应的列名
+		if column.Valid {
+			idx.Columns = append(idx.Columns, column.String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询索引信息失败: %v", err)
+	}
+	for name := range tables {
+		if IsHiddenTable(name) {
+			delete(tables, name)
+		}
+	}
+	return tables, nil
+}
+
+// diffTable 对比一张表的列和索引，没有差异时返回 nil
+func diffTable(name string, from, to *tableDef) *TableDiff {
+	d := &TableDiff{Table: name}
+	for _, col := range sortedKeys(to.Columns) {
+		if _, ok := from.Columns[col]; !ok {
+			d.AddedColumns = append(d.AddedColumns, col)
+		}
+	}
+	for _, col := range sortedKeys(from.Columns) {
+		toCol, ok := to.Columns[col]
+		if !ok {
+			d.RemovedColumns = append(d.RemovedColumns, col)
+			continue
+		}
+		if !sameColumnDef(from.Columns[col], toCol) {
+			d.ChangedColumns = append(d.ChangedColumns, DefinitionChange{Name: col, From: from.Columns[col], To: toCol})
+		}
+	}
+	for _, idx := range sortedKeys(to.Indexes) {
+		if _, ok := from.Indexes[idx]; !ok {
+			d.AddedIndexes = append(d.AddedIndexes, idx)
+		}
+	}
+	for _, idx := range sortedKeys(from.Indexes) {
+		toIdx, ok := to.Indexes[idx]
+		if !ok {
+			d.RemovedIndexes = append(d.RemovedIndexes, idx)
+			continue
+		}
+		fromIdx := from.Indexes[idx]
+		if fromIdx.Unique != toIdx.Unique || strings.Join(fromIdx.Columns, ",") != strings.Join(toIdx.Columns, ",") {
+			d.ChangedIndexes = append(d.ChangedIndexes, DefinitionChange{Name: idx, From: fromIdx, To: toIdx})
+		}
+	}
+
+	if len(d.AddedColumns)+len(d.RemovedColumns)+len(d.ChangedColumns)+
+		len(d.AddedIndexes)+len(d.RemovedIndexes)+len(d.ChangedIndexes) == 0 {
+		return nil
+	}
+	return d
+}
+
+// sameColumnDef 判断两个列定义是否一致
+func sameColumnDef(a, b columnDef) bool {
+	if a.Type != b.Type || a.Nullable != b.Nullable || a.Extra != b.Extra {
+		return false
+	}
+	if a.Default == nil || b.Default == nil {
+		return a.Default == nil && b.Default == nil
+	}
+	return *a.Default == *b.Default
+}
+
+// sortedKeys 返回按字母排序的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatSchemaDiff 将表结构差异序列化为 JSON
+func FormatSchemaDiff(diff *SchemaDiff) (string, error) {
+	data, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diff to JSON: %v", err)
+	}
+	return string(data), nil
+}
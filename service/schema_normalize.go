@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 建表语句中可以去掉的部分，对编写查询没有帮助却占用上下文
+const (
+	stripAutoIncrement = "auto_increment" // 表选项 AUTO_INCREMENT=n，列属性 AUTO_INCREMENT 保留
+	stripRowFormat     = "row_format"     // ROW_FORMAT=...
+	stripCollate       = "collate"        // 列和表的 COLLATE 子句
+	stripCharset       = "charset"        // 列和表的 CHARACTER SET / DEFAULT CHARSET 子句
+	stripEngine        = "engine"         // ENGINE=...
+	stripDisplayWidth  = "display_width"  // 整数类型的显示宽度，如 int(11)，tinyint(1) 保留
+)
+
+// defaultSchemaStrip 默认去掉的部分
+var defaultSchemaStrip = []string{stripAutoIncrement, stripRowFormat, stripCollate, stripCharset}
+
+// schemaStripPatterns 为各部分对应的正则，只作用于引号之外的文本
+var schemaStripPatterns = map[string]*regexp.Regexp{
+	stripAutoIncrement: regexp.MustCompile(`(?i)\s+AUTO_INCREMENT\s*=\s*\d+`),
+	stripRowFormat:     regexp.MustCompile(`(?i)\s+ROW_FORMAT\s*=\s*\w+`),
+	stripCollate:       regexp.MustCompile(`(?i)\s+(?:DEFAULT\s+)?COLLATE\s*=?\s*\w+`),
+	stripCharset:       regexp.MustCompile(`(?i)\s+(?:DEFAULT\s+)?(?:CHARSET|CHARACTER\s+SET)\s*=?\s*\w+`),
+	stripEngine:        regexp.MustCompile(`(?i)\s+ENGINE\s*=\s*\w+`),
+}
+
+// displayWidthPattern 匹配带显示宽度的整数类型
+var displayWidthPattern = regexp.MustCompile(`(?i)\b(tinyint|smallint|mediumint|int|integer|bigint)\((\d+)\)`)
+
+// schemaStrip 当前生效的去除项
+var schemaStrip = defaultSchemaStrip
+
+// InitSchemaNormalize 设置返回给模型的建表语句中需要去掉的部分，传入 none 时原样返回
+func InitSchemaNormalize(options []string) error {
+	if len(options) == 0 {
+		schemaStrip = defaultSchemaStrip
+		return nil
+	}
+	options = normalizeKeywords(options)
+	if len(options) == 1 && options[0] == "none" {
+		schemaStrip = nil
+		return nil
+	}
+	for _, option := range options {
+		if _, ok := schemaStripPatterns[option]; !ok && option != stripDisplayWidth {
+			return fmt.Errorf("不支持的建表语句精简项: %s，可选 auto_increment、row_format、collate、charset、engine、display_width 或 none", option)
+		}
+	}
+	schemaStrip = options
+	Logger.Infow("建表语句精简项", "strip", schemaStrip)
+	return nil
+}
+
+// normalizeSchema 按配置去掉建表语句中的计数器、存储格式和字符集等内容，注释和字符串中的文本不受影响
+func normalizeSchema(schema string) string {
+	if len(schemaStrip) == 0 {
+		return schema
+	}
+	return mapUnquoted(schema, func(s string) string {
+		for _, option := range schemaStrip {
+			if option == stripDisplayWidth {
+				s = displayWidthPattern.ReplaceAllStringFunc(s, stripIntWidth)
+				continue
+			}
+			s = schemaStripPatterns[option].ReplaceAllString(s, "")
+		}
+		return s
+	})
+}
+
+// normalizeSchemaHits 精简检索结果中的建表语句
+func normalizeSchemaHits(hits []SchemaHit) {
+	for i := range hits {
+		hits[i].Schema = normalizeSchema(hits[i].Schema)
+	}
+}
+
+// stripIntWidth 去掉整数类型的显示宽度，tinyint(1) 通常表示布尔值，予以保留
+func stripIntWidth(s string) string {
+	m := displayWidthPattern.FindStringSubmatch(s)
+	if strings.EqualFold(m[1], "tinyint") && m[2] == "1" {
+		return s
+	}
+	return m[1]
+}
+
+// mapUnquoted 只对引号（'、"、`）之外的文本应用 fn
+func mapUnquoted(s string, fn func(string) string) string {
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(s); i++ {
+		quote := s[i]
+		if quote != '\'' && quote != '"' && quote != '`' {
+			continue
+		}
+		b.WriteString(fn(s[start:i]))
+		j := i + 1
+		for j < len(s) {
+			if s[j] == '\\' && quote != '`' {
+				j += 2
+				continue
+			}
+			if s[j] == quote {
+				// 连续两个引号表示转义
+				if j+1 < len(s) && s[j+1] == quote {
+					j += 2
+					continue
+				}
+				break
+			}
+			j++
+		}
+		end := min(j+1, len(s))
+		b.WriteString(s[i:end])
+		i, start = end-1, end
+	}
+	b.WriteString(fn(s[start:]))
+	return b.String()
+}
+
+// TableDDL 返回精简后的建表语句，视图返回空字符串
+func TableDDL(ctx context.Context, db *sql.DB, table string) (string, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return "", err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return "", err
+	}
+	schema, err := tableSchema(ctx, db, table)
+	if errors.Is(err, errViewSchema) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return normalizeSchema(schema), nil
+}
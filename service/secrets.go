@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretResolver 从外部来源读取凭据，ref 为去掉来源前缀之后的引用
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SecretsConfig 控制凭据的外部来源
+type SecretsConfig struct {
+	// VaultAddr 为 Vault 服务地址，如 https://vault.example.com:8200，为空时不能使用 vault: 引用
+	VaultAddr string
+	// VaultToken 为访问 Vault 的令牌
+	VaultToken string
+	// VaultNamespace 为 Vault 企业版的命名空间，可为空
+	VaultNamespace string
+	// Timeout 为单次读取 Vault 的超时时间
+	Timeout time.Duration
+}
+
+var (
+	secretMu sync.RWMutex
+	// secretResolvers 为已注册的凭据来源，键为引用的前缀
+	secretResolvers = map[string]SecretResolver{"file": fileSecretResolver{}}
+)
+
+// RegisterSecretResolver 注册凭据来源，之后形如 <scheme>:<ref> 的环境变量值由其解析
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if r == nil {
+		delete(secretResolvers, scheme)
+		return
+	}
+	secretResolvers[scheme] = r
+}
+
+// InitSecrets 按配置注册 Vault 来源，未配置地址时取消注册
+func InitSecrets(cfg SecretsConfig) {
+	if cfg.VaultAddr == "" {
+		RegisterSecretResolver("vault", nil)
+		return
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	RegisterSecretResolver("vault", &vaultSecretResolver{cfg: cfg})
+}
+
+// ResolveSecret 读取名为 name 的凭据：
+//   - 设置了 <name>_FILE 时读取该文件的内容（Docker/Kubernetes secrets 挂载的文件）
+//   - 环境变量的值形如 file:/path 或 vault:secret/data/mysql#password 时由对应的来源解析
+//   - 否则直接返回环境变量的值
+func ResolveSecret(name string) (string, error) {
+	value := os.Getenv(name)
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		if value != "" {
+			return "", fmt.Errorf("%s 和 %s_FILE 不能同时设置", name, name)
+		}
+		value = "file:" + path
+	}
+
+	secret, err := ResolveSecretValue(value)
+	if err != nil {
+		return "", fmt.Errorf("读取凭据 %s 失败: %v", name, err)
+	}
+	return secret, nil
+}
+
+// ResolveSecretValue 解析配置文件等处直接给出的凭据值：形如 <scheme>:<ref> 且来源已注册时由其读取，否则原样返回
+func ResolveSecretValue(value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	secretMu.RLock()
+	r := secretResolvers[scheme]
+	secretMu.RUnlock()
+	if r == nil {
+		// 不是已注册的来源，按普通的值处理（密码本身可能包含冒号）
+		return value, nil
+	}
+	return r.Resolve(context.Background(), ref)
+}
+
+// fileSecretResolver 从文件读取凭据，去掉末尾的换行
+type fileSecretResolver struct{}
+
+func (fileSecretResolver) Resolve(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// vaultSecretResolver 从 Vault 的 KV 引擎读取凭据，引用格式为 <路径>#<字段>，
+// 如 secret/data/mysql#password；secret 只有一个字段时可以省略字段名。同时支持 KV v1 和 v2
+type vaultSecretResolver struct {
+	cfg SecretsConfig
+}
+
+func (v *vaultSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("Vault 引用缺少路径: %s", ref)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, v.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, strings.TrimRight(v.cfg.VaultAddr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("X-Vault-Token", v.cfg.VaultToken)
+	if v.cfg.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.VaultNamespace)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求 Vault 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vault 返回状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err = json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("解析响应失败: %v", err)
+	}
+	data := secret.Data
+	// KV v2 的字段位于 data.data 中，同时带有 metadata
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	if field == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("Vault 路径 %s 有 %d 个字段，需要用 #<字段> 指定", path, len(data))
+		}
+		for _, value := range data {
+			return secretString(value)
+		}
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("Vault 路径 %s 中没有字段 %s", path, field)
+	}
+	return secretString(value)
+}
+
+// secretString 要求凭据字段为字符串
+func secretString(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("凭据字段不是字符串")
+	}
+	return s, nil
+}
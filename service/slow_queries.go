@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultSlowQueryLimit = 10
+	maxSlowQueryLimit     = 50
+)
+
+// SlowQuery 描述一条慢语句。来自 performance_schema 时为按摘要聚合的统计，来自 mysql.slow_log 时为单次执行
+type SlowQuery struct {
+	SQL          string  `json:"sql"`
+	Schema       string  `json:"schema,omitempty"`
+	Count        int64   `json:"count"`
+	TotalSec     float64 `json:"total_sec"`
+	AvgSec       float64 `json:"avg_sec"`
+	MaxSec       float64 `json:"max_sec"`
+	LockSec      float64 `json:"lock_sec"`
+	RowsExamined int64   `json:"rows_examined"`
+	RowsSent     int64   `json:"rows_sent"`
+	NoIndexUsed  int64   `json:"no_index_used,omitempty"`
+	LastSeen     string  `json:"last_seen,omitempty"`
+	UserHost     string  `json:"user_host,omitempty"`
+}
+
+// slowQueryOrders performance_schema 摘要统计支持的排序方式
+var slowQueryOrders = map[string]string{
+	"total": "SUM_TIMER_WAIT",
+	"avg":   "AVG_TIMER_WAIT",
+	"max":   "MAX_TIMER_WAIT",
+	"count": "COUNT_STAR",
+}
+
+// GetSlowQueries 返回当前库最慢的语句。source 为 performance_schema（按语句摘要聚合，orderBy 可选
+// total、avg、max、count）、slow_log（读取 log_output=TABLE 时的 mysql.slow_log，按单次耗时排序），
+// 为空时优先使用 performance_schema，未开启时退化为 slow_log
+func GetSlowQueries(ctx context.Context, db *sql.DB, source, orderBy string, limit int) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if limit <= 0 {
+		limit = defaultSlowQueryLimit
+	}
+	if limit > maxSlowQueryLimit {
+		limit = maxSlowQueryLimit
+	}
+
+	source = strings.ToLower(source)
+	auto := source == "" || source == "auto"
+	if auto {
+		var err error
+		if source, err = detectSlowQuerySource(ctx, db); err != nil {
+			return "", err
+		}
+	}
+
+	var (
+		queries []SlowQuery
+		err     error
+	)
+	switch source {
+	case "performance_schema":
+		queries, err = slowQueriesFromDigest(ctx, db, orderBy, limit)
+		// 旧版本上摘要表可能不存在或缺少列，自动模式下退化为 slow_log
+		if err != nil && auto {
+			Logger.Warnw("读取 performance_schema 摘要失败，改用 mysql.slow_log", "error", err)
+			source = "slow_log"
+			queries, err = slowQueriesFromLog(ctx, db, limit)
+		}
+	case "slow_log":
+		queries, err = slowQueriesFromLog(ctx, db, limit)
+	default:
+		return "", fmt.Errorf("不支持的慢查询来源: %s，可选 performance_schema、slow_log", source)
+	}
+	if err != nil {
+		return "", err
+	}
+	// 引用被隐藏的表的语句不返回
+	visible := queries[:0]
+	for _, q := range queries {
+		if !referencesHidden(q.SQL) {
+			visible = append(visible, q)
+		}
+	}
+	queries = visible
+
+	data, err := json.MarshalIndent(map[string]any{"source": source, "slow_queries": queries}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// detectSlowQuerySource 根据服务端配置选择慢查询来源
+func detectSlowQuerySource(ctx context.Context, db *sql.DB) (string, error) {
+	var (
+		perfSchema int
+		logOutput  string
+	)
+	if err := db.QueryRowContext(ctx, "SELECT @@performance_schema, @@log_output").Scan(&perfSchema, &logOutput); err != nil {
+		return "", fmt.Errorf("查询慢查询配置失败: %v", err)
+	}
+	if perfSchema == 1 {
+		return "performance_schema", nil
+	}
+	if strings.Contains(strings.ToUpper(logOutput), "TABLE") {
+		return "slow_log", nil
+	}
+	return "", fmt.Errorf("performance_schema 未开启且 log_output=%s，无法读取慢查询；请开启 performance_schema 或将 log_output 设置为 TABLE", logOutput)
+}
+
+// slowQueriesFromDigest 从 performance_schema.events_statements_summary_by_digest 读取当前库的语句摘要统计，
+// 计时单位为皮秒
+func slowQueriesFromDigest(ctx context.Context, db *sql.DB, orderBy string, limit int) ([]SlowQuery, error) {
+	if orderBy == "" {
+		orderBy = "total"
+	}
+	column, ok := slowQueryOrders[strings.ToLower(orderBy)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的排序方式: %s，可选 total、avg、max、count", orderBy)
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(DIGEST_TEXT, ''), COALESCE(SCHEMA_NAME, ''), COUNT_STAR,
+			SUM_TIMER_WAIT / 1e12, AVG_TIMER_WAIT / 1e12, MAX_TIMER_WAIT / 1e12, SUM_LOCK_TIME / 1e12,
+			SUM_ROWS_EXAMINED, SUM_ROWS_SENT, SUM_NO_INDEX_USED, COALESCE(LAST_SEEN, '')
+		FROM performance_schema.events_statements_summary_by_digest
+		WHERE SCHEMA_NAME = DATABASE() AND DIGEST_TEXT IS NOT NULL
+		ORDER BY %s DESC LIMIT ?`, column), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询 performance_schema 失败: %v", err)
+	}
+	defer rows.Close()
+
+	queries := make([]SlowQuery, 0)
+	for rows.Next() {
+		var q SlowQuery
+		if err = rows.Scan(&q.SQL, &q.Schema, &q.Count, &q.TotalSec, &q.AvgSec, &q.MaxSec, &q.LockSec,
+			&q.RowsExamined, &q.RowsSent, &q.NoIndexUsed, &q.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// slowQueriesFromLog 从 mysql.slow_log 读取当前库耗时最长的语句
+func slowQueriesFromLog(ctx context.Context, db *sql.DB, limit int) ([]SlowQuery, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT CONVERT(sql_text USING utf8mb4), db,
+			TIME_TO_SEC(query_time) + MICROSECOND(query_time) / 1e6,
+			TIME_TO_SEC(lock_time) + MICROSECOND(lock_time) / 1e6,
+			rows_examined, rows_sent, CAST(start_time AS CHAR), user_host
+		FROM mysql.slow_log WHERE db = DATABASE()
+		ORDER BY query_time DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询 mysql.slow_log 失败: %v", err)
+	}
+	defer rows.Close()
+
+	queries := make([]SlowQuery, 0)
+	for rows.Next() {
+		q := SlowQuery{Count: 1}
+		if err = rows.Scan(&q.SQL, &q.Schema, &q.TotalSec, &q.LockSec, &q.RowsExamined, &q.RowsSent,
+			&q.LastSeen, &q.UserHost); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		q.AvgSec, q.MaxSec = q.TotalSec, q.TotalSec
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migrateLegacyTables 检测旧版本的索引登记表并原地迁移到当前结构。
+// 早期版本的 mysql_tables 只有 table_name 一列且没有唯一约束，直接补列无法得到 ON CONFLICT(table_name)
+// 依赖的唯一索引，重复的表名也会导致去重状态失效。迁移前先把整个数据库备份到 schema.db.bak-<时间>，
+// 迁移在一个事务中完成，失败时回滚并保留原表
+func migrateLegacyTables(db *sql.DB, dbPath string) error {
+	columns, err := sqliteTableColumns(db, dbTable)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 || !columns["table_name"] {
+		// 新建的数据库，或者不是本服务创建的表，交给建表语句处理
+		return nil
+	}
+	unique, err := sqliteColumnUnique(db, dbTable, "table_name")
+	if err != nil {
+		return err
+	}
+	if unique && columns["id"] {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.bak-%s", dbPath, time.Now().Format("20060102150405"))
+	if _, err = db.Exec("VACUUM INTO ?", backup); err != nil {
+		return fmt.Errorf("备份数据库失败: %v", err)
+	}
+	Logger.Infow("检测到旧版本的索引登记表，已备份数据库", "table", dbTable, "backup", backup)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	legacy := dbTable + "_legacy"
+	if _, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", dbTable, legacy)); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(fmt.Sprintf(indexTableDDL, dbTable)); err != nil {
+		return err
+	}
+
+	// 旧表可能已经补过部分元数据列，有则保留，重复的表名合并为一条
+	selects := []string{"table_name"}
+	for _, c := range []string{"schema_hash", "vector_id", "embedded_at"} {
+		if columns[c] {
+			selects = append(selects, fmt.Sprintf("COALESCE(MAX(%s), %s)", c, legacyDefault(c)))
+		} else {
+			selects = append(selects, legacyDefault(c))
+		}
+	}
+	result, err := tx.Exec(fmt.Sprintf(`
+		INSERT INTO %s (table_name, schema_hash, vector_id, embedded_at)
+		SELECT %s FROM %s
+		WHERE table_name IS NOT NULL AND table_name <> ''
+		GROUP BY table_name`, dbTable, strings.Join(selects, ", "), legacy))
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(fmt.Sprintf("DROP TABLE %s", legacy)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	migrated, _ := result.RowsAffected()
+	Logger.Infow("旧版本的索引登记表迁移完成", "table", dbTable, "rows", migrated, "backup", backup)
+	return nil
+}
+
+// legacyDefault 返回元数据列在旧表中缺失时的默认值
+func legacyDefault(column string) string {
+	if column == "schema_hash" {
+		return "''"
+	}
+	return "0"
+}
+
+// sqliteTableColumns 返回表中已有的列，表不存在时返回空集合
+func sqliteTableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err = rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		existing[name] = true
+	}
+	return existing, rows.Err()
+}
+
+// sqliteColumnUnique 判断列上是否有单列唯一索引（包括 UNIQUE 约束和主键）
+func sqliteColumnUnique(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_list(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	var indexes []string
+	for rows.Next() {
+		var (
+			seq     int
+			name    string
+			unique  int
+			origin  string
+			partial int
+		)
+		if err = rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return false, err
+		}
+		if unique == 1 && partial == 0 {
+			indexes = append(indexes, name)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, index := range indexes {
+		var names []string
+		infoRows, err := db.Query(fmt.Sprintf("PRAGMA index_info(%q)", index))
+		if err != nil {
+			return false, err
+		}
+		for infoRows.Next() {
+			var (
+				seqno, cid int
+				name       sql.NullString
+			)
+			if err = infoRows.Scan(&seqno, &cid, &name); err != nil {
+				infoRows.Close()
+				return false, err
+			}
+			names = append(names, name.String)
+		}
+		infoRows.Close()
+		if len(names) == 1 && names[0] == column {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -7,66 +7,204 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 var dbName = "schema.db" // 修改为不带路径前缀的文件名
 var dbTable = "mysql_tables"
+
+// indexTableDDL 为索引登记表的建表语句，%s 为表名
+const indexTableDDL = `
+	CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		table_name TEXT NOT NULL UNIQUE,
+		schema_hash TEXT NOT NULL DEFAULT '',
+		vector_id INTEGER NOT NULL DEFAULT 0,
+		embedded_at INTEGER NOT NULL DEFAULT 0
+	)`
+
 var sqliteDB *sql.DB
+var sqliteMu sync.RWMutex
 var sqliteOnce sync.Once
 var sqliteInitErr error
 
 // InitSQLite 初始化SQLite数据库连接
 func InitSQLite() error {
+	sqliteOnce.Do(func() {
+		db, err := openSQLite()
+		sqliteMu.Lock()
+		sqliteDB, sqliteInitErr = db, err
+		sqliteMu.Unlock()
+		if err == nil {
+			Logger.Info("SQLite数据库初始化成功")
+		}
+	})
+
+	sqliteMu.RLock()
+	defer sqliteMu.RUnlock()
+	return sqliteInitErr
+}
+
+// sqliteCloseDelay 替换SQLite句柄后，旧句柄延迟关闭，留给已经取得旧句柄但尚未开始的操作
+const sqliteCloseDelay = time.Minute
+
+// ReloadSQLite 重新打开SQLite数据库，新句柄可用后才在锁内替换旧句柄；
+// 旧句柄在 sqliteCloseDelay 之后关闭，Close 还会等待其上已开始的操作结束
+func ReloadSQLite() error {
+	// 标记为已初始化，避免之后的 InitSQLite 再打开一次
+	sqliteOnce.Do(func() {})
+
+	db, err := openSQLite()
+	if err != nil {
+		return err
+	}
+	sqliteMu.Lock()
+	old := sqliteDB
+	sqliteDB, sqliteInitErr = db, nil
+	sqliteMu.Unlock()
+
+	if old != nil {
+		time.AfterFunc(sqliteCloseDelay, func() {
+			if err := old.Close(); err != nil {
+				Logger.Warnw("关闭旧的SQLite句柄失败", "error", err)
+			}
+		})
+	}
+	Logger.Info("SQLite数据库已重新打开")
+	return nil
+}
+
+// sqlite 返回当前的SQLite数据库句柄
+func sqlite() *sql.DB {
+	sqliteMu.RLock()
+	defer sqliteMu.RUnlock()
+	return sqliteDB
+}
+
+// openSQLite 打开SQLite数据库文件并创建所需的表
+func openSQLite() (*sql.DB, error) {
 	currentDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
 	if err != nil {
-		return fmt.Errorf("获取当前工作目录失败: %v", err)
+		return nil, fmt.Errorf("获取当前工作目录失败: %v", err)
 	}
 
 	dbPath := filepath.Join(currentDir, dbName)
 
-	sqliteOnce.Do(func() {
-		// 确保数据库文件所在目录存在
-		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-			f, err := os.Create(dbPath)
-			if err != nil {
-				sqliteInitErr = fmt.Errorf("创建空数据库文件失败: %v", err)
-				return
-			}
-			f.Close()
-			Logger.Infow("创建空数据库文件成功", "path", dbPath)
+	// 确保数据库文件所在目录存在
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		f, err := os.Create(dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("创建空数据库文件失败: %v", err)
 		}
+		f.Close()
+		Logger.Infow("创建空数据库文件成功", "path", dbPath)
+	}
 
-		var db *sql.DB
-		db, sqliteInitErr = sql.Open("sqlite3", dbPath)
-		if sqliteInitErr != nil {
-			sqliteInitErr = fmt.Errorf("打开SQLite数据库失败: %v", sqliteInitErr)
-			return
-		}
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开SQLite数据库失败: %v", err)
+	}
 
-		// 测试连接
-		sqliteInitErr = db.Ping()
-		if sqliteInitErr != nil {
-			return
-		}
+	// 测试连接
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
 
-		// 创建表（如果不存在）
-		_, sqliteInitErr = db.Exec(fmt.Sprintf(`
-			CREATE TABLE IF NOT EXISTS %s (
-				id INTEGER PRIMARY KEY AUTOINCREMENT,
-				table_name TEXT NOT NULL UNIQUE
-			)`, dbTable))
-		if sqliteInitErr != nil {
-			sqliteInitErr = fmt.Errorf("创建表失败: %v", sqliteInitErr)
-			return
-		}
+	// 旧版本只有 table_name 一列且没有唯一约束，先备份再原地迁移
+	if err = migrateLegacyTables(db, dbPath); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("迁移旧版本数据库失败: %v", err)
+	}
+
+	// 创建表（如果不存在）
+	_, err = db.Exec(fmt.Sprintf(indexTableDDL, dbTable))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建表失败: %v", err)
+	}
+
+	// 旧版本的表只有 table_name 一列，补齐索引元数据列
+	if err = ensureIndexColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("升级表结构失败: %v", err)
+	}
+
+	// 创建查询历史表
+	if err = createHistoryTable(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建查询历史表失败: %v", err)
+	}
+
+	// 创建被移出索引的表的登记表
+	if err = createForgottenTable(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建移出登记表失败: %v", err)
+	}
+
+	// 创建排序规则表
+	if err = createRankingTable(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建排序规则表失败: %v", err)
+	}
+
+	// 创建审批审计表
+	if err = createApprovalTable(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建审批审计表失败: %v", err)
+	}
+
+	// 创建列统计表
+	if err = createColumnStatsTable(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建列统计表失败: %v", err)
+	}
+
+	// 创建语句审计表
+	if err = createAuditTable(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建语句审计表失败: %v", err)
+	}
+
+	// 创建表结构快照表
+	if err = createSnapshotTables(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建表结构快照表失败: %v", err)
+	}
+	return db, nil
+}
 
-		sqliteDB = db
-		Logger.Info("SQLite数据库初始化成功")
+// ensureIndexColumns 为旧版本创建的表补齐缺失的元数据列
+func ensureIndexColumns(db *sql.DB) error {
+	return addMissingColumns(db, dbTable, []sqliteColumn{
+		{"schema_hash", "TEXT NOT NULL DEFAULT ''"},
+		{"vector_id", "INTEGER NOT NULL DEFAULT 0"},
+		{"embedded_at", "INTEGER NOT NULL DEFAULT 0"},
 	})
+}
 
-	return sqliteInitErr
+// sqliteColumn 描述需要补齐的列
+type sqliteColumn struct{ name, ddl string }
+
+// addMissingColumns 为已存在的表补齐缺失的列
+func addMissingColumns(db *sql.DB, table string, columns []sqliteColumn) error {
+	existing, err := sqliteTableColumns(db, table)
+	if err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.ddl)); err != nil {
+			return err
+		}
+		Logger.Infow("已为SQLite表补齐列", "table", table, "column", col.name)
+	}
+	return nil
 }
 
 func SaveToSQLite(rows []string) (bool, error) {
@@ -89,7 +227,7 @@ func SaveToSQLite(rows []string) (bool, error) {
 	insertSQL := fmt.Sprintf("INSERT INTO %s (table_name) VALUES %s",
 		dbTable, strings.Join(placeholders, ","))
 
-	_, err := sqliteDB.Exec(insertSQL, args...)
+	_, err := sqlite().Exec(insertSQL, args...)
 	if err != nil {
 		return false, fmt.Errorf("批量插入数据失败: %v", err)
 	}
@@ -121,7 +259,7 @@ func CheckRowExist(row []string) []string {
 		dbTable, strings.Join(placeholders, ","))
 
 	// 查询存在的表
-	rows, err := sqliteDB.Query(querySQL, args...)
+	rows, err := sqlite().Query(querySQL, args...)
 	if err != nil {
 		Logger.Errorw("查询表是否存在失败", "error", err)
 		return res
@@ -150,8 +288,105 @@ func CheckRowExist(row []string) []string {
 	return res
 }
 
+// IndexedTable 表示向量索引中一张表的元数据
+type IndexedTable struct {
+	TableName  string    `json:"table_name"`
+	SchemaHash string    `json:"schema_hash"`
+	VectorID   int64     `json:"vector_id"`
+	EmbeddedAt time.Time `json:"embedded_at"`
+}
+
+// RecordIndexedTable 记录表结构的向量化结果，已存在的表会被更新
+func RecordIndexedTable(tableName, schemaHash string, vectorID int64) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	_, err := sqlite().Exec(fmt.Sprintf(`
+		INSERT INTO %s (table_name, schema_hash, vector_id, embedded_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(table_name) DO UPDATE SET
+			schema_hash = excluded.schema_hash,
+			vector_id = excluded.vector_id,
+			embedded_at = excluded.embedded_at`, dbTable),
+		tableName, schemaHash, vectorID, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("记录索引元数据失败: %v", err)
+	}
+	return nil
+}
+
+// UpdateIndexedVectorID 更新表在向量集合中对应的向量ID
+func UpdateIndexedVectorID(tableName string, vectorID int64) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+	_, err := sqlite().Exec(fmt.Sprintf("UPDATE %s SET vector_id = ? WHERE table_name = ?", dbTable), vectorID, tableName)
+	if err != nil {
+		return fmt.Errorf("更新向量ID失败: %v", err)
+	}
+	return nil
+}
+
+// ReplaceIndexedTables 用全量重建的结果替换所有索引元数据
+func ReplaceIndexedTables(tables []IndexedTable) error {
+	if err := InitSQLite(); err != nil {
+		return fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	tx, err := sqlite().Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(fmt.Sprintf("DELETE FROM %s", dbTable)); err != nil {
+		return fmt.Errorf("清空索引元数据失败: %v", err)
+	}
+	for _, t := range tables {
+		_, err = tx.Exec(fmt.Sprintf(
+			"INSERT INTO %s (table_name, schema_hash, vector_id, embedded_at) VALUES (?, ?, ?, ?)", dbTable),
+			t.TableName, t.SchemaHash, t.VectorID, t.EmbeddedAt.Unix())
+		if err != nil {
+			return fmt.Errorf("写入索引元数据失败: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListIndexedTables 按表名顺序返回所有已索引的表
+func ListIndexedTables() ([]IndexedTable, error) {
+	if err := InitSQLite(); err != nil {
+		return nil, fmt.Errorf("SQLite初始化失败: %v", err)
+	}
+
+	rows, err := sqlite().Query(fmt.Sprintf(
+		"SELECT table_name, schema_hash, vector_id, embedded_at FROM %s ORDER BY table_name", dbTable))
+	if err != nil {
+		return nil, fmt.Errorf("查询索引元数据失败: %v", err)
+	}
+	defer rows.Close()
+
+	var tables []IndexedTable
+	for rows.Next() {
+		var (
+			t          IndexedTable
+			embeddedAt int64
+		)
+		if err = rows.Scan(&t.TableName, &t.SchemaHash, &t.VectorID, &embeddedAt); err != nil {
+			return nil, fmt.Errorf("扫描索引元数据失败: %v", err)
+		}
+		if embeddedAt > 0 {
+			t.EmbeddedAt = time.Unix(embeddedAt, 0)
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
 // CloseSQLite 关闭SQLite数据库连接
 func CloseSQLite() {
+	sqliteMu.Lock()
+	defer sqliteMu.Unlock()
 	if sqliteDB != nil {
 		Logger.Info("关闭SQLite数据库连接")
 		sqliteDB.Close()
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// statementAliases 同义关键字归一为同一种语句类型
+var statementAliases = map[string]string{
+	"desc": "describe",
+}
+
+// cteBodyKeywords WITH 子句之后可以出现的主语句关键字
+var cteBodyKeywords = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true, "replace": true, "table": true, "values": true,
+}
+
+// sqlTokenKind 为词法单元的类型
+type sqlTokenKind int
+
+const (
+	tokenWord   sqlTokenKind = iota // 关键字或未加引号的标识符，统一为小写
+	tokenIdent                      // 反引号标识符，保留原文
+	tokenString                     // 单引号或双引号字符串
+	tokenNumber                     // 数字
+	tokenPunct                      // 单个符号，如 , . ( ) =
+)
+
+// sqlToken 为语句中的一个词法单元
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	// depth 为所在的括号层数，语句开头的括号（如 (SELECT ...) UNION (SELECT ...)）不计入
+	depth int
+	// stmt 为按分号拆分后所在语句的序号
+	stmt int
+}
+
+// lexSQL 将语句拆分为词法单元，跳过普通注释；MySQL 的可执行注释 /*! ... */ 和 MariaDB 的 /*M! ... */
+// 会被服务端执行，其中的内容按正常语句处理
+func lexSQL(sql string) []sqlToken {
+	var (
+		tokens  []sqlToken
+		depth   int
+		stmt    int
+		started bool // 当前语句是否已出现顶层的关键字
+	)
+	emit := func(kind sqlTokenKind, text string) {
+		tokens = append(tokens, sqlToken{kind: kind, text: text, depth: depth, stmt: stmt})
+		if kind == tokenWord && depth == 0 {
+			started = true
+		}
+	}
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+		case c == '\'' || c == '"' || c == '`':
+			start := i + 1
+			for i++; i < len(runes) && runes[i] != c; i++ {
+				if runes[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+			text := string(runes[start:min(i, len(runes))])
+			if c == '`' {
+				emit(tokenIdent, text)
+			} else {
+				emit(tokenString, text)
+			}
+		case c == '#' || (c == '-' && i+2 < len(runes) && runes[i+1] == '-' && unicode.IsSpace(runes[i+2])):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			if n := executableCommentOpener(runes, i); n > 0 {
+				// 可执行注释：跳过 /*!（或 MariaDB 的 /*M!）和可选的版本号，内容照常解析，并记录一个 /*! 符号便于识别
+				emit(tokenPunct, "/*!")
+				i += n - 1
+				for i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+					i++
+				}
+				continue
+			}
+			for i += 3; i < len(runes) && !(runes[i-1] == '*' && runes[i] == '/'); i++ {
+			}
+		case c == '*' && i+1 < len(runes) && runes[i+1] == '/':
+			// 可执行注释的结尾
+			i++
+		case c == '(':
+			if depth > 0 || started {
+				emit(tokenPunct, "(")
+				depth++
+			}
+		case c == ')':
+			if depth > 0 {
+				depth--
+				emit(tokenPunct, ")")
+			}
+		case c == ';':
+			emit(tokenPunct, ";")
+			stmt, depth, started = stmt+1, 0, false
+		case unicode.IsLetter(c) || c == '_' || c == '$':
+			start := i
+			for i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]) || runes[i+1] == '_' || runes[i+1] == '$') {
+				i++
+			}
+			emit(tokenWord, strings.ToLower(string(runes[start:i+1])))
+		case unicode.IsDigit(c):
+			start := i
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.' || unicode.IsLetter(runes[i+1])) {
+				i++
+			}
+			emit(tokenNumber, string(runes[start:i+1]))
+		default:
+			emit(tokenPunct, string(c))
+		}
+	}
+	return tokens
+}
+
+// executableCommentOpener 判断第 i 个字符开始的是否为可执行注释，返回开头标记的长度：
+// MySQL 的 /*! 为 3，MariaDB 的 /*M! 为 4，普通注释或其他字符返回 0
+func executableCommentOpener(runes []rune, i int) int {
+	if i+2 >= len(runes) || runes[i] != '/' || runes[i+1] != '*' {
+		return 0
+	}
+	if runes[i+2] == '!' {
+		return 3
+	}
+	if runes[i+2] == 'M' && i+3 < len(runes) && runes[i+3] == '!' {
+		return 4
+	}
+	return 0
+}
+
+// trimStatementEnd 去掉语句末尾的空白、分号和注释，便于在末尾追加子句；字符串、标识符和可执行注释中的内容不受影响
+func trimStatementEnd(sql string) string {
+	runes := []rune(sql)
+	end := 0
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c) || c == ';':
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(runes) && runes[i] != c; i++ {
+				if runes[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+			end = min(i+1, len(runes))
+		case c == '#' || (c == '-' && i+2 < len(runes) && runes[i+1] == '-' && unicode.IsSpace(runes[i+2])):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+2 < len(runes) && runes[i+1] == '*' && executableCommentOpener(runes, i) == 0:
+			for i += 3; i < len(runes) && !(runes[i-1] == '*' && runes[i] == '/'); i++ {
+			}
+		default:
+			end = i + 1
+		}
+	}
+	return string(runes[:end])
+}
+
+// sqlTokens 将语句拆分为顶层（括号之外）的关键字序列，按分号拆分为多条语句，跳过字符串、反引号标识符和注释
+func sqlTokens(sql string) [][]string {
+	var (
+		statements [][]string
+		current    []string
+		stmt       int
+	)
+	for _, t := range lexSQL(sql) {
+		if t.stmt != stmt {
+			if len(current) > 0 {
+				statements = append(statements, current)
+			}
+			current, stmt = nil, t.stmt
+		}
+		if t.kind == tokenWord && t.depth == 0 {
+			current = append(current, t.text)
+		}
+	}
+	if len(current) > 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
+
+// classifyTokens 根据顶层关键字判断语句类型
+func classifyTokens(tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	keyword := tokens[0]
+	if alias, ok := statementAliases[keyword]; ok {
+		keyword = alias
+	}
+	if (keyword == "explain" || keyword == "describe") && len(tokens) > 1 && tokens[1] == "analyze" {
+		// EXPLAIN ANALYZE 会真正执行语句，按被分析的语句归类
+		for i, t := range tokens[2:] {
+			if t == "with" || cteBodyKeywords[t] {
+				return classifyTokens(tokens[2+i:])
+			}
+		}
+	}
+	if keyword == "with" {
+		// CTE 的定义都在括号中，顶层第一个主语句关键字就是实际执行的语句
+		for _, t := range tokens[1:] {
+			if cteBodyKeywords[t] {
+				return t
+			}
+		}
+	}
+	return keyword
+}
+
+// classifyStatements 返回语句中每条子语句的类型（小写关键字，如 select、drop），
+// 与 statementKeyword 不同，会跳过前导注释、解析 WITH 子句后的主语句，并识别分号分隔的多条语句
+func classifyStatements(sql string) []string {
+	var types []string
+	for _, tokens := range sqlTokens(sql) {
+		if t := classifyTokens(tokens); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// classifyStatement 返回语句的类型，多条语句时返回第一条的类型
+func classifyStatement(sql string) string {
+	if types := classifyStatements(sql); len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// lockingRead 判断语句是否为加锁读取（FOR UPDATE、FOR SHARE、LOCK IN SHARE MODE），
+// 加锁读取会阻塞其他事务的写入，按写语句处理
+func lockingRead(sql string) bool {
+	tokens := lexSQL(sql)
+	isWord := func(i int, word string) bool {
+		return i < len(tokens) && tokens[i].kind == tokenWord && tokens[i].text == word
+	}
+	for i := range tokens {
+		if isWord(i, "for") && (isWord(i+1, "update") || isWord(i+1, "share")) ||
+			isWord(i, "lock") && isWord(i+1, "in") && isWord(i+2, "share") && isWord(i+3, "mode") {
+			return true
+		}
+	}
+	return false
+}
+
+// statementTypeName 返回用于错误信息的语句类型，并标明加锁读取
+func statementTypeName(sql string) string {
+	name := strings.ToUpper(classifyStatement(sql))
+	if lockingRead(sql) {
+		name += "（加锁读取）"
+	}
+	return name
+}
+
+// tableRef 为语句中引用的一张表，schema 为库名限定（未限定时为空）
+type tableRef struct {
+	schema string
+	name   string
+}
+
+// tableRefKeywords 之后跟随表名或表名列表的关键字
+var tableRefKeywords = map[string]bool{
+	"from": true, "join": true, "straight_join": true, "update": true, "into": true, "table": true, "tables": true,
+	"using": true,
+}
+
+// statementTableKeywords 只在语句开头时之后跟随表名的关键字，如 DESC t、HANDLER t OPEN、INSERT t VALUES (...)
+var statementTableKeywords = map[string]bool{
+	"describe": true, "desc": true, "explain": true, "truncate": true, "handler": true, "insert": true, "replace": true,
+}
+
+// tableNameModifiers 关键字与表名之间可能出现的修饰词
+var tableNameModifiers = map[string]bool{
+	"low_priority": true, "delayed": true, "high_priority": true, "ignore": true, "quick": true,
+	"temporary": true, "if": true, "not": true, "exists": true, "only": true,
+}
+
+// notTableNames 出现在表名位置但不是表名的关键字：子查询、EXPLAIN 的选项和 SELECT ... INTO 的目标等
+var notTableNames = map[string]bool{
+	"select": true, "with": true, "values": true, "value": true, "set": true, "lateral": true, "dual": true,
+	"format": true, "analyze": true, "extended": true, "partitions": true, "for": true, "json_table": true,
+	"outfile": true, "dumpfile": true, "local": true, "status": true,
+}
+
+// tableReferences 返回语句中引用的表：FROM、JOIN（包括 STRAIGHT_JOIN）、UPDATE、INTO、TABLE、DELETE ... USING 之后的表名列表
+// （括号中的表列表、别名、PARTITION 和索引提示都会跳过），语句开头的 DESCRIBE、TRUNCATE、HANDLER、省略 INTO 的 INSERT 和 REPLACE，
+// CREATE/DROP INDEX 和 TRIGGER 的 ON、CREATE TABLE ... LIKE 以及 RENAME/ALTER ... TO 之后的表名。按出现顺序返回，不去重
+func tableReferences(sql string) []tableRef {
+	tokens := lexSQL(sql)
+	isName := func(i int) bool {
+		return i >= 0 && i < len(tokens) && (tokens[i].kind == tokenWord || tokens[i].kind == tokenIdent)
+	}
+	isWord := func(i int, words ...string) bool {
+		if i < 0 || i >= len(tokens) || tokens[i].kind != tokenWord {
+			return false
+		}
+		for _, w := range words {
+			if tokens[i].text == w {
+				return true
+			}
+		}
+		return false
+	}
+	isPunct := func(i int, text string) bool {
+		return i >= 0 && i < len(tokens) && tokens[i].kind == tokenPunct && tokens[i].text == text
+	}
+	// skipGroup 跳过从 i 开始的括号组，返回括号之后的位置；i 不是左括号时原样返回
+	skipGroup := func(i int) int {
+		if !isPunct(i, "(") {
+			return i
+		}
+		depth := tokens[i].depth
+		for i++; i < len(tokens) && !(isPunct(i, ")") && tokens[i].depth == depth); i++ {
+		}
+		return i + 1
+	}
+
+	var (
+		refs     []tableRef
+		first    = make(map[int]string) // 每条语句的第一个关键字
+		onTables = make(map[int]bool)   // 语句为 CREATE/DROP INDEX 或 TRIGGER，其中的 ON 之后是表名
+	)
+	// readList 从 j 开始读取表名列表：table [PARTITION (...)] [[AS] alias] [索引提示 ...] [, ...]
+	readList := func(j int, parens bool) {
+		for {
+			for parens && isPunct(j, "(") {
+				j++
+			}
+			for isName(j) && tokens[j].kind == tokenWord && tableNameModifiers[tokens[j].text] {
+				j++
+			}
+			if !isName(j) || tokens[j].kind == tokenWord && (notTableNames[tokens[j].text] || tableRefKeywords[tokens[j].text]) {
+				return
+			}
+			ref := tableRef{name: tokens[j].text}
+			for isPunct(j+1, ".") && isName(j+2) {
+				ref.schema, ref.name = ref.name, tokens[j+2].text
+				j += 2
+			}
+			refs = append(refs, ref)
+			j++
+			if isWord(j, "partition") {
+				j = skipGroup(j + 1)
+			}
+			if isWord(j, "as") {
+				j++
+			}
+			if isName(j) && !isWord(j, "use", "force", "ignore") && (isPunct(j+1, ",") || isPunct(j+1, ")") ||
+				isWord(j+1, "use", "force", "ignore", "read", "write")) {
+				j++
+			}
+			// 索引提示，如 USE INDEX (i)、FORCE KEY FOR JOIN (i)
+			for isWord(j, "use", "force", "ignore") && isWord(j+1, "index", "key") {
+				for j += 2; isWord(j, "for", "join", "order", "group", "by"); j++ {
+				}
+				j = skipGroup(j)
+			}
+			// LOCK TABLES t READ [LOCAL]、t WRITE
+			for isWord(j, "read", "write", "local", "low_priority") {
+				j++
+			}
+			for parens && isPunct(j, ")") {
+				j++
+			}
+			if !isPunct(j, ",") {
+				return
+			}
+			j++
+		}
+	}
+
+	for i, tok := range tokens {
+		if tok.kind != tokenWord {
+			continue
+		}
+		statementStart := tok.depth == 0 && first[tok.stmt] == ""
+		if statementStart {
+			first[tok.stmt] = tok.text
+		}
+		if (first[tok.stmt] == "create" || first[tok.stmt] == "drop") && tok.depth == 0 && (tok.text == "index" || tok.text == "trigger") {
+			onTables[tok.stmt] = true
+		}
+		switch {
+		case tableRefKeywords[tok.text]:
+			// ON DUPLICATE KEY UPDATE 和 FOR UPDATE 之后不是表名
+			if tok.text == "update" && isWord(i-1, "key", "for") {
+				continue
+			}
+			// JOIN ... USING (col) 的括号中是列名
+			readList(i+1, tok.text != "using")
+		case statementStart && statementTableKeywords[tok.text]:
+			readList(i+1, false)
+		case tok.text == "on" && onTables[tok.stmt] && tok.depth == 0:
+			readList(i+1, false)
+		case tok.text == "like" && first[tok.stmt] == "create" && (isName(i-1) || isPunct(i-1, "(")):
+			readList(i+1, false)
+		case tok.text == "to" && (first[tok.stmt] == "rename" || first[tok.stmt] == "alter"):
+			readList(i+1, false)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,96 @@
+package service
+
+import "testing"
+
+func TestIsQueryStatement(t *testing.T) {
+	cases := []struct {
+		sql   string
+		query bool
+		rows  bool
+	}{
+		{"SELECT * FROM a", true, true},
+		{"  /* comment */ select 1", true, true},
+		{"SHOW TABLES", true, true},
+		{"DESC a", true, true},
+		{"EXPLAIN SELECT * FROM a", true, true},
+		{"EXPLAIN DELETE FROM a", true, true},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", true, true},
+		{"SELECT 'for update' FROM a", true, true},
+		{"SELECT * FROM `for` WHERE `update` = 1", true, true},
+		{"SELECT 1; SELECT 2", true, true},
+
+		{"EXPLAIN ANALYZE SELECT * FROM a", true, true},
+		{"EXPLAIN ANALYZE FORMAT=TREE SELECT * FROM a", true, true},
+		{"EXPLAIN ANALYZE DELETE FROM a WHERE id = 1", false, true},
+		{"EXPLAIN ANALYZE UPDATE a, b SET a.x = b.x", false, true},
+		{"DESCRIBE ANALYZE DELETE FROM a", false, true},
+		{"EXPLAIN ANALYZE WITH t AS (SELECT 1) DELETE FROM a", false, true},
+
+		{"SELECT * FROM a WHERE id = 1 FOR UPDATE", false, true},
+		{"SELECT * FROM a FOR UPDATE NOWAIT", false, true},
+		{"SELECT * FROM a FOR SHARE SKIP LOCKED", false, true},
+		{"SELECT * FROM a LOCK IN SHARE MODE", false, true},
+		{"SELECT * FROM a WHERE id IN (SELECT id FROM b FOR UPDATE)", false, true},
+		{"WITH t AS (SELECT 1) SELECT * FROM a FOR UPDATE", false, true},
+
+		{"DELETE FROM a", false, false},
+		{"INSERT INTO a SELECT * FROM b", false, false},
+		{"SELECT 1; DELETE FROM a", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		if got := isQueryStatement(c.sql); got != c.query {
+			t.Errorf("isQueryStatement(%q) = %v, want %v", c.sql, got, c.query)
+		}
+		if got := returnsRows(c.sql); got != c.rows {
+			t.Errorf("returnsRows(%q) = %v, want %v", c.sql, got, c.rows)
+		}
+	}
+}
+
+func TestClassifyStatementExplainAnalyze(t *testing.T) {
+	cases := map[string]string{
+		"EXPLAIN SELECT 1":                    "explain",
+		"EXPLAIN ANALYZE SELECT 1":            "select",
+		"EXPLAIN ANALYZE DELETE FROM a":       "delete",
+		"EXPLAIN ANALYZE FORMAT=TREE TABLE a": "table",
+		"DESC ANALYZE UPDATE a SET x = 1":     "update",
+		"ANALYZE TABLE a":                     "analyze",
+		"EXPLAIN FORMAT=JSON DELETE FROM a":   "explain",
+	}
+	for sql, want := range cases {
+		if got := classifyStatement(sql); got != want {
+			t.Errorf("classifyStatement(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestTrimStatementEnd(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM a":                             "SELECT * FROM a",
+		"SELECT * FROM a;  \n":                        "SELECT * FROM a",
+		"SELECT * FROM a -- recent rows":              "SELECT * FROM a",
+		"SELECT * FROM a; -- recent rows\n":           "SELECT * FROM a",
+		"SELECT * FROM a # note":                      "SELECT * FROM a",
+		"SELECT * FROM a /* note */ ;":                "SELECT * FROM a",
+		"SELECT * FROM a WHERE b = '-- x'":            "SELECT * FROM a WHERE b = '-- x'",
+		"SELECT * FROM a WHERE b = 'it\\'s' -- x":     "SELECT * FROM a WHERE b = 'it\\'s'",
+		"SELECT * FROM `a -- b`":                      "SELECT * FROM `a -- b`",
+		"SELECT * FROM a /*!80000 FOR SHARE */ -- x":  "SELECT * FROM a /*!80000 FOR SHARE */",
+		"-- only a comment":                           "",
+		"SELECT * FROM a /*M!100100 FOR SHARE */ # x": "SELECT * FROM a /*M!100100 FOR SHARE */",
+	}
+	for sql, want := range cases {
+		if got := trimStatementEnd(sql); got != want {
+			t.Errorf("trimStatementEnd(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestTruncationSuggestionsAddLimit(t *testing.T) {
+	calls := truncationSuggestions("SELECT * FROM a -- recent rows", 100, true)
+	last := calls[len(calls)-1]
+	if got := last.Arguments["query"]; got != "SELECT * FROM a LIMIT 100" {
+		t.Errorf("suggested query = %q", got)
+	}
+}
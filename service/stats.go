@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// largestTablesLimit 统计摘要中列出的最大表数量
+const largestTablesLimit = 5
+
+// SchemaStats 当前库表结构的统计摘要，用于确认结构采集是否符合预期
+type SchemaStats struct {
+	Tables                int          `json:"tables"`
+	Views                 int          `json:"views"`
+	Columns               int          `json:"columns"`
+	IndexedTables         int          `json:"indexed_tables"`
+	TablesMissingComment  float64      `json:"tables_missing_comment_pct"`
+	ColumnsMissingComment float64      `json:"columns_missing_comment_pct"`
+	LargestTables         []TableStats `json:"largest_tables"`
+}
+
+// TableStats 单张表的大小信息，行数来自 information_schema，为估算值
+type TableStats struct {
+	Name      string `json:"name"`
+	Rows      int64  `json:"rows"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// GetSchemaStats 从 information_schema 统计当前库的表、视图、列数量，最大的表以及缺少注释的比例
+func GetSchemaStats(ctx context.Context, db *sql.DB) (*SchemaStats, error) {
+	stats := &SchemaStats{LargestTables: make([]TableStats, 0, largestTablesLimit)}
+
+	var tablesWithoutComment int
+	err := db.QueryRowContext(ctx, labelStatement(ctx, `
+		SELECT
+			COALESCE(SUM(TABLE_TYPE = 'BASE TABLE'), 0),
+			COALESCE(SUM(TABLE_TYPE = 'VIEW'), 0),
+			COALESCE(SUM(TABLE_TYPE = 'BASE TABLE' AND COALESCE(TABLE_COMMENT, '') = ''), 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()`)).
+		Scan(&stats.Tables, &stats.Views, &tablesWithoutComment)
+	if err != nil {
+		return nil, fmt.Errorf("统计表数量失败: %v", err)
+	}
+
+	var columnsWithoutComment int
+	err = db.QueryRowContext(ctx, labelStatement(ctx, `
+		SELECT COUNT(1), COALESCE(SUM(COALESCE(COLUMN_COMMENT, '') = ''), 0)
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()`)).
+		Scan(&stats.Columns, &columnsWithoutComment)
+	if err != nil {
+		return nil, fmt.Errorf("统计列数量失败: %v", err)
+	}
+
+	stats.TablesMissingComment = percent(tablesWithoutComment, stats.Tables)
+	stats.ColumnsMissingComment = percent(columnsWithoutComment, stats.Columns)
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0) AS size
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY size DESC, TABLE_NAME LIMIT ?`), largestTablesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("查询最大表失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t TableStats
+		if err = rows.Scan(&t.Name, &t.Rows, &t.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if IsHiddenTable(t.Name) {
+			continue
+		}
+		stats.LargestTables = append(stats.LargestTables, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询最大表失败: %v", err)
+	}
+
+	indexed, err := ListIndexedTables()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range indexed {
+		if !IsHiddenTable(t.TableName) {
+			stats.IndexedTables++
+		}
+	}
+	return stats, nil
+}
+
+// percent 计算百分比并保留一位小数
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part*1000/total) / 10
+}
+
+// FormatSchemaStats 将统计摘要序列化为 JSON
+func FormatSchemaStats(stats *SchemaStats) (string, error) {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stats to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// LogSchemaStats 统计并记录表结构摘要，失败时只记录日志
+func LogSchemaStats(ctx context.Context, db *sql.DB) {
+	stats, err := GetSchemaStats(ctx, db)
+	if err != nil {
+		Logger.Warnw("统计表结构摘要失败", "error", err)
+		return
+	}
+
+	largest := make([]string, len(stats.LargestTables))
+	for i, t := range stats.LargestTables {
+		largest[i] = fmt.Sprintf("%s(%d rows, %d bytes)", t.Name, t.Rows, t.SizeBytes)
+	}
+	Logger.Infow("表结构统计摘要",
+		"tables", stats.Tables,
+		"views", stats.Views,
+		"columns", stats.Columns,
+		"indexed_tables", stats.IndexedTables,
+		"tables_missing_comment_pct", stats.TablesMissingComment,
+		"columns_missing_comment_pct", stats.ColumnsMissingComment,
+		"largest_tables", largest)
+	if stats.IndexedTables < stats.Tables {
+		Logger.Warnw("部分表尚未进入向量索引", "tables", stats.Tables, "indexed_tables", stats.IndexedTables)
+	}
+}
+
+// DBStats 当前库的容量统计，大小来自 information_schema.TABLES，InnoDB 下为近似值
+type DBStats struct {
+	Database      string         `json:"database"`
+	ServerVersion string         `json:"server_version,omitempty"`
+	Note          string         `json:"note,omitempty"`
+	SizeBytes     int64          `json:"size_bytes"`
+	DataBytes     int64          `json:"data_bytes"`
+	IndexBytes    int64          `json:"index_bytes"`
+	Tables        []TableStorage `json:"tables"`
+}
+
+// TableStorage 单张表的存储信息
+type TableStorage struct {
+	Name         string `json:"name"`
+	Engine       string `json:"engine"`
+	RowsEstimate int64  `json:"rows_estimate"`
+	DataBytes    int64  `json:"data_bytes"`
+	IndexBytes   int64  `json:"index_bytes"`
+	FreeBytes    int64  `json:"free_bytes"`
+}
+
+// GetDBStats 统计当前库的总大小以及每张表的引擎、行数估算、数据和索引大小，按总大小降序排列
+func GetDBStats(ctx context.Context, db *sql.DB) (*DBStats, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	stats := &DBStats{Tables: make([]TableStorage, 0)}
+	if err := db.QueryRowContext(ctx, labelStatement(ctx, "SELECT DATABASE(), VERSION()")).Scan(&stats.Database, &stats.ServerVersion); err != nil {
+		return nil, fmt.Errorf("查询当前库失败: %v", err)
+	}
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT TABLE_NAME, COALESCE(ENGINE, ''), COALESCE(TABLE_ROWS, 0),
+			COALESCE(DATA_LENGTH, 0), COALESCE(INDEX_LENGTH, 0), COALESCE(DATA_FREE, 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0) DESC, TABLE_NAME`))
+	if err != nil {
+		return nil, fmt.Errorf("查询表容量失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t TableStorage
+		if err = rows.Scan(&t.Name, &t.Engine, &t.RowsEstimate, &t.DataBytes, &t.IndexBytes, &t.FreeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if IsHiddenTable(t.Name) {
+			continue
+		}
+		stats.DataBytes += t.DataBytes
+		stats.IndexBytes += t.IndexBytes
+		stats.Tables = append(stats.Tables, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表容量失败: %v", err)
+	}
+	stats.SizeBytes = stats.DataBytes + stats.IndexBytes
+	stats.Note = proxyStatsNote()
+	return stats, nil
+}
+
+// FormatDBStats 将容量统计序列化为 JSON
+func FormatDBStats(stats *DBStats) (string, error) {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stats to JSON: %v", err)
+	}
+	return string(data), nil
+}
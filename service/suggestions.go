@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// SuggestedCall 为附加在结果或错误中的建议调用，模型可以直接按其中的工具和参数继续
+type SuggestedCall struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Reason    string         `json:"reason"`
+}
+
+var (
+	availableMu sync.RWMutex
+	// availableTools 为已注册的工具，为空时（以库的方式使用）不过滤建议
+	availableTools = map[string]bool{}
+)
+
+// MarkToolAvailable 登记已注册的工具，建议中只会出现已登记的工具
+func MarkToolAvailable(name string) {
+	availableMu.Lock()
+	defer availableMu.Unlock()
+	availableTools[name] = true
+}
+
+// toolAvailable 判断工具是否可以出现在建议中
+func toolAvailable(name string) bool {
+	availableMu.RLock()
+	defer availableMu.RUnlock()
+	return len(availableTools) == 0 || availableTools[name]
+}
+
+// formatSuggestions 将建议调用格式化为附加在结果末尾的 suggested_next_calls 行，没有可用的建议时返回空字符串
+func formatSuggestions(calls []SuggestedCall) string {
+	available := make([]SuggestedCall, 0, len(calls))
+	for _, c := range calls {
+		if toolAvailable(c.Tool) {
+			available = append(available, c)
+		}
+	}
+	if len(available) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(available)
+	if err != nil {
+		return ""
+	}
+	return "\n\nsuggested_next_calls: " + string(data)
+}
+
+// suggestionError 为附带建议调用的错误
+type suggestionError struct {
+	err   error
+	calls []SuggestedCall
+}
+
+func (e *suggestionError) Error() string {
+	return e.err.Error() + formatSuggestions(e.calls)
+}
+
+func (e *suggestionError) Unwrap() error {
+	return e.err
+}
+
+// withSuggestions 为错误附加建议调用，没有建议时原样返回
+func withSuggestions(err error, calls []SuggestedCall) error {
+	if err == nil || len(calls) == 0 {
+		return err
+	}
+	return &suggestionError{err: err, calls: calls}
+}
+
+// truncationSuggestions 为结果被截断的查询给出取得完整结果的方式，addLimit 为 true 时（语句本身没有 LIMIT）
+// 同时建议显式加上 LIMIT
+func truncationSuggestions(sql string, rows int, addLimit bool) []SuggestedCall {
+	calls := []SuggestedCall{
+		{Tool: "query_page", Arguments: map[string]any{"query": sql, "limit": min(rows, 1000)},
+			Reason: "page through the full result; add ORDER BY for stable pages"},
+		{Tool: "export_query_csv", Arguments: map[string]any{"query": sql},
+			Reason: "export every row as CSV instead of reading it into the conversation"},
+	}
+	if addLimit {
+		calls = append(calls, SuggestedCall{Tool: "execute_sql",
+			Arguments: map[string]any{"query": trimStatementEnd(sql) + " LIMIT " + strconv.Itoa(rows)},
+			Reason:    "add an explicit LIMIT (or narrower WHERE conditions) to control the rows returned"})
+	}
+	return calls
+}
+
+// executionErrorSuggestions 为常见的执行错误（表或列不存在）给出定位正确名称的方式
+func executionErrorSuggestions(sql string, err error) []SuggestedCall {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return nil
+	}
+	switch mysqlErr.Number {
+	case 1146: // Table doesn't exist
+		return []SuggestedCall{
+			{Tool: "list_tables", Reason: "list the tables that actually exist"},
+			{Tool: "get_can_use_table", Reason: "describe the data you need in natural language to find the right tables"},
+		}
+	case 1054: // Unknown column
+		var calls []SuggestedCall
+		for _, table := range referencedTables(sql) {
+			calls = append(calls, SuggestedCall{Tool: "describe_table", Arguments: map[string]any{"table": table},
+				Reason: "check the real column names of " + table})
+		}
+		return calls
+	}
+	return nil
+}
+
+// referencedTables 返回语句中引用的表名（去掉库名前缀），按出现顺序去重，解析规则见 tableReferences
+func referencedTables(sql string) []string {
+	var tables []string
+	for _, ref := range tableReferences(sql) {
+		if ValidateIdentifier(ref.name) == nil && !containsFold(tables, ref.name) {
+			tables = append(tables, ref.name)
+		}
+	}
+	return tables
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// summaryTopValues 分类列返回的高频值数量
+const summaryTopValues = 5
+
+// summarySampleRows 摘要中附带的示例行数
+const summarySampleRows = 3
+
+// ColumnSummary 表示结果集中一列的统计信息
+type ColumnSummary struct {
+	Type      string       `json:"type"` // numeric 或 categorical
+	NullCount int          `json:"null_count"`
+	Min       *float64     `json:"min,omitempty"`
+	Max       *float64     `json:"max,omitempty"`
+	Avg       *float64     `json:"avg,omitempty"`
+	Distinct  int          `json:"distinct,omitempty"`
+	TopValues []ValueCount `json:"top_values,omitempty"`
+}
+
+// ValueCount 表示分类列中一个取值及其出现次数
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// ResultSummary 表示结果集的统计摘要，用于代替超出预算的原始行
+type ResultSummary struct {
+	Summarized bool                     `json:"summarized"`
+	RowCount   int                      `json:"row_count"`
+	Columns    []string                 `json:"columns"`
+	Stats      map[string]ColumnSummary `json:"stats"`
+	SampleRows []map[string]interface{} `json:"sample_rows"`
+	// StatsOmitted 为超出预算而省略统计信息的列数
+	StatsOmitted int    `json:"stats_omitted,omitempty"`
+	Note         string `json:"note"`
+}
+
+// summaryValueRunes 摘要超出预算时高频值保留的最大字符数
+const summaryValueRunes = 64
+
+// summarizeRows 在服务端计算结果集的统计摘要：数值列给出最小/最大/平均值，其他列给出高频值。
+// maxTokens 大于0时摘要本身也要放进预算：依次改用紧凑格式、去掉示例行、缩短高频值，
+// 最后按列的顺序只保留放得下的统计信息
+func summarizeRows(columns []string, resultSet []map[string]interface{}, maxTokens int) (string, error) {
+	summary := ResultSummary{
+		Summarized: true,
+		RowCount:   len(resultSet),
+		Columns:    columns,
+		Stats:      make(map[string]ColumnSummary, len(columns)),
+		Note:       Text("summary_note"),
+	}
+
+	for _, col := range columns {
+		summary.Stats[col] = summarizeColumn(col, resultSet)
+	}
+
+	sampleSize := summarySampleRows
+	if sampleSize > len(resultSet) {
+		sampleSize = len(resultSet)
+	}
+	summary.SampleRows = resultSet[:sampleSize]
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal summary to JSON: %v", err)
+	}
+	if maxTokens <= 0 || EstimateTokens(string(data)) <= maxTokens {
+		return string(data), nil
+	}
+
+	fits := func() (string, bool, error) {
+		data, err := json.Marshal(summary)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to marshal summary to JSON: %v", err)
+		}
+		return string(data), EstimateTokens(string(data)) <= maxTokens, nil
+	}
+	shrinks := []func(){
+		func() {},
+		func() { summary.SampleRows = nil },
+		func() {
+			for col, stat := range summary.Stats {
+				if len(stat.TopValues) > 1 {
+					stat.TopValues = stat.TopValues[:1]
+				}
+				for i, v := range stat.TopValues {
+					stat.TopValues[i].Value = truncateRunes(v.Value, summaryValueRunes)
+				}
+				summary.Stats[col] = stat
+			}
+		},
+	}
+	for _, shrink := range shrinks {
+		shrink()
+		res, ok, err := fits()
+		if err != nil || ok {
+			return res, err
+		}
+	}
+
+	// 仍然超出时按列的顺序保留放得下的统计信息
+	stats := summary.Stats
+	summary.Stats = make(map[string]ColumnSummary, len(columns))
+	base, _, err := fits()
+	if err != nil {
+		return "", err
+	}
+	used := EstimateTokens(base)
+	for i, col := range columns {
+		entry, err := json.Marshal(map[string]ColumnSummary{col: stats[col]})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal summary to JSON: %v", err)
+		}
+		if used += EstimateTokens(string(entry)); used > maxTokens {
+			summary.StatsOmitted = len(columns) - i
+			break
+		}
+		summary.Stats[col] = stats[col]
+	}
+	res, _, err := fits()
+	return res, err
+}
+
+// summarizeColumn 统计一列的取值：所有非空值都能解析为数字时按数值列处理
+func summarizeColumn(col string, resultSet []map[string]interface{}) ColumnSummary {
+	var (
+		nulls   int
+		numbers []float64
+		numeric = true
+		counts  = make(map[string]int)
+	)
+
+	for _, row := range resultSet {
+		val := row[col]
+		if val == nil {
+			nulls++
+			continue
+		}
+		if numeric {
+			if f, ok := toFloat(val); ok {
+				numbers = append(numbers, f)
+			} else {
+				numeric = false
+			}
+		}
+		counts[fmt.Sprint(val)]++
+	}
+
+	if numeric && len(numbers) > 0 {
+		minVal, maxVal, sum := numbers[0], numbers[0], 0.0
+		for _, n := range numbers {
+			if n < minVal {
+				minVal = n
+			}
+			if n > maxVal {
+				maxVal = n
+			}
+			sum += n
+		}
+		avg := sum / float64(len(numbers))
+		return ColumnSummary{Type: "numeric", NullCount: nulls, Min: &minVal, Max: &maxVal, Avg: &avg}
+	}
+
+	top := make([]ValueCount, 0, len(counts))
+	for value, count := range counts {
+		top = append(top, ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Value < top[j].Value
+	})
+	if len(top) > summaryTopValues {
+		top = top[:summaryTopValues]
+	}
+	return ColumnSummary{Type: "categorical", NullCount: nulls, Distinct: len(counts), TopValues: top}
+}
+
+// toFloat 尝试将扫描得到的值转换为浮点数
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case time.Time, bool:
+		return 0, false
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// truncateRunes 将文本截断为最多 n 个字符，截断时末尾加上省略号
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"path"
+	"strings"
+)
+
+// defaultSummaryTablePatterns 常见的汇总表命名约定
+var defaultSummaryTablePatterns = []string{
+	"*_daily", "*_hourly", "*_weekly", "*_monthly", "*_yearly",
+	"*_agg", "*_aggregate", "*_summary", "*_stats", "*_rollup", "*_report",
+	"agg_*", "summary_*", "rpt_*",
+}
+
+// aggregateKeywords 判断问题是否为聚合类问题的关键词，英文关键词按单词匹配
+var aggregateKeywords = []string{
+	"总", "合计", "汇总", "统计", "平均", "趋势", "每天", "每日", "每周", "每月", "每年", "按天", "按月", "多少", "数量", "占比", "排名",
+	"total", "sum", "count", "average", "avg", "trend", "daily", "weekly", "monthly", "yearly",
+	"per", "how many", "how much", "breakdown", "top", "ratio", "growth",
+}
+
+// SummaryTableConfig 控制检索时对汇总表的偏好
+type SummaryTableConfig struct {
+	// Patterns 为汇总表名称的通配符模式（path.Match 语法），为空时使用默认的命名约定
+	Patterns []string
+	// Tables 为明确标记的汇总表
+	Tables []string
+	// Boost 为聚合类问题中汇总表相似度的放大倍数，不大于1时关闭该功能
+	Boost float64
+}
+
+// 全局汇总表配置
+var SummaryTables SummaryTableConfig
+
+// InitSummaryTableConfig 初始化汇总表配置
+func InitSummaryTableConfig(cfg SummaryTableConfig) {
+	if len(cfg.Patterns) == 0 {
+		cfg.Patterns = defaultSummaryTablePatterns
+	}
+	SummaryTables = cfg
+}
+
+// isSummaryTable 判断表是否为汇总表
+func isSummaryTable(name string) bool {
+	lower := strings.ToLower(name)
+	for _, t := range SummaryTables.Tables {
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+	for _, pattern := range SummaryTables.Patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), lower); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isAggregateQuestion 粗略判断问题是否询问聚合结果（总数、趋势、平均值等）
+func isAggregateQuestion(query string) bool {
+	lower := strings.ToLower(query)
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	wordSet := make(map[string]bool, len(words))
+	for _, w := range words {
+		wordSet[w] = true
+	}
+
+	for _, keyword := range aggregateKeywords {
+		switch {
+		case keyword[0] >= 'a' && keyword[0] <= 'z' && strings.Contains(keyword, " "):
+			if strings.Contains(" "+strings.Join(words, " ")+" ", " "+keyword+" ") {
+				return true
+			}
+		case keyword[0] >= 'a' && keyword[0] <= 'z':
+			if wordSet[keyword] {
+				return true
+			}
+		default:
+			if strings.Contains(query, keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// preferSummaryTables 对聚合类问题提升汇总表的相似度，引导调用方使用预聚合的数据而不是扫描原始明细表
+func preferSummaryTables(query string, hits []SchemaHit) []SchemaHit {
+	if SummaryTables.Boost <= 1 || len(hits) == 0 || !isAggregateQuestion(query) {
+		return hits
+	}
+
+	boosted := 0
+	for i := range hits {
+		if name, ok := tableNameFromSchema(hits[i].Schema); ok && isSummaryTable(name) {
+			hits[i].Score *= float32(SummaryTables.Boost)
+			boosted++
+		}
+	}
+	if boosted == 0 {
+		return hits
+	}
+	sortSchemaHits(hits)
+	Logger.Debugw("聚合类问题，已提升汇总表排序", "query", query, "tables", boosted)
+	return hits
+}
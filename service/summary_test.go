@@ -0,0 +1,40 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeRowsBudget(t *testing.T) {
+	columns := make([]string, 50)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("column_%02d", i)
+	}
+	rows := make([]map[string]interface{}, 20)
+	for i := range rows {
+		row := make(map[string]interface{}, len(columns))
+		for _, col := range columns {
+			row[col] = strings.Repeat(fmt.Sprintf("%s-%d ", col, i), 20)
+		}
+		rows[i] = row
+	}
+
+	for _, budget := range []int{4000, 1500, 600} {
+		res, err := summarizeRows(columns, rows, budget)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tokens := EstimateTokens(res); tokens > budget {
+			t.Errorf("budget %d: summary uses %d tokens", budget, tokens)
+		}
+		var summary ResultSummary
+		if err = json.Unmarshal([]byte(res), &summary); err != nil {
+			t.Fatalf("budget %d: %v", budget, err)
+		}
+		if len(summary.Stats)+summary.StatsOmitted != len(columns) {
+			t.Errorf("budget %d: %d stats + %d omitted != %d columns", budget, len(summary.Stats), summary.StatsOmitted, len(columns))
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TableInfo 描述当前库中的一张表或视图
+type TableInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// RowsEstimate 来自 information_schema.TABLES，InnoDB 下为估算值，视图为0
+	RowsEstimate int64  `json:"rows_estimate"`
+	Comment      string `json:"comment,omitempty"`
+}
+
+// ListTables 返回当前库的所有表及其行数估算和注释
+func ListTables(ctx context.Context, db *sql.DB) ([]TableInfo, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT TABLE_NAME, TABLE_TYPE, COALESCE(TABLE_ROWS, 0), COALESCE(TABLE_COMMENT, '')
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME`))
+	if err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %v", err)
+	}
+	defer rows.Close()
+
+	tables := make([]TableInfo, 0)
+	for rows.Next() {
+		var t TableInfo
+		if err = rows.Scan(&t.Name, &t.Type, &t.RowsEstimate, &t.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if IsHiddenTable(t.Name) {
+			continue
+		}
+		tables = append(tables, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %v", err)
+	}
+	return tables, nil
+}
+
+// FormatTables 将表列表序列化为 JSON
+func FormatTables(tables []TableInfo) (string, error) {
+	data, err := json.MarshalIndent(tables, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tables to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// ColumnInfo 描述表中的一列
+type ColumnInfo struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Key      string  `json:"key,omitempty"` // PRI、UNI、MUL
+	Default  *string `json:"default,omitempty"`
+	Extra    string  `json:"extra,omitempty"`
+	Comment  string  `json:"comment,omitempty"`
+	// Hint 来自列说明配置的业务含义和语义类型
+	Hint *ColumnHint `json:"hint,omitempty"`
+	// Stats 为定时采集并保存在 SQLite 中的空值比例和不同值数量
+	Stats *ColumnStats `json:"stats,omitempty"`
+}
+
+// IndexInfo 描述表上的一个索引
+type IndexInfo struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Type    string   `json:"type"`
+	Columns []string `json:"columns"`
+}
+
+// TableDescription 表的结构化描述
+type TableDescription struct {
+	TableInfo
+	Description string       `json:"description,omitempty"`
+	Columns     []ColumnInfo `json:"columns"`
+	Indexes     []IndexInfo  `json:"indexes"`
+	// DDL 为按配置精简后的建表语句，调用方需要时才填充
+	DDL string `json:"ddl,omitempty"`
+}
+
+// DescribeTable 从 information_schema 获取表的列、键、索引和注释
+func DescribeTable(ctx context.Context, db *sql.DB, table string) (*TableDescription, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return nil, err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return nil, err
+	}
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	desc := &TableDescription{Columns: make([]ColumnInfo, 0), Indexes: make([]IndexInfo, 0)}
+	err := db.QueryRowContext(ctx, labelStatement(ctx, `
+		SELECT TABLE_NAME, TABLE_TYPE, COALESCE(TABLE_ROWS, 0), COALESCE(TABLE_COMMENT, '')
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`), table).
+		Scan(&desc.Name, &desc.Type, &desc.RowsEstimate, &desc.Comment)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("表不存在: %s", table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询表信息失败: %v", err)
+	}
+	desc.Description = tableDescription(table)
+	stats, err := LoadColumnStats(table)
+	if err != nil {
+		Logger.Warnw("读取列统计失败", "table", table, "error", err)
+	}
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES', COLUMN_KEY, COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`), table)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			c        ColumnInfo
+			defValue sql.NullString
+		)
+		if err = rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Key, &defValue, &c.Extra, &c.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if defValue.Valid {
+			c.Default = &defValue.String
+		}
+		if hint, ok := columnHint(table, c.Name); ok {
+			c.Hint = &hint
+		}
+		if s, ok := stats[c.Name]; ok {
+			s.Column = ""
+			c.Stats = &s
+		}
+		desc.Columns = append(desc.Columns, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列信息失败: %v", err)
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, labelStatement(ctx, `
+		SELECT INDEX_NAME, NON_UNIQUE = 0, INDEX_TYPE, COLUMN_NAME
+		FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX`), table)
+	if err != nil {
+		return nil, fmt.Errorf("查询索引信息失败: %v", err)
+	}
+	defer rows.Close()
+	positions := make(map[string]int)
+	for rows.Next() {
+		var (
+			name, indexType string
+			unique          bool
+			column          sql.NullString
+		)
+		if err = rows.Scan(&name, &unique, &indexType, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		i, ok := positions[name]
+		if !ok {
+			i = len(desc.Indexes)
+			positions[name] = i
+			desc.Indexes = append(desc.Indexes, IndexInfo{Name: name, Unique: unique, Type: indexType, Columns: []string{}})
+		}
+		// 函数索引没有对应的列名
+		if column.Valid {
+			desc.Indexes[i].Columns = append(desc.Indexes[i].Columns, column.String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询索引信息失败: %v", err)
+	}
+	return desc, nil
+}
+
+// FormatTableDescription 将表描述序列化为 JSON
+func FormatTableDescription(desc *TableDescription) (string, error) {
+	data, err := json.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal table to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+const (
+	defaultTableSampleRows = 10
+	maxTableSampleRows     = 100
+)
+
+// GetTableSample 返回表的前 rows 行，用于查看字段取值形态。表名需存在于 information_schema 中
+func GetTableSample(ctx context.Context, db *sql.DB, table string, rows int, opts ExecOptions) (string, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return "", err
+	}
+	if err := checkTableVisible(table); err != nil {
+		return "", err
+	}
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if rows <= 0 {
+		rows = defaultTableSampleRows
+	}
+	if rows > maxTableSampleRows {
+		rows = maxTableSampleRows
+	}
+
+	var name string
+	err := db.QueryRowContext(ctx,
+		labelStatement(ctx, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"), table).
+		Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("表不存在: %s", table)
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询表信息失败: %v", err)
+	}
+
+	opts.Args = []any{rows}
+	return ExecuteWithOptions(ctx, db, "SELECT * FROM "+quoteIdentifier(name)+" LIMIT ?", opts)
+}
+
+// findColumnsLimit find_columns 返回的最大列数
+const findColumnsLimit = 200
+
+// ColumnMatch 为列搜索的一条结果
+type ColumnMatch struct {
+	Table   string `json:"table"`
+	Column  string `json:"column"`
+	Type    string `json:"type"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// FindColumns 在当前库的 information_schema.COLUMNS 中查找列。pattern 默认按子串匹配列名，
+// 其中的 % 和 _ 按普通字符处理（列名中常见 _，如 user_id）；like 为 true 时 pattern 按 LIKE 模式匹配。
+// comment 为列注释包含的文本，两者都指定时需同时满足
+func FindColumns(ctx context.Context, db *sql.DB, pattern string, like bool, comment string) ([]ColumnMatch, bool, error) {
+	if db == nil {
+		return nil, false, fmt.Errorf("database connection not initialized")
+	}
+	if pattern == "" && comment == "" {
+		return nil, false, fmt.Errorf("请指定 pattern 或 comment")
+	}
+
+	query := `
+		SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, COALESCE(COLUMN_COMMENT, '')
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()`
+	args := []any{}
+	if pattern != "" {
+		if !like {
+			// 使用 ! 作为转义符，不受 NO_BACKSLASH_ESCAPES 影响
+			pattern = "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(pattern) + "%"
+		}
+		query += " AND COLUMN_NAME LIKE ? ESCAPE '!'"
+		args = append(args, pattern)
+	}
+	if comment != "" {
+		query += " AND LOCATE(?, COLUMN_COMMENT) > 0"
+		args = append(args, comment)
+	}
+	// 多取一行用于判断是否截断
+	query += " ORDER BY TABLE_NAME, ORDINAL_POSITION LIMIT ?"
+	args = append(args, findColumnsLimit+1)
+
+	rows, err := db.QueryContext(ctx, labelStatement(ctx, query), args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("查询列信息失败: %v", err)
+	}
+	defer rows.Close()
+
+	matches := make([]ColumnMatch, 0)
+	for rows.Next() {
+		var m ColumnMatch
+		if err = rows.Scan(&m.Table, &m.Column, &m.Type, &m.Comment); err != nil {
+			return nil, false, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if IsHiddenTable(m.Table) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("查询列信息失败: %v", err)
+	}
+
+	truncated := len(matches) > findColumnsLimit
+	if truncated {
+		matches = matches[:findColumnsLimit]
+	}
+	return matches, truncated, nil
+}
+
+// FormatColumnMatches 将列搜索结果序列化为 JSON
+func FormatColumnMatches(matches []ColumnMatch, truncated bool) (string, error) {
+	data, err := json.MarshalIndent(map[string]any{"columns": matches, "truncated": truncated}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal columns to JSON: %v", err)
+	}
+	return string(data), nil
+}
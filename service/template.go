@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateParam 描述查询模板中的一个参数槽位
+type TemplateParam struct {
+	Name        string `yaml:"name" json:"name"`
+	Type        string `yaml:"type" json:"type"` // string、int、float、bool、date，默认 string
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+// QueryTemplate 表示一条预先登记的查询模板，SQL 中以 :name 表示参数槽位
+type QueryTemplate struct {
+	Name        string          `yaml:"name" json:"name"`
+	Description string          `yaml:"description" json:"description,omitempty"`
+	SQL         string          `yaml:"sql" json:"sql"`
+	Params      []TemplateParam `yaml:"params" json:"params,omitempty"`
+
+	// compiled 为槽位替换为 ? 后的语句，slots 为占位符依次对应的参数名
+	compiled string
+	slots    []string
+}
+
+// TemplateConfig 存储查询模板的相关配置
+type TemplateConfig struct {
+	// Strict 为 true 时只允许执行已登记的模板，不开放任何自由 SQL 工具
+	Strict    bool
+	Templates map[string]*QueryTemplate
+}
+
+// 全局查询模板配置
+var Templates TemplateConfig
+
+// InitQueryTemplates 从 YAML 文件加载查询模板
+//
+//	templates:
+//	  - name: orders_by_user
+//	    description: 查询用户在指定日期之后的订单
+//	    sql: SELECT * FROM orders WHERE user_id = :user_id AND created_at >= :since
+//	    params:
+//	      - {name: user_id, type: int}
+//	      - {name: since, type: date}
+func InitQueryTemplates(path string, strict bool) error {
+	Templates = TemplateConfig{Strict: strict, Templates: map[string]*QueryTemplate{}}
+	if path == "" {
+		if strict {
+			return fmt.Errorf("启用了模板严格模式但未配置模板文件")
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取查询模板文件失败: %v", err)
+	}
+	var file struct {
+		Templates []*QueryTemplate `yaml:"templates"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析查询模板文件失败: %v", err)
+	}
+
+	for _, t := range file.Templates {
+		if t.Name == "" || strings.TrimSpace(t.SQL) == "" {
+			return fmt.Errorf("查询模板缺少 name 或 sql")
+		}
+		if _, ok := Templates.Templates[t.Name]; ok {
+			return fmt.Errorf("查询模板重复: %s", t.Name)
+		}
+		if err = t.compile(); err != nil {
+			return fmt.Errorf("查询模板 %s 无效: %v", t.Name, err)
+		}
+		Templates.Templates[t.Name] = t
+	}
+	Logger.Infow("查询模板加载完成", "count", len(Templates.Templates), "strict", strict)
+	return nil
+}
+
+// compile 将 :name 槽位替换为 ? 占位符，并校验槽位与参数声明一致
+func (t *QueryTemplate) compile() error {
+	declared := make(map[string]*TemplateParam, len(t.Params))
+	for i := range t.Params {
+		p := &t.Params[i]
+		if p.Type == "" {
+			p.Type = "string"
+		}
+		switch p.Type {
+		case "string", "int", "float", "bool", "date":
+		default:
+			return fmt.Errorf("参数 %s 的类型 %s 不受支持", p.Name, p.Type)
+		}
+		declared[p.Name] = p
+	}
+
+	var b strings.Builder
+	var quote byte
+	used := make(map[string]bool)
+	src := t.SQL
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			// 字符串和标识符中的冒号不是槽位
+			if c == '\\' && quote != '`' && i+1 < len(src) {
+				b.WriteByte(c)
+				i++
+				c = src[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ':' && i+1 < len(src) && isIdentStart(src[i+1]) && (i == 0 || src[i-1] != ':'):
+			j := i + 1
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			name := src[i+1 : j]
+			if declared[name] == nil {
+				return fmt.Errorf("槽位 :%s 未在 params 中声明", name)
+			}
+			t.slots = append(t.slots, name)
+			used[name] = true
+			b.WriteByte('?')
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	for name := range declared {
+		if !used[name] {
+			return fmt.Errorf("参数 %s 未在 SQL 中使用", name)
+		}
+	}
+	t.compiled = b.String()
+	return nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// ListQueryTemplates 按名称顺序以 JSON 返回所有查询模板
+func ListQueryTemplates() (string, error) {
+	list := make([]*QueryTemplate, 0, len(Templates.Templates))
+	for _, t := range Templates.Templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal templates to JSON: %v", err)
+	}
+	return string(data), nil
+}
+
+// RunQueryTemplate 按参数执行指定模板，参数以预处理语句的方式绑定，不会拼接进 SQL
+func RunQueryTemplate(ctx context.Context, db *sql.DB, name string, params map[string]any, opts ExecOptions) (string, error) {
+	t, ok := Templates.Templates[name]
+	if !ok {
+		return "", fmt.Errorf("查询模板不存在: %s", name)
+	}
+
+	types := make(map[string]string, len(t.Params))
+	for _, p := range t.Params {
+		types[p.Name] = p.Type
+	}
+	for key := range params {
+		if _, ok := types[key]; !ok {
+			return "", fmt.Errorf("模板 %s 没有参数 %s", name, key)
+		}
+	}
+
+	args := make([]any, len(t.slots))
+	for i, slot := range t.slots {
+		value, ok := params[slot]
+		if !ok {
+			return "", fmt.Errorf("缺少参数: %s", slot)
+		}
+		arg, err := convertTemplateParam(types[slot], value)
+		if err != nil {
+			return "", fmt.Errorf("参数 %s 无效: %v", slot, err)
+		}
+		args[i] = arg
+	}
+
+	opts.Args = args
+	return ExecuteWithOptions(ctx, db, t.compiled, opts)
+}
+
+// convertTemplateParam 按声明的类型转换工具传入的参数值
+func convertTemplateParam(paramType string, value any) (any, error) {
+	switch paramType {
+	case "int":
+		switch v := value.(type) {
+		case float64:
+			if v != float64(int64(v)) {
+				return nil, fmt.Errorf("需要整数: %v", v)
+			}
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case "float":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case "date":
+		if v, ok := value.(string); ok {
+			for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339} {
+				if _, err := time.Parse(layout, v); err == nil {
+					return v, nil
+				}
+			}
+			return nil, fmt.Errorf("日期格式应为 YYYY-MM-DD 或 YYYY-MM-DD HH:MM:SS: %s", v)
+		}
+	default:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64, bool:
+			return fmt.Sprint(v), nil
+		}
+	}
+	return nil, fmt.Errorf("需要 %s 类型，实际为 %T", paramType, value)
+}
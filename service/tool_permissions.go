@@ -0,0 +1,167 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ArgRule 限制工具某个参数的取值
+type ArgRule struct {
+	// Min、Max 限制数值参数的范围
+	Min *float64 `yaml:"min" json:"min,omitempty"`
+	Max *float64 `yaml:"max" json:"max,omitempty"`
+	// Allowed 非空时参数只能取其中的值，字符串不区分大小写
+	Allowed []string `yaml:"allowed" json:"allowed,omitempty"`
+	// MaxLength 大于0时限制字符串参数的长度
+	MaxLength int `yaml:"max_length" json:"max_length,omitempty"`
+	// Forbidden 为 true 时不允许传入该参数
+	Forbidden bool `yaml:"forbidden" json:"forbidden,omitempty"`
+}
+
+// ToolRule 为单个工具的权限配置
+type ToolRule struct {
+	// Disabled 为 true 时不注册该工具
+	Disabled bool `yaml:"disabled" json:"disabled,omitempty"`
+	// ReadOnly 为 true 时该工具的 query、sql、queries 参数只能是查询类语句
+	ReadOnly bool               `yaml:"read_only" json:"read_only,omitempty"`
+	Args     map[string]ArgRule `yaml:"args" json:"args,omitempty"`
+}
+
+// ToolPermissionConfig 控制对外暴露哪些工具以及各工具参数的取值范围
+type ToolPermissionConfig struct {
+	// Enabled 非空时只注册这些工具
+	Enabled []string `yaml:"enabled"`
+	// Disabled 不注册的工具，优先于 Enabled
+	Disabled []string            `yaml:"disabled"`
+	Tools    map[string]ToolRule `yaml:"tools"`
+}
+
+// 全局工具权限配置
+var ToolPermissions ToolPermissionConfig
+
+// InitToolPermissions 从 YAML 文件加载工具权限配置，enabled、disabled 非空时覆盖文件中的同名设置：
+//
+//	enabled: [get_can_use_table, describe_table, execute_sql]
+//	tools:
+//	  execute_sql:
+//	    read_only: true
+//	    args:
+//	      timeout_seconds: {max: 30}
+//	      database: {allowed: [analytics]}
+//	  get_table_sample:
+//	    args:
+//	      rows: {min: 1, max: 20}
+func InitToolPermissions(path string, enabled, disabled []string) error {
+	cfg := ToolPermissionConfig{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取工具权限文件失败: %v", err)
+		}
+		if err = yaml.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("解析工具权限文件失败: %v", err)
+		}
+	}
+	if len(enabled) > 0 {
+		cfg.Enabled = enabled
+	}
+	if len(disabled) > 0 {
+		cfg.Disabled = disabled
+	}
+	for name, rule := range cfg.Tools {
+		for arg, r := range rule.Args {
+			if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+				return fmt.Errorf("工具 %s 的参数 %s 配置错误: min 大于 max", name, arg)
+			}
+		}
+	}
+
+	ToolPermissions = cfg
+	if len(cfg.Enabled) > 0 || len(cfg.Disabled) > 0 || len(cfg.Tools) > 0 {
+		Logger.Infow("工具权限配置已加载", "enabled", cfg.Enabled, "disabled", cfg.Disabled, "rules", len(cfg.Tools))
+	}
+	return nil
+}
+
+// ToolEnabled 判断工具是否需要注册
+func ToolEnabled(name string) bool {
+	if containsFold(ToolPermissions.Disabled, name) || ToolPermissions.Tools[name].Disabled {
+		return false
+	}
+	return len(ToolPermissions.Enabled) == 0 || containsFold(ToolPermissions.Enabled, name)
+}
+
+// CheckToolArguments 按工具权限配置检查调用参数，不符合时返回错误
+func CheckToolArguments(name string, args map[string]any) error {
+	rule, ok := ToolPermissions.Tools[name]
+	if !ok {
+		return nil
+	}
+	for arg, r := range rule.Args {
+		value, present := args[arg]
+		if !present || value == nil {
+			continue
+		}
+		if err := r.check(value); err != nil {
+			return fmt.Errorf("工具 %s 的参数 %s 不允许: %v", name, arg, err)
+		}
+	}
+	if rule.ReadOnly {
+		for _, arg := range []string{"query", "sql", "queries"} {
+			for _, statement := range stringValues(args[arg]) {
+				if strings.TrimSpace(statement) != "" && !isQueryStatement(statement) {
+					return fmt.Errorf("工具 %s 只允许执行 SELECT、SHOW、DESCRIBE、EXPLAIN 等查询语句", name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// check 检查单个参数的取值
+func (r ArgRule) check(value any) error {
+	if r.Forbidden {
+		return fmt.Errorf("该参数已被禁用")
+	}
+	if n, ok := value.(float64); ok {
+		if r.Min != nil && n < *r.Min {
+			return fmt.Errorf("取值 %v 小于最小值 %v", n, *r.Min)
+		}
+		if r.Max != nil && n > *r.Max {
+			return fmt.Errorf("取值 %v 大于最大值 %v", n, *r.Max)
+		}
+	}
+	if s, ok := value.(string); ok && r.MaxLength > 0 && len([]rune(s)) > r.MaxLength {
+		return fmt.Errorf("长度超过 %d", r.MaxLength)
+	}
+	if len(r.Allowed) > 0 {
+		for _, v := range flattenValues(value) {
+			if !containsFold(r.Allowed, fmt.Sprint(v)) {
+				return fmt.Errorf("取值 %v 不在允许范围内（%s）", v, strings.Join(r.Allowed, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// flattenValues 将数组参数展开为各个元素，其他参数返回自身
+func flattenValues(value any) []any {
+	if list, ok := value.([]any); ok {
+		return list
+	}
+	return []any{value}
+}
+
+// stringValues 返回参数中的字符串，数组参数返回其中的字符串元素
+func stringValues(value any) []string {
+	var values []string
+	for _, v := range flattenValues(value) {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
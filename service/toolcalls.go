@@ -0,0 +1,57 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// maxRecentToolCalls 内存中保留的最近工具调用条数
+	maxRecentToolCalls = 100
+	// maxToolCallArgsChars 记录的调用参数最大字符数
+	maxToolCallArgsChars = 500
+)
+
+// ToolCall 表示一次工具调用的摘要
+type ToolCall struct {
+	Tool       string    `json:"tool"`
+	Session    string    `json:"session,omitempty"`
+	Arguments  string    `json:"arguments,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+var (
+	toolCallMu  sync.Mutex
+	toolCalls   = make([]ToolCall, 0, maxRecentToolCalls)
+	toolCallPos int
+)
+
+// RecordToolCall 记录一次工具调用，只在内存中保留最近的 maxRecentToolCalls 条
+func RecordToolCall(call ToolCall) {
+	if len(call.Arguments) > maxToolCallArgsChars {
+		call.Arguments = call.Arguments[:maxToolCallArgsChars] + "..."
+	}
+
+	toolCallMu.Lock()
+	defer toolCallMu.Unlock()
+	if len(toolCalls) < maxRecentToolCalls {
+		toolCalls = append(toolCalls, call)
+		return
+	}
+	toolCalls[toolCallPos] = call
+	toolCallPos = (toolCallPos + 1) % maxRecentToolCalls
+}
+
+// RecentToolCalls 返回最近的工具调用，按时间倒序
+func RecentToolCalls() []ToolCall {
+	toolCallMu.Lock()
+	defer toolCallMu.Unlock()
+
+	calls := make([]ToolCall, 0, len(toolCalls))
+	for i := len(toolCalls) - 1; i >= 0; i-- {
+		calls = append(calls, toolCalls[(toolCallPos+i)%len(toolCalls)])
+	}
+	return calls
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxTransactionsPerSession 单个会话同时打开的事务上限，避免占满连接池
+const maxTransactionsPerSession = 3
+
+// TransactionTimeout 事务空闲超过该时长未提交时自动回滚
+var TransactionTimeout = 5 * time.Minute
+
+// InitTransactionConfig 初始化事务配置
+func InitTransactionConfig(timeout time.Duration) {
+	if timeout > 0 {
+		TransactionTimeout = timeout
+	}
+}
+
+// openTransaction 表示一个通过 MCP 打开的事务
+type openTransaction struct {
+	id        string
+	session   string
+	startedAt time.Time
+
+	// mu 保证同一事务上的语句串行执行
+	mu    sync.Mutex
+	tx    *sql.Tx
+	timer *time.Timer
+}
+
+// transactions 按事务句柄保存打开的事务
+var transactions = struct {
+	sync.Mutex
+	m map[string]*openTransaction
+}{m: map[string]*openTransaction{}}
+
+// BeginTransaction 开启事务并返回事务句柄。事务属于当前会话，其他会话无法使用该句柄
+func BeginTransaction(ctx context.Context, db *sql.DB) (string, error) {
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+	if err := Breaker.Allow(); err != nil {
+		return "", err
+	}
+
+	session := sessionFromContext(ctx)
+	transactions.Lock()
+	open := 0
+	for _, t := range transactions.m {
+		if t.session == session {
+			open++
+		}
+	}
+	transactions.Unlock()
+	if open >= maxTransactionsPerSession {
+		return "", fmt.Errorf("当前会话已打开 %d 个事务，请先提交或回滚", open)
+	}
+
+	// 事务的生命周期跨越多次工具调用，不能绑定到本次请求的上下文
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return "", fmt.Errorf("开启事务失败: %w", err)
+	}
+
+	t := &openTransaction{id: newTransactionID(), session: session, startedAt: time.Now(), tx: tx}
+	t.timer = time.AfterFunc(TransactionTimeout, func() { expireTransaction(t.id) })
+
+	transactions.Lock()
+	transactions.m[t.id] = t
+	transactions.Unlock()
+	Logger.Infow("事务已开启", "transaction", t.id, "session", session)
+	return t.id, nil
+}
+
+// ExecuteInTransaction 在事务中执行语句，每次执行都会重新计算空闲超时
+func ExecuteInTransaction(ctx context.Context, id, query string, opts ExecOptions) (string, error) {
+	if opts.Database != "" {
+		return "", fmt.Errorf("事务中不能指定 database，请在语句中使用 database.table 限定表名")
+	}
+	t, err := lookupTransaction(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tx == nil {
+		return "", fmt.Errorf("事务 %s 已结束", id)
+	}
+	t.timer.Reset(TransactionTimeout)
+
+	return withBreaker(func() (string, error) {
+		res, err := runStatement(ctx, t.tx, query, opts)
+		if err != nil || opts.SampleRows <= 0 || !isQueryStatement(query) {
+			return res, err
+		}
+		return appendRowSample(ctx, t.tx, query, res, opts), nil
+	})
+}
+
+// CommitTransaction 提交事务
+func CommitTransaction(ctx context.Context, id string) error {
+	return finishTransaction(ctx, id, true)
+}
+
+// RollbackTransaction 回滚事务
+func RollbackTransaction(ctx context.Context, id string) error {
+	return finishTransaction(ctx, id, false)
+}
+
+// finishTransaction 提交或回滚事务并释放句柄
+func finishTransaction(ctx context.Context, id string, commit bool) error {
+	t, err := lookupTransaction(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tx == nil {
+		return fmt.Errorf("事务 %s 已结束", id)
+	}
+	removeTransaction(t)
+
+	if commit {
+		err = t.tx.Commit()
+	} else {
+		err = t.tx.Rollback()
+	}
+	t.tx = nil
+	if err != nil {
+		return fmt.Errorf("结束事务失败: %w", err)
+	}
+	Logger.Infow("事务已结束", "transaction", id, "commit", commit, "duration", time.Since(t.startedAt).Round(time.Millisecond))
+	return nil
+}
+
+// expireTransaction 回滚空闲超时的事务
+func expireTransaction(id string) {
+	transactions.Lock()
+	t, ok := transactions.m[id]
+	transactions.Unlock()
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tx == nil {
+		return
+	}
+	removeTransaction(t)
+	if err := t.tx.Rollback(); err != nil {
+		Logger.Warnw("回滚超时事务失败", "transaction", id, "error", err)
+	}
+	t.tx = nil
+	Logger.Warnw("事务空闲超时，已自动回滚", "transaction", id, "timeout", TransactionTimeout)
+}
+
+// lookupTransaction 查找当前会话的事务
+func lookupTransaction(ctx context.Context, id string) (*openTransaction, error) {
+	transactions.Lock()
+	t, ok := transactions.m[id]
+	transactions.Unlock()
+	if !ok || t.session != sessionFromContext(ctx) {
+		return nil, fmt.Errorf("事务不存在或已超时回滚: %s", id)
+	}
+	return t, nil
+}
+
+// removeTransaction 从登记表中移除事务并停止超时计时
+func removeTransaction(t *openTransaction) {
+	t.timer.Stop()
+	transactions.Lock()
+	delete(transactions.m, t.id)
+	transactions.Unlock()
+}
+
+// newTransactionID 生成随机的事务句柄
+func newTransactionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "tx_" + hex.EncodeToString(b)
+}
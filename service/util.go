@@ -29,15 +29,46 @@ type EmbeddingResponse struct {
 	} `json:"data"`
 }
 
-// EmbedQuery 将查询文本转换为向量嵌入
+var (
+	embeddingOnce  sync.Once
+	embeddingURL   string
+	embeddingToken string
+	embeddingErr   error
+)
+
+// embeddingEndpoint 在首次使用时读取并校验嵌入接口配置，之后复用同一结果
+func embeddingEndpoint() (string, string, error) {
+	embeddingOnce.Do(func() {
+		embeddingURL = os.Getenv("SILICONFLOW_URL")
+		embeddingToken = os.Getenv("SILICONFLOW_TOKEN")
+		if embeddingURL == "" || embeddingToken == "" {
+			embeddingErr = fmt.Errorf("SiliconFlow配置不完整：请设置 SILICONFLOW_URL 和 SILICONFLOW_TOKEN")
+		}
+	})
+	return embeddingURL, embeddingToken, embeddingErr
+}
+
+// SetEmbeddingEndpoint 直接指定嵌入接口地址和令牌，不再读取 SILICONFLOW_URL 和 SILICONFLOW_TOKEN 环境变量。
+// 需要在首次向量化之前调用，以库的方式使用时通过它传入配置
+func SetEmbeddingEndpoint(url, token string) {
+	embeddingOnce.Do(func() {
+		embeddingURL, embeddingToken = url, token
+		if url == "" || token == "" {
+			embeddingErr = fmt.Errorf("嵌入接口配置不完整：需要同时提供地址和令牌（SILICONFLOW_URL 和 SILICONFLOW_TOKEN）")
+		}
+	})
+}
+
+// EmbedQuery 使用主嵌入模型将查询文本转换为向量嵌入
 func EmbedQuery(query string) ([]float32, error) {
-	// 从main包获取配置
-	sfURL := os.Getenv("SILICONFLOW_URL")
-	sfToken := os.Getenv("SILICONFLOW_TOKEN")
+	return embedWithModel(Embedding.Model, query)
+}
 
-	// 验证配置
-	if sfURL == "" || sfToken == "" {
-		return nil, fmt.Errorf("SiliconFlow配置不完整")
+// embedWithModel 调用嵌入接口，使用指定模型将文本转换为向量嵌入
+func embedWithModel(model, query string) ([]float32, error) {
+	sfURL, sfToken, err := embeddingEndpoint()
+	if err != nil {
+		return nil, err
 	}
 
 	// 创建带超时的上下文
@@ -46,7 +77,7 @@ func EmbedQuery(query string) ([]float32, error) {
 
 	// 使用结构体构建请求参数
 	requestBody := EmbeddingRequest{
-		Model:          "BAAI/bge-m3",
+		Model:          model,
 		Input:          query,
 		EncodingFormat: "float",
 	}
@@ -115,49 +146,69 @@ func EmbedQuery(query string) ([]float32, error) {
 	return embeddings, nil
 }
 
-// UpdateSchema 定时更新数据库表结构
-func UpdateSchema(db *sql.DB, cli *milvusclient.Client) {
-	// 创建定时器，每隔一段时间执行一次更新
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	var updateMutex sync.Mutex
+// UpdateSchema 定时更新数据库表结构。每轮间隔带有随机抖动，配置了咨询锁时
+// 多个实例中只有拿到锁的一个执行本轮更新。client 每轮取一次，重新连接 Milvus 后使用新的客户端
+func UpdateSchema(db *sql.DB, client func() *milvusclient.Client) {
+	timer := time.NewTimer(Scheduler.nextDelay())
+	defer timer.Stop()
 
 	// 定时执行
-	for range ticker.C {
-		// 尝试获取锁，如果已经在执行则跳过本次更新
-		if !updateMutex.TryLock() {
-			Logger.Warn("上一次更新任务仍在进行中，跳过本次更新")
-			continue
-		}
-		defer updateMutex.Unlock()
-		tableCh := make(chan map[string]string, 10)
-		GetAllTableSchema(context.Background(), db, tableCh)
-
-		for tableMap := range tableCh {
-			for tableName, schema := range tableMap {
-				notExistTables := CheckRowExist([]string{tableName})
-				if len(notExistTables) > 0 {
-					// 执行更新操作
-					_, err := SaveToSQLite(notExistTables)
-					if err != nil {
-						Logger.Errorw("数据保存失败", "error", err)
-						continue
-					}
-					vectors, err := EmbedQuery(schema)
-					if err != nil {
-						Logger.Errorw("向量嵌入失败", "error", err)
-						return
-					}
-
-					err = SaveToVDB(context.Background(), cli, []string{schema}, [][]float32{vectors})
-					if err != nil {
-						Logger.Errorw("保存向量失败", "error", err)
-					}
+	for range timer.C {
+		runScheduledUpdate(db, client())
+		timer.Reset(Scheduler.nextDelay())
+	}
+}
+
+// runScheduledUpdate 执行一轮增量索引
+func runScheduledUpdate(db *sql.DB, cli *milvusclient.Client) {
+	// 只读消费者不更新共享的向量集合
+	if !IsIndexWriter() {
+		Logger.Debug("未持有索引租约，跳过本次更新")
+		return
+	}
+	// 尝试获取锁，如果已有更新或全量重建在执行则跳过本次更新
+	if !indexMutex.TryLock() {
+		Logger.Warn("上一次索引任务仍在进行中，跳过本次更新")
+		return
+	}
+	defer indexMutex.Unlock()
+
+	if Scheduler.LockName == "" {
+		updateNewTables(db, cli)
+		return
+	}
+	acquired, err := withAdvisoryLock(context.Background(), db, Scheduler.LockName, func() {
+		updateNewTables(db, cli)
+	})
+	if err != nil {
+		Logger.Warnw("获取索引咨询锁失败，跳过本次更新", "lock", Scheduler.LockName, "error", err)
+		return
+	}
+	if !acquired {
+		Logger.Infow("其他实例正在更新索引，跳过本次更新", "lock", Scheduler.LockName)
+	}
+}
+
+// updateNewTables 将尚未登记在 SQLite 中的表结构向量化
+func updateNewTables(db *sql.DB, cli *milvusclient.Client) {
+	forgotten, err := ListForgottenTables()
+	if err != nil {
+		Logger.Errorw("获取被移出索引的表失败", "error", err)
+		return
+	}
+	purgeHiddenTables(context.Background(), cli)
+
+	tableCh := make(chan map[string]string, 10)
+	go streamTableSchemas(context.Background(), db, tableCh, schemaBudget)
+
+	for tableMap := range tableCh {
+		for tableName, schema := range tableMap {
+			if !forgotten[tableName] && len(CheckRowExist([]string{tableName})) > 0 {
+				if err := IndexTableSchema(context.Background(), cli, tableName, schema); err != nil {
+					Logger.Errorw("表结构索引失败", "table", tableName, "error", err)
 				}
 			}
-
+			schemaBudget.release(int64(len(schema)))
 		}
-
 	}
 }
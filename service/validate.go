@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/index"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+)
+
+// expectedField 描述表结构集合中一个字段的预期定义
+type expectedField struct {
+	name     string
+	dataType entity.FieldType
+	dim      int64 // 仅向量字段需要校验维度
+}
+
+// expectedSchemaFields 根据当前配置返回表结构集合应有的字段
+func expectedSchemaFields() []expectedField {
+	fields := []expectedField{
+		{name: "my_id", dataType: entity.FieldTypeInt64},
+		{name: primaryVectorField, dataType: vectorFieldType(), dim: dim},
+		{name: "schema", dataType: entity.FieldTypeVarChar},
+	}
+	if SecondaryEmbeddingEnabled() {
+		fields = append(fields, expectedField{
+			name:     secondaryVectorField(),
+			dataType: vectorFieldType(),
+			dim:      int64(Embedding.SecondaryDim),
+		})
+	}
+	return fields
+}
+
+// ValidateCollectionSchema 校验已存在的表结构集合与当前配置是否一致，
+// 逐项返回字段名、类型、维度及索引度量方式的不一致之处；返回空列表表示一致
+func ValidateCollectionSchema(ctx context.Context, cli *milvusclient.Client) ([]string, error) {
+	collection, err := cli.DescribeCollection(ctx, milvusclient.NewDescribeCollectionOption(Config.CollectionName))
+	if err != nil {
+		return nil, fmt.Errorf("获取集合 %s 的定义失败: %w", Config.CollectionName, err)
+	}
+
+	actual := make(map[string]*entity.Field)
+	if collection.Schema != nil {
+		for _, field := range collection.Schema.Fields {
+			actual[field.Name] = field
+		}
+	}
+
+	var mismatches []string
+	expected := make(map[string]bool)
+	for _, want := range expectedSchemaFields() {
+		expected[want.name] = true
+		got, ok := actual[want.name]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("缺少字段 %s（期望类型 %s）", want.name, want.dataType.Name()))
+			continue
+		}
+		if got.DataType != want.dataType {
+			mismatches = append(mismatches, fmt.Sprintf("字段 %s 的类型为 %s，期望 %s",
+				want.name, got.DataType.Name(), want.dataType.Name()))
+			continue
+		}
+		if want.dim == 0 {
+			continue
+		}
+		if gotDim, err := got.GetDim(); err != nil || gotDim != want.dim {
+			mismatches = append(mismatches, fmt.Sprintf("字段 %s 的维度为 %d，期望 %d", want.name, gotDim, want.dim))
+			continue
+		}
+		desc, err := cli.DescribeIndex(ctx, milvusclient.NewDescribeIndexOption(Config.CollectionName, want.name))
+		if err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("字段 %s 没有可用的向量索引: %v", want.name, err))
+			continue
+		}
+		if metric := desc.Params()[index.MetricTypeKey]; metric != string(entity.COSINE) {
+			mismatches = append(mismatches, fmt.Sprintf("字段 %s 的索引度量方式为 %s，期望 %s", want.name, metric, entity.COSINE))
+		}
+	}
+
+	// 多出的向量字段在写入时也会因缺列而失败
+	if collection.Schema != nil {
+		for _, field := range collection.Schema.Fields {
+			if !expected[field.Name] && isDenseVectorType(field.DataType) {
+				mismatches = append(mismatches, fmt.Sprintf("存在未配置的向量字段 %s", field.Name))
+			}
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ServerVersion 为 MySQL 服务端版本
+type ServerVersion struct {
+	Major, Minor, Patch int
+	MariaDB             bool
+	Raw                 string
+}
+
+// String 返回版本号文本
+func (v ServerVersion) String() string {
+	if v.MariaDB {
+		return fmt.Sprintf("%d.%d.%d-MariaDB", v.Major, v.Minor, v.Patch)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// atLeast 判断版本是否不低于给定版本
+func (v ServerVersion) atLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// ParseServerVersion 解析 VERSION() 返回的版本文本，如 5.7.44-log、8.0.36、10.6.16-MariaDB
+func ParseServerVersion(raw string) (ServerVersion, error) {
+	v := ServerVersion{Raw: raw, MariaDB: strings.Contains(strings.ToLower(raw), "mariadb")}
+	number := raw
+	if i := strings.IndexFunc(number, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		number = number[:i]
+	}
+	parts := strings.Split(number, ".")
+	if len(parts) < 2 {
+		return v, fmt.Errorf("无法解析服务端版本: %s", raw)
+	}
+	nums := make([]int, 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return v, fmt.Errorf("无法解析服务端版本: %s", raw)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+var (
+	// 当前服务端版本，为 nil 时视为支持所有特性；reload_connections 会在工具调用进行中重新检测，读写需持有 versionMu
+	serverVersion *ServerVersion
+	versionMu     sync.RWMutex
+)
+
+// currentServerVersion 返回检测到的服务端版本，未检测时为 nil
+func currentServerVersion() *ServerVersion {
+	versionMu.RLock()
+	defer versionMu.RUnlock()
+	return serverVersion
+}
+
+// InitServerVersion 检测服务端版本，用于在旧版本（5.6/5.7、MariaDB）上降级不支持的特性。
+// override 非空时不检测，直接按给定版本处理（兼容模式），适用于 VERSION() 被代理改写的场景
+func InitServerVersion(ctx context.Context, db *sql.DB, override string) (*ServerVersion, error) {
+	raw := override
+	if raw == "" {
+		if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&raw); err != nil {
+			return nil, fmt.Errorf("查询服务端版本失败: %v", err)
+		}
+	}
+	v, err := ParseServerVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	versionMu.Lock()
+	serverVersion = &v
+	versionMu.Unlock()
+	return &v, nil
+}
+
+// serverFeature 描述依赖服务端版本的特性
+type serverFeature struct {
+	name       string
+	minimum    [3]int
+	mariaDB    bool // MariaDB 是否支持
+	suggestion string
+}
+
+var (
+	featureExplainTree = serverFeature{"EXPLAIN FORMAT=TREE", [3]int{8, 0, 16}, false,
+		"请使用 json 或 traditional 格式"}
+	featureExplainForConnection = serverFeature{"EXPLAIN FOR CONNECTION", [3]int{5, 7, 2}, false,
+		"只能查看语句文本"}
+	featureJSON             = serverFeature{"JSON 数据类型", [3]int{5, 7, 8}, true, ""}
+	featureMaxExecutionTime = serverFeature{"MAX_EXECUTION_TIME 优化器提示", [3]int{5, 7, 8}, false, ""}
+	featureHistogram        = serverFeature{"列直方图统计（COLUMN_STATISTICS）", [3]int{8, 0, 3}, false, ""}
+)
+
+// requireFeature 服务端版本不支持该特性时返回说明性的错误，版本未知时不做限制
+func requireFeature(f serverFeature) error {
+	v := currentServerVersion()
+	if v == nil {
+		return nil
+	}
+	supported := v.atLeast(f.minimum[0], f.minimum[1], f.minimum[2])
+	if v.MariaDB {
+		supported = f.mariaDB
+	}
+	if supported {
+		return nil
+	}
+	msg := fmt.Sprintf("服务端版本 %s 不支持 %s（需要 MySQL %d.%d.%d 及以上）",
+		v, f.name, f.minimum[0], f.minimum[1], f.minimum[2])
+	if f.suggestion != "" {
+		msg += "，" + f.suggestion
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// ServerVersionString 返回检测到的服务端版本，未检测时为空
+func ServerVersionString() string {
+	v := currentServerVersion()
+	if v == nil {
+		return ""
+	}
+	return v.Raw
+}
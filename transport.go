@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"mcp-mysql/service"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// adminTools 为开启访问控制后只有 admin 权限的角色才能调用的工具
+var adminTools = map[string]bool{
+	"forget_table":         true,
+	"set_table_ranking":    true,
+	"reload_connections":   true,
+	"verify_backups":       true,
+	"kill_query":           true,
+	"reindex_schemas":      true,
+	"compact_vector_index": true,
+}
+
+// serveMCP 按配置的传输方式启动 MCP 服务
+func serveMCP(s *server.MCPServer) error {
+	if Config.Transport.Type != "sse" {
+		return server.ServeStdio(s)
+	}
+
+	baseURL := Config.Transport.BaseURL
+	if baseURL == "" {
+		baseURL = "http://localhost" + Config.Transport.Addr
+		if !strings.HasPrefix(Config.Transport.Addr, ":") {
+			baseURL = "http://" + Config.Transport.Addr
+		}
+	}
+	sse := server.NewSSEServer(s, server.WithBaseURL(baseURL))
+	if !service.AccessControlEnabled() {
+		logger.Warnw("SSE 传输未配置 ACCESS_CONTROL_FILE，任何能访问该地址的客户端都拥有全部权限", "addr", Config.Transport.Addr)
+	}
+	logger.Infow("SSE 传输监听", "addr", Config.Transport.Addr, "base_url", baseURL)
+	return http.ListenAndServe(Config.Transport.Addr, authenticateClient(sse))
+}
+
+// authenticateClient 开启访问控制时按 Authorization: Bearer 令牌识别客户端，并把身份记录在请求上下文中，
+// 之后该请求中的工具调用都按客户端的角色检查权限；无法识别的客户端返回 401
+func authenticateClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !service.AccessControlEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		identity, ok := service.AuthenticateToken(strings.TrimSpace(token))
+		if !ok {
+			logger.Warnw("拒绝未识别的客户端", "remote", r.RemoteAddr, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-mysql"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(service.WithClientIdentity(r.Context(), identity)))
+	})
+}
+
+// authorizeTool 在调用前按客户端身份对应的角色检查是否可以调用该工具
+func authorizeTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := service.CheckToolAccess(ctx, name, adminTools[name]); err != nil {
+			logger.Warnw("客户端角色不允许调用工具", "tool", name, "client", service.ClientIdentityFromContext(ctx), "error", err)
+			return nil, err
+		}
+		return handler(ctx, request)
+	}
+}
+
+// validateTransport 检查传输方式配置
+func validateTransport() error {
+	switch Config.Transport.Type {
+	case "stdio", "sse":
+		return nil
+	}
+	return fmt.Errorf("不支持的传输方式: %s，可选 stdio、sse", Config.Transport.Type)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcp-mysql/service"
+)
+
+// configItem 调试页面展示的一项配置
+type configItem struct {
+	Name  string
+	Value string
+}
+
+// webUIPage 调试页面的渲染数据
+type webUIPage struct {
+	Config      []configItem
+	Tables      []service.IndexedTable
+	TablesError string
+	ToolCalls   []service.ToolCall
+	History     []service.HistoryEntry
+	HistoryErr  string
+	CanRerun    bool
+	Rerun       *webUIRerun
+}
+
+// webUIRerun 重新执行历史查询的结果
+type webUIRerun struct {
+	ID     int64
+	Query  string
+	Result string
+	Error  string
+}
+
+var webUITemplate = template.Must(template.New("webui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>mcp-mysql</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+table { border-collapse: collapse; margin-bottom: 24px; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; font-size: 13px; }
+th { background: #f0f0f0; }
+pre { white-space: pre-wrap; margin: 0; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>mcp-mysql</h1>
+
+{{with .Rerun}}
+<h2>重新执行 #{{.ID}}</h2>
+<pre>{{.Query}}</pre>
+{{if .Error}}<p class="error">{{.Error}}</p>{{else}}<pre>{{.Result}}</pre>{{end}}
+{{end}}
+
+<h2>当前配置</h2>
+<table>
+{{range .Config}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>已索引的表（{{len .Tables}}）</h2>
+{{if .TablesError}}<p class="error">{{.TablesError}}</p>{{end}}
+<table>
+<tr><th>表名</th><th>向量ID</th><th>索引时间</th></tr>
+{{range .Tables}}<tr><td>{{.TableName}}</td><td>{{.VectorID}}</td><td>{{.EmbeddedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+{{end}}</table>
+
+<h2>最近的工具调用</h2>
+<table>
+<tr><th>时间</th><th>工具</th><th>会话</th><th>参数</th><th>耗时(ms)</th><th>错误</th></tr>
+{{range .ToolCalls}}<tr><td>{{.At.Format "2006-01-02 15:04:05"}}</td><td>{{.Tool}}</td><td>{{.Session}}</td><td><pre>{{.Arguments}}</pre></td><td>{{.DurationMs}}</td><td class="error">{{.Error}}</td></tr>
+{{end}}</table>
+
+<h2>查询历史</h2>
+{{if .HistoryErr}}<p class="error">{{.HistoryErr}}</p>{{end}}
+<table>
+<tr><th>ID</th><th>时间</th><th>SQL</th><th>成功</th><th>耗时(ms)</th><th>备注</th>{{if .CanRerun}}<th></th>{{end}}</tr>
+{{range .History}}<tr><td>{{.ID}}</td><td>{{.CreatedAt.Format "2006-01-02 15:04:05"}}</td><td><pre>{{.Query}}</pre>{{if .Error}}<p class="error">{{.Error}}</p>{{end}}</td><td>{{.Success}}</td><td>{{.DurationMs}}</td><td>{{.Note}}</td>{{if $.CanRerun}}<td><form method="post" action="/rerun"><input type="hidden" name="id" value="{{.ID}}"><button type="submit">重新执行</button></form></td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// webUICookie 为保存调试页面令牌的 cookie
+const webUICookie = "mcp_mysql_debug"
+
+// startWebUI 在调试监听地址上提供检查页面，展示配置、已索引的表、最近的工具调用和查询历史。
+// 访问需要令牌：首次访问带 ?token=<令牌>，之后由 cookie 保持；token 为空时随机生成并写入日志
+func startWebUI(addr, token string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		renderWebUI(w, nil)
+	})
+	mux.HandleFunc("/rerun", handleWebUIRerun)
+
+	if token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			logger.Errorw("生成调试页面令牌失败，不启动调试页面", "error", err)
+			return
+		}
+		token = hex.EncodeToString(buf)
+	}
+	addr = webUIListenAddr(addr)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           requireWebUIToken(token, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if Config.Debug.Token == "" {
+			logger.Infow("调试页面已启动", "addr", addr, "url", "http://"+addr+"/?token="+token)
+		} else {
+			logger.Infow("调试页面已启动", "addr", addr)
+		}
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("调试页面监听失败", "addr", addr, "error", err)
+		}
+	}()
+}
+
+// webUIListenAddr 只给出端口（如 :8090）时只监听本机地址
+func webUIListenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		if ip := net.ParseIP(host); err == nil && (ip == nil || !ip.IsLoopback()) && host != "localhost" {
+			logger.Warnw("调试页面监听的不是本机地址，请确认令牌没有泄露", "addr", addr)
+		}
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// requireWebUIToken 检查请求携带的令牌（?token=、cookie 或 Authorization: Bearer），
+// 浏览器发起的 POST 还需要 Origin（或 Referer）与页面同源，防止其他网站跨站提交
+func requireWebUIToken(token string, next http.Handler) http.Handler {
+	valid := func(value string) bool {
+		return value != "" && subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if valid(r.URL.Query().Get("token")) && r.Method == http.MethodGet {
+			http.SetCookie(w, &http.Cookie{Name: webUICookie, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+			// 去掉地址中的令牌，避免留在浏览器历史中
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+			return
+		}
+		bearer, hasBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		cookie, _ := r.Cookie(webUICookie)
+		switch {
+		case hasBearer && valid(bearer):
+			// 命令行等非浏览器客户端，不受跨站提交影响
+		case cookie != nil && valid(cookie.Value):
+			if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+				logger.Warnw("拒绝跨站提交到调试页面", "remote", r.RemoteAddr, "origin", r.Header.Get("Origin"))
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		default:
+			http.Error(w, "unauthorized: open the URL with ?token=<DEBUG_TOKEN>", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin 判断请求的 Origin（没有时用 Referer）是否与请求的 Host 一致，两者都没有时视为跨站
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(origin)
+	return err == nil && origin != "" && u.Host == r.Host
+}
+
+// handleWebUIRerun 重新执行一条历史查询，结果显示在页面顶部并记录为新的查询历史
+func handleWebUIRerun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(service.WithStatementLabel(r.Context(), "webui", "rerun"), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	entry, res, err := service.RerunQueryHistory(ctx, db, id)
+	rerun := &webUIRerun{ID: id, Result: res}
+	if entry != nil {
+		rerun.Query = entry.Query
+		recordHistory(entry.Query, time.Since(start), err)
+	}
+	if err != nil {
+		logger.Errorw("重新执行历史查询失败", "history_id", id, "error", err)
+		rerun.Error = err.Error()
+	}
+	renderWebUI(w, rerun)
+}
+
+// renderWebUI 收集页面数据并渲染
+func renderWebUI(w http.ResponseWriter, rerun *webUIRerun) {
+	page := webUIPage{
+		Config:    webUIConfig(),
+		ToolCalls: service.RecentToolCalls(),
+		CanRerun:  !service.Templates.Strict,
+		Rerun:     rerun,
+	}
+	var err error
+	if page.Tables, err = service.ListIndexedTables(); err != nil {
+		page.TablesError = err.Error()
+	}
+	if page.History, err = service.ListQueryHistory("", "", 50); err != nil {
+		page.HistoryErr = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err = webUITemplate.Execute(w, page); err != nil {
+		logger.Errorw("渲染调试页面失败", "error", err)
+	}
+}
+
+// webUIConfig 返回页面展示的配置，密码和令牌只显示是否已设置
+func webUIConfig() []configItem {
+	configured := func(v string) string {
+		if v == "" {
+			return "未设置"
+		}
+		return "已设置"
+	}
+	conn := connectionConfig()
+	return []configItem{
+		{"MySQL", fmt.Sprintf("%s@%s:%s/%s", conn.DB.User, conn.DB.Host, conn.DB.Port, conn.DB.Name)},
+		{"MySQL 密码", configured(conn.DB.Password)},
+		{"服务端版本", service.ServerVersionString()},
+		{"连接名称", Config.DB.ConnectionName},
+		{"执行策略文件", Config.DB.PolicyFile},
+		{"Milvus", fmt.Sprintf("%s:%s/%s", conn.Milvus.Host, conn.Milvus.Port, conn.Milvus.Collection)},
+		{"嵌入模型", Config.Embedding.Model},
+		{"嵌入接口令牌", configured(Config.SiliconFlow.Token)},
+		{"LLM 模型", Config.LLM.Model},
+		{"重排序服务", Config.Discovery.RerankURL},
+		{"查询模板文件", Config.Templates.File},
+		{"模板严格模式", strconv.FormatBool(Config.Templates.Strict)},
+		{"管理类工具", strconv.FormatBool(Config.Admin.Enabled)},
+		{"kill_query", strconv.FormatBool(Config.Admin.KillQuery)},
+		{"传输方式", Config.Transport.Type},
+		{"访问控制文件", Config.Transport.AccessFile},
+		{"危险语句审批", Config.Approval.URL},
+		{"增量索引间隔", Config.Scheduler.Interval.String()},
+		{"索引租约", service.IndexLeaseStatus()},
+		{"查询历史导出", Config.HistoryExport.Type},
+	}
+}